@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 // Interactive 这个是总体交互的计数
 type Interactive struct {
 	BizId      int64 `json:"biz_id"`
@@ -11,3 +13,12 @@ type Interactive struct {
 	Liked     bool `json:"liked"`
 	Collected bool `json:"collected"`
 }
+
+// UserBiz 用户点赞或者收藏过的资源，导出用户数据的时候用
+type UserBiz struct {
+	Biz   string `json:"biz"`
+	BizId int64  `json:"biz_id"`
+	// Cid 收藏夹，点赞的没有
+	Cid   int64     `json:"cid,omitempty"`
+	Ctime time.Time `json:"ctime"`
+}
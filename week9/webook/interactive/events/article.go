@@ -14,7 +14,10 @@ const topicReadEvent = "article_read_event"
 
 type ReadEvent struct {
 	Aid int64
+	// Uid 匿名用户是 0
 	Uid int64
+	// Anonymous 没有登录的用户，阅读数照样算，但是不能当成 uid 为 0 的用户处理
+	Anonymous bool
 }
 
 var _ events.Consumer = &InteractiveReadEventConsumer{}
@@ -88,7 +91,7 @@ func (r *InteractiveReadEventConsumer) BatchConsume(msgs []*sarama.ConsumerMessa
 	ids := make([]int64, 0, len(msgs))
 	for _, evt := range evts {
 		bizs = append(bizs, "article")
-		ids = append(ids, evt.Uid)
+		ids = append(ids, evt.Aid)
 	}
 	return r.repo.BatchIncrReadCnt(ctx, bizs, ids)
 }
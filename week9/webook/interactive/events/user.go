@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/interactive/repository"
+	"gitee.com/geekbang/basic-go/webook/internal/events"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/saramax"
+	"github.com/IBM/sarama"
+	"time"
+)
+
+const topicUserDeletedEvent = "user_deleted_event"
+
+// UserDeletedEvent 和用户模块发出来的保持一致
+type UserDeletedEvent struct {
+	Uid int64
+}
+
+var _ events.Consumer = &UserDeletedEventConsumer{}
+
+// UserDeletedEventConsumer 用户注销之后，删除他的点赞和收藏
+type UserDeletedEventConsumer struct {
+	client sarama.Client
+	repo   repository.InteractiveRepository
+	l      logger.LoggerV1
+}
+
+func NewUserDeletedEventConsumer(
+	client sarama.Client,
+	l logger.LoggerV1,
+	repo repository.InteractiveRepository) *UserDeletedEventConsumer {
+	return &UserDeletedEventConsumer{
+		client: client,
+		repo:   repo,
+		l:      l,
+	}
+}
+
+func (c *UserDeletedEventConsumer) Start() error {
+	cg, err := sarama.NewConsumerGroupFromClient("interactive_user_deleted",
+		c.client)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := cg.Consume(context.Background(),
+			[]string{topicUserDeletedEvent},
+			saramax.NewHandler[UserDeletedEvent](c.l, c.Consume))
+		if err != nil {
+			c.l.Error("退出了消费循环异常", logger.Error(err))
+		}
+	}()
+	return err
+}
+
+func (c *UserDeletedEventConsumer) Consume(msg *sarama.ConsumerMessage,
+	evt UserDeletedEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	return c.repo.DeleteByUid(ctx, evt.Uid)
+}
@@ -18,6 +18,11 @@ type InteractiveDAO interface {
 	GetCollectionInfo(ctx context.Context, biz string, bizId, uid int64) (UserCollectionBiz, error)
 	BatchIncrReadCnt(ctx context.Context, bizs []string, ids []int64) error
 	GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error)
+	// FindLikesByUid 用户所有有效的点赞
+	FindLikesByUid(ctx context.Context, uid int64) ([]UserLikeBiz, error)
+	FindCollectionsByUid(ctx context.Context, uid int64) ([]UserCollectionBiz, error)
+	// DeleteByUid 删除用户的点赞、收藏和收藏夹，同时扣减对应的计数
+	DeleteByUid(ctx context.Context, uid int64) error
 }
 
 type GORMInteractiveDAO struct {
@@ -26,7 +31,7 @@ type GORMInteractiveDAO struct {
 
 func (dao *GORMInteractiveDAO) GetByIds(ctx context.Context, biz string, ids []int64) ([]Interactive, error) {
 	var res []Interactive
-	err := dao.db.WithContext(ctx).Where("biz = ? AND id IN ?", biz, ids).Find(&res).Error
+	err := dao.db.WithContext(ctx).Where("biz = ? AND biz_id IN ?", biz, ids).Find(&res).Error
 	return res, err
 }
 
@@ -181,6 +186,69 @@ func (dao *GORMInteractiveDAO) Get(ctx context.Context, biz string, bizId int64)
 	return res, err
 }
 
+func (dao *GORMInteractiveDAO) FindLikesByUid(ctx context.Context, uid int64) ([]UserLikeBiz, error) {
+	var res []UserLikeBiz
+	err := dao.db.WithContext(ctx).
+		Where("uid = ? AND status = ?", uid, 1).
+		Order("id").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) FindCollectionsByUid(ctx context.Context, uid int64) ([]UserCollectionBiz, error) {
+	var res []UserCollectionBiz
+	err := dao.db.WithContext(ctx).
+		Where("uid = ?", uid).
+		Order("id").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMInteractiveDAO) DeleteByUid(ctx context.Context, uid int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var likes []UserLikeBiz
+		err := tx.Where("uid = ? AND status = ?", uid, 1).Find(&likes).Error
+		if err != nil {
+			return err
+		}
+		for _, l := range likes {
+			err = tx.Model(&Interactive{}).
+				Where("biz = ? AND biz_id = ? AND like_cnt > 0", l.Biz, l.BizId).
+				Updates(map[string]any{
+					"like_cnt": gorm.Expr("`like_cnt`-1"),
+					"utime":    now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		var cbs []UserCollectionBiz
+		err = tx.Where("uid = ?", uid).Find(&cbs).Error
+		if err != nil {
+			return err
+		}
+		for _, cb := range cbs {
+			err = tx.Model(&Interactive{}).
+				Where("biz = ? AND biz_id = ? AND collect_cnt > 0", cb.Biz, cb.BizId).
+				Updates(map[string]any{
+					"collect_cnt": gorm.Expr("`collect_cnt`-1"),
+					"utime":       now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		err = tx.Where("uid = ?", uid).Delete(&UserLikeBiz{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("uid = ?", uid).Delete(&UserCollectionBiz{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("uid = ?", uid).Delete(&Collection{}).Error
+	})
+}
+
 // 正常来说，一张主表和与它有关联关系的表会共用一个DAO，
 // 所以我们就用一个 DAO 来操作
 
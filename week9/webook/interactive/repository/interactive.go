@@ -8,6 +8,7 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
 	"github.com/ecodeclub/ekit/slice"
+	"time"
 )
 
 //go:generate mockgen -source=./interactive.go -package=repomocks -destination=mocks/interactive.mock.go InteractiveRepository
@@ -23,6 +24,11 @@ type InteractiveRepository interface {
 	Liked(ctx context.Context, biz string, id int64, uid int64) (bool, error)
 	Collected(ctx context.Context, biz string, id int64, uid int64) (bool, error)
 	GetByIds(ctx context.Context, biz string, ids []int64) ([]domain.Interactive, error)
+	Likes(ctx context.Context, uid int64) ([]domain.UserBiz, error)
+	Collections(ctx context.Context, uid int64) ([]domain.UserBiz, error)
+	// DeleteByUid 用户注销之后删除他的点赞和收藏，
+	// 缓存里面的计数没有处理，等过期就好了
+	DeleteByUid(ctx context.Context, uid int64) error
 }
 
 type CachedReadCntRepository struct {
@@ -42,6 +48,39 @@ func (c *CachedReadCntRepository) GetByIds(ctx context.Context, biz string, ids
 		}), nil
 }
 
+func (c *CachedReadCntRepository) Likes(ctx context.Context, uid int64) ([]domain.UserBiz, error) {
+	likes, err := c.dao.FindLikesByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(likes, func(idx int, src dao2.UserLikeBiz) domain.UserBiz {
+		return domain.UserBiz{
+			Biz:   src.Biz,
+			BizId: src.BizId,
+			Ctime: time.UnixMilli(src.Ctime),
+		}
+	}), nil
+}
+
+func (c *CachedReadCntRepository) Collections(ctx context.Context, uid int64) ([]domain.UserBiz, error) {
+	cbs, err := c.dao.FindCollectionsByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(cbs, func(idx int, src dao2.UserCollectionBiz) domain.UserBiz {
+		return domain.UserBiz{
+			Biz:   src.Biz,
+			BizId: src.BizId,
+			Cid:   src.Cid,
+			Ctime: time.UnixMilli(src.Ctime),
+		}
+	}), nil
+}
+
+func (c *CachedReadCntRepository) DeleteByUid(ctx context.Context, uid int64) error {
+	return c.dao.DeleteByUid(ctx, uid)
+}
+
 func (c *CachedReadCntRepository) Liked(ctx context.Context, biz string, id int64, uid int64) (bool, error) {
 	_, err := c.dao.GetLikeInfo(ctx, biz, id, uid)
 	switch err {
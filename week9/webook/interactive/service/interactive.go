@@ -19,6 +19,10 @@ type InteractiveService interface {
 	Collect(ctx context.Context, biz string, bizId, cid, uid int64) error
 	Get(ctx context.Context, biz string, bizId, uid int64) (domain.Interactive, error)
 	GetByIds(ctx context.Context, biz string, bizIds []int64) (map[int64]domain.Interactive, error)
+	// Likes 用户所有的点赞
+	Likes(ctx context.Context, uid int64) ([]domain.UserBiz, error)
+	// Collections 用户所有的收藏
+	Collections(ctx context.Context, uid int64) ([]domain.UserBiz, error)
 }
 
 type interactiveService struct {
@@ -39,6 +43,14 @@ func (i *interactiveService) GetByIds(ctx context.Context, biz string,
 	return res, nil
 }
 
+func (i *interactiveService) Likes(ctx context.Context, uid int64) ([]domain.UserBiz, error) {
+	return i.repo.Likes(ctx, uid)
+}
+
+func (i *interactiveService) Collections(ctx context.Context, uid int64) ([]domain.UserBiz, error) {
+	return i.repo.Collections(ctx, uid)
+}
+
 func (i *interactiveService) IncrReadCnt(ctx context.Context, biz string, bizId int64) error {
 	return i.repo.IncrReadCnt(ctx, biz, bizId)
 }
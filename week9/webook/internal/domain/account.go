@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// AccountMergeLog 账号合并的审计记录。被合并的账号已经删除了，只能从这里查到
+type AccountMergeLog struct {
+	Id int64
+	// SourceUid 被合并的账号
+	SourceUid int64
+	// TargetUid 保留下来的账号
+	TargetUid int64
+	// 被合并的账号原本的手机号码和邮箱
+	SourcePhone string
+	SourceEmail string
+
+	// 迁移了多少数据
+	Articles    int64
+	Likes       int64
+	Collections int64
+	Bindings    int64
+	Ctime       time.Time
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type AsyncSms struct {
 	Id      int64
 	TplId   string
@@ -7,4 +9,40 @@ type AsyncSms struct {
 	Numbers []string
 	// 重试的配置
 	RetryMax int
+
+	// 下面这些字段只有在查询的时候才有意义
+	RetryCnt int
+	Status   AsyncSmsStatus
+	// 下一次可以发送的时间
+	NextTime time.Time
+	Ctime    time.Time
+	Utime    time.Time
+}
+
+type AsyncSmsStatus uint8
+
+func (s AsyncSmsStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s AsyncSmsStatus) String() string {
+	switch s {
+	case AsyncSmsStatusWaiting:
+		return "waiting"
+	case AsyncSmsStatusFailed:
+		return "failed"
+	case AsyncSmsStatusSuccess:
+		return "success"
+	default:
+		return "unknown"
+	}
 }
+
+const (
+	// AsyncSmsStatusWaiting 等待发送，包括等待重试
+	AsyncSmsStatusWaiting AsyncSmsStatus = iota
+	// AsyncSmsStatusFailed 失败了，并且超过了重试次数
+	AsyncSmsStatusFailed
+	// AsyncSmsStatusSuccess 发送成功
+	AsyncSmsStatusSuccess
+)
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// CodePolicy 验证码的存储策略，不同业务可以不一样
+type CodePolicy struct {
+	// TTL 验证码的有效期
+	TTL time.Duration
+	// ResendInterval 多久之后才可以重新发送
+	ResendInterval time.Duration
+	// MaxAttempts 最多可以验证几次
+	MaxAttempts int
+}
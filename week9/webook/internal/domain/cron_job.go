@@ -14,9 +14,13 @@ type CronJob struct {
 	Cfg        string
 	Expression string
 	NextTime   time.Time
+	// Version 抢占成功之后的版本号，同时也是这一次抢占的 fencing token
+	Version int64
 
 	// 放弃抢占状态
 	CancelFunc func()
+	// Lost 续约失败，也就是任务被别的节点抢走了的时候，会被关闭
+	Lost <-chan struct{}
 }
 
 func (j CronJob) Next(t time.Time) time.Time {
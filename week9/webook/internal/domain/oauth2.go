@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// OAuth2Info 第三方平台授权之后拿到的用户信息
+type OAuth2Info struct {
+	// Provider 平台的名字，比如说 wechat, github, dingtalk
+	Provider string
+	// ExternalId 在第三方平台上的用户 ID，微信、钉钉是 OpenId，也就是应用内唯一
+	ExternalId string
+	// UnionId 整个公司账号内唯一，不是所有的平台都有
+	UnionId  string
+	Nickname string
+}
+
+// OAuth2Binding 用户绑定的第三方账号，一个用户在一个平台上只能绑定一个账号
+type OAuth2Binding struct {
+	Id    int64
+	Uid   int64
+	Info  OAuth2Info
+	Ctime time.Time
+}
@@ -0,0 +1,24 @@
+package domain
+
+// UserStats 作者维度的统计，读和点赞都是已发表的文章收到的
+type UserStats struct {
+	ArticleCnt int64
+	ReadCnt    int64
+	LikeCnt    int64
+	CollectCnt int64
+}
+
+// PublicProfile 作者主页，别人也能看到的部分
+type PublicProfile struct {
+	Id          int64
+	Nickname    string
+	Avatar      string
+	AvatarThumb string
+	AboutMe     string
+	ArticleCnt  int64
+	// FollowerCnt 粉丝数，目前还没有关注的模块，所以一直是 0。
+	// 先把字段留出来，前端不用等关注功能上线再改
+	FollowerCnt int64
+	// Articles 最近发表的文章
+	Articles []Article
+}
@@ -0,0 +1,9 @@
+package domain
+
+// Role 角色。权限就是一个字符串，比如说 "sms:manage"，"*" 表示所有的权限
+type Role struct {
+	Id          int64
+	Name        string
+	Description string
+	Permissions []string
+}
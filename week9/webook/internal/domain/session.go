@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// Session 一次登录就是一个会话，对应 JWT 里面的 ssid
+type Session struct {
+	Ssid      string
+	Uid       int64
+	UserAgent string
+	IP        string
+	Ctime     time.Time
+	// Utime 最后活跃的时间，不是每个请求都会更新
+	Utime time.Time
+	// Expire 会话的过期时间，和长 token 一致
+	Expire time.Time
+	// RefreshId 当前有效的长 token，每次刷新都会换一个新的
+	RefreshId string
+}
@@ -0,0 +1,82 @@
+package domain
+
+import "time"
+
+// DataExport 导出个人数据的任务，由定时任务异步执行
+type DataExport struct {
+	Id     int64
+	Uid    int64
+	Status DataExportStatus
+	// Key 导出的文件在对象存储里面的 key，完成之后才有
+	Key   string
+	Ctime time.Time
+	Utime time.Time
+}
+
+type DataExportStatus uint8
+
+func (s DataExportStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s DataExportStatus) String() string {
+	switch s {
+	case DataExportStatusPending:
+		return "pending"
+	case DataExportStatusRunning:
+		return "running"
+	case DataExportStatusDone:
+		return "done"
+	case DataExportStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	DataExportStatusPending DataExportStatus = iota
+	DataExportStatusRunning
+	DataExportStatusDone
+	DataExportStatusFailed
+)
+
+// AccountDeletion 注销账号的申请，冷静期过了之后才会真的执行
+type AccountDeletion struct {
+	Uid    int64
+	Status AccountDeletionStatus
+	// ExecTime 冷静期结束的时间，在这之前可以撤销
+	ExecTime time.Time
+	Ctime    time.Time
+	Utime    time.Time
+}
+
+type AccountDeletionStatus uint8
+
+func (s AccountDeletionStatus) ToUint8() uint8 {
+	return uint8(s)
+}
+
+func (s AccountDeletionStatus) String() string {
+	switch s {
+	case AccountDeletionStatusPending:
+		return "pending"
+	case AccountDeletionStatusCancelled:
+		return "cancelled"
+	case AccountDeletionStatusRunning:
+		return "running"
+	case AccountDeletionStatusDone:
+		return "done"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// AccountDeletionStatusPending 冷静期
+	AccountDeletionStatusPending AccountDeletionStatus = iota
+	AccountDeletionStatusCancelled
+	// AccountDeletionStatusRunning 正在删除，中途失败了会停在这个状态，重新执行是幂等的
+	AccountDeletionStatusRunning
+	AccountDeletionStatusDone
+)
@@ -1,22 +1,101 @@
 package errs
 
+import "gitee.com/geekbang/basic-go/webook/pkg/errs"
+
+// 错误码的规则见 pkg/errs，这里的错误码已经发布了，不能修改，只能新增
+
 // User 部分，模块代码使用 01
-const (
+var (
 	// UserInvalidInput 这是一个非常含糊的错误码，代表用户相关的API参数不对
-	UserInvalidInput = 401001
+	UserInvalidInput = errs.Register(401001, "UserInvalidInput", "参数错误", "invalid input")
 	// UserInternalServerError 这是一个非常函数的错误码。代表用户模块系统内部错误
-	UserInternalServerError = 501001
+	UserInternalServerError = errs.Register(501001, "UserInternalServerError", "系统错误", "internal server error")
 	// 假设说我们需要进一步关心别的错误
 
 	// UserInvalidOrPassword 用户输入的账号或者密码不对
-	UserInvalidOrPassword = 401002
+	UserInvalidOrPassword = errs.Register(401002, "UserInvalidOrPassword",
+		"用户名或者密码不正确，请重试", "invalid account or password")
 	// UserDuplicateEmail 邮箱冲突
-	UserDuplicateEmail = 401003
+	UserDuplicateEmail = errs.Register(401003, "UserDuplicateEmail", "邮箱冲突", "email already registered")
+	// UserInvalidPhone 手机号码格式不对
+	UserInvalidPhone = errs.Register(401004, "UserInvalidPhone", "手机号码格式不对", "invalid phone number")
+	// UserCodeSendTooMany 发送验证码太频繁，或者触发了防刷的限流
+	UserCodeSendTooMany = errs.Register(401005, "UserCodeSendTooMany",
+		"发送太频繁，请稍后再试", "too many codes sent, please try again later")
+	// UserCaptchaRequired 需要先完成人机验证，前端收到之后要弹出人机验证
+	UserCaptchaRequired = errs.Register(401006, "UserCaptchaRequired", "请先完成人机验证", "captcha required")
+	// UserCaptchaFailed 人机验证没有通过
+	UserCaptchaFailed = errs.Register(401007, "UserCaptchaFailed", "人机验证失败", "captcha verification failed")
+	// UserOAuth2BoundByOther 第三方账号已经绑定了别的用户
+	UserOAuth2BoundByOther = errs.Register(401008, "UserOAuth2BoundByOther",
+		"该账号已经绑定了别的用户", "the account is bound to another user")
+	// UserOAuth2AlreadyBound 已经绑定过这个平台的另外一个账号，要先解绑
+	UserOAuth2AlreadyBound = errs.Register(401009, "UserOAuth2AlreadyBound",
+		"已经绑定过其它账号，请先解绑", "another account is already bound, please unbind it first")
+	// UserOAuth2NotBound 没有绑定这个平台的账号
+	UserOAuth2NotBound = errs.Register(401010, "UserOAuth2NotBound", "没有绑定该账号", "account not bound")
+	// UserOAuth2LastLoginMethod 这是唯一的登录方式，不能解绑
+	UserOAuth2LastLoginMethod = errs.Register(401011, "UserOAuth2LastLoginMethod",
+		"这是唯一的登录方式，请先绑定手机号码或者邮箱", "this is the only login method, please bind a phone number or email first")
+	// UserPhoneBoundByOther 手机号码属于别的账号，绑定的时候响应里面会带上合并账号用的凭证
+	UserPhoneBoundByOther = errs.Register(401012, "UserPhoneBoundByOther",
+		"该手机号码已经注册过账号", "the phone number is registered by another account")
+	// UserEmailBoundByOther 邮箱属于别的账号，绑定的时候响应里面会带上合并账号用的凭证
+	UserEmailBoundByOther = errs.Register(401013, "UserEmailBoundByOther",
+		"该邮箱已经注册过账号", "the email is registered by another account")
+	// UserContactExists 已经绑定了别的手机号码或者邮箱
+	UserContactExists = errs.Register(401014, "UserContactExists",
+		"已经绑定了别的手机号码或者邮箱", "another phone number or email is already bound")
+	// UserMergeConflict 两个账号的信息冲突，不能合并
+	UserMergeConflict = errs.Register(401015, "UserMergeConflict",
+		"两个账号绑定了不同的手机号码、邮箱或者同一个平台的第三方账号，请先解绑",
+		"the two accounts have conflicting bindings, please unbind them first")
+	// UserInvalidMergeTicket 合并账号的凭证不对或者过期了
+	UserInvalidMergeTicket = errs.Register(401016, "UserInvalidMergeTicket", "请重新验证", "please verify again")
+	// UserSessionNotFound 要踢下线的会话不存在，可能已经过期了
+	UserSessionNotFound = errs.Register(401017, "UserSessionNotFound", "会话不存在", "session not found")
+	// UserPasswordNotSet 还没有设置过密码，不能修改，只能重置
+	UserPasswordNotSet = errs.Register(401018, "UserPasswordNotSet",
+		"还没有设置密码，请使用忘记密码", "password not set, please reset it instead")
+	// UserExportInProgress 上一次导出个人数据还没有完成
+	UserExportInProgress = errs.Register(401019, "UserExportInProgress",
+		"上一次导出还没有完成", "the previous export is still in progress")
+	// UserExportNotFound 还没有导出过个人数据
+	UserExportNotFound = errs.Register(401020, "UserExportNotFound", "还没有导出过", "no export found")
+	// UserDeletionNotFound 没有可以撤销的注销申请
+	UserDeletionNotFound = errs.Register(401021, "UserDeletionNotFound",
+		"没有可以撤销的注销申请", "no cancellable deletion request")
+	// UserInvalidAvatar 头像太大，或者不是支持的图片格式
+	UserInvalidAvatar = errs.Register(401022, "UserInvalidAvatar",
+		"图片损坏或者尺寸太大", "the image is corrupted or too large")
+	// UserNotFound 查看的用户不存在
+	UserNotFound = errs.Register(401023, "UserNotFound", "用户不存在", "user not found")
+	// UserInvalidCode 验证码不对或者过期了
+	UserInvalidCode = errs.Register(401024, "UserInvalidCode", "验证码错误", "invalid verification code")
 )
 
 // Article 部分，模块代码使用 02
-const (
+var (
 	// ArticleInvalidInput 含糊的输入错误
-	ArticleInvalidInput        = 402001
-	ArticleInternalServerError = 502001
+	ArticleInvalidInput        = errs.Register(402001, "ArticleInvalidInput", "参数错误", "invalid input")
+	ArticleInternalServerError = errs.Register(502001, "ArticleInternalServerError", "系统错误", "internal server error")
+)
+
+// 异步短信部分，模块代码使用 03
+var (
+	AsyncSmsInvalidInput        = errs.Register(403001, "AsyncSmsInvalidInput", "请求有误", "invalid request")
+	AsyncSmsInternalServerError = errs.Register(503001, "AsyncSmsInternalServerError", "系统错误", "internal server error")
+	// AsyncSmsNotRequeueable 短信不存在或者已经发送成功，不能重新入队
+	AsyncSmsNotRequeueable = errs.Register(403002, "AsyncSmsNotRequeueable",
+		"短信不存在或者已经发送成功", "the sms does not exist or has been sent")
+)
+
+// 权限管理部分，模块代码使用 04
+var (
+	RBACInvalidInput        = errs.Register(404001, "RBACInvalidInput", "请求有误", "invalid request")
+	RBACInternalServerError = errs.Register(504001, "RBACInternalServerError", "系统错误", "internal server error")
+	// RBACRoleNotFound 角色不存在，角色目前只能直接在数据库里面创建
+	RBACRoleNotFound = errs.Register(404002, "RBACRoleNotFound", "角色不存在", "role not found")
+	// RBACUserRoleNotFound 用户没有这个角色
+	RBACUserRoleNotFound = errs.Register(404003, "RBACUserRoleNotFound", "用户没有这个角色", "the user does not have this role")
 )
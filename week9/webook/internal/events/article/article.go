@@ -9,7 +9,10 @@ const topicReadEvent = "article_read_event"
 
 type ReadEvent struct {
 	Aid int64
+	// Uid 匿名用户是 0
 	Uid int64
+	// Anonymous 没有登录的用户，阅读数照样算，但是不能当成 uid 为 0 的用户处理
+	Anonymous bool
 }
 
 type Producer interface {
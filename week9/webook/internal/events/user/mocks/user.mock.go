@@ -0,0 +1,53 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./user.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./user.go -package=evtmocks -destination=mocks/user.mock.go Producer
+//
+// Package evtmocks is a generated GoMock package.
+package evtmocks
+
+import (
+	reflect "reflect"
+
+	user "gitee.com/geekbang/basic-go/webook/internal/events/user"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockProducer is a mock of Producer interface.
+type MockProducer struct {
+	ctrl     *gomock.Controller
+	recorder *MockProducerMockRecorder
+}
+
+// MockProducerMockRecorder is the mock recorder for MockProducer.
+type MockProducerMockRecorder struct {
+	mock *MockProducer
+}
+
+// NewMockProducer creates a new mock instance.
+func NewMockProducer(ctrl *gomock.Controller) *MockProducer {
+	mock := &MockProducer{ctrl: ctrl}
+	mock.recorder = &MockProducerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProducer) EXPECT() *MockProducerMockRecorder {
+	return m.recorder
+}
+
+// ProduceUserDeletedEvent mocks base method.
+func (m *MockProducer) ProduceUserDeletedEvent(evt user.UserDeletedEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProduceUserDeletedEvent", evt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProduceUserDeletedEvent indicates an expected call of ProduceUserDeletedEvent.
+func (mr *MockProducerMockRecorder) ProduceUserDeletedEvent(evt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProduceUserDeletedEvent", reflect.TypeOf((*MockProducer)(nil).ProduceUserDeletedEvent), evt)
+}
@@ -0,0 +1,43 @@
+package user
+
+import (
+	"encoding/json"
+	"github.com/IBM/sarama"
+	"strconv"
+)
+
+const topicUserDeletedEvent = "user_deleted_event"
+
+// UserDeletedEvent 用户注销了，别的模块收到之后删除或者匿名化自己的数据
+type UserDeletedEvent struct {
+	Uid int64
+}
+
+//go:generate mockgen -source=./user.go -package=evtmocks -destination=mocks/user.mock.go Producer
+type Producer interface {
+	ProduceUserDeletedEvent(evt UserDeletedEvent) error
+}
+
+type SaramaSyncProducer struct {
+	producer sarama.SyncProducer
+}
+
+func NewSaramaSyncProducer(producer sarama.SyncProducer) Producer {
+	return &SaramaSyncProducer{
+		producer: producer,
+	}
+}
+
+func (s *SaramaSyncProducer) ProduceUserDeletedEvent(evt UserDeletedEvent) error {
+	val, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: topicUserDeletedEvent,
+		// 同一个用户的消息落到同一个分区
+		Key:   sarama.StringEncoder(strconv.FormatInt(evt.Uid, 10)),
+		Value: sarama.ByteEncoder(val),
+	})
+	return err
+}
@@ -0,0 +1,163 @@
+//go:build manual
+
+package integration
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/integration/startup"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	oauth2mocks "gitee.com/geekbang/basic-go/webook/internal/service/oauth2/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/web"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// 这是一个只能手动运行的测试，为了摆脱第三方平台那个部分而引入的测试
+func TestOAuth2Callback(t *testing.T) {
+	db := startup.InitTestDB()
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) oauth2.Provider
+		before func(t *testing.T)
+		// 验证并且删除数据
+		after      func(t *testing.T)
+		wantCode   int
+		wantResult web.Result
+	}{
+		{
+			name: "注册新用户",
+			mock: func(ctrl *gomock.Controller) oauth2.Provider {
+				p := oauth2mocks.NewMockProvider(ctrl)
+				p.EXPECT().Name().Return("wechat").AnyTimes()
+				p.EXPECT().AuthURL(gomock.Any(), gomock.Any()).Return("https://open.weixin.qq.com", nil)
+				p.EXPECT().VerifyCode(gomock.Any(), "my-code").
+					Return(domain.OAuth2Info{
+						Provider:   "wechat",
+						ExternalId: "123",
+						UnionId:    "1234",
+					}, nil)
+				return p
+			},
+			before: func(t *testing.T) {
+				// 什么也不需要做
+			},
+			after: func(t *testing.T) {
+				// 验证数据库
+				var b dao.UserOAuthBinding
+				err := db.First(&b, "provider = ? AND external_id = ?", "wechat", "123").Error
+				assert.NoError(t, err)
+				// 只需要验证 union id 就差不多了
+				assert.Equal(t, "1234", b.UnionId.String)
+				assert.True(t, b.Uid > 0)
+				db.Delete(&dao.User{}, "id = ?", b.Uid)
+				db.Delete(&b)
+			},
+			wantCode: 200,
+			wantResult: web.Result{
+				Msg: "登录成功",
+			},
+		},
+		{
+			name: "已有的用户",
+			mock: func(ctrl *gomock.Controller) oauth2.Provider {
+				p := oauth2mocks.NewMockProvider(ctrl)
+				p.EXPECT().Name().Return("wechat").AnyTimes()
+				p.EXPECT().AuthURL(gomock.Any(), gomock.Any()).Return("https://open.weixin.qq.com", nil)
+				p.EXPECT().VerifyCode(gomock.Any(), "my-code").
+					Return(domain.OAuth2Info{
+						Provider:   "wechat",
+						ExternalId: "2345",
+						UnionId:    "23456",
+					}, nil)
+				return p
+			},
+			before: func(t *testing.T) {
+				// 插入数据，假装用户存在
+				err := db.Create(&dao.User{Id: 2345}).Error
+				require.NoError(t, err)
+				err = db.Create(&dao.UserOAuthBinding{
+					Uid:        2345,
+					Provider:   "wechat",
+					ExternalId: "2345",
+					UnionId: sql.NullString{
+						String: "23456",
+						Valid:  true,
+					},
+				}).Error
+				require.NoError(t, err)
+			},
+			after: func(t *testing.T) {
+				// 验证数据库，不会创建新的绑定
+				var cnt int64
+				err := db.Model(&dao.UserOAuthBinding{}).
+					Where("provider = ? AND external_id = ?", "wechat", "2345").
+					Count(&cnt).Error
+				assert.NoError(t, err)
+				assert.Equal(t, int64(1), cnt)
+				db.Delete(&dao.User{}, "id = ?", 2345)
+				db.Delete(&dao.UserOAuthBinding{}, "uid = ?", 2345)
+			},
+			wantCode: 200,
+			wantResult: web.Result{
+				Msg: "登录成功",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			tc.before(t)
+
+			oauth2Svc := startup.InitOAuth2Svc()
+			jwtHdl := startup.InitJwtHdl()
+			providers := oauth2.NewProviders(tc.mock(ctrl))
+			hdl := web.NewOAuth2Handler(providers, oauth2Svc, jwtHdl)
+			server := gin.Default()
+			hdl.RegisterRoutes(server)
+
+			// 先拿授权地址，拿到 state 的 cookie
+			req, err := http.NewRequest(http.MethodGet, "/oauth2/wechat/authurl", nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			cookies := recorder.Result().Cookies()
+			require.Len(t, cookies, 1)
+			state := stateFromCookie(t, cookies[0].Value)
+
+			req, err = http.NewRequest(http.MethodGet,
+				"/oauth2/wechat/callback?code=my-code&state="+url.QueryEscape(state), nil)
+			require.NoError(t, err)
+			req.AddCookie(cookies[0])
+			recorder = httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			code := recorder.Code
+			// 反序列化为结果
+			var result web.Result
+			err = json.Unmarshal(recorder.Body.Bytes(), &result)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantCode, code)
+			assert.Equal(t, tc.wantResult, result)
+			tc.after(t)
+		})
+	}
+}
+
+// stateFromCookie 测试里面不需要校验签名，直接读出来
+func stateFromCookie(t *testing.T, tokenStr string) string {
+	var sc web.StateClaims
+	_, _, err := jwt.NewParser().ParseUnverified(tokenStr, &sc)
+	require.NoError(t, err)
+	return sc.State
+}
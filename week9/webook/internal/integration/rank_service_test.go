@@ -36,6 +36,9 @@ func (r *RankingServiceTestSuite) TearDownTest() {
 	require.NoError(r.T(), err)
 	err = r.db.Exec("TRUNCATE TABLE `published_articles`").Error
 	require.NoError(r.T(), err)
+	// fencing token 是单调递增的，不清理的话下一次测试就会被认为是旧的持有者
+	err = r.rdb.Del(context.Background(), "{ranking:article}:fencing_token").Err()
+	require.NoError(r.T(), err)
 }
 
 func (r *RankingServiceTestSuite) TestRankTopN() {
@@ -80,7 +83,7 @@ func (r *RankingServiceTestSuite) TestRankTopN() {
 				ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 				defer cancel()
 
-				vals, err := rdb.Get(ctx, "ranking:article").Bytes()
+				vals, err := rdb.Get(ctx, "{ranking:article}").Bytes()
 				require.NoError(t, err)
 				var data []int64
 				err = json.Unmarshal(vals, &data)
@@ -95,7 +98,7 @@ func (r *RankingServiceTestSuite) TestRankTopN() {
 			tc.before(t)
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 			defer cancel()
-			err := svc.RankTopN(ctx)
+			err := svc.RankTopN(ctx, 1)
 			assert.Equal(t, tc.wantErr, err)
 			tc.after(t)
 		})
@@ -4,8 +4,8 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/integration/startup"
 	"gitee.com/geekbang/basic-go/webook/internal/job"
 	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock/redislock"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
-	rlock "github.com/gotomicro/redis-lock"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/robfig/cron/v3"
 	"github.com/stretchr/testify/require"
@@ -21,8 +21,8 @@ func TestRankingJob(t *testing.T) {
 	rdb := startup.InitRedis()
 	svc := svcmocks.NewMockRankingService(ctrl)
 	// 会调用三次
-	svc.EXPECT().RankTopN(gomock.Any()).Times(3).Return(nil)
-	j := job.NewRankingJob(svc, rlock.NewClient(rdb),
+	svc.EXPECT().RankTopN(gomock.Any(), gomock.Any()).Times(3).Return(nil)
+	j := job.NewRankingJob(svc, redislock.NewClient(rdb),
 		logger.NewNoOpLogger(), time.Minute)
 	c := cron.New(cron.WithSeconds())
 	bd := job.NewCronJobBuilder(logger.NewNoOpLogger(),
@@ -35,4 +35,5 @@ func TestRankingJob(t *testing.T) {
 	time.Sleep(time.Second * 3)
 	ctx := c.Stop()
 	<-ctx.Done()
+	require.NoError(t, j.Close())
 }
@@ -44,11 +44,17 @@ func (s *AsyncSMSTestSuite) TestSend() {
 		wantErr error
 	}{
 		{
-			name: "异步",
+			// 服务商一切正常的时候，走的是同步发送
+			name: "同步",
 			mock: func(ctrl *gomock.Controller) sms.Service {
 				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "123",
+					[]string{"123456"}, []string{"15212345678"}).Return(nil)
 				return svc
 			},
+			tplId:   "123",
+			args:    []string{"123456"},
+			numbers: []string{"15212345678"},
 		},
 	}
 	for _, tc := range testCases {
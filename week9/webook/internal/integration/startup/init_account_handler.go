@@ -0,0 +1,13 @@
+package startup
+
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/internal/web"
+)
+
+// InitAccountHandler 集成测试不读配置文件，合并账号凭证用固定的密钥
+func InitAccountHandler(svc service.AccountService,
+	codeSvc service.CodeService,
+	codeGuard service.CodeGuard) *web.AccountHandler {
+	return web.NewAccountHandler(svc, codeSvc, codeGuard, []byte("k6CswdUm77WKcbM68UQUuxVsHSpTCwgA"))
+}
@@ -0,0 +1,12 @@
+package startup
+
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email/localemail"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+)
+
+// InitAccountNotifier 邮件输出到控制台
+func InitAccountNotifier(smsSvc sms.Service) service.AccountNotifier {
+	return service.NewAccountNotifier(smsSvc, "", localemail.NewService())
+}
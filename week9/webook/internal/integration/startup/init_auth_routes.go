@@ -0,0 +1,28 @@
+package startup
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"runtime"
+)
+
+// InitAuthRoutes 直接读 dev.yaml 里面的 auth.routes，这样就不会和实际的配置不一致。
+// 测试的工作目录不固定，所以用这个源文件的位置来找配置文件
+func InitAuthRoutes() []middleware.AuthRoute {
+	_, file, _, _ := runtime.Caller(0)
+	v := viper.New()
+	v.SetConfigFile(filepath.Join(filepath.Dir(file), "..", "..", "..", "config", "dev.yaml"))
+	if err := v.ReadInConfig(); err != nil {
+		panic(fmt.Errorf("读取登录校验的路由配置失败 %w", err))
+	}
+	var routes []middleware.AuthRoute
+	if err := v.UnmarshalKey("auth.routes", &routes); err != nil {
+		panic(fmt.Errorf("初始化登录校验的路由配置失败 %w", err))
+	}
+	if err := middleware.CheckAuthRoutes(routes); err != nil {
+		panic(err)
+	}
+	return routes
+}
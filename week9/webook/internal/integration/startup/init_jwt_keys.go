@@ -0,0 +1,27 @@
+package startup
+
+import (
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+)
+
+// InitJWTKeys 集成测试就用 HS256，不需要准备密钥文件
+func InitJWTKeys() *ijwt.Keys {
+	keys, err := ijwt.NewKeys(ijwt.KeysConfig{
+		Access: ijwt.KeySetConfig{
+			SignKid: "test-access",
+			Keys: []ijwt.KeyConfig{
+				{Kid: "test-access", Alg: "HS256", Secret: "moyn8y9abnd7q4zkq2m73yw8tu9j5ixm"},
+			},
+		},
+		Refresh: ijwt.KeySetConfig{
+			SignKid: "test-refresh",
+			Keys: []ijwt.KeyConfig{
+				{Kid: "test-refresh", Alg: "HS256", Secret: "moyn8y9abnd7q4zkq2m73yw8tu9j5ixA"},
+			},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return keys
+}
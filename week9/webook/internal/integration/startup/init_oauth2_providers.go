@@ -0,0 +1,13 @@
+package startup
+
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2/wechat"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"net/http"
+)
+
+// InitPhantomOAuth2Providers 没啥用的虚拟的第三方登录，只有一个微信
+func InitPhantomOAuth2Providers(l logger.LoggerV1) *oauth2.Providers {
+	return oauth2.NewProviders(wechat.NewService(oauth2.Config{}, http.DefaultClient, l))
+}
@@ -8,6 +8,7 @@ import (
 	dao2 "gitee.com/geekbang/basic-go/webook/interactive/repository/dao"
 	service2 "gitee.com/geekbang/basic-go/webook/interactive/service"
 	article2 "gitee.com/geekbang/basic-go/webook/internal/events/article"
+	"gitee.com/geekbang/basic-go/webook/internal/events/user"
 	"gitee.com/geekbang/basic-go/webook/internal/job"
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
@@ -19,6 +20,7 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/web"
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	"gitee.com/geekbang/basic-go/webook/ioc"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx/middleware/rbac"
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 )
@@ -33,6 +35,38 @@ var userSvcProvider = wire.NewSet(
 	cache.NewRedisUserCache,
 	repository.NewCachedUserRepository,
 	service.NewUserService)
+var oauth2SvcProvider = wire.NewSet(
+	dao.NewGORMOAuth2BindingDAO,
+	repository.NewOAuth2BindingRepository,
+	service.NewOAuth2Service)
+var accountSvcProvider = wire.NewSet(
+	dao.NewGORMAccountMergeDAO,
+	InitAccountNotifier,
+	repository.NewAccountMergeRepository,
+	service.NewAccountService)
+var sessionSvcProvider = wire.NewSet(
+	cache.NewRedisSessionCache,
+	repository.NewSessionRepository,
+	service.NewSessionService)
+var rbacSvcProvider = wire.NewSet(
+	dao.NewGORMRBACDAO,
+	cache.NewRedisRBACCache,
+	repository.NewCachedRBACRepository,
+	service.NewRBACService)
+
+// userDataSvcProvider 没有配置 bucket，导出的文件放在本地
+var userDataSvcProvider = wire.NewSet(
+	dao.NewGORMUserDataDAO,
+	ioc.InitExportFileDAO,
+	repository.NewUserDataRepository,
+	user.NewSaramaSyncProducer,
+	ioc.InitUserDataService)
+
+// profileSvcProvider 没有配置 bucket，头像放在本地
+var profileSvcProvider = wire.NewSet(
+	ioc.InitAvatarDAO,
+	repository.NewAvatarRepository,
+	service.NewProfileService)
 var articlSvcProvider = wire.NewSet(
 	article.NewGORMArticleDAO,
 	article2.NewSaramaSyncProducer,
@@ -64,23 +98,45 @@ func InitWebServer() *gin.Engine {
 	wire.Build(
 		thirdProvider,
 		userSvcProvider,
+		oauth2SvcProvider,
+		accountSvcProvider,
+		sessionSvcProvider,
+		rbacSvcProvider,
+		userDataSvcProvider,
+		profileSvcProvider,
 		articlSvcProvider,
 		interactiveSvcProvider,
 		cache.NewRedisCodeCache,
 		repository.NewCachedCodeRepository,
+		dao.NewGORMAsyncSmsDAO,
+		repository.NewAsyncSMSRepository,
+		service.NewAsyncSmsService,
 		// service 部分
 		// 集成测试我们显式指定使用内存实现
 		ioc.InitSmsMemoryService,
 
-		// 指定啥也不干的 wechat service
-		InitPhantomWechatService,
+		// 指定啥也不干的第三方登录
+		InitPhantomOAuth2Providers,
 		service.NewSMSCodeService,
+		ioc.InitCodeGuard,
 		// handler 部分
 		web.NewUserHandler,
-		web.NewOAuth2WechatHandler,
+		web.NewOAuth2Handler,
+		InitAccountHandler,
+		web.NewSessionHandler,
+		service.NewPasswordService,
+		web.NewPasswordHandler,
+		web.NewUserDataHandler,
+		web.NewJWKSHandler,
 		web.NewArticleHandler,
 		web.NewObservabilityHandler,
+		web.NewAsyncSmsHandler,
+		web.NewRBACHandler,
+		web.NewOpenAPIHandler,
+		rbac.NewRoutes,
 		ijwt.NewRedisHandler,
+		InitJWTKeys,
+		InitAuthRoutes,
 
 		// gin 的中间件
 		ioc.GinMiddlewares,
@@ -139,7 +195,12 @@ func InitJobScheduler() *job.Scheduler {
 	return &job.Scheduler{}
 }
 
+func InitOAuth2Svc() service.OAuth2Service {
+	wire.Build(thirdProvider, userSvcProvider, oauth2SvcProvider)
+	return service.NewOAuth2Service(nil, nil)
+}
+
 func InitJwtHdl() ijwt.Handler {
-	wire.Build(thirdProvider, ijwt.NewRedisHandler)
-	return ijwt.NewRedisHandler(nil)
+	wire.Build(thirdProvider, sessionSvcProvider, InitJWTKeys, ijwt.NewRedisHandler)
+	return ijwt.NewRedisHandler(nil, nil)
 }
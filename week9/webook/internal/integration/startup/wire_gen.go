@@ -12,6 +12,7 @@ import (
 	dao2 "gitee.com/geekbang/basic-go/webook/interactive/repository/dao"
 	service2 "gitee.com/geekbang/basic-go/webook/interactive/service"
 	article2 "gitee.com/geekbang/basic-go/webook/internal/events/article"
+	"gitee.com/geekbang/basic-go/webook/internal/events/user"
 	"gitee.com/geekbang/basic-go/webook/internal/job"
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
@@ -23,6 +24,7 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/web"
 	"gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	"gitee.com/geekbang/basic-go/webook/ioc"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx/middleware/rbac"
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 )
@@ -32,35 +34,73 @@ import (
 //go:generate wire
 func InitWebServer() *gin.Engine {
 	cmdable := InitRedis()
-	handler := jwt.NewRedisHandler(cmdable)
-	loggerV1 := InitLog()
-	v := ioc.GinMiddlewares(cmdable, handler, loggerV1)
+	sessionCache := cache.NewRedisSessionCache(cmdable)
+	sessionRepository := repository.NewSessionRepository(sessionCache)
+	sessionService := service.NewSessionService(sessionRepository)
+	keys := InitJWTKeys()
+	handler := jwt.NewRedisHandler(sessionService, keys)
+	v := InitAuthRoutes()
 	gormDB := InitTestDB()
+	rbacdao := dao.NewGORMRBACDAO(gormDB)
+	rbacCache := cache.NewRedisRBACCache(cmdable)
+	rbacRepository := repository.NewCachedRBACRepository(rbacdao, rbacCache)
+	rbacService := service.NewRBACService(rbacRepository)
+	routes := rbac.NewRoutes()
+	loggerV1 := InitLog()
+	v2 := ioc.GinMiddlewares(cmdable, handler, v, rbacService, routes, loggerV1)
 	userDAO := dao.NewGORMUserDAO(gormDB)
 	userCache := cache.NewRedisUserCache(cmdable)
 	userRepository := repository.NewCachedUserRepository(userDAO, userCache)
 	userService := service.NewUserService(userRepository)
+	articleDAO := article.NewGORMArticleDAO(gormDB)
+	articleCache := cache.NewRedisArticleCache(cmdable)
+	articleRepository := repository.NewArticleRepository(articleDAO, articleCache, userRepository, loggerV1)
+	avatarDAO := ioc.InitAvatarDAO()
+	avatarRepository := repository.NewAvatarRepository(avatarDAO)
+	interactiveDAO := dao2.NewGORMInteractiveDAO(gormDB)
+	interactiveCache := cache2.NewRedisInteractiveCache(cmdable)
+	interactiveRepository := repository2.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
+	interactiveService := service2.NewInteractiveService(interactiveRepository, loggerV1)
+	profileService := service.NewProfileService(userRepository, articleRepository, avatarRepository, interactiveService, loggerV1)
 	smsService := ioc.InitSmsMemoryService()
 	codeCache := cache.NewRedisCodeCache(cmdable)
 	codeRepository := repository.NewCachedCodeRepository(codeCache)
 	codeService := service.NewSMSCodeService(smsService, codeRepository)
-	userHandler := web.NewUserHandler(userService, codeService, handler)
-	articleDAO := article.NewGORMArticleDAO(gormDB)
-	articleCache := cache.NewRedisArticleCache(cmdable)
-	articleRepository := repository.NewArticleRepository(articleDAO, articleCache, userRepository, loggerV1)
+	codeGuard := ioc.InitCodeGuard(cmdable, loggerV1)
+	userHandler := web.NewUserHandler(userService, profileService, codeService, codeGuard, handler)
 	client := InitKafka()
 	syncProducer := NewSyncProducer(client)
 	producer := article2.NewSaramaSyncProducer(syncProducer)
 	articleService := service.NewArticleService(articleRepository, loggerV1, producer)
-	interactiveDAO := dao2.NewGORMInteractiveDAO(gormDB)
-	interactiveCache := cache2.NewRedisInteractiveCache(cmdable)
-	interactiveRepository := repository2.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
-	interactiveService := service2.NewInteractiveService(interactiveRepository, loggerV1)
 	articleHandler := web.NewArticleHandler(articleService, interactiveService, loggerV1)
 	observabilityHandler := web.NewObservabilityHandler()
-	wechatService := InitPhantomWechatService(loggerV1)
-	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, userService, handler)
-	engine := ioc.InitWebServer(v, userHandler, articleHandler, observabilityHandler, oAuth2WechatHandler, loggerV1)
+	providers := InitPhantomOAuth2Providers(loggerV1)
+	oAuth2BindingDAO := dao.NewGORMOAuth2BindingDAO(gormDB)
+	oAuth2BindingRepository := repository.NewOAuth2BindingRepository(oAuth2BindingDAO)
+	oAuth2Service := service.NewOAuth2Service(oAuth2BindingRepository, userRepository)
+	oAuth2Handler := web.NewOAuth2Handler(providers, oAuth2Service, handler)
+	accountMergeDAO := dao.NewGORMAccountMergeDAO(gormDB)
+	accountMergeRepository := repository.NewAccountMergeRepository(accountMergeDAO, userCache)
+	accountNotifier := InitAccountNotifier(smsService)
+	accountService := service.NewAccountService(userRepository, oAuth2BindingRepository, accountMergeRepository, sessionRepository, accountNotifier, loggerV1)
+	accountHandler := InitAccountHandler(accountService, codeService, codeGuard)
+	sessionHandler := web.NewSessionHandler(sessionService, handler)
+	passwordService := service.NewPasswordService(userRepository, sessionRepository, loggerV1)
+	passwordHandler := web.NewPasswordHandler(passwordService, codeService, codeGuard)
+	userDataDAO := dao.NewGORMUserDataDAO(gormDB)
+	exportFileDAO := ioc.InitExportFileDAO()
+	userDataRepository := repository.NewUserDataRepository(userDataDAO, exportFileDAO)
+	userProducer := user.NewSaramaSyncProducer(syncProducer)
+	userDataService := ioc.InitUserDataService(userDataRepository, userRepository, oAuth2BindingRepository, sessionRepository, articleRepository, interactiveService, userProducer, loggerV1)
+	userDataHandler := web.NewUserDataHandler(userDataService)
+	jwksHandler := web.NewJWKSHandler(keys)
+	asyncSmsDAO := dao.NewGORMAsyncSmsDAO(gormDB)
+	asyncSmsRepository := repository.NewAsyncSMSRepository(asyncSmsDAO)
+	asyncSmsService := service.NewAsyncSmsService(asyncSmsRepository)
+	asyncSmsHandler := web.NewAsyncSmsHandler(asyncSmsService)
+	rbacHandler := web.NewRBACHandler(rbacService)
+	openAPIHandler := web.NewOpenAPIHandler(v, routes)
+	engine := ioc.InitWebServer(v2, userHandler, articleHandler, observabilityHandler, oAuth2Handler, accountHandler, sessionHandler, passwordHandler, userDataHandler, jwksHandler, asyncSmsHandler, rbacHandler, openAPIHandler, loggerV1)
 	return engine
 }
 
@@ -152,9 +192,25 @@ func InitJobScheduler() *job.Scheduler {
 	return scheduler
 }
 
+func InitOAuth2Svc() service.OAuth2Service {
+	gormDB := InitTestDB()
+	oAuth2BindingDAO := dao.NewGORMOAuth2BindingDAO(gormDB)
+	oAuth2BindingRepository := repository.NewOAuth2BindingRepository(oAuth2BindingDAO)
+	userDAO := dao.NewGORMUserDAO(gormDB)
+	cmdable := InitRedis()
+	userCache := cache.NewRedisUserCache(cmdable)
+	userRepository := repository.NewCachedUserRepository(userDAO, userCache)
+	oAuth2Service := service.NewOAuth2Service(oAuth2BindingRepository, userRepository)
+	return oAuth2Service
+}
+
 func InitJwtHdl() jwt.Handler {
 	cmdable := InitRedis()
-	handler := jwt.NewRedisHandler(cmdable)
+	sessionCache := cache.NewRedisSessionCache(cmdable)
+	sessionRepository := repository.NewSessionRepository(sessionCache)
+	sessionService := service.NewSessionService(sessionRepository)
+	keys := InitJWTKeys()
+	handler := jwt.NewRedisHandler(sessionService, keys)
 	return handler
 }
 
@@ -168,6 +224,20 @@ var thirdProvider = wire.NewSet(InitRedis, InitTestDB,
 
 var userSvcProvider = wire.NewSet(dao.NewGORMUserDAO, cache.NewRedisUserCache, repository.NewCachedUserRepository, service.NewUserService)
 
+var oauth2SvcProvider = wire.NewSet(dao.NewGORMOAuth2BindingDAO, repository.NewOAuth2BindingRepository, service.NewOAuth2Service)
+
+var accountSvcProvider = wire.NewSet(dao.NewGORMAccountMergeDAO, InitAccountNotifier, repository.NewAccountMergeRepository, service.NewAccountService)
+
+var sessionSvcProvider = wire.NewSet(cache.NewRedisSessionCache, repository.NewSessionRepository, service.NewSessionService)
+
+var rbacSvcProvider = wire.NewSet(dao.NewGORMRBACDAO, cache.NewRedisRBACCache, repository.NewCachedRBACRepository, service.NewRBACService)
+
+// userDataSvcProvider 没有配置 bucket，导出的文件放在本地
+var userDataSvcProvider = wire.NewSet(dao.NewGORMUserDataDAO, ioc.InitExportFileDAO, repository.NewUserDataRepository, user.NewSaramaSyncProducer, ioc.InitUserDataService)
+
+// profileSvcProvider 没有配置 bucket，头像放在本地
+var profileSvcProvider = wire.NewSet(ioc.InitAvatarDAO, repository.NewAvatarRepository, service.NewProfileService)
+
 var articlSvcProvider = wire.NewSet(article.NewGORMArticleDAO, article2.NewSaramaSyncProducer, cache.NewRedisArticleCache, repository.NewArticleRepository, service.NewArticleService)
 
 var interactiveSvcProvider = wire.NewSet(service2.NewInteractiveService, repository2.NewCachedInteractiveRepository, dao2.NewGORMInteractiveDAO, cache2.NewRedisInteractiveCache)
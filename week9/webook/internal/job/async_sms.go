@@ -0,0 +1,112 @@
+package job
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// AsyncSmsCleanupJob 定时清理发送成功的异步短信，避免表无限膨胀
+// 失败的不清理，留着人工排查或者重新入队
+type AsyncSmsCleanupJob struct {
+	svc service.AsyncSmsService
+	// 发送成功的记录保留多久
+	retention time.Duration
+	timeout   time.Duration
+	l         logger.LoggerV1
+}
+
+func NewAsyncSmsCleanupJob(svc service.AsyncSmsService,
+	l logger.LoggerV1,
+	retention time.Duration) *AsyncSmsCleanupJob {
+	return &AsyncSmsCleanupJob{
+		svc:       svc,
+		retention: retention,
+		timeout:   time.Minute,
+		l:         l,
+	}
+}
+
+func (a *AsyncSmsCleanupJob) Name() string {
+	return "async_sms_cleanup"
+}
+
+func (a *AsyncSmsCleanupJob) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	cnt, err := a.svc.CleanupSuccess(ctx, time.Now().Add(-a.retention))
+	a.l.Info("清理发送成功的异步短信", logger.Int64("cnt", cnt))
+	return err
+}
+
+// AlertFunc 失败积压超过阈值的时候回调，你可以接入自己公司的告警系统
+type AlertFunc func(ctx context.Context, failedCnt int64)
+
+// AsyncSmsMonitorJob 定时统计异步短信的积压情况，上报到 prometheus
+// 失败积压超过阈值就告警
+type AsyncSmsMonitorJob struct {
+	svc    service.AsyncSmsService
+	vector *prometheus.GaugeVec
+	// 失败的数量超过这个值就告警
+	threshold int64
+	alert     AlertFunc
+	l         logger.LoggerV1
+}
+
+func NewAsyncSmsMonitorJob(svc service.AsyncSmsService,
+	l logger.LoggerV1,
+	opt prometheus.GaugeOpts,
+	threshold int64) *AsyncSmsMonitorJob {
+	vector := prometheus.NewGaugeVec(opt, []string{"status"})
+	prometheus.MustRegister(vector)
+	res := &AsyncSmsMonitorJob{
+		svc:       svc,
+		vector:    vector,
+		threshold: threshold,
+		l:         l,
+	}
+	// 默认的告警就是打 ERROR 日志，一般日志系统都会针对 ERROR 配置告警
+	res.alert = func(ctx context.Context, failedCnt int64) {
+		l.Error("异步短信失败积压超过阈值",
+			logger.Int64("failed", failedCnt),
+			logger.Int64("threshold", threshold))
+	}
+	return res
+}
+
+// SetAlertFunc 替换默认的告警方式
+func (a *AsyncSmsMonitorJob) SetAlertFunc(fn AlertFunc) {
+	a.alert = fn
+}
+
+func (a *AsyncSmsMonitorJob) Name() string {
+	return "async_sms_monitor"
+}
+
+func (a *AsyncSmsMonitorJob) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	backlog, err := a.svc.Backlog(ctx)
+	if err != nil {
+		return err
+	}
+	// 没有记录的状态也要上报 0，不然 gauge 会一直停留在旧的值上
+	for _, status := range []domain.AsyncSmsStatus{
+		domain.AsyncSmsStatusWaiting,
+		domain.AsyncSmsStatusFailed,
+		domain.AsyncSmsStatusSuccess,
+	} {
+		a.vector.WithLabelValues(status.String()).Set(float64(backlog[status]))
+	}
+	failed := backlog[domain.AsyncSmsStatusFailed]
+	if failed >= a.threshold {
+		a.alert(ctx, failed)
+	}
+	return nil
+}
+
+var _ Job = (*AsyncSmsCleanupJob)(nil)
+var _ Job = (*AsyncSmsMonitorJob)(nil)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
 	"golang.org/x/sync/semaphore"
 	"time"
@@ -113,7 +114,10 @@ func (s *Scheduler) Start(ctx context.Context) error {
 				j.CancelFunc()
 			}()
 
-			err1 := exec.Exec(ctx, j)
+			// 续约失败，说明任务已经被别的节点抢走了，要通知 Executor 中断执行
+			execCtx, cancel := dlock.WithLost(ctx, j.Lost)
+			defer cancel()
+			err1 := exec.Exec(execCtx, j)
 			if err1 != nil {
 				s.l.Error("调度任务执行失败",
 					logger.Int64("id", j.Id),
@@ -32,9 +32,11 @@ func TestScheduler_Start(t *testing.T) {
 						Executor:   "local",
 						Cfg:        "hello,world",
 						Expression: "my cron expression",
+						CancelFunc: func() {},
 					}, nil)
 				svc.EXPECT().Preempt(gomock.Any()).AnyTimes().
 					Return(domain.CronJob{}, errors.New("db 错误"))
+				svc.EXPECT().ResetNextTime(gomock.Any(), gomock.Any()).Return(nil)
 				return svc
 			},
 			wantErr: context.DeadlineExceeded,
@@ -53,6 +55,7 @@ func TestScheduler_Start(t *testing.T) {
 						Executor:   "fake news",
 						Cfg:        "hello,world",
 						Expression: "my cron expression",
+						CancelFunc: func() {},
 					}, nil)
 				svc.EXPECT().Preempt(gomock.Any()).AnyTimes().
 					Return(domain.CronJob{}, errors.New("db 错误"))
@@ -0,0 +1,49 @@
+package job
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"time"
+)
+
+// UserDataJob 执行导出个人数据和注销账号，
+// 多个实例同时执行也没关系，每一条都会先抢占再执行
+type UserDataJob struct {
+	svc service.UserDataService
+	// batchSize 每一次最多处理多少条
+	batchSize int
+	timeout   time.Duration
+	l         logger.LoggerV1
+}
+
+func NewUserDataJob(svc service.UserDataService, l logger.LoggerV1) *UserDataJob {
+	return &UserDataJob{
+		svc:       svc,
+		batchSize: 10,
+		timeout:   time.Minute,
+		l:         l,
+	}
+}
+
+func (u *UserDataJob) Name() string {
+	return "user_data"
+}
+
+func (u *UserDataJob) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+	defer cancel()
+	exported, err := u.svc.RunExports(ctx, u.batchSize)
+	if err != nil {
+		return err
+	}
+	deleted, err := u.svc.RunDeletions(ctx, u.batchSize)
+	if exported > 0 || deleted > 0 {
+		u.l.Info("处理个人数据导出和注销",
+			logger.Int64("exported", int64(exported)),
+			logger.Int64("deleted", int64(deleted)))
+	}
+	return err
+}
+
+var _ Job = (*UserDataJob)(nil)
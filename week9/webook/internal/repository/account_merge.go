@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"time"
+)
+
+//go:generate mockgen -source=./account_merge.go -package=repomocks -destination=mocks/account_merge.mock.go AccountMergeRepository
+type AccountMergeRepository interface {
+	// Merge 把 sourceUid 合并到 targetUid，sourceUid 会被删除
+	Merge(ctx context.Context, sourceUid int64, targetUid int64) (domain.AccountMergeLog, error)
+	// FindLogs uid 作为保留的账号或者被合并的账号的审计记录
+	FindLogs(ctx context.Context, uid int64) ([]domain.AccountMergeLog, error)
+}
+
+type accountMergeRepository struct {
+	dao dao.AccountMergeDAO
+	// 合并之后，两个用户的缓存都要删除
+	userCache cache.UserCache
+}
+
+func NewAccountMergeRepository(d dao.AccountMergeDAO, c cache.UserCache) AccountMergeRepository {
+	return &accountMergeRepository{
+		dao:       d,
+		userCache: c,
+	}
+}
+
+func (r *accountMergeRepository) Merge(ctx context.Context,
+	sourceUid int64, targetUid int64) (domain.AccountMergeLog, error) {
+	log, err := r.dao.Merge(ctx, sourceUid, targetUid)
+	if err != nil {
+		return domain.AccountMergeLog{}, err
+	}
+	// 文章、点赞数这些缓存都有过期时间，这里就不处理了
+	_ = r.userCache.Delete(ctx, sourceUid)
+	_ = r.userCache.Delete(ctx, targetUid)
+	return r.toDomain(log), nil
+}
+
+func (r *accountMergeRepository) FindLogs(ctx context.Context, uid int64) ([]domain.AccountMergeLog, error) {
+	logs, err := r.dao.FindLogsByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.AccountMergeLog, 0, len(logs))
+	for _, l := range logs {
+		res = append(res, r.toDomain(l))
+	}
+	return res, nil
+}
+
+func (r *accountMergeRepository) toDomain(l dao.UserMergeLog) domain.AccountMergeLog {
+	return domain.AccountMergeLog{
+		Id:          l.Id,
+		SourceUid:   l.SourceUid,
+		TargetUid:   l.TargetUid,
+		SourcePhone: l.SourcePhone,
+		SourceEmail: l.SourceEmail,
+		Articles:    l.Articles,
+		Likes:       l.Likes,
+		Collections: l.Collections,
+		Bindings:    l.Bindings,
+		Ctime:       time.UnixMilli(l.Ctime),
+	}
+}
@@ -26,6 +26,12 @@ type ArticleRepository interface {
 
 	GetPublishedById(ctx context.Context, id int64) (domain.Article, error)
 	ListPub(ctx context.Context, utime time.Time, offset int, limit int) ([]domain.Article, error)
+	// DeleteByAuthor 注销账号的时候删除所有的文章。
+	// 线上库的缓存没有删，等它过期
+	DeleteByAuthor(ctx context.Context, author int64) error
+	// ListPubByAuthor 作者已发表的文章，不包含仅自己可见的
+	ListPubByAuthor(ctx context.Context, author int64, offset int, limit int) ([]domain.Article, error)
+	CountPubByAuthor(ctx context.Context, author int64) (int64, error)
 }
 
 type CachedArticleRepository struct {
@@ -55,6 +61,31 @@ func (repo *CachedArticleRepository) ListPub(ctx context.Context, utime time.Tim
 	}), nil
 }
 
+func (repo *CachedArticleRepository) DeleteByAuthor(ctx context.Context, author int64) error {
+	err := repo.dao.DeleteByAuthor(ctx, author)
+	if err != nil {
+		return err
+	}
+	return repo.cache.DelFirstPage(ctx, author)
+}
+
+func (repo *CachedArticleRepository) ListPubByAuthor(ctx context.Context, author int64, offset int, limit int) ([]domain.Article, error) {
+	val, err := repo.dao.GetPubByAuthor(ctx, author, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[article.PublishedArticle, domain.Article](val, func(idx int, src article.PublishedArticle) domain.Article {
+		res := repo.toDomain(article.Article(src))
+		res.Ctime = time.UnixMilli(src.Ctime)
+		res.Utime = time.UnixMilli(src.Utime)
+		return res
+	}), nil
+}
+
+func (repo *CachedArticleRepository) CountPubByAuthor(ctx context.Context, author int64) (int64, error) {
+	return repo.dao.CountPubByAuthor(ctx, author)
+}
+
 func NewArticleRepository(dao article.ArticleDAO,
 	c cache.ArticleCache,
 	userRepo UserRepository,
@@ -4,10 +4,15 @@ import (
 	"context"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"github.com/ecodeclub/ekit/slice"
 	"github.com/ecodeclub/ekit/sqlx"
+	"time"
 )
 
-var ErrWaitingSMSNotFound = dao.ErrWaitingSMSNotFound
+var (
+	ErrWaitingSMSNotFound     = dao.ErrWaitingSMSNotFound
+	ErrAsyncSmsNotRequeueable = dao.ErrAsyncSmsNotRequeueable
+)
 
 //go:generate mockgen -source=./async_sms_repository.go -package=repomocks -destination=mocks/async_sms_repository.mock.go AsyncSmsRepository
 type AsyncSmsRepository interface {
@@ -16,6 +21,17 @@ type AsyncSmsRepository interface {
 	Add(ctx context.Context, s domain.AsyncSms) error
 	PreemptWaitingSMS(ctx context.Context) (domain.AsyncSms, error)
 	ReportScheduleResult(ctx context.Context, id int64, success bool) error
+	// PreemptWaitingSMSBatch 批量抢占，没有的话返回空切片
+	PreemptWaitingSMSBatch(ctx context.Context, limit int) ([]domain.AsyncSms, error)
+	// ReportScheduleResults 批量上报执行结果
+	ReportScheduleResults(ctx context.Context, successIds, failedIds []int64) error
+
+	FindById(ctx context.Context, id int64) (domain.AsyncSms, error)
+	List(ctx context.Context, status domain.AsyncSmsStatus, offset, limit int) ([]domain.AsyncSms, error)
+	Requeue(ctx context.Context, id int64) error
+	CountByStatus(ctx context.Context) (map[domain.AsyncSmsStatus]int64, error)
+	// DeleteSuccessBefore 删除 t 之前发送成功的记录，返回删除的条数
+	DeleteSuccessBefore(ctx context.Context, t time.Time, limit int) (int64, error)
 }
 
 type asyncSmsRepository struct {
@@ -47,13 +63,7 @@ func (a *asyncSmsRepository) PreemptWaitingSMS(ctx context.Context) (domain.Asyn
 	if err != nil {
 		return domain.AsyncSms{}, err
 	}
-	return domain.AsyncSms{
-		Id:       as.Id,
-		TplId:    as.Config.Val.TplId,
-		Numbers:  as.Config.Val.Numbers,
-		Args:     as.Config.Val.Args,
-		RetryMax: as.RetryMax,
-	}, nil
+	return a.toDomain(as), nil
 }
 
 func (a *asyncSmsRepository) ReportScheduleResult(ctx context.Context, id int64, success bool) error {
@@ -62,3 +72,75 @@ func (a *asyncSmsRepository) ReportScheduleResult(ctx context.Context, id int64,
 	}
 	return a.dao.MarkFailed(ctx, id)
 }
+
+func (a *asyncSmsRepository) PreemptWaitingSMSBatch(ctx context.Context, limit int) ([]domain.AsyncSms, error) {
+	res, err := a.dao.GetWaitingSMSBatch(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(res, func(idx int, src dao.AsyncSms) domain.AsyncSms {
+		return a.toDomain(src)
+	}), nil
+}
+
+func (a *asyncSmsRepository) ReportScheduleResults(ctx context.Context, successIds, failedIds []int64) error {
+	err := a.dao.MarkSuccessBatch(ctx, successIds)
+	if err != nil {
+		return err
+	}
+	return a.dao.MarkFailedBatch(ctx, failedIds)
+}
+
+func (a *asyncSmsRepository) FindById(ctx context.Context, id int64) (domain.AsyncSms, error) {
+	as, err := a.dao.FindById(ctx, id)
+	if err != nil {
+		return domain.AsyncSms{}, err
+	}
+	return a.toDomain(as), nil
+}
+
+func (a *asyncSmsRepository) List(ctx context.Context,
+	status domain.AsyncSmsStatus, offset, limit int) ([]domain.AsyncSms, error) {
+	res, err := a.dao.ListByStatus(ctx, status.ToUint8(), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(res, func(idx int, src dao.AsyncSms) domain.AsyncSms {
+		return a.toDomain(src)
+	}), nil
+}
+
+func (a *asyncSmsRepository) Requeue(ctx context.Context, id int64) error {
+	return a.dao.Requeue(ctx, id)
+}
+
+func (a *asyncSmsRepository) CountByStatus(ctx context.Context) (map[domain.AsyncSmsStatus]int64, error) {
+	cnts, err := a.dao.CountByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[domain.AsyncSmsStatus]int64, len(cnts))
+	for status, cnt := range cnts {
+		res[domain.AsyncSmsStatus(status)] = cnt
+	}
+	return res, nil
+}
+
+func (a *asyncSmsRepository) DeleteSuccessBefore(ctx context.Context, t time.Time, limit int) (int64, error) {
+	return a.dao.DeleteSuccessBefore(ctx, t.UnixMilli(), limit)
+}
+
+func (a *asyncSmsRepository) toDomain(as dao.AsyncSms) domain.AsyncSms {
+	return domain.AsyncSms{
+		Id:       as.Id,
+		TplId:    as.Config.Val.TplId,
+		Numbers:  as.Config.Val.Numbers,
+		Args:     as.Config.Val.Args,
+		RetryMax: as.RetryMax,
+		RetryCnt: as.RetryCnt,
+		Status:   domain.AsyncSmsStatus(as.Status),
+		NextTime: time.UnixMilli(as.NextTime),
+		Ctime:    time.UnixMilli(as.Ctime),
+		Utime:    time.UnixMilli(as.Utime),
+	}
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+)
+
+//go:generate mockgen -source=./avatar.go -package=repomocks -destination=mocks/avatar.mock.go AvatarRepository
+type AvatarRepository interface {
+	// Upload 返回可以直接访问的 URL
+	Upload(ctx context.Context, key string, data []byte, contentType string) (string, error)
+}
+
+type avatarRepository struct {
+	dao dao.AvatarDAO
+}
+
+func NewAvatarRepository(d dao.AvatarDAO) AvatarRepository {
+	return &avatarRepository{
+		dao: d,
+	}
+}
+
+func (r *avatarRepository) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	return r.dao.Put(ctx, key, data, contentType)
+}
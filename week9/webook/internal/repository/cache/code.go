@@ -5,9 +5,14 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"github.com/redis/go-redis/v9"
+	"time"
 )
 
+// 测试的时候用 redismocks 模拟 Redis，升级 go-redis 之后要重新生成
+//go:generate mockgen -package=redismocks -destination=redismocks/cmd.mock.go github.com/redis/go-redis/v9 Cmdable
+
 var (
 	//go:embed lua/set_code.lua
 	luaSetCode string
@@ -21,7 +26,7 @@ var (
 //go:generate mockgen -source=./code.go -package=cachemocks -destination=mocks/code.mock.go CodeCache
 type CodeCache interface {
 	Set(ctx context.Context, biz string,
-		phone string, code string) error
+		phone string, code string, policy domain.CodePolicy) error
 
 	Verify(ctx context.Context, biz string,
 		phone string, inputCode string) (bool, error)
@@ -39,16 +44,19 @@ func NewRedisCodeCache(cmd redis.Cmdable) CodeCache {
 }
 
 // Set 如果该手机在该业务场景下，验证码不存在（都已经过期），那么发送
-// 如果已经有一个验证码，但是发出去已经一分钟了，允许重发
+// 如果已经有一个验证码，但是发出去已经超过了重发间隔，允许重发
 // 如果已经有一个验证码，但是没有过期时间，说明有不知名错误
-// 如果已经有一个验证码，但是发出去不到一分钟，不允许重发
-// 验证码有效期 10 分钟
+// 如果已经有一个验证码，但是发出去还没到重发间隔，不允许重发
+// 有效期、重发间隔和验证次数都由 policy 决定
 func (c *RedisCodeCache) Set(ctx context.Context,
 	biz string,
 	phone string,
-	code string) error {
+	code string,
+	policy domain.CodePolicy) error {
 
-	res, err := c.redis.Eval(ctx, luaSetCode, []string{c.key(biz, phone)}, code).Int()
+	res, err := c.redis.Eval(ctx, luaSetCode, []string{c.key(biz, phone)}, code,
+		int64(policy.TTL/time.Second), int64(policy.ResendInterval/time.Second),
+		policy.MaxAttempts).Int()
 	if err != nil {
 		return err
 	}
@@ -81,6 +89,9 @@ func (c *RedisCodeCache) Verify(ctx context.Context,
 	case -1:
 		//	验证次数耗尽，一般都是意味着有人在捣乱
 		return false, ErrCodeVerifyTooManyTimes
+	case -3:
+		// 没有发过验证码，或者已经过期了
+		return false, ErrKeyNotExist
 	default:
 		// 验证码不对
 		return false, nil
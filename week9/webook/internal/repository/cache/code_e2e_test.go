@@ -127,9 +127,19 @@ func TestRedisCodeCache_Set_e2e(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.before(t)
-			err := c.Set(tc.ctx, tc.biz, tc.phone, tc.code)
+			err := c.Set(tc.ctx, tc.biz, tc.phone, tc.code, testCodePolicy)
 			assert.Equal(t, tc.wantErr, err)
 			tc.after(t)
 		})
 	}
 }
+
+func TestRedisCodeCache_Suite_e2e(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		t.Fatal(err)
+	}
+	testCodeCache(t, NewRedisCodeCache(rdb), time.Sleep)
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+// HybridCodeCache 优先使用 Redis，Redis 出错了就降级到本地缓存
+// 降级期间发出去的验证码只在本实例上，所以验证请求要落到同一个实例上才能验证通过，
+// 这在 Redis 崩溃的时候是可以接受的，总比短信登录完全不可用要好
+type HybridCodeCache struct {
+	redis CodeCache
+	local CodeCache
+	l     logger.LoggerV1
+}
+
+func NewHybridCodeCache(redis CodeCache, local CodeCache, l logger.LoggerV1) CodeCache {
+	return &HybridCodeCache{
+		redis: redis,
+		local: local,
+		l:     l,
+	}
+}
+
+func (h *HybridCodeCache) Set(ctx context.Context, biz string, phone string, code string,
+	policy domain.CodePolicy) error {
+	err := h.redis.Set(ctx, biz, phone, code, policy)
+	switch err {
+	case nil, ErrCodeSendTooMany, ErrUnknownForCode:
+		// 都是 Redis 正常响应了的
+		return err
+	}
+	h.l.Warn("Redis 设置验证码失败，降级到本地缓存",
+		logger.String("biz", biz),
+		logger.Error(err))
+	return h.local.Set(ctx, biz, phone, code, policy)
+}
+
+func (h *HybridCodeCache) Verify(ctx context.Context, biz string, phone string, inputCode string) (bool, error) {
+	ok, err := h.redis.Verify(ctx, biz, phone, inputCode)
+	switch err {
+	case nil:
+		if ok {
+			return true, nil
+		}
+		// Redis 里面的验证码不对，不需要再去本地缓存里面找
+		return false, nil
+	case ErrCodeVerifyTooManyTimes:
+		return false, err
+	case ErrKeyNotExist:
+		// 可能是 Redis 崩溃期间发的验证码，在本地缓存里面
+		return h.local.Verify(ctx, biz, phone, inputCode)
+	}
+	h.l.Warn("Redis 验证验证码失败，降级到本地缓存",
+		logger.String("biz", biz),
+		logger.Error(err))
+	return h.local.Verify(ctx, biz, phone, inputCode)
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	cachemocks "gitee.com/geekbang/basic-go/webook/internal/repository/cache/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache/redismocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+// TestHybridCodeCache_Suite Redis 一直出错，全部降级到本地缓存，语义也要保持一致
+func TestHybridCodeCache_Suite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismocks.NewMockCmdable(ctrl)
+	cmd.EXPECT().Eval(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(redis.NewCmdResult(nil, errors.New("连不上 Redis"))).AnyTimes()
+	local, advance := newTestLocalCodeCache(t)
+	testCodeCache(t, NewHybridCodeCache(NewRedisCodeCache(cmd), local, logger.NewNoOpLogger()), advance)
+}
+
+func TestHybridCodeCache_Verify(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (CodeCache, CodeCache)
+
+		wantOk  bool
+		wantErr error
+	}{
+		{
+			name: "Redis 验证通过",
+			mock: func(ctrl *gomock.Controller) (CodeCache, CodeCache) {
+				rc := cachemocks.NewMockCodeCache(ctrl)
+				rc.EXPECT().Verify(gomock.Any(), "login", "152", "123456").Return(true, nil)
+				return rc, cachemocks.NewMockCodeCache(ctrl)
+			},
+			wantOk: true,
+		},
+		{
+			name: "Redis 验证不通过，不会再查本地缓存",
+			mock: func(ctrl *gomock.Controller) (CodeCache, CodeCache) {
+				rc := cachemocks.NewMockCodeCache(ctrl)
+				rc.EXPECT().Verify(gomock.Any(), "login", "152", "123456").Return(false, nil)
+				return rc, cachemocks.NewMockCodeCache(ctrl)
+			},
+		},
+		{
+			name: "Redis 里面没有，是降级期间发的",
+			mock: func(ctrl *gomock.Controller) (CodeCache, CodeCache) {
+				rc := cachemocks.NewMockCodeCache(ctrl)
+				rc.EXPECT().Verify(gomock.Any(), "login", "152", "123456").Return(false, ErrKeyNotExist)
+				lc := cachemocks.NewMockCodeCache(ctrl)
+				lc.EXPECT().Verify(gomock.Any(), "login", "152", "123456").Return(true, nil)
+				return rc, lc
+			},
+			wantOk: true,
+		},
+		{
+			name: "Redis 验证次数耗尽",
+			mock: func(ctrl *gomock.Controller) (CodeCache, CodeCache) {
+				rc := cachemocks.NewMockCodeCache(ctrl)
+				rc.EXPECT().Verify(gomock.Any(), "login", "152", "123456").
+					Return(false, ErrCodeVerifyTooManyTimes)
+				return rc, cachemocks.NewMockCodeCache(ctrl)
+			},
+			wantErr: ErrCodeVerifyTooManyTimes,
+		},
+		{
+			name: "Redis 出错，降级",
+			mock: func(ctrl *gomock.Controller) (CodeCache, CodeCache) {
+				rc := cachemocks.NewMockCodeCache(ctrl)
+				rc.EXPECT().Verify(gomock.Any(), "login", "152", "123456").
+					Return(false, errors.New("连不上 Redis"))
+				lc := cachemocks.NewMockCodeCache(ctrl)
+				lc.EXPECT().Verify(gomock.Any(), "login", "152", "123456").Return(true, nil)
+				return rc, lc
+			},
+			wantOk: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			rc, lc := tc.mock(ctrl)
+			c := NewHybridCodeCache(rc, lc, logger.NewNoOpLogger())
+			ok, err := c.Verify(context.Background(), "login", "152", "123456")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantOk, ok)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	lru "github.com/hashicorp/golang-lru"
 	"sync"
 	"time"
@@ -17,94 +18,96 @@ import (
 //     扩展性（如果开源软件的某些功能需要定制，框架是否支持定制，以及定制的难度高不高）
 //     性能（追求性能的公司，往往有能力自研）
 
-// LocalCodeCache 本地缓存实现
+// LocalCodeCache 本地缓存实现，语义和 set_code.lua、verify_code.lua 保持一致
 type LocalCodeCache struct {
+	// lru.Cache 本身是线程安全的，但是我们的操作都是先读后写，所以还是要加锁
 	cache *lru.Cache
-	// 普通锁，或者说写锁
-	lock sync.Mutex
-	// 读写锁
-	// 可以多个人加读锁
-	rwLock     sync.RWMutex
+	// 按照 key 加锁，不同的手机号码之间互不影响
+	locks *keyLocks
+	// policy 里面没有指定有效期的时候使用
 	expiration time.Duration
-	maps       sync.Map
+	// 我选用的本地缓存，很不幸的是，没有获得过期时间的接口，所以都是自己维持了一个过期时间字段
+	// 为了测试，可以替换掉
+	now func() time.Time
 }
 
 func NewLocalCodeCache(c *lru.Cache, expiration time.Duration) *LocalCodeCache {
 	return &LocalCodeCache{
 		cache:      c,
+		locks:      newKeyLocks(),
 		expiration: expiration,
+		now:        time.Now,
 	}
 }
 
-func (l *LocalCodeCache) Set(ctx context.Context, biz string, phone string, code string) error {
-
-	l.lock.Lock()
-	defer l.lock.Unlock()
-	// 这里可以考虑用读写锁来优化，但是效果不会很好
-	// 因为你可以预期，大部分时候是要走到写锁里面的
-
-	// 我选用的本地缓存，很不幸的是，没有获得过期时间的接口，所以都是自己维持了一个过期时间字段
+func (l *LocalCodeCache) Set(ctx context.Context, biz string, phone string, code string,
+	policy domain.CodePolicy) error {
 	key := l.key(biz, phone)
-	// 如果你的 key 非常多，这个 maps 本身就占据了很多内存
-	//lock, _ := l.maps.LoadOrStore(key, &sync.Mutex{})
-	//lock.(*sync.Mutex).Lock()
-	//defer lock.(*sync.Mutex).Unlock()
-
-	//lock, _ := l.maps.LoadOrStore(key, &sync.Mutex{})
-	//lock.(*sync.Mutex).Lock()
-	//defer func() {
-	//	l.maps.Delete(key)
-	//	lock.(*sync.Mutex).Unlock()
-	//}()
-
-	now := time.Now()
-	val, ok := l.cache.Get(key)
-	if !ok {
-		// 说明没有验证码
-		l.cache.Add(key, codeItem{
-			code:   code,
-			cnt:    3,
-			expire: now.Add(l.expiration),
-		})
-		return nil
+	unlock := l.locks.lock(key)
+	defer unlock()
+
+	expiration := policy.TTL
+	if expiration <= 0 {
+		expiration = l.expiration
 	}
-	itm, ok := val.(codeItem)
-	if !ok {
-		// 理论上来说这是不可能的
-		return errors.New("系统错误")
+	now := l.now()
+	itm, ok, err := l.get(key, now)
+	if err != nil {
+		return err
 	}
-	if itm.expire.Sub(now) > time.Minute*9 {
-		// 不到一分钟
+	// 和 Redis 的实现一样，剩余的有效期还比 expiration - interval 长，说明还没到重发间隔
+	if ok && itm.expire.Sub(now) >= expiration-policy.ResendInterval {
 		return ErrCodeSendTooMany
 	}
-	// 重发
-	l.cache.Add(key, codeItem{
+	l.cache.Add(key, &codeItem{
 		code:   code,
-		cnt:    3,
-		expire: now.Add(l.expiration),
+		cnt:    policy.MaxAttempts,
+		expire: now.Add(expiration),
 	})
 	return nil
 }
 
 func (l *LocalCodeCache) Verify(ctx context.Context, biz string, phone string, inputCode string) (bool, error) {
-	l.lock.Lock()
-	defer l.lock.Unlock()
 	key := l.key(biz, phone)
-	val, ok := l.cache.Get(key)
-	if !ok {
-		// 都没发验证码
-		return false, ErrKeyNotExist
+	unlock := l.locks.lock(key)
+	defer unlock()
+
+	itm, ok, err := l.get(key, l.now())
+	if err != nil {
+		return false, err
 	}
-	itm, ok := val.(codeItem)
 	if !ok {
-		// 理论上来说这是不可能的
-		return false, errors.New("系统错误")
+		// 都没发验证码，或者已经过期了
+		return false, ErrKeyNotExist
 	}
 	if itm.cnt <= 0 {
 		return false, ErrCodeVerifyTooManyTimes
 	}
+	if itm.code == inputCode {
+		// 和 Redis 的实现一样，验证通过之后验证码就不可用了
+		itm.cnt = -1
+		return true, nil
+	}
 	itm.cnt--
-	return itm.code == inputCode, nil
+	return false, nil
+}
+
+// get 过期的当作不存在
+func (l *LocalCodeCache) get(key string, now time.Time) (*codeItem, bool, error) {
+	val, ok := l.cache.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	itm, ok := val.(*codeItem)
+	if !ok {
+		// 理论上来说这是不可能的
+		return nil, false, errors.New("系统错误")
+	}
+	if !itm.expire.After(now) {
+		l.cache.Remove(key)
+		return nil, false, nil
+	}
+	return itm, true, nil
 }
 
 func (l *LocalCodeCache) key(biz string, phone string) string {
@@ -118,3 +121,43 @@ type codeItem struct {
 	// 过期时间
 	expire time.Time
 }
+
+// keyLocks 按照 key 加锁
+// 锁用完之后就删掉，不然 key 非常多的时候，这些锁本身就占据了很多内存
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*refLock
+}
+
+type refLock struct {
+	sync.Mutex
+	// 正在等待或者持有这把锁的 goroutine 数量
+	ref int
+}
+
+func newKeyLocks() *keyLocks {
+	return &keyLocks{locks: make(map[string]*refLock)}
+}
+
+// lock 返回解锁的方法
+func (k *keyLocks) lock(key string) func() {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &refLock{}
+		k.locks[key] = l
+	}
+	l.ref++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		l.ref--
+		if l.ref == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -37,7 +37,7 @@ func TestLocalCodeCache_Set(t *testing.T) {
 			mock: func() *lru.Cache {
 				c, err := lru.New(10)
 				require.NoError(t, err)
-				c.Add("phone_code:login:152", codeItem{
+				c.Add("phone_code:login:152", &codeItem{
 					code: "123456",
 					cnt:  3,
 					// 还有九分钟多过期
@@ -71,7 +71,7 @@ func TestLocalCodeCache_Set(t *testing.T) {
 			mock: func() *lru.Cache {
 				c, err := lru.New(10)
 				require.NoError(t, err)
-				c.Add("phone_code:login:152", codeItem{
+				c.Add("phone_code:login:152", &codeItem{
 					code: "123456",
 					cnt:  3,
 					// 还有八分钟
@@ -88,7 +88,7 @@ func TestLocalCodeCache_Set(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c := NewLocalCodeCache(tc.mock(), time.Minute*10)
-			err := c.Set(context.Background(), tc.biz, tc.phone, tc.code)
+			err := c.Set(context.Background(), tc.biz, tc.phone, tc.code, testCodePolicy)
 			assert.Equal(t, tc.wantErr, err)
 		})
 	}
@@ -111,7 +111,7 @@ func TestLocalCodeCache_Verify(t *testing.T) {
 			mock: func() *lru.Cache {
 				c, err := lru.New(10)
 				require.NoError(t, err)
-				c.Add("phone_code:login:152", codeItem{
+				c.Add("phone_code:login:152", &codeItem{
 					code:   "123456",
 					cnt:    3,
 					expire: time.Now().Add(time.Minute * 8),
@@ -128,7 +128,7 @@ func TestLocalCodeCache_Verify(t *testing.T) {
 			mock: func() *lru.Cache {
 				c, err := lru.New(10)
 				require.NoError(t, err)
-				c.Add("phone_code:login:152", codeItem{
+				c.Add("phone_code:login:152", &codeItem{
 					code:   "123456",
 					cnt:    3,
 					expire: time.Now().Add(time.Minute * 8),
@@ -171,7 +171,7 @@ func TestLocalCodeCache_Verify(t *testing.T) {
 			mock: func() *lru.Cache {
 				c, err := lru.New(10)
 				require.NoError(t, err)
-				c.Add("phone_code:login:152", codeItem{
+				c.Add("phone_code:login:152", &codeItem{
 					code:   "123456",
 					cnt:    0,
 					expire: time.Now().Add(time.Minute * 8),
@@ -194,3 +194,8 @@ func TestLocalCodeCache_Verify(t *testing.T) {
 		})
 	}
 }
+
+func TestLocalCodeCache_Suite(t *testing.T) {
+	c, advance := newTestLocalCodeCache(t)
+	testCodeCache(t, c, advance)
+}
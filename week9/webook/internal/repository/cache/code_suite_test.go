@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// testCodeCache CodeCache 的通用测试，所有的实现都要通过，保证语义一致
+// advance 让时间往前走，本地缓存拨动时钟就可以，Redis 就只能真的等了
+func testCodeCache(t *testing.T, c CodeCache, advance func(d time.Duration)) {
+	// Redis 的过期时间精确到秒，所以时间不能设置得太短
+	policy := domain.CodePolicy{
+		TTL:            time.Second * 3,
+		ResendInterval: time.Second,
+		MaxAttempts:    3,
+	}
+	ctx := context.Background()
+	// 每次都用不同的手机号码，避免测试之间互相影响
+	phone := func() string {
+		return fmt.Sprintf("152%08d", time.Now().UnixNano()%100000000)
+	}
+
+	t.Run("重发间隔内不能重发", func(t *testing.T) {
+		p := phone()
+		require.NoError(t, c.Set(ctx, "login", p, "123456", policy))
+		assert.Equal(t, ErrCodeSendTooMany, c.Set(ctx, "login", p, "234567", policy))
+		// 还是原来的验证码
+		ok, err := c.Verify(ctx, "login", p, "123456")
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("超过重发间隔可以重发", func(t *testing.T) {
+		p := phone()
+		require.NoError(t, c.Set(ctx, "login", p, "123456", policy))
+		advance(policy.ResendInterval + time.Millisecond*600)
+		require.NoError(t, c.Set(ctx, "login", p, "234567", policy))
+		ok, err := c.Verify(ctx, "login", p, "234567")
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("验证通过之后不能再用", func(t *testing.T) {
+		p := phone()
+		require.NoError(t, c.Set(ctx, "login", p, "123456", policy))
+		ok, err := c.Verify(ctx, "login", p, "123456")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = c.Verify(ctx, "login", p, "123456")
+		assert.Equal(t, ErrCodeVerifyTooManyTimes, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("验证次数耗尽", func(t *testing.T) {
+		p := phone()
+		require.NoError(t, c.Set(ctx, "login", p, "123456", policy))
+		for i := 0; i < policy.MaxAttempts; i++ {
+			ok, err := c.Verify(ctx, "login", p, "000000")
+			require.NoError(t, err)
+			assert.False(t, ok)
+		}
+		// 输入对的也没用了
+		ok, err := c.Verify(ctx, "login", p, "123456")
+		assert.Equal(t, ErrCodeVerifyTooManyTimes, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("没有发过验证码", func(t *testing.T) {
+		ok, err := c.Verify(ctx, "login", phone(), "123456")
+		assert.Equal(t, ErrKeyNotExist, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("验证码过期", func(t *testing.T) {
+		p := phone()
+		require.NoError(t, c.Set(ctx, "login", p, "123456", policy))
+		advance(policy.TTL + time.Millisecond*100)
+		ok, err := c.Verify(ctx, "login", p, "123456")
+		assert.Equal(t, ErrKeyNotExist, err)
+		assert.False(t, ok)
+		// 过期之后可以重新发送
+		require.NoError(t, c.Set(ctx, "login", p, "234567", policy))
+	})
+}
+
+// newTestLocalCodeCache 时钟可以拨动的本地缓存
+func newTestLocalCodeCache(t *testing.T) (*LocalCodeCache, func(d time.Duration)) {
+	c, err := lru.New(100)
+	require.NoError(t, err)
+	lc := NewLocalCodeCache(c, time.Minute*10)
+	now := time.Now()
+	lc.now = func() time.Time {
+		return now
+	}
+	return lc, func(d time.Duration) {
+		now = now.Add(d)
+	}
+}
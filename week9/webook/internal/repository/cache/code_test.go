@@ -2,13 +2,22 @@ package cache
 
 import (
 	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/cache/redismocks"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"testing"
+	"time"
 )
 
+// testCodePolicy 有效期十分钟，一分钟之后可以重发，可以验证三次
+var testCodePolicy = domain.CodePolicy{
+	TTL:            time.Minute * 10,
+	ResendInterval: time.Minute,
+	MaxAttempts:    3,
+}
+
 func TestRedisCodeCache_Set(t *testing.T) {
 	testCases := []struct {
 		name string
@@ -30,7 +39,7 @@ func TestRedisCodeCache_Set(t *testing.T) {
 				cmd := redismocks.NewMockCmdable(ctrl)
 				mockRes := redis.NewCmdResult(int64(0), nil)
 				cmd.EXPECT().Eval(gomock.Any(), luaSetCode,
-					gomock.Any(), gomock.Any()).
+					gomock.Any(), "123456", int64(600), int64(60), 3).
 					Return(mockRes)
 				return cmd
 			},
@@ -44,7 +53,7 @@ func TestRedisCodeCache_Set(t *testing.T) {
 				cmd := redismocks.NewMockCmdable(ctrl)
 				mockRes := redis.NewCmdResult(int64(-1), nil)
 				cmd.EXPECT().Eval(gomock.Any(), luaSetCode,
-					gomock.Any(), gomock.Any()).
+					gomock.Any(), "123456", int64(600), int64(60), 3).
 					Return(mockRes)
 				return cmd
 			},
@@ -59,7 +68,7 @@ func TestRedisCodeCache_Set(t *testing.T) {
 				cmd := redismocks.NewMockCmdable(ctrl)
 				mockRes := redis.NewCmdResult(int64(-2), nil)
 				cmd.EXPECT().Eval(gomock.Any(), luaSetCode,
-					gomock.Any(), gomock.Any()).
+					gomock.Any(), "123456", int64(600), int64(60), 3).
 					Return(mockRes)
 				return cmd
 			},
@@ -75,7 +84,7 @@ func TestRedisCodeCache_Set(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
 			c := NewRedisCodeCache(tc.mock(ctrl))
-			err := c.Set(tc.ctx, tc.biz, tc.phone, tc.code)
+			err := c.Set(tc.ctx, tc.biz, tc.phone, tc.code, testCodePolicy)
 			assert.Equal(t, tc.wantErr, err)
 		})
 	}
@@ -0,0 +1,12 @@
+local key = KEYS[1]
+local listKey = KEYS[2]
+local uid = ARGV[1]
+local ssid = ARGV[2]
+
+-- 只能删除自己的会话
+if redis.call("HGET", key, "uid") ~= uid then
+    return 0
+end
+redis.call("DEL", key)
+redis.call("ZREM", listKey, ssid)
+return 1
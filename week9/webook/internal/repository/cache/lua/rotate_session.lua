@@ -0,0 +1,18 @@
+local key = KEYS[1]
+-- 客户端拿来刷新的长 token
+local old = ARGV[1]
+local new = ARGV[2]
+
+local rid = redis.call("HGET", key, "rid")
+if rid == false then
+    -- 会话不存在
+    return 0
+end
+if rid ~= old then
+    -- 旧的长 token 又被用了一次，很可能被偷了，整个会话作废
+    -- 用户的会话列表里面还留着这个 ssid，查询的时候会跳过
+    redis.call("DEL", key)
+    return -1
+end
+redis.call("HSET", key, "rid", new)
+return 1
@@ -0,0 +1,16 @@
+-- 热榜数据的 key
+local key = KEYS[1]
+-- 最近一次写入热榜的 fencing token，不能过期，不然就没法拒绝旧的持有者了
+local tokenKey = KEYS[2]
+local token = tonumber(ARGV[1])
+local val = ARGV[2]
+local expiration = ARGV[3]
+
+local cur = tonumber(redis.call("get", tokenKey))
+if cur ~= nil and cur > token then
+    -- 已经有持有更新的锁的节点写过了，说明当前节点的锁已经丢了
+    return -1
+end
+redis.call("set", tokenKey, token)
+redis.call("set", key, val, "PX", expiration)
+return 0
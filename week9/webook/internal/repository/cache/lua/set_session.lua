@@ -0,0 +1,17 @@
+-- 会话的详情
+local key = KEYS[1]
+-- 用户的会话列表，score 是过期时间
+local listKey = KEYS[2]
+local ssid = ARGV[1]
+local now = ARGV[5]
+local expireAt = ARGV[6]
+
+redis.call("HSET", key, "uid", ARGV[2], "ua", ARGV[3], "ip", ARGV[4],
+        "ctime", now, "utime", now, "expire", expireAt, "rid", ARGV[7])
+redis.call("PEXPIREAT", key, expireAt)
+-- 顺便清理掉已经过期的
+redis.call("ZREMRANGEBYSCORE", listKey, "-inf", now)
+redis.call("ZADD", listKey, expireAt, ssid)
+-- 会话的有效期都一样，所以新的会话总是最晚过期的
+redis.call("PEXPIREAT", listKey, expireAt)
+return 0
@@ -0,0 +1,14 @@
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+-- 多久更新一次最后活跃时间，避免每个请求都写 Redis
+local interval = tonumber(ARGV[2])
+
+local utime = redis.call("HGET", key, "utime")
+if utime == false then
+    -- 会话不存在，已经退出登录、被踢下线或者过期了
+    return 0
+end
+if now - tonumber(utime) >= interval then
+    redis.call("HSET", key, "utime", now)
+end
+return 1
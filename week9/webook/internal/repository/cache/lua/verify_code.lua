@@ -6,6 +6,10 @@ local expectedCode = ARGV[1]
 
 local cnt = tonumber(redis.call("get", cntKey))
 local code = redis.call("get", key)
+-- 没有发过验证码，或者已经过期了
+if cnt == nil or code == false then
+    return -3
+end
 -- 验证次数已经耗尽了
 if cnt <= 0 then
     return -1
@@ -15,10 +19,10 @@ end
 -- 立刻再次再次发送验证码
 if code == expectedCode then
     -- 把次数标记位 -1，认为验证码不可用
-    redis.call("set", cntKey, -1)
+    redis.call("set", cntKey, -1, "KEEPTTL")
     return 0
 else
     -- 可能使用户手一抖输错了
     redis.call("decr", cntKey)
     return -2
-end
\ No newline at end of file
+end
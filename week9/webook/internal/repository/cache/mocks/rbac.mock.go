@@ -0,0 +1,82 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./rbac.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./rbac.go -package=cachemocks -destination=mocks/rbac.mock.go RBACCache
+//
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRBACCache is a mock of RBACCache interface.
+type MockRBACCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockRBACCacheMockRecorder
+}
+
+// MockRBACCacheMockRecorder is the mock recorder for MockRBACCache.
+type MockRBACCacheMockRecorder struct {
+	mock *MockRBACCache
+}
+
+// NewMockRBACCache creates a new mock instance.
+func NewMockRBACCache(ctrl *gomock.Controller) *MockRBACCache {
+	mock := &MockRBACCache{ctrl: ctrl}
+	mock.recorder = &MockRBACCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRBACCache) EXPECT() *MockRBACCacheMockRecorder {
+	return m.recorder
+}
+
+// DeletePermissions mocks base method.
+func (m *MockRBACCache) DeletePermissions(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePermissions", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePermissions indicates an expected call of DeletePermissions.
+func (mr *MockRBACCacheMockRecorder) DeletePermissions(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePermissions", reflect.TypeOf((*MockRBACCache)(nil).DeletePermissions), ctx, uid)
+}
+
+// GetPermissions mocks base method.
+func (m *MockRBACCache) GetPermissions(ctx context.Context, uid int64) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPermissions", ctx, uid)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPermissions indicates an expected call of GetPermissions.
+func (mr *MockRBACCacheMockRecorder) GetPermissions(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPermissions", reflect.TypeOf((*MockRBACCache)(nil).GetPermissions), ctx, uid)
+}
+
+// SetPermissions mocks base method.
+func (m *MockRBACCache) SetPermissions(ctx context.Context, uid int64, perms []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPermissions", ctx, uid, perms)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPermissions indicates an expected call of SetPermissions.
+func (mr *MockRBACCacheMockRecorder) SetPermissions(ctx, uid, perms any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPermissions", reflect.TypeOf((*MockRBACCache)(nil).SetPermissions), ctx, uid, perms)
+}
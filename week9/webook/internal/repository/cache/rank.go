@@ -2,24 +2,36 @@ package cache
 
 import (
 	"context"
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"github.com/redis/go-redis/v9"
 	"time"
 )
 
+var (
+	//go:embed lua/set_rank.lua
+	luaSetRank string
+	// ErrStaleFencingToken 计算热榜的节点已经失去了分布式锁，别的节点已经写入了更新的结果
+	ErrStaleFencingToken = errors.New("fencing token 过期")
+)
+
 type RankingCache interface {
-	Set(ctx context.Context, arts []domain.Article) error
+	// Set token 是计算热榜时持有的分布式锁的 fencing token
+	// 比已经写入的 token 小的时候，返回 ErrStaleFencingToken
+	Set(ctx context.Context, token int64, arts []domain.Article) error
 	Get(ctx context.Context) ([]domain.Article, error)
 }
 
 type RedisRankingCache struct {
 	client     redis.Cmdable
 	key        string
+	tokenKey   string
 	expiration time.Duration
 }
 
-func (r *RedisRankingCache) Set(ctx context.Context, arts []domain.Article) error {
+func (r *RedisRankingCache) Set(ctx context.Context, token int64, arts []domain.Article) error {
 	// 这里我们不会缓存内容
 	for i := 0; i < len(arts); i++ {
 		arts[i].Content = arts[i].Abstract()
@@ -29,8 +41,16 @@ func (r *RedisRankingCache) Set(ctx context.Context, arts []domain.Article) erro
 		return err
 	}
 	// 过期时间要设置得比定时计算的间隔长
-	return r.client.Set(ctx, r.key, val,
-		r.expiration).Err()
+	res, err := r.client.Eval(ctx, luaSetRank,
+		[]string{r.key, r.tokenKey},
+		token, val, r.expiration.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if res == -1 {
+		return ErrStaleFencingToken
+	}
+	return nil
 }
 
 func (r *RedisRankingCache) Get(ctx context.Context) ([]domain.Article, error) {
@@ -40,12 +60,14 @@ func (r *RedisRankingCache) Get(ctx context.Context) ([]domain.Article, error) {
 	}
 	var res []domain.Article
 	err = json.Unmarshal(val, &res)
-	return nil, err
+	return res, err
 }
 
 func NewRedisRankingCache(client redis.Cmdable) *RedisRankingCache {
 	return &RedisRankingCache{
-		key:        "ranking:article",
+		// 用同一个 hash tag，Redis Cluster 上才能在一个脚本里面操作这两个 key
+		key:        "{ranking:article}",
+		tokenKey:   "{ranking:article}:fencing_token",
 		client:     client,
 		expiration: time.Minute * 3,
 	}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// RBACCache 缓存用户的权限，每一个需要权限的请求都要查一次
+//
+//go:generate mockgen -source=./rbac.go -package=cachemocks -destination=mocks/rbac.mock.go RBACCache
+type RBACCache interface {
+	GetPermissions(ctx context.Context, uid int64) ([]string, error)
+	SetPermissions(ctx context.Context, uid int64, perms []string) error
+	DeletePermissions(ctx context.Context, uid int64) error
+}
+
+type RedisRBACCache struct {
+	cmd redis.Cmdable
+	// 角色的权限是直接在数据库里面改的，改了之后最多这么久才生效
+	expiration time.Duration
+}
+
+func NewRedisRBACCache(cmd redis.Cmdable) RBACCache {
+	return &RedisRBACCache{
+		cmd:        cmd,
+		expiration: time.Minute * 15,
+	}
+}
+
+func (cache *RedisRBACCache) GetPermissions(ctx context.Context, uid int64) ([]string, error) {
+	data, err := cache.cmd.Get(ctx, cache.key(uid)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var perms []string
+	err = json.Unmarshal(data, &perms)
+	return perms, err
+}
+
+func (cache *RedisRBACCache) SetPermissions(ctx context.Context, uid int64, perms []string) error {
+	// 没有任何权限的也要缓存，绝大多数用户都是这样的
+	if perms == nil {
+		perms = []string{}
+	}
+	data, err := json.Marshal(perms)
+	if err != nil {
+		return err
+	}
+	return cache.cmd.Set(ctx, cache.key(uid), data, cache.expiration).Err()
+}
+
+func (cache *RedisRBACCache) DeletePermissions(ctx context.Context, uid int64) error {
+	return cache.cmd.Del(ctx, cache.key(uid)).Err()
+}
+
+func (cache *RedisRBACCache) key(uid int64) string {
+	return fmt.Sprintf("users:permissions:%d", uid)
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+var (
+	//go:embed lua/set_session.lua
+	luaSetSession string
+	//go:embed lua/touch_session.lua
+	luaTouchSession string
+	//go:embed lua/del_session.lua
+	luaDelSession string
+	//go:embed lua/rotate_session.lua
+	luaRotateSession string
+
+	ErrRefreshTokenReused = errors.New("长 token 被重复使用")
+)
+
+//go:generate mockgen -source=./session.go -package=cachemocks -destination=mocks/session.mock.go SessionCache
+type SessionCache interface {
+	Set(ctx context.Context, s domain.Session) error
+	// Touch 会话存在的时候返回 true，距离上一次更新超过 interval 的，会更新最后活跃时间
+	Touch(ctx context.Context, uid int64, ssid string, now time.Time, interval time.Duration) (bool, error)
+	// Rotate 把会话当前的长 token 从 oldRid 换成 newRid。
+	// 会话不存在的时候返回 ErrKeyNotExist，
+	// oldRid 已经被换掉了的时候，删除整个会话，返回 ErrRefreshTokenReused
+	Rotate(ctx context.Context, uid int64, ssid string, oldRid string, newRid string) error
+	// ListByUid 用户所有没有过期的会话
+	ListByUid(ctx context.Context, uid int64) ([]domain.Session, error)
+	// Delete 会话不存在或者不属于 uid 的时候返回 ErrKeyNotExist
+	Delete(ctx context.Context, uid int64, ssid string) error
+	DeleteByUid(ctx context.Context, uid int64) error
+	// DeleteOthers 删除 uid 除了 keepSsid 以外的所有会话
+	DeleteOthers(ctx context.Context, uid int64, keepSsid string) error
+}
+
+// RedisSessionCache 每个会话是一个 hash，每个用户还有一个 zset 记录他有哪些会话。
+// key 里面用 {uid} 做 hash tag，同一个用户的 key 都在一个 slot 里面，Redis Cluster 下面 lua 脚本才能同时操作它们
+type RedisSessionCache struct {
+	cmd redis.Cmdable
+}
+
+func NewRedisSessionCache(cmd redis.Cmdable) SessionCache {
+	return &RedisSessionCache{
+		cmd: cmd,
+	}
+}
+
+func (c *RedisSessionCache) Set(ctx context.Context, s domain.Session) error {
+	return c.cmd.Eval(ctx, luaSetSession,
+		[]string{c.key(s.Uid, s.Ssid), c.listKey(s.Uid)},
+		s.Ssid, s.Uid, s.UserAgent, s.IP,
+		s.Ctime.UnixMilli(), s.Expire.UnixMilli(), s.RefreshId).Err()
+}
+
+func (c *RedisSessionCache) Touch(ctx context.Context, uid int64, ssid string,
+	now time.Time, interval time.Duration) (bool, error) {
+	res, err := c.cmd.Eval(ctx, luaTouchSession, []string{c.key(uid, ssid)},
+		now.UnixMilli(), interval.Milliseconds()).Int()
+	return res == 1, err
+}
+
+func (c *RedisSessionCache) Rotate(ctx context.Context, uid int64, ssid string,
+	oldRid string, newRid string) error {
+	res, err := c.cmd.Eval(ctx, luaRotateSession, []string{c.key(uid, ssid)},
+		oldRid, newRid).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 1:
+		return nil
+	case 0:
+		return ErrKeyNotExist
+	default:
+		return ErrRefreshTokenReused
+	}
+}
+
+func (c *RedisSessionCache) ListByUid(ctx context.Context, uid int64) ([]domain.Session, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	ssids, err := c.cmd.ZRangeByScore(ctx, c.listKey(uid), &redis.ZRangeBy{
+		Min: now,
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ssids) == 0 {
+		return nil, nil
+	}
+	pipe := c.cmd.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ssids))
+	for _, ssid := range ssids {
+		cmds = append(cmds, pipe.HGetAll(ctx, c.key(uid, ssid)))
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.Session, 0, len(ssids))
+	for i, cmd := range cmds {
+		vals := cmd.Val()
+		// 已经被删除了，但是还没从列表里面移除
+		if len(vals) == 0 {
+			continue
+		}
+		res = append(res, c.toDomain(ssids[i], vals))
+	}
+	return res, nil
+}
+
+func (c *RedisSessionCache) Delete(ctx context.Context, uid int64, ssid string) error {
+	res, err := c.cmd.Eval(ctx, luaDelSession,
+		[]string{c.key(uid, ssid), c.listKey(uid)}, uid, ssid).Int()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return ErrKeyNotExist
+	}
+	return nil
+}
+
+func (c *RedisSessionCache) DeleteByUid(ctx context.Context, uid int64) error {
+	ssids, err := c.cmd.ZRange(ctx, c.listKey(uid), 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(ssids)+1)
+	for _, ssid := range ssids {
+		keys = append(keys, c.key(uid, ssid))
+	}
+	keys = append(keys, c.listKey(uid))
+	return c.cmd.Del(ctx, keys...).Err()
+}
+
+func (c *RedisSessionCache) DeleteOthers(ctx context.Context, uid int64, keepSsid string) error {
+	ssids, err := c.cmd.ZRange(ctx, c.listKey(uid), 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(ssids))
+	members := make([]any, 0, len(ssids))
+	for _, ssid := range ssids {
+		if ssid == keepSsid {
+			continue
+		}
+		keys = append(keys, c.key(uid, ssid))
+		members = append(members, ssid)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	pipe := c.cmd.TxPipeline()
+	pipe.Del(ctx, keys...)
+	pipe.ZRem(ctx, c.listKey(uid), members...)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (c *RedisSessionCache) toDomain(ssid string, vals map[string]string) domain.Session {
+	uid, _ := strconv.ParseInt(vals["uid"], 10, 64)
+	ctime, _ := strconv.ParseInt(vals["ctime"], 10, 64)
+	utime, _ := strconv.ParseInt(vals["utime"], 10, 64)
+	expire, _ := strconv.ParseInt(vals["expire"], 10, 64)
+	return domain.Session{
+		Ssid:      ssid,
+		Uid:       uid,
+		UserAgent: vals["ua"],
+		IP:        vals["ip"],
+		Ctime:     time.UnixMilli(ctime),
+		Utime:     time.UnixMilli(utime),
+		Expire:    time.UnixMilli(expire),
+		RefreshId: vals["rid"],
+	}
+}
+
+func (c *RedisSessionCache) key(uid int64, ssid string) string {
+	return fmt.Sprintf("users:{%d}:session:%s", uid, ssid)
+}
+
+func (c *RedisSessionCache) listKey(uid int64) string {
+	return fmt.Sprintf("users:{%d}:sessions", uid)
+}
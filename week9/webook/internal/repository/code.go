@@ -2,18 +2,21 @@ package repository
 
 import (
 	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
 )
 
 var (
 	ErrCodeVerifyTooManyTimes = cache.ErrCodeVerifyTooManyTimes
 	ErrCodeSendTooMany        = cache.ErrCodeSendTooMany
+	// ErrCodeNotExist 没有发过验证码，或者已经过期了
+	ErrCodeNotExist = cache.ErrKeyNotExist
 )
 
 //go:generate mockgen -source=./code.go -package=repomocks -destination=mocks/code.mock.go CodeRepository
 type CodeRepository interface {
 	Store(ctx context.Context, biz string,
-		phone string, code string) error
+		phone string, code string, policy domain.CodePolicy) error
 
 	Verify(ctx context.Context, biz string,
 		phone string, inputCode string) (bool, error)
@@ -32,8 +35,9 @@ func NewCachedCodeRepository(c cache.CodeCache) CodeRepository {
 func (repo *CachedCodeRepository) Store(ctx context.Context,
 	biz string,
 	phone string,
-	code string) error {
-	err := repo.cache.Set(ctx, biz, phone, code)
+	code string,
+	policy domain.CodePolicy) error {
+	err := repo.cache.Set(ctx, biz, phone, code, policy)
 	return err
 }
 
@@ -7,14 +7,18 @@ import (
 	"time"
 )
 
-var ErrNoMoreJob = dao.ErrNoMoreJob
+var (
+	ErrNoMoreJob  = dao.ErrNoMoreJob
+	ErrJobNotHold = dao.ErrJobNotHold
+)
 
 //go:generate mockgen -source=./cron_job.go -package=repomocks -destination=mocks/cron_job.mock.go CronJobRepository
 type CronJobRepository interface {
 	Preempt(ctx context.Context) (domain.CronJob, error)
-	UpdateNextTime(ctx context.Context, id int64, t time.Time) error
-	UpdateUtime(ctx context.Context, id int64) error
-	Release(ctx context.Context, id int64) error
+	// UpdateNextTime、UpdateUtime 和 Release 都要求 version 和抢占时候的一致
+	UpdateNextTime(ctx context.Context, id, version int64, t time.Time) error
+	UpdateUtime(ctx context.Context, id, version int64) error
+	Release(ctx context.Context, id, version int64) error
 	AddJob(ctx context.Context, j domain.CronJob) error
 }
 
@@ -26,16 +30,16 @@ func (p *PreemptCronJobRepository) AddJob(ctx context.Context, j domain.CronJob)
 	return p.dao.Insert(ctx, p.toEntity(j))
 }
 
-func (p *PreemptCronJobRepository) Release(ctx context.Context, id int64) error {
-	return p.dao.Release(ctx, id)
+func (p *PreemptCronJobRepository) Release(ctx context.Context, id, version int64) error {
+	return p.dao.Release(ctx, id, version)
 }
 
 func NewPreemptCronJobRepository(dao dao.JobDAO) CronJobRepository {
 	return &PreemptCronJobRepository{dao: dao}
 }
 
-func (p *PreemptCronJobRepository) UpdateUtime(ctx context.Context, id int64) error {
-	return p.dao.UpdateUtime(ctx, id)
+func (p *PreemptCronJobRepository) UpdateUtime(ctx context.Context, id, version int64) error {
+	return p.dao.UpdateUtime(ctx, id, version)
 }
 
 func (p *PreemptCronJobRepository) Preempt(ctx context.Context) (domain.CronJob, error) {
@@ -46,8 +50,8 @@ func (p *PreemptCronJobRepository) Preempt(ctx context.Context) (domain.CronJob,
 	return p.toDomain(j), nil
 }
 
-func (p *PreemptCronJobRepository) UpdateNextTime(ctx context.Context, id int64, t time.Time) error {
-	return p.dao.UpdateNextTime(ctx, id, t)
+func (p *PreemptCronJobRepository) UpdateNextTime(ctx context.Context, id, version int64, t time.Time) error {
+	return p.dao.UpdateNextTime(ctx, id, version, t)
 }
 
 func (p *PreemptCronJobRepository) toEntity(j domain.CronJob) dao.Job {
@@ -69,5 +73,6 @@ func (p *PreemptCronJobRepository) toDomain(j dao.Job) domain.CronJob {
 		Cfg:        j.Cfg,
 		Executor:   j.Executor,
 		NextTime:   time.UnixMilli(j.NextTime),
+		Version:    j.Version,
 	}
 }
@@ -0,0 +1,228 @@
+package dao
+
+import (
+	"context"
+	intrdao "gitee.com/geekbang/basic-go/webook/interactive/repository/dao"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao/article"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+//go:generate mockgen -source=./account_merge.go -package=daomocks -destination=mocks/account_merge.mock.go AccountMergeDAO
+type AccountMergeDAO interface {
+	// Merge 在一个事务里面把 sourceUid 的数据迁移到 targetUid，删除 sourceUid，并且记录审计日志
+	Merge(ctx context.Context, sourceUid int64, targetUid int64) (UserMergeLog, error)
+	FindLogsByUid(ctx context.Context, uid int64) ([]UserMergeLog, error)
+}
+
+// GORMAccountMergeDAO 账号合并要改动文章、点赞、收藏几个模块的表，
+// 为了放在同一个事务里面，只能直接操作这些表，而不是调用它们的 DAO
+type GORMAccountMergeDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMAccountMergeDAO(db *gorm.DB) AccountMergeDAO {
+	return &GORMAccountMergeDAO{
+		db: db,
+	}
+}
+
+func (d *GORMAccountMergeDAO) Merge(ctx context.Context, sourceUid int64, targetUid int64) (UserMergeLog, error) {
+	var log UserMergeLog
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		// 锁住两个用户，防止并发合并
+		var users []User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []int64{sourceUid, targetUid}).
+			Find(&users).Error
+		if err != nil {
+			return err
+		}
+		if len(users) != 2 {
+			return ErrDataNotFound
+		}
+		src, dst := users[0], users[1]
+		if src.Id != sourceUid {
+			src, dst = dst, src
+		}
+		log = UserMergeLog{
+			SourceUid:   sourceUid,
+			TargetUid:   targetUid,
+			SourcePhone: src.Phone.String,
+			SourceEmail: src.Email.String,
+			Ctime:       now,
+		}
+
+		log.Articles, err = d.mergeArticles(tx, sourceUid, targetUid)
+		if err != nil {
+			return err
+		}
+		log.Likes, err = d.mergeLikes(tx, sourceUid, targetUid, now)
+		if err != nil {
+			return err
+		}
+		log.Collections, err = d.mergeCollections(tx, sourceUid, targetUid, now)
+		if err != nil {
+			return err
+		}
+		res := tx.Model(&UserOAuthBinding{}).Where("uid = ?", sourceUid).
+			Updates(map[string]any{"uid": targetUid, "utime": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		log.Bindings = res.RowsAffected
+
+		// 先删掉 source，手机号码和邮箱的唯一索引才不会冲突
+		err = tx.Delete(&User{}, "id = ?", sourceUid).Error
+		if err != nil {
+			return err
+		}
+		updates := map[string]any{"utime": now}
+		if !dst.Phone.Valid && src.Phone.Valid {
+			updates["phone"] = src.Phone
+		}
+		if !dst.Email.Valid && src.Email.Valid {
+			updates["email"] = src.Email
+			// 邮箱是和密码一起用的，保留下来的账号没有密码，就用被合并的账号的
+			if dst.Password == "" {
+				updates["password"] = src.Password
+			}
+		}
+		err = tx.Model(&User{}).Where("id = ?", targetUid).Updates(updates).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&log).Error
+	})
+	return log, err
+}
+
+func (d *GORMAccountMergeDAO) mergeArticles(tx *gorm.DB, sourceUid int64, targetUid int64) (int64, error) {
+	res := tx.Model(&article.Article{}).Where("author_id = ?", sourceUid).
+		Update("author_id", targetUid)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	// 线上库和制作库的数据是一一对应的，所以只统计制作库的
+	err := tx.Model(&article.PublishedArticle{}).Where("author_id = ?", sourceUid).
+		Update("author_id", targetUid).Error
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Model(&article.PublishedArticleV1{}).Where("author_id = ?", sourceUid).
+		Update("author_id", targetUid).Error
+	return res.RowsAffected, err
+}
+
+// mergeLikes 两个账号都点赞过同一个资源的，合并之后只能算一次
+func (d *GORMAccountMergeDAO) mergeLikes(tx *gorm.DB, sourceUid int64, targetUid int64, now int64) (int64, error) {
+	var likes []intrdao.UserLikeBiz
+	err := tx.Where("uid = ?", sourceUid).Find(&likes).Error
+	if err != nil {
+		return 0, err
+	}
+	for _, l := range likes {
+		var exist intrdao.UserLikeBiz
+		err = tx.Where("biz = ? AND biz_id = ? AND uid = ?", l.Biz, l.BizId, targetUid).
+			First(&exist).Error
+		switch err {
+		case gorm.ErrRecordNotFound:
+			err = tx.Model(&intrdao.UserLikeBiz{}).Where("id = ?", l.Id).
+				Updates(map[string]any{"uid": targetUid, "utime": now}).Error
+		case nil:
+			err = d.mergeDuplicateLike(tx, l, exist, now)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(likes)), nil
+}
+
+func (d *GORMAccountMergeDAO) mergeDuplicateLike(tx *gorm.DB,
+	src intrdao.UserLikeBiz, dst intrdao.UserLikeBiz, now int64) error {
+	if src.Status == 1 {
+		var err error
+		if dst.Status == 1 {
+			// 两个都是有效的点赞，点赞数要减掉一个
+			err = d.decrCnt(tx, src.Biz, src.BizId, "like_cnt", now)
+		} else {
+			// 只有 source 的是有效的，那么保留下来的账号也变成有效的，点赞数不变
+			err = tx.Model(&intrdao.UserLikeBiz{}).Where("id = ?", dst.Id).
+				Updates(map[string]any{"status": 1, "utime": now}).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&intrdao.UserLikeBiz{}, "id = ?", src.Id).Error
+}
+
+// mergeCollections 收藏夹直接迁移，两个账号都收藏过同一个资源的，只保留一个
+func (d *GORMAccountMergeDAO) mergeCollections(tx *gorm.DB, sourceUid int64, targetUid int64, now int64) (int64, error) {
+	err := tx.Model(&intrdao.Collection{}).Where("uid = ?", sourceUid).
+		Updates(map[string]any{"uid": targetUid, "utime": now}).Error
+	if err != nil {
+		return 0, err
+	}
+	var cbs []intrdao.UserCollectionBiz
+	err = tx.Where("uid = ?", sourceUid).Find(&cbs).Error
+	if err != nil {
+		return 0, err
+	}
+	for _, cb := range cbs {
+		var cnt int64
+		err = tx.Model(&intrdao.UserCollectionBiz{}).
+			Where("biz = ? AND biz_id = ? AND uid = ?", cb.Biz, cb.BizId, targetUid).
+			Count(&cnt).Error
+		if err != nil {
+			return 0, err
+		}
+		if cnt == 0 {
+			err = tx.Model(&intrdao.UserCollectionBiz{}).Where("id = ?", cb.Id).
+				Updates(map[string]any{"uid": targetUid, "utime": now}).Error
+		} else {
+			err = tx.Delete(&intrdao.UserCollectionBiz{}, "id = ?", cb.Id).Error
+			if err == nil {
+				err = d.decrCnt(tx, cb.Biz, cb.BizId, "collect_cnt", now)
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(cbs)), nil
+}
+
+func (d *GORMAccountMergeDAO) decrCnt(tx *gorm.DB, biz string, bizId int64, col string, now int64) error {
+	return tx.Model(&intrdao.Interactive{}).
+		Where("biz = ? AND biz_id = ? AND "+col+" > 0", biz, bizId).
+		Updates(map[string]any{
+			col:     gorm.Expr(col + " - 1"),
+			"utime": now,
+		}).Error
+}
+
+func (d *GORMAccountMergeDAO) FindLogsByUid(ctx context.Context, uid int64) ([]UserMergeLog, error) {
+	var res []UserMergeLog
+	err := d.db.WithContext(ctx).
+		Where("target_uid = ? OR source_uid = ?", uid, uid).
+		Order("id DESC").Find(&res).Error
+	return res, err
+}
+
+// UserMergeLog 账号合并的审计记录
+type UserMergeLog struct {
+	Id          int64  `gorm:"primaryKey,autoIncrement"`
+	SourceUid   int64  `gorm:"index"`
+	TargetUid   int64  `gorm:"index"`
+	SourcePhone string `gorm:"type:varchar(32)"`
+	SourceEmail string `gorm:"type:varchar(256)"`
+	Articles    int64
+	Likes       int64
+	Collections int64
+	Bindings    int64
+	Ctime       int64
+}
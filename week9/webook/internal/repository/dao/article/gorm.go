@@ -3,11 +3,14 @@ package article
 import (
 	"context"
 	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"time"
 )
 
+var statusPublished = domain.ArticleStatusPublished.ToUint8()
+
 type GORMArticleDAO struct {
 	db *gorm.DB
 }
@@ -31,6 +34,25 @@ func (dao *GORMArticleDAO) GetByAuthor(ctx context.Context, author int64, offset
 	return arts, err
 }
 
+func (dao *GORMArticleDAO) GetPubByAuthor(ctx context.Context, author int64, offset, limit int) ([]PublishedArticle, error) {
+	var res []PublishedArticle
+	err := dao.db.WithContext(ctx).
+		Where("author_id = ? AND status = ?", author, statusPublished).
+		Offset(offset).
+		Limit(limit).
+		Order("utime DESC").
+		Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMArticleDAO) CountPubByAuthor(ctx context.Context, author int64) (int64, error) {
+	var cnt int64
+	err := dao.db.WithContext(ctx).Model(&PublishedArticle{}).
+		Where("author_id = ? AND status = ?", author, statusPublished).
+		Count(&cnt).Error
+	return cnt, err
+}
+
 func (dao *GORMArticleDAO) GetPubById(ctx context.Context, id int64) (PublishedArticle, error) {
 	var pub PublishedArticle
 	err := dao.db.WithContext(ctx).
@@ -47,6 +69,16 @@ func (dao *GORMArticleDAO) GetById(ctx context.Context, id int64) (Article, erro
 	return art, err
 }
 
+func (dao *GORMArticleDAO) DeleteByAuthor(ctx context.Context, author int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("author_id = ?", author).Delete(&Article{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("author_id = ?", author).Delete(&PublishedArticle{}).Error
+	})
+}
+
 func NewGORMArticleDAO(db *gorm.DB) ArticleDAO {
 	return &GORMArticleDAO{
 		db: db,
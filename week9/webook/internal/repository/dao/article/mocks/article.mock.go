@@ -11,6 +11,7 @@ package artdaomocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	article "gitee.com/geekbang/basic-go/webook/internal/repository/dao/article"
 	gomock "go.uber.org/mock/gomock"
@@ -39,6 +40,35 @@ func (m *MockArticleDAO) EXPECT() *MockArticleDAOMockRecorder {
 	return m.recorder
 }
 
+// CountPubByAuthor mocks base method.
+func (m *MockArticleDAO) CountPubByAuthor(ctx context.Context, author int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountPubByAuthor", ctx, author)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountPubByAuthor indicates an expected call of CountPubByAuthor.
+func (mr *MockArticleDAOMockRecorder) CountPubByAuthor(ctx, author any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountPubByAuthor", reflect.TypeOf((*MockArticleDAO)(nil).CountPubByAuthor), ctx, author)
+}
+
+// DeleteByAuthor mocks base method.
+func (m *MockArticleDAO) DeleteByAuthor(ctx context.Context, author int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByAuthor", ctx, author)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByAuthor indicates an expected call of DeleteByAuthor.
+func (mr *MockArticleDAOMockRecorder) DeleteByAuthor(ctx, author any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByAuthor", reflect.TypeOf((*MockArticleDAO)(nil).DeleteByAuthor), ctx, author)
+}
+
 // GetByAuthor mocks base method.
 func (m *MockArticleDAO) GetByAuthor(ctx context.Context, author int64, offset, limit int) ([]article.Article, error) {
 	m.ctrl.T.Helper()
@@ -69,6 +99,21 @@ func (mr *MockArticleDAOMockRecorder) GetById(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockArticleDAO)(nil).GetById), ctx, id)
 }
 
+// GetPubByAuthor mocks base method.
+func (m *MockArticleDAO) GetPubByAuthor(ctx context.Context, author int64, offset, limit int) ([]article.PublishedArticle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPubByAuthor", ctx, author, offset, limit)
+	ret0, _ := ret[0].([]article.PublishedArticle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPubByAuthor indicates an expected call of GetPubByAuthor.
+func (mr *MockArticleDAOMockRecorder) GetPubByAuthor(ctx, author, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPubByAuthor", reflect.TypeOf((*MockArticleDAO)(nil).GetPubByAuthor), ctx, author, offset, limit)
+}
+
 // GetPubById mocks base method.
 func (m *MockArticleDAO) GetPubById(ctx context.Context, id int64) (article.PublishedArticle, error) {
 	m.ctrl.T.Helper()
@@ -99,6 +144,21 @@ func (mr *MockArticleDAOMockRecorder) Insert(ctx, art any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockArticleDAO)(nil).Insert), ctx, art)
 }
 
+// ListPubByUtime mocks base method.
+func (m *MockArticleDAO) ListPubByUtime(ctx context.Context, utime time.Time, offset, limit int) ([]article.PublishedArticle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPubByUtime", ctx, utime, offset, limit)
+	ret0, _ := ret[0].([]article.PublishedArticle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPubByUtime indicates an expected call of ListPubByUtime.
+func (mr *MockArticleDAOMockRecorder) ListPubByUtime(ctx, utime, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPubByUtime", reflect.TypeOf((*MockArticleDAO)(nil).ListPubByUtime), ctx, utime, offset, limit)
+}
+
 // Sync mocks base method.
 func (m *MockArticleDAO) Sync(ctx context.Context, art article.Article) (int64, error) {
 	m.ctrl.T.Helper()
@@ -21,6 +21,16 @@ func (m *MongoDBDAO) ListPubByUtime(ctx context.Context, utime time.Time, offset
 	panic("implement me")
 }
 
+func (m *MongoDBDAO) GetPubByAuthor(ctx context.Context, author int64, offset, limit int) ([]PublishedArticle, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m *MongoDBDAO) CountPubByAuthor(ctx context.Context, author int64) (int64, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (m *MongoDBDAO) GetPubById(ctx context.Context, id int64) (PublishedArticle, error) {
 	//TODO implement me
 	panic("implement me")
@@ -36,6 +46,11 @@ func (m *MongoDBDAO) GetById(ctx context.Context, id int64) (Article, error) {
 	panic("implement me")
 }
 
+func (m *MongoDBDAO) DeleteByAuthor(ctx context.Context, author int64) error {
+	//TODO implement me
+	panic("implement me")
+}
+
 func InitCollections(db *mongo.Database) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
@@ -18,4 +18,9 @@ type ArticleDAO interface {
 	Sync(ctx context.Context, art Article) (int64, error)
 	SyncStatus(ctx context.Context, author, id int64, status uint8) error
 	ListPubByUtime(ctx context.Context, utime time.Time, offset int, limit int) ([]PublishedArticle, error)
+	// DeleteByAuthor 删除作者所有的文章，包括制作库和线上库
+	DeleteByAuthor(ctx context.Context, author int64) error
+	// GetPubByAuthor 作者在线上库里面已发表的文章，按照更新时间倒序
+	GetPubByAuthor(ctx context.Context, author int64, offset, limit int) ([]PublishedArticle, error)
+	CountPubByAuthor(ctx context.Context, author int64) (int64, error)
 }
@@ -2,13 +2,20 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"github.com/ecodeclub/ekit/sqlx"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"math/rand"
+	"strings"
 	"time"
 )
 
-var ErrWaitingSMSNotFound = gorm.ErrRecordNotFound
+var (
+	ErrWaitingSMSNotFound = gorm.ErrRecordNotFound
+	// ErrAsyncSmsNotRequeueable 记录不存在，或者已经发送成功了
+	ErrAsyncSmsNotRequeueable = errors.New("异步短信不能重新入队")
+)
 
 //go:generate mockgen -source=./async_sms.go -package=daomocks -destination=mocks/async_sms.mock.go AsyncSmsDAO
 type AsyncSmsDAO interface {
@@ -16,82 +23,220 @@ type AsyncSmsDAO interface {
 	GetWaitingSMS(ctx context.Context) (AsyncSms, error)
 	MarkSuccess(ctx context.Context, id int64) error
 	MarkFailed(ctx context.Context, id int64) error
+	// GetWaitingSMSBatch 一次抢占最多 limit 条，没有可以发送的就返回空切片
+	GetWaitingSMSBatch(ctx context.Context, limit int) ([]AsyncSms, error)
+	MarkSuccessBatch(ctx context.Context, ids []int64) error
+	MarkFailedBatch(ctx context.Context, ids []int64) error
+
+	// 下面是给管理后台用的
+
+	FindById(ctx context.Context, id int64) (AsyncSms, error)
+	ListByStatus(ctx context.Context, status uint8, offset, limit int) ([]AsyncSms, error)
+	// Requeue 把一条记录重新放回等待队列，重试次数清零
+	Requeue(ctx context.Context, id int64) error
+	// CountByStatus 统计每个状态下有多少条记录
+	CountByStatus(ctx context.Context) (map[uint8]int64, error)
+	// DeleteSuccessBefore 删除 utime 早于 t 的发送成功的记录，最多删除 limit 条
+	DeleteSuccessBefore(ctx context.Context, t int64, limit int) (int64, error)
 }
 
 const (
-	// 因为本身状态没有暴露出去，所以不需要在 domain 里面定义
-	asyncStatusWaiting = iota
-	// 失败了，并且超过了重试次数
-	asyncStatusFailed
-	asyncStatusSuccess
+	AsyncStatusWaiting = iota
+	// AsyncStatusFailed 失败了，并且超过了重试次数
+	AsyncStatusFailed
+	AsyncStatusSuccess
 )
 
 type GORMAsyncSmsDAO struct {
 	db *gorm.DB
+	// 重试间隔的初始值，每重试一次翻倍
+	backoffBase time.Duration
+	// 重试间隔的最大值
+	backoffMax time.Duration
 }
 
 func NewGORMAsyncSmsDAO(db *gorm.DB) AsyncSmsDAO {
 	return &GORMAsyncSmsDAO{
-		db: db,
+		db:          db,
+		backoffBase: time.Minute,
+		backoffMax:  time.Minute * 30,
 	}
 }
 
 func (g *GORMAsyncSmsDAO) Insert(ctx context.Context, s AsyncSms) error {
-	return g.db.Create(&s).Error
+	now := time.Now()
+	s.Ctime = now.UnixMilli()
+	s.Utime = now.UnixMilli()
+	// 为了避开一些偶发性的失败，我们一分钟之后才开始第一次异步发送
+	s.NextTime = now.Add(g.backoffBase).UnixMilli()
+	return g.db.WithContext(ctx).Create(&s).Error
 }
 
 func (g *GORMAsyncSmsDAO) GetWaitingSMS(ctx context.Context) (AsyncSms, error) {
-	// 如果在高并发情况下,SELECT for UPDATE 对数据库的压力很大
-	// 但是我们不是高并发，因为你部署N台机器，才有 N 个goroutine 来查询
-	// 并发不过百，随便写
-	var s AsyncSms
+	res, err := g.GetWaitingSMSBatch(ctx, 1)
+	if err != nil {
+		return AsyncSms{}, err
+	}
+	if len(res) == 0 {
+		return AsyncSms{}, ErrWaitingSMSNotFound
+	}
+	return res[0], nil
+}
+
+func (g *GORMAsyncSmsDAO) GetWaitingSMSBatch(ctx context.Context, limit int) ([]AsyncSms, error) {
+	// SKIP LOCKED 会跳过已经被别的节点锁住的行，
+	// 所以多个节点同时抢占的时候，彼此之间不会阻塞，而是各自拿到不同的一批
+	var res []AsyncSms
 	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 为了避开一些偶发性的失败，我们只找 1 分钟前的异步短信发送
-		now := time.Now().UnixMilli()
-		endTime := now - time.Minute.Milliseconds()
-		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("utime < ? and status = ?",
-				endTime, asyncStatusWaiting).First(&s).Error
-		// SELECT xx FROM xxx WHERE xx FOR UPDATE，锁住了
-		if err != nil {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("next_time <= ? and status = ?",
+				now.UnixMilli(), AsyncStatusWaiting).
+			Order("next_time").
+			Limit(limit).Find(&res).Error
+		if err != nil || len(res) == 0 {
 			return err
 		}
 
-		// 只要更新了更新时间，根据我们前面的规则，就不可能被别的节点抢占了
-		err = tx.Model(&AsyncSms{}).
-			Where("id = ?", s.Id).
+		// 只要把下一次的时间往后推，根据我们前面的规则，就不可能被别的节点抢占了
+		// 如果这一次发送失败了，那么到了 next_time 就会被再次抢占，也就是重试
+		// 每一条的重试次数不同，退避的时间也不同，所以用 CASE 一次性更新
+		ids := make([]int64, 0, len(res))
+		var sb strings.Builder
+		args := make([]any, 0, len(res)*2)
+		sb.WriteString("CASE id")
+		for _, s := range res {
+			ids = append(ids, s.Id)
+			sb.WriteString(" WHEN ? THEN ?")
+			args = append(args, s.Id, now.Add(g.backoff(s.RetryCnt)).UnixMilli())
+		}
+		sb.WriteString(" END")
+		return tx.Model(&AsyncSms{}).
+			Where("id IN ?", ids).
 			Updates(map[string]any{
 				"retry_cnt": gorm.Expr("retry_cnt + 1"),
-				// 更新成了当前时间戳，确保我在发送过程中，没人会再次抢到它
-				// 也相当于，重试间隔一分钟
-				"utime": now,
+				"utime":     now.UnixMilli(),
+				"next_time": gorm.Expr(sb.String(), args...),
 			}).Error
-		return err
 	})
-	return s, err
+	return res, err
+}
+
+// backoff 指数退避，并且加上随机抖动，
+// 避免服务商恢复的时候，大量的重试请求在同一时刻涌过去
+func (g *GORMAsyncSmsDAO) backoff(retryCnt int) time.Duration {
+	interval := g.backoffBase
+	for i := 0; i < retryCnt && interval < g.backoffMax; i++ {
+		interval = interval * 2
+	}
+	if interval > g.backoffMax {
+		interval = g.backoffMax
+	}
+	// 一半固定，一半随机
+	half := interval / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
 }
 
 func (g *GORMAsyncSmsDAO) MarkSuccess(ctx context.Context, id int64) error {
+	return g.MarkSuccessBatch(ctx, []int64{id})
+}
+
+func (g *GORMAsyncSmsDAO) MarkFailed(ctx context.Context, id int64) error {
+	return g.MarkFailedBatch(ctx, []int64{id})
+}
+
+func (g *GORMAsyncSmsDAO) MarkSuccessBatch(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
 	now := time.Now().UnixMilli()
 	return g.db.WithContext(ctx).Model(&AsyncSms{}).
-		Where("id =?", id).
+		Where("id IN ?", ids).
 		Updates(map[string]any{
 			"utime":  now,
-			"status": asyncStatusSuccess,
+			"status": AsyncStatusSuccess,
 		}).Error
 }
 
-func (g *GORMAsyncSmsDAO) MarkFailed(ctx context.Context, id int64) error {
+func (g *GORMAsyncSmsDAO) MarkFailedBatch(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
 	now := time.Now().UnixMilli()
 	return g.db.WithContext(ctx).Model(&AsyncSms{}).
-		// 只有到达了重试次数才会更新
-		Where("id =? and `retry_cnt`>=`retry_max`", id).
+		// 只有到达了重试次数才会更新，没到的等着 next_time 之后重试
+		Where("id IN ? and `retry_cnt`>=`retry_max`", ids).
 		Updates(map[string]any{
 			"utime":  now,
-			"status": asyncStatusFailed,
+			"status": AsyncStatusFailed,
 		}).Error
 }
 
+func (g *GORMAsyncSmsDAO) FindById(ctx context.Context, id int64) (AsyncSms, error) {
+	var s AsyncSms
+	err := g.db.WithContext(ctx).Where("id = ?", id).First(&s).Error
+	return s, err
+}
+
+func (g *GORMAsyncSmsDAO) ListByStatus(ctx context.Context, status uint8,
+	offset, limit int) ([]AsyncSms, error) {
+	var res []AsyncSms
+	err := g.db.WithContext(ctx).
+		Where("status = ?", status).
+		Order("id DESC").
+		Offset(offset).Limit(limit).
+		Find(&res).Error
+	return res, err
+}
+
+func (g *GORMAsyncSmsDAO) Requeue(ctx context.Context, id int64) error {
+	now := time.Now().UnixMilli()
+	res := g.db.WithContext(ctx).Model(&AsyncSms{}).
+		// 发送成功的就没必要再发一次了
+		Where("id = ? AND status != ?", id, AsyncStatusSuccess).
+		Updates(map[string]any{
+			"status":    AsyncStatusWaiting,
+			"retry_cnt": 0,
+			"next_time": now,
+			"utime":     now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrAsyncSmsNotRequeueable
+	}
+	return nil
+}
+
+func (g *GORMAsyncSmsDAO) CountByStatus(ctx context.Context) (map[uint8]int64, error) {
+	type statusCnt struct {
+		Status uint8
+		Cnt    int64
+	}
+	var cnts []statusCnt
+	err := g.db.WithContext(ctx).Model(&AsyncSms{}).
+		Select("status, COUNT(*) AS cnt").
+		Group("status").Scan(&cnts).Error
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[uint8]int64, len(cnts))
+	for _, c := range cnts {
+		res[c.Status] = c.Cnt
+	}
+	return res, nil
+}
+
+func (g *GORMAsyncSmsDAO) DeleteSuccessBefore(ctx context.Context, t int64, limit int) (int64, error) {
+	// 分批删除，避免一次删太多，长时间锁住表
+	res := g.db.WithContext(ctx).
+		Where("status = ? AND utime < ?", AsyncStatusSuccess, t).
+		Limit(limit).
+		Delete(&AsyncSms{})
+	return res.RowsAffected, res.Error
+}
+
 type AsyncSms struct {
 	Id int64
 	// 使用我在 ekit 里面支持的 JSON 字段
@@ -100,7 +245,9 @@ type AsyncSms struct {
 	RetryCnt int
 	// 重试的最大次数
 	RetryMax int
-	Status   uint8
+	Status   uint8 `gorm:"index:status_next_time"`
+	// 下一次可以发送的时间，每次重试都会按照指数退避往后推
+	NextTime int64 `gorm:"index:status_next_time"`
 	Ctime    int64
 	Utime    int64 `gorm:"index"`
 }
@@ -0,0 +1,209 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestGORMAsyncSmsDAO_backoff(t *testing.T) {
+	dao := &GORMAsyncSmsDAO{
+		backoffBase: time.Minute,
+		backoffMax:  time.Minute * 30,
+	}
+	testCases := []struct {
+		name     string
+		retryCnt int
+		// 不加抖动的间隔，结果应该落在 [interval/2, interval] 之间
+		interval time.Duration
+	}{
+		{
+			name:     "第一次发送",
+			retryCnt: 0,
+			interval: time.Minute,
+		},
+		{
+			name:     "第二次重试",
+			retryCnt: 2,
+			interval: time.Minute * 4,
+		},
+		{
+			name:     "超过最大间隔",
+			retryCnt: 10,
+			interval: time.Minute * 30,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// 有随机数，所以多跑几次
+			for i := 0; i < 100; i++ {
+				res := dao.backoff(tc.retryCnt)
+				assert.True(t, res >= tc.interval/2 && res <= tc.interval,
+					"间隔 %s 超出了范围", res)
+			}
+		})
+	}
+}
+
+func TestGORMAsyncSmsDAO_Requeue(t *testing.T) {
+	testCases := []struct {
+		name    string
+		sqlmock func(t *testing.T) *sql.DB
+
+		id      int64
+		wantErr error
+	}{
+		{
+			name: "重新入队成功",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `async_sms` SET .* WHERE id = .* AND status != .*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+			id: 1,
+		},
+		{
+			name: "不存在或者已经发送成功",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `async_sms` SET .*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				return db
+			},
+			id:      1,
+			wantErr: ErrAsyncSmsNotRequeueable,
+		},
+		{
+			name: "数据库错误",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectExec("UPDATE `async_sms` SET .*").
+					WillReturnError(errors.New("mock db error"))
+				return db
+			},
+			id:      1,
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := initAsyncSmsDB(t, tc.sqlmock(t))
+			err := NewGORMAsyncSmsDAO(db).Requeue(context.Background(), tc.id)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestGORMAsyncSmsDAO_CountByStatus(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectQuery("SELECT status, COUNT\\(\\*\\) AS cnt FROM `async_sms` GROUP BY .*").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "cnt"}).
+			AddRow(AsyncStatusWaiting, 10).
+			AddRow(AsyncStatusFailed, 3))
+	db := initAsyncSmsDB(t, sqlDB)
+	res, err := NewGORMAsyncSmsDAO(db).CountByStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[uint8]int64{
+		AsyncStatusWaiting: 10,
+		AsyncStatusFailed:  3,
+	}, res)
+}
+
+func initAsyncSmsDB(t *testing.T, sqlDB *sql.DB) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func TestGORMAsyncSmsDAO_GetWaitingSMSBatch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		sqlmock func(t *testing.T) *sql.DB
+
+		limit   int
+		wantIds []int64
+		wantErr error
+	}{
+		{
+			name: "抢占成功",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `async_sms` WHERE .* LIMIT 2 FOR UPDATE SKIP LOCKED").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "retry_cnt"}).
+						AddRow(1, 0).AddRow(2, 1))
+				mock.ExpectExec("UPDATE `async_sms` SET `next_time`=CASE id WHEN .* THEN .* WHEN .* THEN .* END.* WHERE id IN \\(\\?,\\?\\)").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+				return db
+			},
+			limit:   2,
+			wantIds: []int64{1, 2},
+		},
+		{
+			name: "没有可以发送的",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `async_sms` WHERE .* FOR UPDATE SKIP LOCKED").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "retry_cnt"}))
+				mock.ExpectCommit()
+				return db
+			},
+			limit:   2,
+			wantIds: []int64{},
+		},
+		{
+			name: "更新失败",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `async_sms` WHERE .* FOR UPDATE SKIP LOCKED").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "retry_cnt"}).
+						AddRow(1, 0))
+				mock.ExpectExec("UPDATE `async_sms` SET .*").
+					WillReturnError(errors.New("mock db error"))
+				mock.ExpectRollback()
+				return db
+			},
+			limit:   2,
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := initAsyncSmsDB(t, tc.sqlmock(t))
+			res, err := NewGORMAsyncSmsDAO(db).GetWaitingSMSBatch(context.Background(), tc.limit)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			ids := make([]int64, 0, len(res))
+			for _, s := range res {
+				ids = append(ids, s.Id)
+			}
+			assert.Equal(t, tc.wantIds, ids)
+		})
+	}
+}
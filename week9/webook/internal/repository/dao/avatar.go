@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"bytes"
+	"context"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ecodeclub/ekit"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AvatarDAO 头像是公开的，所以直接返回可以访问的 URL，不需要签名
+//
+//go:generate mockgen -source=./avatar.go -package=daomocks -destination=mocks/avatar.mock.go AvatarDAO
+type AvatarDAO interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+}
+
+type S3AvatarDAO struct {
+	oss    *s3.S3
+	bucket string
+	// baseURL 访问头像的地址前缀，一般是 CDN 的域名
+	baseURL string
+}
+
+func NewS3AvatarDAO(oss *s3.S3, bucket string, baseURL string) AvatarDAO {
+	return &S3AvatarDAO{
+		oss:     oss,
+		bucket:  bucket,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (d *S3AvatarDAO) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	_, err := d.oss.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      &d.bucket,
+		Key:         &key,
+		Body:        bytes.NewReader(data),
+		ContentType: &contentType,
+		ACL:         ekit.ToPtr[string](s3.ObjectCannedACLPublicRead),
+	})
+	if err != nil {
+		return "", err
+	}
+	return d.baseURL + "/" + key, nil
+}
+
+// LocalAvatarDAO 开发环境没有对象存储的时候用
+type LocalAvatarDAO struct {
+	dir string
+}
+
+func NewLocalAvatarDAO(dir string) AvatarDAO {
+	return &LocalAvatarDAO{
+		dir: dir,
+	}
+}
+
+func (d *LocalAvatarDAO) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	path, err := filepath.Abs(filepath.Join(d.dir, filepath.FromSlash(key)))
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return "", err
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String(), nil
+}
@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"bytes"
+	"context"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ecodeclub/ekit"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ExportFileDAO 导出的个人数据放在对象存储里面，用户通过签名的链接下载
+//
+//go:generate mockgen -source=./export_file.go -package=daomocks -destination=mocks/export_file.mock.go ExportFileDAO
+type ExportFileDAO interface {
+	Put(ctx context.Context, key string, data []byte) error
+	// SignURL 生成一个 expiration 之内有效的下载链接
+	SignURL(ctx context.Context, key string, expiration time.Duration) (string, error)
+}
+
+// S3ExportFileDAO 过期的文件可以在 bucket 上配置生命周期规则来删除
+type S3ExportFileDAO struct {
+	oss    *s3.S3
+	bucket string
+}
+
+func NewS3ExportFileDAO(oss *s3.S3, bucket string) ExportFileDAO {
+	return &S3ExportFileDAO{
+		oss:    oss,
+		bucket: bucket,
+	}
+}
+
+func (d *S3ExportFileDAO) Put(ctx context.Context, key string, data []byte) error {
+	_, err := d.oss.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      &d.bucket,
+		Key:         &key,
+		Body:        bytes.NewReader(data),
+		ContentType: ekit.ToPtr[string]("application/zip"),
+	})
+	return err
+}
+
+func (d *S3ExportFileDAO) SignURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	req, _ := d.oss.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: &d.bucket,
+		Key:    &key,
+	})
+	req.SetContext(ctx)
+	return req.Presign(expiration)
+}
+
+// LocalExportFileDAO 开发环境没有对象存储的时候用，链接是本地文件的路径
+type LocalExportFileDAO struct {
+	dir string
+}
+
+func NewLocalExportFileDAO(dir string) ExportFileDAO {
+	return &LocalExportFileDAO{
+		dir: dir,
+	}
+}
+
+func (d *LocalExportFileDAO) Put(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(d.dir, filepath.FromSlash(key))
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (d *LocalExportFileDAO) SignURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	path, err := filepath.Abs(filepath.Join(d.dir, filepath.FromSlash(key)))
+	if err != nil {
+		return "", err
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String(), nil
+}
@@ -7,7 +7,8 @@ import (
 )
 
 func InitTables(db *gorm.DB) error {
-	return db.AutoMigrate(&User{}, &article.Article{},
+	err := db.AutoMigrate(&User{}, &UserOAuthBinding{}, &UserMergeLog{},
+		&article.Article{},
 		&article.PublishedArticle{},
 		&article.PublishedArticleV1{},
 		&AsyncSms{},
@@ -16,5 +17,18 @@ func InitTables(db *gorm.DB) error {
 		&dao.Collection{},
 		&dao.UserCollectionBiz{},
 		&Job{},
+		&Role{},
+		&RolePermission{},
+		&UserRole{},
+		&UserDataExport{},
+		&UserDeletion{},
 	)
+	if err != nil {
+		return err
+	}
+	err = migrateWechatColumns(db)
+	if err != nil {
+		return err
+	}
+	return seedRoles(db)
 }
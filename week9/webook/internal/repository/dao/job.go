@@ -2,22 +2,30 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"gorm.io/gorm"
 	"time"
 )
 
 var ErrNoMoreJob = gorm.ErrRecordNotFound
 
+// ErrJobNotHold 版本号对不上，说明任务已经被别的节点抢占了
+var ErrJobNotHold = errors.New("任务已经被别的节点抢占")
+
+// JobDAO 里面带 version 的方法，都会校验 version，
+// 版本号对不上的时候返回 ErrJobNotHold
 type JobDAO interface {
 	Preempt(ctx context.Context) (Job, error)
-	UpdateNextTime(ctx context.Context, id int64, t time.Time) error
-	UpdateUtime(ctx context.Context, id int64) error
-	Release(ctx context.Context, id int64) error
+	UpdateNextTime(ctx context.Context, id, version int64, t time.Time) error
+	UpdateUtime(ctx context.Context, id, version int64) error
+	Release(ctx context.Context, id, version int64) error
 	Insert(ctx context.Context, j Job) error
 }
 
 type GORMJobDAO struct {
 	db *gorm.DB
+	// 超过这个时间没有续约的任务，就认为持有者已经崩溃了，可以被重新抢占
+	leaseTimeout time.Duration
 }
 
 func (dao *GORMJobDAO) Insert(ctx context.Context, j Job) error {
@@ -28,22 +36,34 @@ func (dao *GORMJobDAO) Insert(ctx context.Context, j Job) error {
 }
 
 func NewGORMJobDAO(db *gorm.DB) JobDAO {
-	return &GORMJobDAO{db: db}
+	return &GORMJobDAO{db: db, leaseTimeout: time.Minute}
 }
 
-func (dao *GORMJobDAO) Release(ctx context.Context, id int64) error {
-	return dao.db.WithContext(ctx).Model(&Job{}).
-		Where("id = ?", id).Updates(map[string]any{
+func (dao *GORMJobDAO) Release(ctx context.Context, id, version int64) error {
+	res := dao.db.WithContext(ctx).Model(&Job{}).
+		Where("id = ? AND version = ?", id, version).Updates(map[string]any{
 		"status": jobStatusWaiting,
 		"utime":  time.Now().UnixMilli(),
-	}).Error
+	})
+	return dao.checkHold(res)
 }
 
-func (dao *GORMJobDAO) UpdateUtime(ctx context.Context, id int64) error {
-	return dao.db.WithContext(ctx).Model(&Job{}).
-		Where("id=?", id).Updates(map[string]any{
+func (dao *GORMJobDAO) UpdateUtime(ctx context.Context, id, version int64) error {
+	res := dao.db.WithContext(ctx).Model(&Job{}).
+		Where("id=? AND version = ?", id, version).Updates(map[string]any{
 		"utime": time.Now().UnixMilli(),
-	}).Error
+	})
+	return dao.checkHold(res)
+}
+
+func (dao *GORMJobDAO) checkHold(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrJobNotHold
+	}
+	return nil
 }
 
 func (dao *GORMJobDAO) Preempt(ctx context.Context) (Job, error) {
@@ -52,17 +72,17 @@ func (dao *GORMJobDAO) Preempt(ctx context.Context) (Job, error) {
 		// 每一个循环都重新计算 time.Now，因为之前可能已经花了一些时间了
 		now := time.Now().UnixMilli()
 		var j Job
-		// 到了调度的时间
+		// 到了调度的时间，或者持有者已经很久没有续约了
 		err := db.Where(
-			"next_time <= ? AND status = ?",
-			now, jobStatusWaiting).First(&j).Error
+			"(next_time <= ? AND status = ?) OR (status = ? AND utime < ?)",
+			now, jobStatusWaiting,
+			jobStatusRunning, now-dao.leaseTimeout.Milliseconds()).First(&j).Error
 		if err != nil {
 			// 数据库有问题
 			return Job{}, err
 		}
 		// 然后要开始抢占
-		// 这里利用 utime 来执行 CAS 操作
-		// 其它一些公司可能会有一些 version 之类的字段
+		// 这里利用 version 来执行 CAS 操作，同时更新 utime 作为续约时间
 		res := db.Model(&Job{}).
 			Where("id = ? AND version=?", j.Id, j.Version).
 			Updates(map[string]any{
@@ -72,22 +92,25 @@ func (dao *GORMJobDAO) Preempt(ctx context.Context) (Job, error) {
 			})
 		if res.Error != nil {
 			// 数据库错误
-			return Job{}, err
+			return Job{}, res.Error
 		}
 		// 抢占成功
 		if res.RowsAffected == 1 {
+			// 新的版本号就是这一次抢占的 fencing token
+			j.Version = j.Version + 1
 			return j, nil
 		}
 		// 没有抢占到，也就是同一时刻被人抢走了，那么就下一个循环
 	}
 }
 
-func (dao *GORMJobDAO) UpdateNextTime(ctx context.Context, id int64, t time.Time) error {
-	return dao.db.WithContext(ctx).Model(&Job{}).
-		Where("id=?", id).Updates(map[string]any{
+func (dao *GORMJobDAO) UpdateNextTime(ctx context.Context, id, version int64, t time.Time) error {
+	res := dao.db.WithContext(ctx).Model(&Job{}).
+		Where("id=? AND version = ?", id, version).Updates(map[string]any{
 		"utime":     time.Now().UnixMilli(),
 		"next_time": t.UnixMilli(),
-	}).Error
+	})
+	return dao.checkHold(res)
 }
 
 type Job struct {
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./account_merge.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./account_merge.go -package=daomocks -destination=mocks/account_merge.mock.go AccountMergeDAO
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAccountMergeDAO is a mock of AccountMergeDAO interface.
+type MockAccountMergeDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountMergeDAOMockRecorder
+}
+
+// MockAccountMergeDAOMockRecorder is the mock recorder for MockAccountMergeDAO.
+type MockAccountMergeDAOMockRecorder struct {
+	mock *MockAccountMergeDAO
+}
+
+// NewMockAccountMergeDAO creates a new mock instance.
+func NewMockAccountMergeDAO(ctrl *gomock.Controller) *MockAccountMergeDAO {
+	mock := &MockAccountMergeDAO{ctrl: ctrl}
+	mock.recorder = &MockAccountMergeDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountMergeDAO) EXPECT() *MockAccountMergeDAOMockRecorder {
+	return m.recorder
+}
+
+// FindLogsByUid mocks base method.
+func (m *MockAccountMergeDAO) FindLogsByUid(ctx context.Context, uid int64) ([]dao.UserMergeLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLogsByUid", ctx, uid)
+	ret0, _ := ret[0].([]dao.UserMergeLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLogsByUid indicates an expected call of FindLogsByUid.
+func (mr *MockAccountMergeDAOMockRecorder) FindLogsByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLogsByUid", reflect.TypeOf((*MockAccountMergeDAO)(nil).FindLogsByUid), ctx, uid)
+}
+
+// Merge mocks base method.
+func (m *MockAccountMergeDAO) Merge(ctx context.Context, sourceUid, targetUid int64) (dao.UserMergeLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, sourceUid, targetUid)
+	ret0, _ := ret[0].(dao.UserMergeLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockAccountMergeDAOMockRecorder) Merge(ctx, sourceUid, targetUid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockAccountMergeDAO)(nil).Merge), ctx, sourceUid, targetUid)
+}
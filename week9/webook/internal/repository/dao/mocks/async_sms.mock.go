@@ -39,6 +39,51 @@ func (m *MockAsyncSmsDAO) EXPECT() *MockAsyncSmsDAOMockRecorder {
 	return m.recorder
 }
 
+// CountByStatus mocks base method.
+func (m *MockAsyncSmsDAO) CountByStatus(ctx context.Context) (map[uint8]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByStatus", ctx)
+	ret0, _ := ret[0].(map[uint8]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByStatus indicates an expected call of CountByStatus.
+func (mr *MockAsyncSmsDAOMockRecorder) CountByStatus(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByStatus", reflect.TypeOf((*MockAsyncSmsDAO)(nil).CountByStatus), ctx)
+}
+
+// DeleteSuccessBefore mocks base method.
+func (m *MockAsyncSmsDAO) DeleteSuccessBefore(ctx context.Context, t int64, limit int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSuccessBefore", ctx, t, limit)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSuccessBefore indicates an expected call of DeleteSuccessBefore.
+func (mr *MockAsyncSmsDAOMockRecorder) DeleteSuccessBefore(ctx, t, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSuccessBefore", reflect.TypeOf((*MockAsyncSmsDAO)(nil).DeleteSuccessBefore), ctx, t, limit)
+}
+
+// FindById mocks base method.
+func (m *MockAsyncSmsDAO) FindById(ctx context.Context, id int64) (dao.AsyncSms, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(dao.AsyncSms)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockAsyncSmsDAOMockRecorder) FindById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockAsyncSmsDAO)(nil).FindById), ctx, id)
+}
+
 // GetWaitingSMS mocks base method.
 func (m *MockAsyncSmsDAO) GetWaitingSMS(ctx context.Context) (dao.AsyncSms, error) {
 	m.ctrl.T.Helper()
@@ -54,6 +99,21 @@ func (mr *MockAsyncSmsDAOMockRecorder) GetWaitingSMS(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWaitingSMS", reflect.TypeOf((*MockAsyncSmsDAO)(nil).GetWaitingSMS), ctx)
 }
 
+// GetWaitingSMSBatch mocks base method.
+func (m *MockAsyncSmsDAO) GetWaitingSMSBatch(ctx context.Context, limit int) ([]dao.AsyncSms, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWaitingSMSBatch", ctx, limit)
+	ret0, _ := ret[0].([]dao.AsyncSms)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWaitingSMSBatch indicates an expected call of GetWaitingSMSBatch.
+func (mr *MockAsyncSmsDAOMockRecorder) GetWaitingSMSBatch(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWaitingSMSBatch", reflect.TypeOf((*MockAsyncSmsDAO)(nil).GetWaitingSMSBatch), ctx, limit)
+}
+
 // Insert mocks base method.
 func (m *MockAsyncSmsDAO) Insert(ctx context.Context, s dao.AsyncSms) error {
 	m.ctrl.T.Helper()
@@ -68,6 +128,21 @@ func (mr *MockAsyncSmsDAOMockRecorder) Insert(ctx, s any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockAsyncSmsDAO)(nil).Insert), ctx, s)
 }
 
+// ListByStatus mocks base method.
+func (m *MockAsyncSmsDAO) ListByStatus(ctx context.Context, status uint8, offset, limit int) ([]dao.AsyncSms, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByStatus", ctx, status, offset, limit)
+	ret0, _ := ret[0].([]dao.AsyncSms)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByStatus indicates an expected call of ListByStatus.
+func (mr *MockAsyncSmsDAOMockRecorder) ListByStatus(ctx, status, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByStatus", reflect.TypeOf((*MockAsyncSmsDAO)(nil).ListByStatus), ctx, status, offset, limit)
+}
+
 // MarkFailed mocks base method.
 func (m *MockAsyncSmsDAO) MarkFailed(ctx context.Context, id int64) error {
 	m.ctrl.T.Helper()
@@ -82,6 +157,20 @@ func (mr *MockAsyncSmsDAOMockRecorder) MarkFailed(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkFailed", reflect.TypeOf((*MockAsyncSmsDAO)(nil).MarkFailed), ctx, id)
 }
 
+// MarkFailedBatch mocks base method.
+func (m *MockAsyncSmsDAO) MarkFailedBatch(ctx context.Context, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkFailedBatch", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkFailedBatch indicates an expected call of MarkFailedBatch.
+func (mr *MockAsyncSmsDAOMockRecorder) MarkFailedBatch(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkFailedBatch", reflect.TypeOf((*MockAsyncSmsDAO)(nil).MarkFailedBatch), ctx, ids)
+}
+
 // MarkSuccess mocks base method.
 func (m *MockAsyncSmsDAO) MarkSuccess(ctx context.Context, id int64) error {
 	m.ctrl.T.Helper()
@@ -95,3 +184,31 @@ func (mr *MockAsyncSmsDAOMockRecorder) MarkSuccess(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkSuccess", reflect.TypeOf((*MockAsyncSmsDAO)(nil).MarkSuccess), ctx, id)
 }
+
+// MarkSuccessBatch mocks base method.
+func (m *MockAsyncSmsDAO) MarkSuccessBatch(ctx context.Context, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkSuccessBatch", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkSuccessBatch indicates an expected call of MarkSuccessBatch.
+func (mr *MockAsyncSmsDAOMockRecorder) MarkSuccessBatch(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkSuccessBatch", reflect.TypeOf((*MockAsyncSmsDAO)(nil).MarkSuccessBatch), ctx, ids)
+}
+
+// Requeue mocks base method.
+func (m *MockAsyncSmsDAO) Requeue(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Requeue", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Requeue indicates an expected call of Requeue.
+func (mr *MockAsyncSmsDAOMockRecorder) Requeue(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Requeue", reflect.TypeOf((*MockAsyncSmsDAO)(nil).Requeue), ctx, id)
+}
@@ -0,0 +1,54 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./avatar.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./avatar.go -package=daomocks -destination=mocks/avatar.mock.go AvatarDAO
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAvatarDAO is a mock of AvatarDAO interface.
+type MockAvatarDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockAvatarDAOMockRecorder
+}
+
+// MockAvatarDAOMockRecorder is the mock recorder for MockAvatarDAO.
+type MockAvatarDAOMockRecorder struct {
+	mock *MockAvatarDAO
+}
+
+// NewMockAvatarDAO creates a new mock instance.
+func NewMockAvatarDAO(ctrl *gomock.Controller) *MockAvatarDAO {
+	mock := &MockAvatarDAO{ctrl: ctrl}
+	mock.recorder = &MockAvatarDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAvatarDAO) EXPECT() *MockAvatarDAOMockRecorder {
+	return m.recorder
+}
+
+// Put mocks base method.
+func (m *MockAvatarDAO) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", ctx, key, data, contentType)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockAvatarDAOMockRecorder) Put(ctx, key, data, contentType any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockAvatarDAO)(nil).Put), ctx, key, data, contentType)
+}
@@ -0,0 +1,69 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./export_file.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./export_file.go -package=daomocks -destination=mocks/export_file.mock.go ExportFileDAO
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockExportFileDAO is a mock of ExportFileDAO interface.
+type MockExportFileDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockExportFileDAOMockRecorder
+}
+
+// MockExportFileDAOMockRecorder is the mock recorder for MockExportFileDAO.
+type MockExportFileDAOMockRecorder struct {
+	mock *MockExportFileDAO
+}
+
+// NewMockExportFileDAO creates a new mock instance.
+func NewMockExportFileDAO(ctrl *gomock.Controller) *MockExportFileDAO {
+	mock := &MockExportFileDAO{ctrl: ctrl}
+	mock.recorder = &MockExportFileDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExportFileDAO) EXPECT() *MockExportFileDAOMockRecorder {
+	return m.recorder
+}
+
+// Put mocks base method.
+func (m *MockExportFileDAO) Put(ctx context.Context, key string, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", ctx, key, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockExportFileDAOMockRecorder) Put(ctx, key, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockExportFileDAO)(nil).Put), ctx, key, data)
+}
+
+// SignURL mocks base method.
+func (m *MockExportFileDAO) SignURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignURL", ctx, key, expiration)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignURL indicates an expected call of SignURL.
+func (mr *MockExportFileDAOMockRecorder) SignURL(ctx, key, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignURL", reflect.TypeOf((*MockExportFileDAO)(nil).SignURL), ctx, key, expiration)
+}
@@ -0,0 +1,113 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./oauth2_binding.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./oauth2_binding.go -package=daomocks -destination=mocks/oauth2_binding.mock.go OAuth2BindingDAO
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOAuth2BindingDAO is a mock of OAuth2BindingDAO interface.
+type MockOAuth2BindingDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuth2BindingDAOMockRecorder
+}
+
+// MockOAuth2BindingDAOMockRecorder is the mock recorder for MockOAuth2BindingDAO.
+type MockOAuth2BindingDAOMockRecorder struct {
+	mock *MockOAuth2BindingDAO
+}
+
+// NewMockOAuth2BindingDAO creates a new mock instance.
+func NewMockOAuth2BindingDAO(ctrl *gomock.Controller) *MockOAuth2BindingDAO {
+	mock := &MockOAuth2BindingDAO{ctrl: ctrl}
+	mock.recorder = &MockOAuth2BindingDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuth2BindingDAO) EXPECT() *MockOAuth2BindingDAOMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockOAuth2BindingDAO) Delete(ctx context.Context, uid int64, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockOAuth2BindingDAOMockRecorder) Delete(ctx, uid, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockOAuth2BindingDAO)(nil).Delete), ctx, uid, provider)
+}
+
+// FindByExternalId mocks base method.
+func (m *MockOAuth2BindingDAO) FindByExternalId(ctx context.Context, provider, externalId string) (dao.UserOAuthBinding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByExternalId", ctx, provider, externalId)
+	ret0, _ := ret[0].(dao.UserOAuthBinding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByExternalId indicates an expected call of FindByExternalId.
+func (mr *MockOAuth2BindingDAOMockRecorder) FindByExternalId(ctx, provider, externalId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByExternalId", reflect.TypeOf((*MockOAuth2BindingDAO)(nil).FindByExternalId), ctx, provider, externalId)
+}
+
+// FindByUid mocks base method.
+func (m *MockOAuth2BindingDAO) FindByUid(ctx context.Context, uid int64) ([]dao.UserOAuthBinding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].([]dao.UserOAuthBinding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockOAuth2BindingDAOMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockOAuth2BindingDAO)(nil).FindByUid), ctx, uid)
+}
+
+// Insert mocks base method.
+func (m *MockOAuth2BindingDAO) Insert(ctx context.Context, b dao.UserOAuthBinding) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, b)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockOAuth2BindingDAOMockRecorder) Insert(ctx, b any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockOAuth2BindingDAO)(nil).Insert), ctx, b)
+}
+
+// InsertWithUser mocks base method.
+func (m *MockOAuth2BindingDAO) InsertWithUser(ctx context.Context, b dao.UserOAuthBinding) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertWithUser", ctx, b)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertWithUser indicates an expected call of InsertWithUser.
+func (mr *MockOAuth2BindingDAOMockRecorder) InsertWithUser(ctx, b any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertWithUser", reflect.TypeOf((*MockOAuth2BindingDAO)(nil).InsertWithUser), ctx, b)
+}
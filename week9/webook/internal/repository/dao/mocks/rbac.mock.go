@@ -0,0 +1,128 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./rbac.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./rbac.go -package=daomocks -destination=mocks/rbac.mock.go RBACDAO
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRBACDAO is a mock of RBACDAO interface.
+type MockRBACDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockRBACDAOMockRecorder
+}
+
+// MockRBACDAOMockRecorder is the mock recorder for MockRBACDAO.
+type MockRBACDAOMockRecorder struct {
+	mock *MockRBACDAO
+}
+
+// NewMockRBACDAO creates a new mock instance.
+func NewMockRBACDAO(ctrl *gomock.Controller) *MockRBACDAO {
+	mock := &MockRBACDAO{ctrl: ctrl}
+	mock.recorder = &MockRBACDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRBACDAO) EXPECT() *MockRBACDAOMockRecorder {
+	return m.recorder
+}
+
+// DeleteUserRole mocks base method.
+func (m *MockRBACDAO) DeleteUserRole(ctx context.Context, uid, roleId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserRole", ctx, uid, roleId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserRole indicates an expected call of DeleteUserRole.
+func (mr *MockRBACDAOMockRecorder) DeleteUserRole(ctx, uid, roleId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserRole", reflect.TypeOf((*MockRBACDAO)(nil).DeleteUserRole), ctx, uid, roleId)
+}
+
+// FindPermissionsByRoleIds mocks base method.
+func (m *MockRBACDAO) FindPermissionsByRoleIds(ctx context.Context, roleIds []int64) ([]dao.RolePermission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPermissionsByRoleIds", ctx, roleIds)
+	ret0, _ := ret[0].([]dao.RolePermission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPermissionsByRoleIds indicates an expected call of FindPermissionsByRoleIds.
+func (mr *MockRBACDAOMockRecorder) FindPermissionsByRoleIds(ctx, roleIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPermissionsByRoleIds", reflect.TypeOf((*MockRBACDAO)(nil).FindPermissionsByRoleIds), ctx, roleIds)
+}
+
+// FindPermissionsByUid mocks base method.
+func (m *MockRBACDAO) FindPermissionsByUid(ctx context.Context, uid int64) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPermissionsByUid", ctx, uid)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPermissionsByUid indicates an expected call of FindPermissionsByUid.
+func (mr *MockRBACDAOMockRecorder) FindPermissionsByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPermissionsByUid", reflect.TypeOf((*MockRBACDAO)(nil).FindPermissionsByUid), ctx, uid)
+}
+
+// FindRoleByName mocks base method.
+func (m *MockRBACDAO) FindRoleByName(ctx context.Context, name string) (dao.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRoleByName", ctx, name)
+	ret0, _ := ret[0].(dao.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRoleByName indicates an expected call of FindRoleByName.
+func (mr *MockRBACDAOMockRecorder) FindRoleByName(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRoleByName", reflect.TypeOf((*MockRBACDAO)(nil).FindRoleByName), ctx, name)
+}
+
+// FindRolesByUid mocks base method.
+func (m *MockRBACDAO) FindRolesByUid(ctx context.Context, uid int64) ([]dao.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRolesByUid", ctx, uid)
+	ret0, _ := ret[0].([]dao.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRolesByUid indicates an expected call of FindRolesByUid.
+func (mr *MockRBACDAOMockRecorder) FindRolesByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRolesByUid", reflect.TypeOf((*MockRBACDAO)(nil).FindRolesByUid), ctx, uid)
+}
+
+// InsertUserRole mocks base method.
+func (m *MockRBACDAO) InsertUserRole(ctx context.Context, uid, roleId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertUserRole", ctx, uid, roleId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertUserRole indicates an expected call of InsertUserRole.
+func (mr *MockRBACDAOMockRecorder) InsertUserRole(ctx, uid, roleId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUserRole", reflect.TypeOf((*MockRBACDAO)(nil).InsertUserRole), ctx, uid, roleId)
+}
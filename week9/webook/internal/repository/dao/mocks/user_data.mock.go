@@ -0,0 +1,174 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./user_data.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./user_data.go -package=daomocks -destination=mocks/user_data.mock.go UserDataDAO
+//
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserDataDAO is a mock of UserDataDAO interface.
+type MockUserDataDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserDataDAOMockRecorder
+}
+
+// MockUserDataDAOMockRecorder is the mock recorder for MockUserDataDAO.
+type MockUserDataDAOMockRecorder struct {
+	mock *MockUserDataDAO
+}
+
+// NewMockUserDataDAO creates a new mock instance.
+func NewMockUserDataDAO(ctrl *gomock.Controller) *MockUserDataDAO {
+	mock := &MockUserDataDAO{ctrl: ctrl}
+	mock.recorder = &MockUserDataDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserDataDAO) EXPECT() *MockUserDataDAOMockRecorder {
+	return m.recorder
+}
+
+// FindDeletion mocks base method.
+func (m *MockUserDataDAO) FindDeletion(ctx context.Context, uid int64) (dao.UserDeletion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeletion", ctx, uid)
+	ret0, _ := ret[0].(dao.UserDeletion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeletion indicates an expected call of FindDeletion.
+func (mr *MockUserDataDAOMockRecorder) FindDeletion(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeletion", reflect.TypeOf((*MockUserDataDAO)(nil).FindDeletion), ctx, uid)
+}
+
+// FindDueDeletions mocks base method.
+func (m *MockUserDataDAO) FindDueDeletions(ctx context.Context, statuses []uint8, now int64, limit int) ([]dao.UserDeletion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDueDeletions", ctx, statuses, now, limit)
+	ret0, _ := ret[0].([]dao.UserDeletion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDueDeletions indicates an expected call of FindDueDeletions.
+func (mr *MockUserDataDAOMockRecorder) FindDueDeletions(ctx, statuses, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDueDeletions", reflect.TypeOf((*MockUserDataDAO)(nil).FindDueDeletions), ctx, statuses, now, limit)
+}
+
+// FindLatestExport mocks base method.
+func (m *MockUserDataDAO) FindLatestExport(ctx context.Context, uid int64) (dao.UserDataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLatestExport", ctx, uid)
+	ret0, _ := ret[0].(dao.UserDataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLatestExport indicates an expected call of FindLatestExport.
+func (mr *MockUserDataDAOMockRecorder) FindLatestExport(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLatestExport", reflect.TypeOf((*MockUserDataDAO)(nil).FindLatestExport), ctx, uid)
+}
+
+// FindPreemptableExports mocks base method.
+func (m *MockUserDataDAO) FindPreemptableExports(ctx context.Context, pending, running uint8, preemptBefore int64, limit int) ([]dao.UserDataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPreemptableExports", ctx, pending, running, preemptBefore, limit)
+	ret0, _ := ret[0].([]dao.UserDataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPreemptableExports indicates an expected call of FindPreemptableExports.
+func (mr *MockUserDataDAOMockRecorder) FindPreemptableExports(ctx, pending, running, preemptBefore, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPreemptableExports", reflect.TypeOf((*MockUserDataDAO)(nil).FindPreemptableExports), ctx, pending, running, preemptBefore, limit)
+}
+
+// InsertExport mocks base method.
+func (m *MockUserDataDAO) InsertExport(ctx context.Context, e dao.UserDataExport) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertExport", ctx, e)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertExport indicates an expected call of InsertExport.
+func (mr *MockUserDataDAOMockRecorder) InsertExport(ctx, e any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertExport", reflect.TypeOf((*MockUserDataDAO)(nil).InsertExport), ctx, e)
+}
+
+// PreemptExport mocks base method.
+func (m *MockUserDataDAO) PreemptExport(ctx context.Context, id int64, pending, running uint8, preemptBefore int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PreemptExport", ctx, id, pending, running, preemptBefore)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PreemptExport indicates an expected call of PreemptExport.
+func (mr *MockUserDataDAOMockRecorder) PreemptExport(ctx, id, pending, running, preemptBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreemptExport", reflect.TypeOf((*MockUserDataDAO)(nil).PreemptExport), ctx, id, pending, running, preemptBefore)
+}
+
+// UpdateDeletionStatus mocks base method.
+func (m *MockUserDataDAO) UpdateDeletionStatus(ctx context.Context, uid int64, from, to uint8) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDeletionStatus", ctx, uid, from, to)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateDeletionStatus indicates an expected call of UpdateDeletionStatus.
+func (mr *MockUserDataDAOMockRecorder) UpdateDeletionStatus(ctx, uid, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDeletionStatus", reflect.TypeOf((*MockUserDataDAO)(nil).UpdateDeletionStatus), ctx, uid, from, to)
+}
+
+// UpdateExportStatus mocks base method.
+func (m *MockUserDataDAO) UpdateExportStatus(ctx context.Context, id int64, from, to uint8, key string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateExportStatus", ctx, id, from, to, key)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateExportStatus indicates an expected call of UpdateExportStatus.
+func (mr *MockUserDataDAOMockRecorder) UpdateExportStatus(ctx, id, from, to, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateExportStatus", reflect.TypeOf((*MockUserDataDAO)(nil).UpdateExportStatus), ctx, id, from, to, key)
+}
+
+// UpsertDeletion mocks base method.
+func (m *MockUserDataDAO) UpsertDeletion(ctx context.Context, d dao.UserDeletion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertDeletion", ctx, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertDeletion indicates an expected call of UpsertDeletion.
+func (mr *MockUserDataDAOMockRecorder) UpsertDeletion(ctx, d any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertDeletion", reflect.TypeOf((*MockUserDataDAO)(nil).UpsertDeletion), ctx, d)
+}
@@ -0,0 +1,147 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ErrOAuth2BindingDuplicate 第三方账号已经被绑定了，或者用户已经绑定过这个平台了
+var ErrOAuth2BindingDuplicate = errors.New("第三方账号绑定冲突")
+
+//go:generate mockgen -source=./oauth2_binding.go -package=daomocks -destination=mocks/oauth2_binding.mock.go OAuth2BindingDAO
+type OAuth2BindingDAO interface {
+	Insert(ctx context.Context, b UserOAuthBinding) error
+	// InsertWithUser 在同一个事务里面创建一个新用户，并且绑定第三方账号，返回用户 ID
+	InsertWithUser(ctx context.Context, b UserOAuthBinding) (int64, error)
+	FindByExternalId(ctx context.Context, provider string, externalId string) (UserOAuthBinding, error)
+	FindByUid(ctx context.Context, uid int64) ([]UserOAuthBinding, error)
+	// Delete 没有删除任何数据的时候返回 ErrDataNotFound
+	Delete(ctx context.Context, uid int64, provider string) error
+}
+
+type GORMOAuth2BindingDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMOAuth2BindingDAO(db *gorm.DB) OAuth2BindingDAO {
+	return &GORMOAuth2BindingDAO{
+		db: db,
+	}
+}
+
+func (d *GORMOAuth2BindingDAO) Insert(ctx context.Context, b UserOAuthBinding) error {
+	return d.insert(d.db.WithContext(ctx), b)
+}
+
+func (d *GORMOAuth2BindingDAO) InsertWithUser(ctx context.Context, b UserOAuthBinding) (int64, error) {
+	var uid int64
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		u := User{
+			Nickname: sql.NullString{
+				String: b.Nickname,
+				Valid:  b.Nickname != "",
+			},
+			Ctime: now,
+			Utime: now,
+		}
+		err := tx.Create(&u).Error
+		if err != nil {
+			return err
+		}
+		uid = u.Id
+		b.Uid = u.Id
+		return d.insert(tx, b)
+	})
+	return uid, err
+}
+
+func (d *GORMOAuth2BindingDAO) insert(db *gorm.DB, b UserOAuthBinding) error {
+	now := time.Now().UnixMilli()
+	b.Ctime = now
+	b.Utime = now
+	err := db.Create(&b).Error
+	if me, ok := err.(*mysql.MySQLError); ok {
+		const uniqueIndexErrNo uint16 = 1062
+		if me.Number == uniqueIndexErrNo {
+			return ErrOAuth2BindingDuplicate
+		}
+	}
+	return err
+}
+
+func (d *GORMOAuth2BindingDAO) FindByExternalId(ctx context.Context,
+	provider string, externalId string) (UserOAuthBinding, error) {
+	var b UserOAuthBinding
+	err := d.db.WithContext(ctx).
+		First(&b, "provider = ? AND external_id = ?", provider, externalId).Error
+	return b, err
+}
+
+func (d *GORMOAuth2BindingDAO) FindByUid(ctx context.Context, uid int64) ([]UserOAuthBinding, error) {
+	var res []UserOAuthBinding
+	err := d.db.WithContext(ctx).Where("uid = ?", uid).
+		Order("id").Find(&res).Error
+	return res, err
+}
+
+func (d *GORMOAuth2BindingDAO) Delete(ctx context.Context, uid int64, provider string) error {
+	res := d.db.WithContext(ctx).
+		Where("uid = ? AND provider = ?", uid, provider).
+		Delete(&UserOAuthBinding{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrDataNotFound
+	}
+	return nil
+}
+
+// UserOAuthBinding 用户绑定的第三方账号
+// 原本微信的数据是放在 users 表里面的，但是每多一个平台就要多两列
+type UserOAuthBinding struct {
+	Id int64 `gorm:"primaryKey,autoIncrement"`
+	// 一个用户在一个平台上只能绑定一个账号
+	Uid      int64  `gorm:"uniqueIndex:uid_provider"`
+	Provider string `gorm:"type:varchar(32);uniqueIndex:uid_provider;uniqueIndex:provider_external_id"`
+	// ExternalId 第三方平台上的用户 ID，一个第三方账号只能绑定一个用户
+	ExternalId string         `gorm:"type:varchar(256);uniqueIndex:provider_external_id"`
+	UnionId    sql.NullString `gorm:"type:varchar(256)"`
+	Nickname   string         `gorm:"type:varchar(128)"`
+	Ctime      int64
+	Utime      int64
+}
+
+func (UserOAuthBinding) TableName() string {
+	return "user_oauth_bindings"
+}
+
+// migrateWechatColumns 把 users 表里面微信的两列挪到 user_oauth_bindings 里面，
+// 挪完之后就删掉这两列。MySQL 的 DDL 会隐式提交，所以这里没有用事务，
+// 中途失败了重新执行一遍就可以，已经挪过去的数据会被 INSERT IGNORE 忽略
+func migrateWechatColumns(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasColumn(&User{}, "wechat_open_id") {
+		return nil
+	}
+	err := db.Exec("INSERT IGNORE INTO `user_oauth_bindings`" +
+		"(`uid`, `provider`, `external_id`, `union_id`, `nickname`, `ctime`, `utime`) " +
+		"SELECT `id`, 'wechat', `wechat_open_id`, `wechat_union_id`, '', `ctime`, `utime` " +
+		"FROM `users` WHERE `wechat_open_id` IS NOT NULL").Error
+	if err != nil {
+		return err
+	}
+	err = m.DropColumn(&User{}, "wechat_open_id")
+	if err != nil {
+		return err
+	}
+	if m.HasColumn(&User{}, "wechat_union_id") {
+		return m.DropColumn(&User{}, "wechat_union_id")
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ErrUserRoleDuplicate 用户已经有这个角色了
+var ErrUserRoleDuplicate = errors.New("用户已经有这个角色")
+
+//go:generate mockgen -source=./rbac.go -package=daomocks -destination=mocks/rbac.mock.go RBACDAO
+type RBACDAO interface {
+	// FindPermissionsByUid 用户所有角色的权限，去重之后的
+	FindPermissionsByUid(ctx context.Context, uid int64) ([]string, error)
+	FindRolesByUid(ctx context.Context, uid int64) ([]Role, error)
+	FindRoleByName(ctx context.Context, name string) (Role, error)
+	FindPermissionsByRoleIds(ctx context.Context, roleIds []int64) ([]RolePermission, error)
+	InsertUserRole(ctx context.Context, uid int64, roleId int64) error
+	// DeleteUserRole 没有删除任何数据的时候返回 ErrDataNotFound
+	DeleteUserRole(ctx context.Context, uid int64, roleId int64) error
+}
+
+type GORMRBACDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMRBACDAO(db *gorm.DB) RBACDAO {
+	return &GORMRBACDAO{
+		db: db,
+	}
+}
+
+func (d *GORMRBACDAO) FindPermissionsByUid(ctx context.Context, uid int64) ([]string, error) {
+	var res []string
+	err := d.db.WithContext(ctx).Model(&RolePermission{}).
+		Distinct("role_permissions.permission").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.uid = ?", uid).
+		Pluck("role_permissions.permission", &res).Error
+	return res, err
+}
+
+func (d *GORMRBACDAO) FindRolesByUid(ctx context.Context, uid int64) ([]Role, error) {
+	var res []Role
+	err := d.db.WithContext(ctx).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.uid = ?", uid).
+		Order("roles.id").Find(&res).Error
+	return res, err
+}
+
+func (d *GORMRBACDAO) FindRoleByName(ctx context.Context, name string) (Role, error) {
+	var r Role
+	err := d.db.WithContext(ctx).First(&r, "name = ?", name).Error
+	return r, err
+}
+
+func (d *GORMRBACDAO) FindPermissionsByRoleIds(ctx context.Context, roleIds []int64) ([]RolePermission, error) {
+	var res []RolePermission
+	err := d.db.WithContext(ctx).Where("role_id IN ?", roleIds).
+		Order("id").Find(&res).Error
+	return res, err
+}
+
+func (d *GORMRBACDAO) InsertUserRole(ctx context.Context, uid int64, roleId int64) error {
+	err := d.db.WithContext(ctx).Create(&UserRole{
+		Uid:    uid,
+		RoleId: roleId,
+		Ctime:  time.Now().UnixMilli(),
+	}).Error
+	if me, ok := err.(*mysql.MySQLError); ok {
+		const uniqueIndexErrNo uint16 = 1062
+		if me.Number == uniqueIndexErrNo {
+			return ErrUserRoleDuplicate
+		}
+	}
+	return err
+}
+
+func (d *GORMRBACDAO) DeleteUserRole(ctx context.Context, uid int64, roleId int64) error {
+	res := d.db.WithContext(ctx).
+		Where("uid = ? AND role_id = ?", uid, roleId).
+		Delete(&UserRole{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrDataNotFound
+	}
+	return nil
+}
+
+// Role 角色，角色和权限目前都是直接在数据库里面维护的
+type Role struct {
+	Id          int64  `gorm:"primaryKey,autoIncrement"`
+	Name        string `gorm:"type:varchar(64);unique"`
+	Description string `gorm:"type:varchar(256)"`
+	Ctime       int64
+	Utime       int64
+}
+
+type RolePermission struct {
+	Id         int64  `gorm:"primaryKey,autoIncrement"`
+	RoleId     int64  `gorm:"uniqueIndex:role_permission"`
+	Permission string `gorm:"type:varchar(128);uniqueIndex:role_permission"`
+	Ctime      int64
+}
+
+type UserRole struct {
+	Id     int64 `gorm:"primaryKey,autoIncrement"`
+	Uid    int64 `gorm:"uniqueIndex:uid_role"`
+	RoleId int64 `gorm:"uniqueIndex:uid_role"`
+	Ctime  int64
+}
+
+// seedRoles 预置一个拥有所有权限的 admin 角色
+// 第一个管理员只能直接在数据库里面分配，后面的管理员可以通过接口分配
+func seedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+		admin := Role{
+			Name:        "admin",
+			Description: "管理员，拥有所有权限",
+			Ctime:       now,
+			Utime:       now,
+		}
+		err := tx.Where(Role{Name: admin.Name}).FirstOrCreate(&admin).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where(RolePermission{RoleId: admin.Id, Permission: "*"}).
+			FirstOrCreate(&RolePermission{RoleId: admin.Id, Permission: "*", Ctime: now}).Error
+	})
+}
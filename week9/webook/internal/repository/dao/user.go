@@ -21,8 +21,17 @@ type UserDAO interface {
 	UpdateNonZeroFields(ctx context.Context, u User) error
 	FindByPhone(ctx context.Context, phone string) (User, error)
 	FindByEmail(ctx context.Context, email string) (User, error)
-	FindByWechat(ctx context.Context, openId string) (User, error)
 	FindById(ctx context.Context, id int64) (User, error)
+	// UpdatePhone 手机号码冲突的时候返回 ErrUserDuplicate
+	UpdatePhone(ctx context.Context, id int64, phone string) error
+	// UpdateEmail 邮箱冲突的时候返回 ErrUserDuplicate
+	UpdateEmail(ctx context.Context, id int64, email string) error
+	// UpdatePassword password 是加密之后的
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	// Anonymize 注销账号，清空所有的个人信息，只保留 ID
+	Anonymize(ctx context.Context, id int64) error
+	// UpdateAvatar avatar 和 thumb 都是上传之后的 URL
+	UpdateAvatar(ctx context.Context, id int64, avatar string, thumb string) error
 }
 
 type GORMUserDAO struct {
@@ -41,7 +50,7 @@ func (ud *GORMUserDAO) UpdateNonZeroFields(ctx context.Context, u User) error {
 	// 会使用非零值来更新
 	// 另外一种做法是显式指定只更新必要的字段，
 	// 那么这意味着 DAO 和 service 中非敏感字段语义耦合了
-	return ud.db.Updates(&u).Error
+	return uniqueConflict(ud.db.WithContext(ctx).Updates(&u).Error)
 }
 
 func (ud *GORMUserDAO) Insert(ctx context.Context, u User) error {
@@ -49,6 +58,11 @@ func (ud *GORMUserDAO) Insert(ctx context.Context, u User) error {
 	u.Ctime = now
 	u.Utime = now
 	err := ud.db.WithContext(ctx).Create(&u).Error
+	return uniqueConflict(err)
+}
+
+// uniqueConflict 邮箱或者手机号码的唯一索引冲突，插入和更新都可能遇到
+func uniqueConflict(err error) error {
 	if me, ok := err.(*mysql.MySQLError); ok {
 		const uniqueIndexErrNo uint16 = 1062
 		if me.Number == uniqueIndexErrNo {
@@ -58,6 +72,57 @@ func (ud *GORMUserDAO) Insert(ctx context.Context, u User) error {
 	return err
 }
 
+func (ud *GORMUserDAO) UpdatePhone(ctx context.Context, id int64, phone string) error {
+	return ud.updateUnique(ctx, id, "phone", phone)
+}
+
+func (ud *GORMUserDAO) UpdateEmail(ctx context.Context, id int64, email string) error {
+	return ud.updateUnique(ctx, id, "email", email)
+}
+
+func (ud *GORMUserDAO) UpdatePassword(ctx context.Context, id int64, password string) error {
+	return ud.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).
+		Updates(map[string]any{
+			"password": password,
+			"utime":    time.Now().UnixMilli(),
+		}).Error
+}
+
+func (ud *GORMUserDAO) Anonymize(ctx context.Context, id int64) error {
+	return ud.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).
+		Updates(map[string]any{
+			"email":        nil,
+			"phone":        nil,
+			"password":     "",
+			"birthday":     nil,
+			"nickname":     nil,
+			"about_me":     nil,
+			"avatar":       nil,
+			"avatar_thumb": nil,
+			"utime":        time.Now().UnixMilli(),
+		}).Error
+}
+
+func (ud *GORMUserDAO) UpdateAvatar(ctx context.Context, id int64, avatar string, thumb string) error {
+	return ud.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).
+		Updates(map[string]any{
+			"avatar":       avatar,
+			"avatar_thumb": thumb,
+			"utime":        time.Now().UnixMilli(),
+		}).Error
+}
+
+// updateUnique 更新有唯一索引的列，这里不能用 UpdateNonZeroFields，
+// 因为 domain 转过来的 Birthday 之类的字段会被更新为 NULL
+func (ud *GORMUserDAO) updateUnique(ctx context.Context, id int64, col string, val string) error {
+	err := ud.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).
+		Updates(map[string]any{
+			col:     val,
+			"utime": time.Now().UnixMilli(),
+		}).Error
+	return uniqueConflict(err)
+}
+
 func (ud *GORMUserDAO) FindByPhone(ctx context.Context, phone string) (User, error) {
 	var u User
 	err := ud.db.WithContext(ctx).First(&u, "phone = ?", phone).Error
@@ -70,12 +135,6 @@ func (ud *GORMUserDAO) FindByEmail(ctx context.Context, email string) (User, err
 	return u, err
 }
 
-func (ud *GORMUserDAO) FindByWechat(ctx context.Context, openId string) (User, error) {
-	var u User
-	err := ud.db.WithContext(ctx).First(&u, "wechat_open_id = ?", openId).Error
-	return u, err
-}
-
 func (ud *GORMUserDAO) FindById(ctx context.Context, id int64) (User, error) {
 	var u User
 	err := ud.db.WithContext(ctx).First(&u, "id = ?", id).Error
@@ -105,12 +164,11 @@ type User struct {
 	// 指定是 varchar 这个类型，并且长度是 1024
 	// 因此你可以看到在 web 里面有这个校验
 	AboutMe sql.NullString `gorm:"type:varchar(1024)"`
+	// 头像和缩略图的 URL，文件本身是放在 OSS 上的
+	Avatar      sql.NullString `gorm:"type:varchar(512)"`
+	AvatarThumb sql.NullString `gorm:"type:varchar(512)"`
 
-	// 微信有关数据。有些公司会尝试把这些数据分离出去做一个单独的表
-	// 从而避免这个表有过多的列，但是暂时来说
-	// 我们还没到这个地步
-	WechatOpenId  sql.NullString `gorm:"type:varchar(256);unique"`
-	WechatUnionId sql.NullString `gorm:"type:varchar(256)"`
+	// 微信之类的第三方账号，放在了 user_oauth_bindings 里面
 
 	// 创建时间
 	Ctime int64
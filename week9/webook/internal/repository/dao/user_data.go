@@ -0,0 +1,151 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+//go:generate mockgen -source=./user_data.go -package=daomocks -destination=mocks/user_data.mock.go UserDataDAO
+type UserDataDAO interface {
+	InsertExport(ctx context.Context, e UserDataExport) (int64, error)
+	// FindLatestExport 用户最近一次导出，没有的话返回 ErrDataNotFound
+	FindLatestExport(ctx context.Context, uid int64) (UserDataExport, error)
+	// FindPreemptableExports 状态是 pending 的，或者状态是 running 但是在 preemptBefore 之前就被抢占了的，
+	// 后者说明执行导出的实例崩溃了，或者超时了
+	FindPreemptableExports(ctx context.Context, pending uint8, running uint8,
+		preemptBefore int64, limit int) ([]UserDataExport, error)
+	// PreemptExport 把 FindPreemptableExports 找出来的任务改成 running，并且记录抢占的时间。
+	// 多个实例同时执行导出的时候，只有一个能抢到
+	PreemptExport(ctx context.Context, id int64, pending uint8, running uint8, preemptBefore int64) (bool, error)
+	// UpdateExportStatus 只有当前状态是 from 的时候才会更新，返回有没有更新
+	UpdateExportStatus(ctx context.Context, id int64, from uint8, to uint8, key string) (bool, error)
+
+	// UpsertDeletion 一个用户只有一条注销申请，撤销之后再申请就是覆盖
+	UpsertDeletion(ctx context.Context, d UserDeletion) error
+	FindDeletion(ctx context.Context, uid int64) (UserDeletion, error)
+	// FindDueDeletions 冷静期已经过了，并且状态是 statuses 之一的
+	FindDueDeletions(ctx context.Context, statuses []uint8, now int64, limit int) ([]UserDeletion, error)
+	// UpdateDeletionStatus 和 UpdateExportStatus 一样
+	UpdateDeletionStatus(ctx context.Context, uid int64, from uint8, to uint8) (bool, error)
+}
+
+type GORMUserDataDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMUserDataDAO(db *gorm.DB) UserDataDAO {
+	return &GORMUserDataDAO{
+		db: db,
+	}
+}
+
+func (d *GORMUserDataDAO) InsertExport(ctx context.Context, e UserDataExport) (int64, error) {
+	now := time.Now().UnixMilli()
+	e.Ctime = now
+	e.Utime = now
+	err := d.db.WithContext(ctx).Create(&e).Error
+	return e.Id, err
+}
+
+func (d *GORMUserDataDAO) FindLatestExport(ctx context.Context, uid int64) (UserDataExport, error) {
+	var e UserDataExport
+	err := d.db.WithContext(ctx).Where("uid = ?", uid).
+		Order("id DESC").First(&e).Error
+	return e, err
+}
+
+func (d *GORMUserDataDAO) FindPreemptableExports(ctx context.Context, pending uint8, running uint8,
+	preemptBefore int64, limit int) ([]UserDataExport, error) {
+	var res []UserDataExport
+	err := d.db.WithContext(ctx).
+		Where("status = ? OR (status = ? AND preempt_time < ?)", pending, running, preemptBefore).
+		Order("id").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (d *GORMUserDataDAO) PreemptExport(ctx context.Context, id int64, pending uint8, running uint8,
+	preemptBefore int64) (bool, error) {
+	now := time.Now().UnixMilli()
+	res := d.db.WithContext(ctx).Model(&UserDataExport{}).
+		Where("id = ? AND (status = ? OR (status = ? AND preempt_time < ?))",
+			id, pending, running, preemptBefore).
+		Updates(map[string]any{
+			"status":       running,
+			"preempt_time": now,
+			"utime":        now,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (d *GORMUserDataDAO) UpdateExportStatus(ctx context.Context, id int64,
+	from uint8, to uint8, key string) (bool, error) {
+	res := d.db.WithContext(ctx).Model(&UserDataExport{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]any{
+			"status":     to,
+			"object_key": key,
+			"utime":      time.Now().UnixMilli(),
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (d *GORMUserDataDAO) UpsertDeletion(ctx context.Context, del UserDeletion) error {
+	now := time.Now().UnixMilli()
+	del.Ctime = now
+	del.Utime = now
+	return d.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"status":    del.Status,
+			"exec_time": del.ExecTime,
+			"utime":     now,
+		}),
+	}).Create(&del).Error
+}
+
+func (d *GORMUserDataDAO) FindDeletion(ctx context.Context, uid int64) (UserDeletion, error) {
+	var del UserDeletion
+	err := d.db.WithContext(ctx).Where("uid = ?", uid).First(&del).Error
+	return del, err
+}
+
+func (d *GORMUserDataDAO) FindDueDeletions(ctx context.Context, statuses []uint8,
+	now int64, limit int) ([]UserDeletion, error) {
+	var res []UserDeletion
+	err := d.db.WithContext(ctx).
+		Where("status IN ? AND exec_time <= ?", statuses, now).
+		Order("exec_time").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (d *GORMUserDataDAO) UpdateDeletionStatus(ctx context.Context, uid int64,
+	from uint8, to uint8) (bool, error) {
+	res := d.db.WithContext(ctx).Model(&UserDeletion{}).
+		Where("uid = ? AND status = ?", uid, from).
+		Updates(map[string]any{
+			"status": to,
+			"utime":  time.Now().UnixMilli(),
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+type UserDataExport struct {
+	Id        int64  `gorm:"primaryKey,autoIncrement"`
+	Uid       int64  `gorm:"index"`
+	Status    uint8  `gorm:"index"`
+	ObjectKey string `gorm:"type:varchar(256)"`
+	// PreemptTime 最近一次被抢占的时间，running 太久了说明执行的实例出问题了，可以重新抢占
+	PreemptTime int64
+	Ctime       int64
+	Utime       int64
+}
+
+type UserDeletion struct {
+	Id       int64 `gorm:"primaryKey,autoIncrement"`
+	Uid      int64 `gorm:"unique"`
+	Status   uint8
+	ExecTime int64 `gorm:"index"`
+	Ctime    int64
+	Utime    int64
+}
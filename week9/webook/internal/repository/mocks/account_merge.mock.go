@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./account_merge.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./account_merge.go -package=repomocks -destination=mocks/account_merge.mock.go AccountMergeRepository
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAccountMergeRepository is a mock of AccountMergeRepository interface.
+type MockAccountMergeRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountMergeRepositoryMockRecorder
+}
+
+// MockAccountMergeRepositoryMockRecorder is the mock recorder for MockAccountMergeRepository.
+type MockAccountMergeRepositoryMockRecorder struct {
+	mock *MockAccountMergeRepository
+}
+
+// NewMockAccountMergeRepository creates a new mock instance.
+func NewMockAccountMergeRepository(ctrl *gomock.Controller) *MockAccountMergeRepository {
+	mock := &MockAccountMergeRepository{ctrl: ctrl}
+	mock.recorder = &MockAccountMergeRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountMergeRepository) EXPECT() *MockAccountMergeRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindLogs mocks base method.
+func (m *MockAccountMergeRepository) FindLogs(ctx context.Context, uid int64) ([]domain.AccountMergeLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLogs", ctx, uid)
+	ret0, _ := ret[0].([]domain.AccountMergeLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLogs indicates an expected call of FindLogs.
+func (mr *MockAccountMergeRepositoryMockRecorder) FindLogs(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLogs", reflect.TypeOf((*MockAccountMergeRepository)(nil).FindLogs), ctx, uid)
+}
+
+// Merge mocks base method.
+func (m *MockAccountMergeRepository) Merge(ctx context.Context, sourceUid, targetUid int64) (domain.AccountMergeLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, sourceUid, targetUid)
+	ret0, _ := ret[0].(domain.AccountMergeLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockAccountMergeRepositoryMockRecorder) Merge(ctx, sourceUid, targetUid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockAccountMergeRepository)(nil).Merge), ctx, sourceUid, targetUid)
+}
@@ -11,6 +11,7 @@ package repomocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
 	gomock "go.uber.org/mock/gomock"
@@ -53,6 +54,66 @@ func (mr *MockAsyncSmsRepositoryMockRecorder) Add(ctx, s any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockAsyncSmsRepository)(nil).Add), ctx, s)
 }
 
+// CountByStatus mocks base method.
+func (m *MockAsyncSmsRepository) CountByStatus(ctx context.Context) (map[domain.AsyncSmsStatus]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByStatus", ctx)
+	ret0, _ := ret[0].(map[domain.AsyncSmsStatus]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByStatus indicates an expected call of CountByStatus.
+func (mr *MockAsyncSmsRepositoryMockRecorder) CountByStatus(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByStatus", reflect.TypeOf((*MockAsyncSmsRepository)(nil).CountByStatus), ctx)
+}
+
+// DeleteSuccessBefore mocks base method.
+func (m *MockAsyncSmsRepository) DeleteSuccessBefore(ctx context.Context, t time.Time, limit int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSuccessBefore", ctx, t, limit)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSuccessBefore indicates an expected call of DeleteSuccessBefore.
+func (mr *MockAsyncSmsRepositoryMockRecorder) DeleteSuccessBefore(ctx, t, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSuccessBefore", reflect.TypeOf((*MockAsyncSmsRepository)(nil).DeleteSuccessBefore), ctx, t, limit)
+}
+
+// FindById mocks base method.
+func (m *MockAsyncSmsRepository) FindById(ctx context.Context, id int64) (domain.AsyncSms, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(domain.AsyncSms)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindById indicates an expected call of FindById.
+func (mr *MockAsyncSmsRepositoryMockRecorder) FindById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockAsyncSmsRepository)(nil).FindById), ctx, id)
+}
+
+// List mocks base method.
+func (m *MockAsyncSmsRepository) List(ctx context.Context, status domain.AsyncSmsStatus, offset, limit int) ([]domain.AsyncSms, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, status, offset, limit)
+	ret0, _ := ret[0].([]domain.AsyncSms)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockAsyncSmsRepositoryMockRecorder) List(ctx, status, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAsyncSmsRepository)(nil).List), ctx, status, offset, limit)
+}
+
 // PreemptWaitingSMS mocks base method.
 func (m *MockAsyncSmsRepository) PreemptWaitingSMS(ctx context.Context) (domain.AsyncSms, error) {
 	m.ctrl.T.Helper()
@@ -68,6 +129,21 @@ func (mr *MockAsyncSmsRepositoryMockRecorder) PreemptWaitingSMS(ctx any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreemptWaitingSMS", reflect.TypeOf((*MockAsyncSmsRepository)(nil).PreemptWaitingSMS), ctx)
 }
 
+// PreemptWaitingSMSBatch mocks base method.
+func (m *MockAsyncSmsRepository) PreemptWaitingSMSBatch(ctx context.Context, limit int) ([]domain.AsyncSms, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PreemptWaitingSMSBatch", ctx, limit)
+	ret0, _ := ret[0].([]domain.AsyncSms)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PreemptWaitingSMSBatch indicates an expected call of PreemptWaitingSMSBatch.
+func (mr *MockAsyncSmsRepositoryMockRecorder) PreemptWaitingSMSBatch(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreemptWaitingSMSBatch", reflect.TypeOf((*MockAsyncSmsRepository)(nil).PreemptWaitingSMSBatch), ctx, limit)
+}
+
 // ReportScheduleResult mocks base method.
 func (m *MockAsyncSmsRepository) ReportScheduleResult(ctx context.Context, id int64, success bool) error {
 	m.ctrl.T.Helper()
@@ -81,3 +157,31 @@ func (mr *MockAsyncSmsRepositoryMockRecorder) ReportScheduleResult(ctx, id, succ
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportScheduleResult", reflect.TypeOf((*MockAsyncSmsRepository)(nil).ReportScheduleResult), ctx, id, success)
 }
+
+// ReportScheduleResults mocks base method.
+func (m *MockAsyncSmsRepository) ReportScheduleResults(ctx context.Context, successIds, failedIds []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReportScheduleResults", ctx, successIds, failedIds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReportScheduleResults indicates an expected call of ReportScheduleResults.
+func (mr *MockAsyncSmsRepositoryMockRecorder) ReportScheduleResults(ctx, successIds, failedIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportScheduleResults", reflect.TypeOf((*MockAsyncSmsRepository)(nil).ReportScheduleResults), ctx, successIds, failedIds)
+}
+
+// Requeue mocks base method.
+func (m *MockAsyncSmsRepository) Requeue(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Requeue", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Requeue indicates an expected call of Requeue.
+func (mr *MockAsyncSmsRepositoryMockRecorder) Requeue(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Requeue", reflect.TypeOf((*MockAsyncSmsRepository)(nil).Requeue), ctx, id)
+}
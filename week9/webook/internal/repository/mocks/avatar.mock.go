@@ -0,0 +1,54 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./avatar.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./avatar.go -package=repomocks -destination=mocks/avatar.mock.go AvatarRepository
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAvatarRepository is a mock of AvatarRepository interface.
+type MockAvatarRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAvatarRepositoryMockRecorder
+}
+
+// MockAvatarRepositoryMockRecorder is the mock recorder for MockAvatarRepository.
+type MockAvatarRepositoryMockRecorder struct {
+	mock *MockAvatarRepository
+}
+
+// NewMockAvatarRepository creates a new mock instance.
+func NewMockAvatarRepository(ctrl *gomock.Controller) *MockAvatarRepository {
+	mock := &MockAvatarRepository{ctrl: ctrl}
+	mock.recorder = &MockAvatarRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAvatarRepository) EXPECT() *MockAvatarRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Upload mocks base method.
+func (m *MockAvatarRepository) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upload", ctx, key, data, contentType)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upload indicates an expected call of Upload.
+func (mr *MockAvatarRepositoryMockRecorder) Upload(ctx, key, data, contentType any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockAvatarRepository)(nil).Upload), ctx, key, data, contentType)
+}
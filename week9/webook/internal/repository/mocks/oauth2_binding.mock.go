@@ -0,0 +1,113 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./oauth2_binding.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./oauth2_binding.go -package=repomocks -destination=mocks/oauth2_binding.mock.go OAuth2BindingRepository
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOAuth2BindingRepository is a mock of OAuth2BindingRepository interface.
+type MockOAuth2BindingRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuth2BindingRepositoryMockRecorder
+}
+
+// MockOAuth2BindingRepositoryMockRecorder is the mock recorder for MockOAuth2BindingRepository.
+type MockOAuth2BindingRepositoryMockRecorder struct {
+	mock *MockOAuth2BindingRepository
+}
+
+// NewMockOAuth2BindingRepository creates a new mock instance.
+func NewMockOAuth2BindingRepository(ctrl *gomock.Controller) *MockOAuth2BindingRepository {
+	mock := &MockOAuth2BindingRepository{ctrl: ctrl}
+	mock.recorder = &MockOAuth2BindingRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuth2BindingRepository) EXPECT() *MockOAuth2BindingRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockOAuth2BindingRepository) Create(ctx context.Context, b domain.OAuth2Binding) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, b)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockOAuth2BindingRepositoryMockRecorder) Create(ctx, b any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockOAuth2BindingRepository)(nil).Create), ctx, b)
+}
+
+// CreateWithUser mocks base method.
+func (m *MockOAuth2BindingRepository) CreateWithUser(ctx context.Context, info domain.OAuth2Info) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWithUser", ctx, info)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWithUser indicates an expected call of CreateWithUser.
+func (mr *MockOAuth2BindingRepositoryMockRecorder) CreateWithUser(ctx, info any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWithUser", reflect.TypeOf((*MockOAuth2BindingRepository)(nil).CreateWithUser), ctx, info)
+}
+
+// Delete mocks base method.
+func (m *MockOAuth2BindingRepository) Delete(ctx context.Context, uid int64, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockOAuth2BindingRepositoryMockRecorder) Delete(ctx, uid, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockOAuth2BindingRepository)(nil).Delete), ctx, uid, provider)
+}
+
+// FindByExternalId mocks base method.
+func (m *MockOAuth2BindingRepository) FindByExternalId(ctx context.Context, provider, externalId string) (domain.OAuth2Binding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByExternalId", ctx, provider, externalId)
+	ret0, _ := ret[0].(domain.OAuth2Binding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByExternalId indicates an expected call of FindByExternalId.
+func (mr *MockOAuth2BindingRepositoryMockRecorder) FindByExternalId(ctx, provider, externalId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByExternalId", reflect.TypeOf((*MockOAuth2BindingRepository)(nil).FindByExternalId), ctx, provider, externalId)
+}
+
+// FindByUid mocks base method.
+func (m *MockOAuth2BindingRepository) FindByUid(ctx context.Context, uid int64) ([]domain.OAuth2Binding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].([]domain.OAuth2Binding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockOAuth2BindingRepositoryMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockOAuth2BindingRepository)(nil).FindByUid), ctx, uid)
+}
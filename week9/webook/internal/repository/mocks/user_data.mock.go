@@ -0,0 +1,217 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./user_data.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./user_data.go -package=repomocks -destination=mocks/user_data.mock.go UserDataRepository
+//
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserDataRepository is a mock of UserDataRepository interface.
+type MockUserDataRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserDataRepositoryMockRecorder
+}
+
+// MockUserDataRepositoryMockRecorder is the mock recorder for MockUserDataRepository.
+type MockUserDataRepositoryMockRecorder struct {
+	mock *MockUserDataRepository
+}
+
+// NewMockUserDataRepository creates a new mock instance.
+func NewMockUserDataRepository(ctrl *gomock.Controller) *MockUserDataRepository {
+	mock := &MockUserDataRepository{ctrl: ctrl}
+	mock.recorder = &MockUserDataRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserDataRepository) EXPECT() *MockUserDataRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CompleteExport mocks base method.
+func (m *MockUserDataRepository) CompleteExport(ctx context.Context, id int64, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteExport", ctx, id, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteExport indicates an expected call of CompleteExport.
+func (mr *MockUserDataRepositoryMockRecorder) CompleteExport(ctx, id, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteExport", reflect.TypeOf((*MockUserDataRepository)(nil).CompleteExport), ctx, id, key)
+}
+
+// CreateExport mocks base method.
+func (m *MockUserDataRepository) CreateExport(ctx context.Context, uid int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateExport", ctx, uid)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateExport indicates an expected call of CreateExport.
+func (mr *MockUserDataRepositoryMockRecorder) CreateExport(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateExport", reflect.TypeOf((*MockUserDataRepository)(nil).CreateExport), ctx, uid)
+}
+
+// FailExport mocks base method.
+func (m *MockUserDataRepository) FailExport(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailExport", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailExport indicates an expected call of FailExport.
+func (mr *MockUserDataRepositoryMockRecorder) FailExport(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailExport", reflect.TypeOf((*MockUserDataRepository)(nil).FailExport), ctx, id)
+}
+
+// FindDeletion mocks base method.
+func (m *MockUserDataRepository) FindDeletion(ctx context.Context, uid int64) (domain.AccountDeletion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeletion", ctx, uid)
+	ret0, _ := ret[0].(domain.AccountDeletion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeletion indicates an expected call of FindDeletion.
+func (mr *MockUserDataRepositoryMockRecorder) FindDeletion(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeletion", reflect.TypeOf((*MockUserDataRepository)(nil).FindDeletion), ctx, uid)
+}
+
+// FindDueDeletions mocks base method.
+func (m *MockUserDataRepository) FindDueDeletions(ctx context.Context, now time.Time, limit int) ([]domain.AccountDeletion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDueDeletions", ctx, now, limit)
+	ret0, _ := ret[0].([]domain.AccountDeletion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDueDeletions indicates an expected call of FindDueDeletions.
+func (mr *MockUserDataRepositoryMockRecorder) FindDueDeletions(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDueDeletions", reflect.TypeOf((*MockUserDataRepository)(nil).FindDueDeletions), ctx, now, limit)
+}
+
+// FindLatestExport mocks base method.
+func (m *MockUserDataRepository) FindLatestExport(ctx context.Context, uid int64) (domain.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLatestExport", ctx, uid)
+	ret0, _ := ret[0].(domain.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLatestExport indicates an expected call of FindLatestExport.
+func (mr *MockUserDataRepositoryMockRecorder) FindLatestExport(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLatestExport", reflect.TypeOf((*MockUserDataRepository)(nil).FindLatestExport), ctx, uid)
+}
+
+// FindPendingExports mocks base method.
+func (m *MockUserDataRepository) FindPendingExports(ctx context.Context, limit int) ([]domain.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPendingExports", ctx, limit)
+	ret0, _ := ret[0].([]domain.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPendingExports indicates an expected call of FindPendingExports.
+func (mr *MockUserDataRepositoryMockRecorder) FindPendingExports(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPendingExports", reflect.TypeOf((*MockUserDataRepository)(nil).FindPendingExports), ctx, limit)
+}
+
+// PreemptExport mocks base method.
+func (m *MockUserDataRepository) PreemptExport(ctx context.Context, id int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PreemptExport", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PreemptExport indicates an expected call of PreemptExport.
+func (mr *MockUserDataRepositoryMockRecorder) PreemptExport(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreemptExport", reflect.TypeOf((*MockUserDataRepository)(nil).PreemptExport), ctx, id)
+}
+
+// SaveDeletion mocks base method.
+func (m *MockUserDataRepository) SaveDeletion(ctx context.Context, d domain.AccountDeletion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDeletion", ctx, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDeletion indicates an expected call of SaveDeletion.
+func (mr *MockUserDataRepositoryMockRecorder) SaveDeletion(ctx, d any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDeletion", reflect.TypeOf((*MockUserDataRepository)(nil).SaveDeletion), ctx, d)
+}
+
+// SaveExportFile mocks base method.
+func (m *MockUserDataRepository) SaveExportFile(ctx context.Context, key string, data []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveExportFile", ctx, key, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveExportFile indicates an expected call of SaveExportFile.
+func (mr *MockUserDataRepositoryMockRecorder) SaveExportFile(ctx, key, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveExportFile", reflect.TypeOf((*MockUserDataRepository)(nil).SaveExportFile), ctx, key, data)
+}
+
+// SignExportURL mocks base method.
+func (m *MockUserDataRepository) SignExportURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignExportURL", ctx, key, expiration)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SignExportURL indicates an expected call of SignExportURL.
+func (mr *MockUserDataRepositoryMockRecorder) SignExportURL(ctx, key, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignExportURL", reflect.TypeOf((*MockUserDataRepository)(nil).SignExportURL), ctx, key, expiration)
+}
+
+// UpdateDeletionStatus mocks base method.
+func (m *MockUserDataRepository) UpdateDeletionStatus(ctx context.Context, uid int64, from, to domain.AccountDeletionStatus) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDeletionStatus", ctx, uid, from, to)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateDeletionStatus indicates an expected call of UpdateDeletionStatus.
+func (mr *MockUserDataRepositoryMockRecorder) UpdateDeletionStatus(ctx, uid, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDeletionStatus", reflect.TypeOf((*MockUserDataRepository)(nil).UpdateDeletionStatus), ctx, uid, from, to)
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"time"
+)
+
+var (
+	ErrOAuth2BindingNotFound  = dao.ErrDataNotFound
+	ErrOAuth2BindingDuplicate = dao.ErrOAuth2BindingDuplicate
+)
+
+//go:generate mockgen -source=./oauth2_binding.go -package=repomocks -destination=mocks/oauth2_binding.mock.go OAuth2BindingRepository
+type OAuth2BindingRepository interface {
+	Create(ctx context.Context, b domain.OAuth2Binding) error
+	// CreateWithUser 第一次用第三方账号登录，创建一个新用户并且绑定，返回用户 ID
+	CreateWithUser(ctx context.Context, info domain.OAuth2Info) (int64, error)
+	FindByExternalId(ctx context.Context, provider string, externalId string) (domain.OAuth2Binding, error)
+	FindByUid(ctx context.Context, uid int64) ([]domain.OAuth2Binding, error)
+	Delete(ctx context.Context, uid int64, provider string) error
+}
+
+// oauth2BindingRepository 目前还没有缓存，
+// 第三方登录的频率远远比不上按照 ID 查询用户
+type oauth2BindingRepository struct {
+	dao dao.OAuth2BindingDAO
+}
+
+func NewOAuth2BindingRepository(d dao.OAuth2BindingDAO) OAuth2BindingRepository {
+	return &oauth2BindingRepository{
+		dao: d,
+	}
+}
+
+func (r *oauth2BindingRepository) Create(ctx context.Context, b domain.OAuth2Binding) error {
+	return r.dao.Insert(ctx, r.toEntity(b))
+}
+
+func (r *oauth2BindingRepository) CreateWithUser(ctx context.Context, info domain.OAuth2Info) (int64, error) {
+	return r.dao.InsertWithUser(ctx, r.toEntity(domain.OAuth2Binding{Info: info}))
+}
+
+func (r *oauth2BindingRepository) FindByExternalId(ctx context.Context,
+	provider string, externalId string) (domain.OAuth2Binding, error) {
+	b, err := r.dao.FindByExternalId(ctx, provider, externalId)
+	if err != nil {
+		return domain.OAuth2Binding{}, err
+	}
+	return r.toDomain(b), nil
+}
+
+func (r *oauth2BindingRepository) FindByUid(ctx context.Context, uid int64) ([]domain.OAuth2Binding, error) {
+	bs, err := r.dao.FindByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.OAuth2Binding, 0, len(bs))
+	for _, b := range bs {
+		res = append(res, r.toDomain(b))
+	}
+	return res, nil
+}
+
+func (r *oauth2BindingRepository) Delete(ctx context.Context, uid int64, provider string) error {
+	return r.dao.Delete(ctx, uid, provider)
+}
+
+func (r *oauth2BindingRepository) toEntity(b domain.OAuth2Binding) dao.UserOAuthBinding {
+	return dao.UserOAuthBinding{
+		Id:         b.Id,
+		Uid:        b.Uid,
+		Provider:   b.Info.Provider,
+		ExternalId: b.Info.ExternalId,
+		UnionId: sql.NullString{
+			String: b.Info.UnionId,
+			Valid:  b.Info.UnionId != "",
+		},
+		Nickname: b.Info.Nickname,
+	}
+}
+
+func (r *oauth2BindingRepository) toDomain(b dao.UserOAuthBinding) domain.OAuth2Binding {
+	return domain.OAuth2Binding{
+		Id:  b.Id,
+		Uid: b.Uid,
+		Info: domain.OAuth2Info{
+			Provider:   b.Provider,
+			ExternalId: b.ExternalId,
+			UnionId:    b.UnionId.String,
+			Nickname:   b.Nickname,
+		},
+		Ctime: time.UnixMilli(b.Ctime),
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"github.com/ecodeclub/ekit/syncx/atomicx"
 )
 
+var ErrStaleFencingToken = cache.ErrStaleFencingToken
+
 type RankingRepository interface {
-	ReplaceTopN(ctx context.Context, arts []domain.Article) error
+	// ReplaceTopN token 是分布式锁的 fencing token，
+	// 用来拒绝已经失去锁的节点写入的旧结果
+	ReplaceTopN(ctx context.Context, token int64, arts []domain.Article) error
 	GetTopN(ctx context.Context) ([]domain.Article, error)
 }
 
@@ -29,10 +33,16 @@ func NewCachedRankingRepository(
 }
 
 func (c *CachedRankingRepository) ReplaceTopN(ctx context.Context,
-	arts []domain.Article) error {
+	token int64, arts []domain.Article) error {
+	// 先写 Redis，因为 fencing token 是在 Redis 里面校验的
+	// 如果是旧的持有者，那么本地缓存也不能更新
+	err := c.redisCache.Set(ctx, token, arts)
+	if err == ErrStaleFencingToken {
+		return err
+	}
 	// 这一步必然不会出错
 	_ = c.localCache.Set(ctx, arts)
-	return c.redisCache.Set(ctx, arts)
+	return err
 }
 
 func (c *CachedRankingRepository) GetTopN(ctx context.Context) ([]domain.Article, error) {
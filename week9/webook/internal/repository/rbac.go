@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+)
+
+var (
+	ErrRoleNotFound      = dao.ErrDataNotFound
+	ErrUserRoleDuplicate = dao.ErrUserRoleDuplicate
+)
+
+//go:generate mockgen -source=./rbac.go -package=repomocks -destination=mocks/rbac.mock.go RBACRepository
+type RBACRepository interface {
+	// FindPermissions 用户所有角色的权限
+	FindPermissions(ctx context.Context, uid int64) ([]string, error)
+	FindRoles(ctx context.Context, uid int64) ([]domain.Role, error)
+	// AddUserRole 角色不存在返回 ErrRoleNotFound，已经有了返回 ErrUserRoleDuplicate
+	AddUserRole(ctx context.Context, uid int64, role string) error
+	// RemoveUserRole 角色不存在或者用户没有这个角色，都返回 ErrRoleNotFound
+	RemoveUserRole(ctx context.Context, uid int64, role string) error
+}
+
+type CachedRBACRepository struct {
+	dao   dao.RBACDAO
+	cache cache.RBACCache
+}
+
+func NewCachedRBACRepository(d dao.RBACDAO, c cache.RBACCache) RBACRepository {
+	return &CachedRBACRepository{
+		dao:   d,
+		cache: c,
+	}
+}
+
+func (r *CachedRBACRepository) FindPermissions(ctx context.Context, uid int64) ([]string, error) {
+	perms, err := r.cache.GetPermissions(ctx, uid)
+	if err == nil {
+		return perms, nil
+	}
+	perms, err = r.dao.FindPermissionsByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	// 忽略掉这里的错误
+	_ = r.cache.SetPermissions(ctx, uid, perms)
+	return perms, nil
+}
+
+func (r *CachedRBACRepository) FindRoles(ctx context.Context, uid int64) ([]domain.Role, error) {
+	roles, err := r.dao.FindRolesByUid(ctx, uid)
+	if err != nil || len(roles) == 0 {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(roles))
+	for _, role := range roles {
+		ids = append(ids, role.Id)
+	}
+	rps, err := r.dao.FindPermissionsByRoleIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	perms := make(map[int64][]string, len(roles))
+	for _, rp := range rps {
+		perms[rp.RoleId] = append(perms[rp.RoleId], rp.Permission)
+	}
+	res := make([]domain.Role, 0, len(roles))
+	for _, role := range roles {
+		res = append(res, domain.Role{
+			Id:          role.Id,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: perms[role.Id],
+		})
+	}
+	return res, nil
+}
+
+func (r *CachedRBACRepository) AddUserRole(ctx context.Context, uid int64, role string) error {
+	rl, err := r.dao.FindRoleByName(ctx, role)
+	if err != nil {
+		return err
+	}
+	err = r.dao.InsertUserRole(ctx, uid, rl.Id)
+	if err != nil {
+		return err
+	}
+	return r.cache.DeletePermissions(ctx, uid)
+}
+
+func (r *CachedRBACRepository) RemoveUserRole(ctx context.Context, uid int64, role string) error {
+	rl, err := r.dao.FindRoleByName(ctx, role)
+	if err != nil {
+		return err
+	}
+	err = r.dao.DeleteUserRole(ctx, uid, rl.Id)
+	if err != nil {
+		return err
+	}
+	return r.cache.DeletePermissions(ctx, uid)
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	"time"
+)
+
+var (
+	ErrSessionNotFound    = cache.ErrKeyNotExist
+	ErrRefreshTokenReused = cache.ErrRefreshTokenReused
+)
+
+//go:generate mockgen -source=./session.go -package=repomocks -destination=mocks/session.mock.go SessionRepository
+type SessionRepository interface {
+	Create(ctx context.Context, s domain.Session) error
+	// Touch 会话不存在的时候返回 false
+	Touch(ctx context.Context, uid int64, ssid string) (bool, error)
+	Rotate(ctx context.Context, uid int64, ssid string, oldRid string, newRid string) error
+	FindByUid(ctx context.Context, uid int64) ([]domain.Session, error)
+	Delete(ctx context.Context, uid int64, ssid string) error
+	DeleteByUid(ctx context.Context, uid int64) error
+	DeleteOthers(ctx context.Context, uid int64, keepSsid string) error
+}
+
+// sessionRepository 会话只放在 Redis 里面，
+// Redis 数据丢了，也就是所有人都要重新登录
+type sessionRepository struct {
+	cache cache.SessionCache
+	// 最后活跃时间的精度，没必要每个请求都更新
+	touchInterval time.Duration
+}
+
+func NewSessionRepository(c cache.SessionCache) SessionRepository {
+	return &sessionRepository{
+		cache:         c,
+		touchInterval: time.Minute,
+	}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, s domain.Session) error {
+	return r.cache.Set(ctx, s)
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, uid int64, ssid string) (bool, error) {
+	return r.cache.Touch(ctx, uid, ssid, time.Now(), r.touchInterval)
+}
+
+func (r *sessionRepository) Rotate(ctx context.Context, uid int64, ssid string, oldRid string, newRid string) error {
+	return r.cache.Rotate(ctx, uid, ssid, oldRid, newRid)
+}
+
+func (r *sessionRepository) FindByUid(ctx context.Context, uid int64) ([]domain.Session, error) {
+	return r.cache.ListByUid(ctx, uid)
+}
+
+func (r *sessionRepository) Delete(ctx context.Context, uid int64, ssid string) error {
+	return r.cache.Delete(ctx, uid, ssid)
+}
+
+func (r *sessionRepository) DeleteByUid(ctx context.Context, uid int64) error {
+	return r.cache.DeleteByUid(ctx, uid)
+}
+
+func (r *sessionRepository) DeleteOthers(ctx context.Context, uid int64, keepSsid string) error {
+	return r.cache.DeleteOthers(ctx, uid, keepSsid)
+}
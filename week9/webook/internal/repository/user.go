@@ -20,9 +20,15 @@ type UserRepository interface {
 	FindByPhone(ctx context.Context, phone string) (domain.User, error)
 	FindByEmail(ctx context.Context, email string) (domain.User, error)
 	FindById(ctx context.Context, id int64) (domain.User, error)
-	// FindByWechat 暂时可以认为按照 openId来查询
-	// 将来可能需要按照 unionId 来查询
-	FindByWechat(ctx context.Context, openId string) (domain.User, error)
+	// UpdatePhone 手机号码已经被别人用了的时候返回 ErrUserDuplicate
+	UpdatePhone(ctx context.Context, id int64, phone string) error
+	// UpdateEmail 邮箱已经被别人用了的时候返回 ErrUserDuplicate
+	UpdateEmail(ctx context.Context, id int64, email string) error
+	// UpdatePassword password 是加密之后的
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	// Anonymize 注销账号，清空所有的个人信息
+	Anonymize(ctx context.Context, id int64) error
+	UpdateAvatar(ctx context.Context, id int64, avatar string, thumb string) error
 }
 
 // CachedUserRepository 使用了缓存的 repository 实现
@@ -49,6 +55,46 @@ func (ur *CachedUserRepository) Update(ctx context.Context, u domain.User) error
 	return ur.cache.Delete(ctx, u.Id)
 }
 
+func (ur *CachedUserRepository) UpdatePhone(ctx context.Context, id int64, phone string) error {
+	err := ur.dao.UpdatePhone(ctx, id, phone)
+	if err != nil {
+		return err
+	}
+	return ur.cache.Delete(ctx, id)
+}
+
+func (ur *CachedUserRepository) UpdateEmail(ctx context.Context, id int64, email string) error {
+	err := ur.dao.UpdateEmail(ctx, id, email)
+	if err != nil {
+		return err
+	}
+	return ur.cache.Delete(ctx, id)
+}
+
+func (ur *CachedUserRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	err := ur.dao.UpdatePassword(ctx, id, password)
+	if err != nil {
+		return err
+	}
+	return ur.cache.Delete(ctx, id)
+}
+
+func (ur *CachedUserRepository) Anonymize(ctx context.Context, id int64) error {
+	err := ur.dao.Anonymize(ctx, id)
+	if err != nil {
+		return err
+	}
+	return ur.cache.Delete(ctx, id)
+}
+
+func (ur *CachedUserRepository) UpdateAvatar(ctx context.Context, id int64, avatar string, thumb string) error {
+	err := ur.dao.UpdateAvatar(ctx, id, avatar, thumb)
+	if err != nil {
+		return err
+	}
+	return ur.cache.Delete(ctx, id)
+}
+
 func (ur *CachedUserRepository) Create(ctx context.Context, u domain.User) error {
 	return ur.dao.Insert(ctx, dao.User{
 		Email: sql.NullString{
@@ -60,14 +106,6 @@ func (ur *CachedUserRepository) Create(ctx context.Context, u domain.User) error
 			Valid:  u.Phone != "",
 		},
 		Password: u.Password,
-		WechatUnionId: sql.NullString{
-			String: u.WechatInfo.UnionId,
-			Valid:  u.WechatInfo.UnionId != "",
-		},
-		WechatOpenId: sql.NullString{
-			String: u.WechatInfo.OpenId,
-			Valid:  u.WechatInfo.OpenId != "",
-		},
 	})
 }
 
@@ -83,12 +121,6 @@ func (ur *CachedUserRepository) FindByEmail(ctx context.Context,
 	return ur.entityToDomain(u), err
 }
 
-func (ur *CachedUserRepository) FindByWechat(ctx context.Context,
-	openId string) (domain.User, error) {
-	u, err := ur.dao.FindByWechat(ctx, openId)
-	return ur.entityToDomain(u), err
-}
-
 func (ur *CachedUserRepository) FindById(ctx context.Context,
 	id int64) (domain.User, error) {
 	u, err := ur.cache.Get(ctx, id)
@@ -149,6 +181,14 @@ func (ur *CachedUserRepository) domainToEntity(u domain.User) dao.User {
 			String: u.AboutMe,
 			Valid:  u.AboutMe != "",
 		},
+		Avatar: sql.NullString{
+			String: u.Avatar,
+			Valid:  u.Avatar != "",
+		},
+		AvatarThumb: sql.NullString{
+			String: u.AvatarThumb,
+			Valid:  u.AvatarThumb != "",
+		},
 		Password: u.Password,
 	}
 }
@@ -159,17 +199,15 @@ func (ur *CachedUserRepository) entityToDomain(ue dao.User) domain.User {
 		birthday = time.UnixMilli(ue.Birthday.Int64)
 	}
 	return domain.User{
-		Id:       ue.Id,
-		Email:    ue.Email.String,
-		Password: ue.Password,
-		Phone:    ue.Phone.String,
-		Nickname: ue.Nickname.String,
-		AboutMe:  ue.AboutMe.String,
-		Birthday: birthday,
-		Ctime:    time.UnixMilli(ue.Ctime),
-		WechatInfo: domain.WechatInfo{
-			OpenId:  ue.WechatOpenId.String,
-			UnionId: ue.WechatUnionId.String,
-		},
+		Id:          ue.Id,
+		Email:       ue.Email.String,
+		Password:    ue.Password,
+		Phone:       ue.Phone.String,
+		Nickname:    ue.Nickname.String,
+		AboutMe:     ue.AboutMe.String,
+		Avatar:      ue.Avatar.String,
+		AvatarThumb: ue.AvatarThumb.String,
+		Birthday:    birthday,
+		Ctime:       time.UnixMilli(ue.Ctime),
 	}
 }
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"github.com/ecodeclub/ekit/slice"
+	"time"
+)
+
+var ErrUserDataNotFound = dao.ErrDataNotFound
+
+// UserDataRepository 导出个人数据和注销账号
+//
+//go:generate mockgen -source=./user_data.go -package=repomocks -destination=mocks/user_data.mock.go UserDataRepository
+type UserDataRepository interface {
+	CreateExport(ctx context.Context, uid int64) (int64, error)
+	// FindLatestExport 没有导出过返回 ErrUserDataNotFound
+	FindLatestExport(ctx context.Context, uid int64) (domain.DataExport, error)
+	// FindPendingExports 等待中的导出，包括执行超时了的，比如说执行的实例崩溃了
+	FindPendingExports(ctx context.Context, limit int) ([]domain.DataExport, error)
+	// PreemptExport 抢占一个等待中或者执行超时了的导出任务，抢不到返回 false
+	PreemptExport(ctx context.Context, id int64) (bool, error)
+	// CompleteExport 导出成功，key 是导出文件的 key
+	CompleteExport(ctx context.Context, id int64, key string) error
+	FailExport(ctx context.Context, id int64) error
+	SaveExportFile(ctx context.Context, key string, data []byte) error
+	SignExportURL(ctx context.Context, key string, expiration time.Duration) (string, error)
+
+	// SaveDeletion 申请注销或者撤销注销
+	SaveDeletion(ctx context.Context, d domain.AccountDeletion) error
+	// FindDeletion 没有申请过返回 ErrUserDataNotFound
+	FindDeletion(ctx context.Context, uid int64) (domain.AccountDeletion, error)
+	// FindDueDeletions 冷静期已经过了的，包括之前执行到一半失败了的
+	FindDueDeletions(ctx context.Context, now time.Time, limit int) ([]domain.AccountDeletion, error)
+	UpdateDeletionStatus(ctx context.Context, uid int64,
+		from domain.AccountDeletionStatus, to domain.AccountDeletionStatus) (bool, error)
+}
+
+type userDataRepository struct {
+	dao     dao.UserDataDAO
+	fileDAO dao.ExportFileDAO
+	// preemptTimeout 导出任务 running 超过这个时间就可以被重新抢占，要比一次导出的时间长
+	preemptTimeout time.Duration
+}
+
+func NewUserDataRepository(d dao.UserDataDAO, fileDAO dao.ExportFileDAO) UserDataRepository {
+	return &userDataRepository{
+		dao:            d,
+		fileDAO:        fileDAO,
+		preemptTimeout: time.Minute * 10,
+	}
+}
+
+func (r *userDataRepository) CreateExport(ctx context.Context, uid int64) (int64, error) {
+	return r.dao.InsertExport(ctx, dao.UserDataExport{
+		Uid:    uid,
+		Status: domain.DataExportStatusPending.ToUint8(),
+	})
+}
+
+func (r *userDataRepository) FindLatestExport(ctx context.Context, uid int64) (domain.DataExport, error) {
+	e, err := r.dao.FindLatestExport(ctx, uid)
+	if err != nil {
+		return domain.DataExport{}, err
+	}
+	return r.exportToDomain(e), nil
+}
+
+func (r *userDataRepository) FindPendingExports(ctx context.Context, limit int) ([]domain.DataExport, error) {
+	es, err := r.dao.FindPreemptableExports(ctx,
+		domain.DataExportStatusPending.ToUint8(),
+		domain.DataExportStatusRunning.ToUint8(),
+		r.preemptBefore(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(es, func(idx int, src dao.UserDataExport) domain.DataExport {
+		return r.exportToDomain(src)
+	}), nil
+}
+
+func (r *userDataRepository) PreemptExport(ctx context.Context, id int64) (bool, error) {
+	return r.dao.PreemptExport(ctx, id,
+		domain.DataExportStatusPending.ToUint8(),
+		domain.DataExportStatusRunning.ToUint8(),
+		r.preemptBefore())
+}
+
+func (r *userDataRepository) preemptBefore() int64 {
+	return time.Now().Add(-r.preemptTimeout).UnixMilli()
+}
+
+func (r *userDataRepository) CompleteExport(ctx context.Context, id int64, key string) error {
+	_, err := r.dao.UpdateExportStatus(ctx, id,
+		domain.DataExportStatusRunning.ToUint8(),
+		domain.DataExportStatusDone.ToUint8(), key)
+	return err
+}
+
+func (r *userDataRepository) FailExport(ctx context.Context, id int64) error {
+	_, err := r.dao.UpdateExportStatus(ctx, id,
+		domain.DataExportStatusRunning.ToUint8(),
+		domain.DataExportStatusFailed.ToUint8(), "")
+	return err
+}
+
+func (r *userDataRepository) SaveExportFile(ctx context.Context, key string, data []byte) error {
+	return r.fileDAO.Put(ctx, key, data)
+}
+
+func (r *userDataRepository) SignExportURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return r.fileDAO.SignURL(ctx, key, expiration)
+}
+
+func (r *userDataRepository) SaveDeletion(ctx context.Context, d domain.AccountDeletion) error {
+	return r.dao.UpsertDeletion(ctx, dao.UserDeletion{
+		Uid:      d.Uid,
+		Status:   d.Status.ToUint8(),
+		ExecTime: d.ExecTime.UnixMilli(),
+	})
+}
+
+func (r *userDataRepository) FindDeletion(ctx context.Context, uid int64) (domain.AccountDeletion, error) {
+	d, err := r.dao.FindDeletion(ctx, uid)
+	if err != nil {
+		return domain.AccountDeletion{}, err
+	}
+	return r.deletionToDomain(d), nil
+}
+
+func (r *userDataRepository) FindDueDeletions(ctx context.Context, now time.Time,
+	limit int) ([]domain.AccountDeletion, error) {
+	ds, err := r.dao.FindDueDeletions(ctx, []uint8{
+		domain.AccountDeletionStatusPending.ToUint8(),
+		domain.AccountDeletionStatusRunning.ToUint8(),
+	}, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map(ds, func(idx int, src dao.UserDeletion) domain.AccountDeletion {
+		return r.deletionToDomain(src)
+	}), nil
+}
+
+func (r *userDataRepository) UpdateDeletionStatus(ctx context.Context, uid int64,
+	from domain.AccountDeletionStatus, to domain.AccountDeletionStatus) (bool, error) {
+	return r.dao.UpdateDeletionStatus(ctx, uid, from.ToUint8(), to.ToUint8())
+}
+
+func (r *userDataRepository) exportToDomain(e dao.UserDataExport) domain.DataExport {
+	return domain.DataExport{
+		Id:     e.Id,
+		Uid:    e.Uid,
+		Status: domain.DataExportStatus(e.Status),
+		Key:    e.ObjectKey,
+		Ctime:  time.UnixMilli(e.Ctime),
+		Utime:  time.UnixMilli(e.Utime),
+	}
+}
+
+func (r *userDataRepository) deletionToDomain(d dao.UserDeletion) domain.AccountDeletion {
+	return domain.AccountDeletion{
+		Uid:      d.Uid,
+		Status:   domain.AccountDeletionStatus(d.Status),
+		ExecTime: time.UnixMilli(d.ExecTime),
+		Ctime:    time.UnixMilli(d.Ctime),
+		Utime:    time.UnixMilli(d.Utime),
+	}
+}
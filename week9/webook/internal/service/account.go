@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+)
+
+var (
+	ErrPhoneBoundByOther = errs.UserPhoneBoundByOther
+	ErrEmailBoundByOther = errs.UserEmailBoundByOther
+	// 这几个对外共用一个错误码，所以用普通的 error，在 web 里面转成错误码，
+	// 不然 errors.Is 会把同一个错误码的 error 当成同一个
+	ErrAccountPhoneExists = errors.New("已经绑定了别的手机号码")
+	ErrAccountEmailExists = errors.New("已经绑定了别的邮箱")
+	// ErrAccountNotFound 和 ErrAccountMergeSelf 一般是凭证有问题，所以让用户重新验证
+	ErrAccountNotFound      = errors.New("要合并的账号不存在")
+	ErrAccountMergeSelf     = errors.New("不能合并同一个账号")
+	ErrAccountMergeConflict = errs.UserMergeConflict
+)
+
+// AccountService 账号关联。一个人先用短信登录，后面又用微信登录，就会有两个账号，
+// 所以登录之后可以绑定手机号码、邮箱，已经有账号的可以合并过来。
+// 绑定第三方账号在 OAuth2Service 里面
+// 调用之前都要先验证过手机号码或者邮箱是这个人的，这里不会再验证
+//
+//go:generate mockgen -source=./account.go -package=svcmocks -destination=mocks/account.mock.go AccountService
+type AccountService interface {
+	// BindPhone 手机号码已经属于别的账号的时候，返回 ErrPhoneBoundByOther，这时候可以考虑合并
+	BindPhone(ctx context.Context, uid int64, phone string) error
+	// BindEmail 邮箱已经属于别的账号的时候，返回 ErrEmailBoundByOther，这时候可以考虑合并
+	BindEmail(ctx context.Context, uid int64, email string) error
+	// MergeByPhone 把手机号码所属的账号合并到 uid 里面，uid 是保留下来的账号
+	MergeByPhone(ctx context.Context, uid int64, phone string) (domain.AccountMergeLog, error)
+	// MergeByEmail 把邮箱所属的账号合并到 uid 里面，uid 是保留下来的账号
+	MergeByEmail(ctx context.Context, uid int64, email string) (domain.AccountMergeLog, error)
+	MergeLogs(ctx context.Context, uid int64) ([]domain.AccountMergeLog, error)
+	// ChangePhone 换绑手机号码，新的手机号码已经属于别的账号的时候返回 ErrPhoneBoundByOther。
+	// 换绑成功之后会通知原来的手机号码
+	ChangePhone(ctx context.Context, uid int64, phone string) error
+	// ChangeEmail 换绑邮箱，新的邮箱已经属于别的账号的时候返回 ErrEmailBoundByOther。
+	// 换绑成功之后会通知原来的邮箱
+	ChangeEmail(ctx context.Context, uid int64, email string) error
+}
+
+type accountService struct {
+	userRepo    repository.UserRepository
+	oauth2Repo  repository.OAuth2BindingRepository
+	mergeRepo   repository.AccountMergeRepository
+	sessionRepo repository.SessionRepository
+	notifier    AccountNotifier
+	l           logger.LoggerV1
+}
+
+func NewAccountService(userRepo repository.UserRepository,
+	oauth2Repo repository.OAuth2BindingRepository,
+	mergeRepo repository.AccountMergeRepository,
+	sessionRepo repository.SessionRepository,
+	notifier AccountNotifier,
+	l logger.LoggerV1) AccountService {
+	return &accountService{
+		userRepo:    userRepo,
+		oauth2Repo:  oauth2Repo,
+		mergeRepo:   mergeRepo,
+		sessionRepo: sessionRepo,
+		notifier:    notifier,
+		l:           l,
+	}
+}
+
+func (svc *accountService) BindPhone(ctx context.Context, uid int64, phone string) error {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Phone == phone {
+		return nil
+	}
+	if u.Phone != "" {
+		return ErrAccountPhoneExists
+	}
+	err = svc.userRepo.UpdatePhone(ctx, uid, phone)
+	if err == repository.ErrUserDuplicate {
+		return ErrPhoneBoundByOther
+	}
+	return err
+}
+
+func (svc *accountService) BindEmail(ctx context.Context, uid int64, email string) error {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Email == email {
+		return nil
+	}
+	if u.Email != "" {
+		return ErrAccountEmailExists
+	}
+	err = svc.userRepo.UpdateEmail(ctx, uid, email)
+	if err == repository.ErrUserDuplicate {
+		return ErrEmailBoundByOther
+	}
+	return err
+}
+
+func (svc *accountService) ChangePhone(ctx context.Context, uid int64, phone string) error {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Phone == phone {
+		return nil
+	}
+	err = svc.userRepo.UpdatePhone(ctx, uid, phone)
+	if err == repository.ErrUserDuplicate {
+		return ErrPhoneBoundByOther
+	}
+	if err != nil {
+		return err
+	}
+	svc.notify(ctx, uid, ContactPhone, u.Phone, phone)
+	return nil
+}
+
+func (svc *accountService) ChangeEmail(ctx context.Context, uid int64, email string) error {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Email == email {
+		return nil
+	}
+	err = svc.userRepo.UpdateEmail(ctx, uid, email)
+	if err == repository.ErrUserDuplicate {
+		return ErrEmailBoundByOther
+	}
+	if err != nil {
+		return err
+	}
+	svc.notify(ctx, uid, ContactEmail, u.Email, email)
+	return nil
+}
+
+// notify 原来没有绑定的就不用通知了。通知失败不影响换绑
+func (svc *accountService) notify(ctx context.Context, uid int64, kind, oldContact, newContact string) {
+	if oldContact == "" {
+		return
+	}
+	err := svc.notifier.ContactChanged(ctx, kind, oldContact, newContact)
+	if err != nil {
+		svc.l.Error("发送换绑通知失败",
+			logger.Int64("uid", uid),
+			logger.String("kind", kind),
+			logger.Error(err))
+	}
+}
+
+func (svc *accountService) MergeByPhone(ctx context.Context,
+	uid int64, phone string) (domain.AccountMergeLog, error) {
+	src, err := svc.userRepo.FindByPhone(ctx, phone)
+	if err == repository.ErrUserNotFound {
+		return domain.AccountMergeLog{}, ErrAccountNotFound
+	}
+	if err != nil {
+		return domain.AccountMergeLog{}, err
+	}
+	return svc.merge(ctx, uid, src)
+}
+
+func (svc *accountService) MergeByEmail(ctx context.Context,
+	uid int64, email string) (domain.AccountMergeLog, error) {
+	src, err := svc.userRepo.FindByEmail(ctx, email)
+	if err == repository.ErrUserNotFound {
+		return domain.AccountMergeLog{}, ErrAccountNotFound
+	}
+	if err != nil {
+		return domain.AccountMergeLog{}, err
+	}
+	return svc.merge(ctx, uid, src)
+}
+
+func (svc *accountService) merge(ctx context.Context,
+	uid int64, src domain.User) (domain.AccountMergeLog, error) {
+	if src.Id == uid {
+		return domain.AccountMergeLog{}, ErrAccountMergeSelf
+	}
+	dst, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return domain.AccountMergeLog{}, err
+	}
+	err = svc.checkConflict(ctx, src, dst)
+	if err != nil {
+		return domain.AccountMergeLog{}, err
+	}
+	log, err := svc.mergeRepo.Merge(ctx, src.Id, dst.Id)
+	if err != nil {
+		return domain.AccountMergeLog{}, err
+	}
+	// 被合并的账号已经删掉了，它的登录状态也要失效，不然旧的设备还能用这个 uid 操作。
+	// 合并已经完成了，这里失败了也只能记录下来
+	err = svc.sessionRepo.DeleteByUid(ctx, src.Id)
+	if err != nil {
+		svc.l.Error("合并账号之后退出被合并账号的登录失败",
+			logger.Int64("source", src.Id), logger.Error(err))
+	}
+	svc.l.Info("合并账号",
+		logger.Int64("source", log.SourceUid),
+		logger.Int64("target", log.TargetUid),
+		logger.Int64("articles", log.Articles),
+		logger.Int64("likes", log.Likes),
+		logger.Int64("collections", log.Collections),
+		logger.Int64("bindings", log.Bindings))
+	return log, nil
+}
+
+// checkConflict 两个账号都有的信息，只能保留一个，所以不能合并，要用户先解绑
+func (svc *accountService) checkConflict(ctx context.Context, src, dst domain.User) error {
+	if src.Phone != "" && dst.Phone != "" && src.Phone != dst.Phone {
+		return ErrAccountMergeConflict
+	}
+	if src.Email != "" && dst.Email != "" && src.Email != dst.Email {
+		return ErrAccountMergeConflict
+	}
+	srcBindings, err := svc.oauth2Repo.FindByUid(ctx, src.Id)
+	if err != nil {
+		return err
+	}
+	if len(srcBindings) == 0 {
+		return nil
+	}
+	dstBindings, err := svc.oauth2Repo.FindByUid(ctx, dst.Id)
+	if err != nil {
+		return err
+	}
+	providers := make(map[string]struct{}, len(dstBindings))
+	for _, b := range dstBindings {
+		providers[b.Info.Provider] = struct{}{}
+	}
+	for _, b := range srcBindings {
+		if _, ok := providers[b.Info.Provider]; ok {
+			return ErrAccountMergeConflict
+		}
+	}
+	return nil
+}
+
+func (svc *accountService) MergeLogs(ctx context.Context, uid int64) ([]domain.AccountMergeLog, error) {
+	return svc.mergeRepo.FindLogs(ctx, uid)
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+)
+
+const (
+	ContactPhone = "phone"
+	ContactEmail = "email"
+)
+
+// AccountNotifier 账号的安全信息变了，通知用户原来的手机号码或者邮箱，
+// 这样被盗号的时候用户还有机会发现
+//
+//go:generate mockgen -source=./account_notifier.go -package=svcmocks -destination=mocks/account_notifier.mock.go AccountNotifier
+type AccountNotifier interface {
+	// ContactChanged kind 是 ContactPhone 或者 ContactEmail，通知发到 oldContact
+	ContactChanged(ctx context.Context, kind string, oldContact string, newContact string) error
+}
+
+type contactNotifier struct {
+	smsSvc sms.Service
+	// smsTplId 换绑通知的短信模板，参数是新的手机号码
+	smsTplId string
+	emailSvc email.Service
+}
+
+func NewAccountNotifier(smsSvc sms.Service, smsTplId string, emailSvc email.Service) AccountNotifier {
+	return &contactNotifier{
+		smsSvc:   smsSvc,
+		smsTplId: smsTplId,
+		emailSvc: emailSvc,
+	}
+}
+
+func (n *contactNotifier) ContactChanged(ctx context.Context,
+	kind string, oldContact string, newContact string) error {
+	switch kind {
+	case ContactPhone:
+		return n.smsSvc.Send(ctx, n.smsTplId, []string{maskPhone(newContact)}, oldContact)
+	case ContactEmail:
+		body := fmt.Sprintf("您的 webook 账号绑定的邮箱已经修改为 %s。如果不是您本人操作，请立刻修改密码。",
+			maskEmail(newContact))
+		return n.emailSvc.Send(ctx, oldContact, "webook 账号变更提醒", body)
+	default:
+		return fmt.Errorf("未知的联系方式 %s", kind)
+	}
+}
+
+// maskPhone 通知里面不要出现完整的手机号码，152****5678
+func maskPhone(phone string) string {
+	if len(phone) < 7 {
+		return phone
+	}
+	return phone[:3] + "****" + phone[len(phone)-4:]
+}
+
+// maskEmail a****@qq.com
+func maskEmail(addr string) string {
+	for i := 0; i < len(addr); i++ {
+		if addr[i] == '@' {
+			if i <= 1 {
+				return addr
+			}
+			return addr[:1] + "****" + addr[i:]
+		}
+	}
+	return addr
+}
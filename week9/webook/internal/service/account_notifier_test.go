@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	emailmocks "gitee.com/geekbang/basic-go/webook/internal/service/email/mocks"
+	smsmocks "gitee.com/geekbang/basic-go/webook/internal/service/sms/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestAccountNotifier_ContactChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	smsSvc := smsmocks.NewMockService(ctrl)
+	smsSvc.EXPECT().Send(gomock.Any(), "tpl", []string{"152****5678"}, "15287654321").Return(nil)
+	emailSvc := emailmocks.NewMockService(ctrl)
+	emailSvc.EXPECT().Send(gomock.Any(), "old@qq.com", "webook 账号变更提醒",
+		"您的 webook 账号绑定的邮箱已经修改为 n****@qq.com。如果不是您本人操作，请立刻修改密码。").
+		Return(nil)
+	n := NewAccountNotifier(smsSvc, "tpl", emailSvc)
+
+	assert.NoError(t, n.ContactChanged(context.Background(), ContactPhone, "15287654321", "15212345678"))
+	assert.NoError(t, n.ContactChanged(context.Background(), ContactEmail, "old@qq.com", "new@qq.com"))
+	assert.Error(t, n.ContactChanged(context.Background(), "wechat", "a", "b"))
+}
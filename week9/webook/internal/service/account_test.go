@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestAccountService_BindPhone(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+
+		wantErr error
+	}{
+		{
+			name: "绑定成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				repo.EXPECT().UpdatePhone(gomock.Any(), int64(1), "15212345678").Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "已经绑定过同一个手机号码",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "15212345678"}, nil)
+				return repo
+			},
+		},
+		{
+			name: "已经绑定了别的手机号码",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "15287654321"}, nil)
+				return repo
+			},
+			wantErr: ErrAccountPhoneExists,
+		},
+		{
+			name: "手机号码属于别的账号",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				repo.EXPECT().UpdatePhone(gomock.Any(), int64(1), "15212345678").
+					Return(repository.ErrUserDuplicate)
+				return repo
+			},
+			wantErr: ErrPhoneBoundByOther,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewAccountService(tc.mock(ctrl),
+				repomocks.NewMockOAuth2BindingRepository(ctrl),
+				repomocks.NewMockAccountMergeRepository(ctrl),
+				repomocks.NewMockSessionRepository(ctrl),
+				svcmocks.NewMockAccountNotifier(ctrl),
+				logger.NewNoOpLogger())
+			err := svc.BindPhone(context.Background(), 1, "15212345678")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestAccountService_MergeByPhone(t *testing.T) {
+	const phone = "15212345678"
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository,
+			repository.OAuth2BindingRepository, repository.AccountMergeRepository,
+			repository.SessionRepository)
+
+		wantLog domain.AccountMergeLog
+		wantErr error
+	}{
+		{
+			name: "合并成功",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository,
+				repository.OAuth2BindingRepository, repository.AccountMergeRepository,
+				repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), phone).
+					Return(domain.User{Id: 2, Phone: phone}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				oauth2Repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				oauth2Repo.EXPECT().FindByUid(gomock.Any(), int64(2)).
+					Return([]domain.OAuth2Binding{{Info: domain.OAuth2Info{Provider: "github"}}}, nil)
+				oauth2Repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return([]domain.OAuth2Binding{{Info: domain.OAuth2Info{Provider: "wechat"}}}, nil)
+				mergeRepo := repomocks.NewMockAccountMergeRepository(ctrl)
+				mergeRepo.EXPECT().Merge(gomock.Any(), int64(2), int64(1)).
+					Return(domain.AccountMergeLog{SourceUid: 2, TargetUid: 1, Articles: 3}, nil)
+				sessionRepo := repomocks.NewMockSessionRepository(ctrl)
+				sessionRepo.EXPECT().DeleteByUid(gomock.Any(), int64(2)).Return(nil)
+				return userRepo, oauth2Repo, mergeRepo, sessionRepo
+			},
+			wantLog: domain.AccountMergeLog{SourceUid: 2, TargetUid: 1, Articles: 3},
+		},
+		{
+			name: "手机号码没有注册",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository,
+				repository.OAuth2BindingRepository, repository.AccountMergeRepository,
+				repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), phone).
+					Return(domain.User{}, repository.ErrUserNotFound)
+				return userRepo, repomocks.NewMockOAuth2BindingRepository(ctrl),
+					repomocks.NewMockAccountMergeRepository(ctrl), nil
+			},
+			wantErr: ErrAccountNotFound,
+		},
+		{
+			name: "合并自己",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository,
+				repository.OAuth2BindingRepository, repository.AccountMergeRepository,
+				repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), phone).
+					Return(domain.User{Id: 1, Phone: phone}, nil)
+				return userRepo, repomocks.NewMockOAuth2BindingRepository(ctrl),
+					repomocks.NewMockAccountMergeRepository(ctrl), nil
+			},
+			wantErr: ErrAccountMergeSelf,
+		},
+		{
+			name: "两个账号的邮箱不一样",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository,
+				repository.OAuth2BindingRepository, repository.AccountMergeRepository,
+				repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), phone).
+					Return(domain.User{Id: 2, Phone: phone, Email: "456@qq.com"}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Email: "123@qq.com"}, nil)
+				return userRepo, repomocks.NewMockOAuth2BindingRepository(ctrl),
+					repomocks.NewMockAccountMergeRepository(ctrl), nil
+			},
+			wantErr: ErrAccountMergeConflict,
+		},
+		{
+			name: "两个账号都绑定了微信",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository,
+				repository.OAuth2BindingRepository, repository.AccountMergeRepository,
+				repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), phone).
+					Return(domain.User{Id: 2, Phone: phone}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1}, nil)
+				oauth2Repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				oauth2Repo.EXPECT().FindByUid(gomock.Any(), int64(2)).
+					Return([]domain.OAuth2Binding{{Info: domain.OAuth2Info{Provider: "wechat"}}}, nil)
+				oauth2Repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return([]domain.OAuth2Binding{{Info: domain.OAuth2Info{Provider: "wechat"}}}, nil)
+				return userRepo, oauth2Repo, repomocks.NewMockAccountMergeRepository(ctrl), nil
+			},
+			wantErr: ErrAccountMergeConflict,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, oauth2Repo, mergeRepo, sessionRepo := tc.mock(ctrl)
+			svc := NewAccountService(userRepo, oauth2Repo, mergeRepo, sessionRepo,
+				svcmocks.NewMockAccountNotifier(ctrl), logger.NewNoOpLogger())
+			log, err := svc.MergeByPhone(context.Background(), 1, phone)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantLog, log)
+		})
+	}
+}
+
+func TestAccountService_ChangePhone(t *testing.T) {
+	const phone = "15212345678"
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, AccountNotifier)
+
+		wantErr error
+	}{
+		{
+			name: "换绑成功，通知原来的手机号码",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, AccountNotifier) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "15287654321"}, nil)
+				repo.EXPECT().UpdatePhone(gomock.Any(), int64(1), phone).Return(nil)
+				notifier := svcmocks.NewMockAccountNotifier(ctrl)
+				notifier.EXPECT().ContactChanged(gomock.Any(), ContactPhone, "15287654321", phone).
+					Return(nil)
+				return repo, notifier
+			},
+		},
+		{
+			name: "原来没有手机号码，不用通知",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, AccountNotifier) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				repo.EXPECT().UpdatePhone(gomock.Any(), int64(1), phone).Return(nil)
+				return repo, svcmocks.NewMockAccountNotifier(ctrl)
+			},
+		},
+		{
+			name: "通知失败不影响换绑",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, AccountNotifier) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "15287654321"}, nil)
+				repo.EXPECT().UpdatePhone(gomock.Any(), int64(1), phone).Return(nil)
+				notifier := svcmocks.NewMockAccountNotifier(ctrl)
+				notifier.EXPECT().ContactChanged(gomock.Any(), ContactPhone, "15287654321", phone).
+					Return(errors.New("mock error"))
+				return repo, notifier
+			},
+		},
+		{
+			name: "手机号码属于别的账号",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, AccountNotifier) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "15287654321"}, nil)
+				repo.EXPECT().UpdatePhone(gomock.Any(), int64(1), phone).
+					Return(repository.ErrUserDuplicate)
+				return repo, svcmocks.NewMockAccountNotifier(ctrl)
+			},
+			wantErr: ErrPhoneBoundByOther,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, notifier := tc.mock(ctrl)
+			svc := NewAccountService(repo,
+				repomocks.NewMockOAuth2BindingRepository(ctrl),
+				repomocks.NewMockAccountMergeRepository(ctrl),
+				repomocks.NewMockSessionRepository(ctrl),
+				notifier, logger.NewNoOpLogger())
+			err := svc.ChangePhone(context.Background(), 1, phone)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
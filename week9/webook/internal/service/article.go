@@ -24,6 +24,7 @@ type ArticleService interface {
 	// GetPublishedById 查找已经发表的
 	// 正常来说在微服务架构下，读者服务和创作者服务会是两个独立的服务
 	// 单体应用下可以混在一起，毕竟现在也没几个方法
+	// uid 为 0 是没有登录的读者，阅读事件会标记为匿名
 	GetPublishedById(ctx context.Context, id, uid int64) (domain.Article, error)
 	// ListPub 根据更新时间来分页，更新时间必须小于 startTime
 	ListPub(ctx context.Context, startTime time.Time, offset, limit int) ([]domain.Article, error)
@@ -85,8 +86,9 @@ func (svc *articleService) GetPublishedById(ctx context.Context, id, uid int64)
 	go func() {
 		if err == nil {
 			er := svc.producer.ProduceReadEvent(events.ReadEvent{
-				Aid: id,
-				Uid: uid,
+				Aid:       id,
+				Uid:       uid,
+				Anonymous: uid == 0,
 			})
 			if er != nil {
 				svc.logger.Error("发送消息失败",
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"time"
+)
+
+var ErrAsyncSmsNotRequeueable = errs.AsyncSmsNotRequeueable
+
+// AsyncSmsService 管理异步短信的队列，主要是给管理后台和定时任务用的
+// 真正的异步发送逻辑在 sms/async 里面
+//
+//go:generate mockgen -source=./async_sms.go -package=svcmocks -destination=./mocks/async_sms.mock.go AsyncSmsService
+type AsyncSmsService interface {
+	List(ctx context.Context, status domain.AsyncSmsStatus, offset, limit int) ([]domain.AsyncSms, error)
+	Detail(ctx context.Context, id int64) (domain.AsyncSms, error)
+	// Requeue 重新放回等待队列，一般是人工确认服务商已经恢复之后，重发失败的短信
+	Requeue(ctx context.Context, id int64) error
+	// Backlog 各个状态下的积压数量
+	Backlog(ctx context.Context) (map[domain.AsyncSmsStatus]int64, error)
+	// CleanupSuccess 清理 before 之前发送成功的记录，返回清理的条数
+	CleanupSuccess(ctx context.Context, before time.Time) (int64, error)
+}
+
+type asyncSmsService struct {
+	repo repository.AsyncSmsRepository
+	// 每一批删除多少条
+	batchSize int
+}
+
+func NewAsyncSmsService(repo repository.AsyncSmsRepository) AsyncSmsService {
+	return &asyncSmsService{
+		repo:      repo,
+		batchSize: 100,
+	}
+}
+
+func (a *asyncSmsService) List(ctx context.Context,
+	status domain.AsyncSmsStatus, offset, limit int) ([]domain.AsyncSms, error) {
+	return a.repo.List(ctx, status, offset, limit)
+}
+
+func (a *asyncSmsService) Detail(ctx context.Context, id int64) (domain.AsyncSms, error) {
+	return a.repo.FindById(ctx, id)
+}
+
+func (a *asyncSmsService) Requeue(ctx context.Context, id int64) error {
+	err := a.repo.Requeue(ctx, id)
+	if err == repository.ErrAsyncSmsNotRequeueable {
+		return ErrAsyncSmsNotRequeueable
+	}
+	return err
+}
+
+func (a *asyncSmsService) Backlog(ctx context.Context) (map[domain.AsyncSmsStatus]int64, error) {
+	return a.repo.CountByStatus(ctx)
+}
+
+func (a *asyncSmsService) CleanupSuccess(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+		cnt, err := a.repo.DeleteSuccessBefore(ctx, before, a.batchSize)
+		total += cnt
+		if err != nil {
+			return total, err
+		}
+		// 不足一批，说明已经删完了
+		if cnt < int64(a.batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestAsyncSmsService_CleanupSuccess(t *testing.T) {
+	const batchSize = 2
+	before := time.Now()
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.AsyncSmsRepository
+
+		wantCnt int64
+		wantErr error
+	}{
+		{
+			name: "分批删除",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().DeleteSuccessBefore(gomock.Any(), before, batchSize).
+					Return(int64(2), nil).Times(2)
+				repo.EXPECT().DeleteSuccessBefore(gomock.Any(), before, batchSize).
+					Return(int64(1), nil)
+				return repo
+			},
+			wantCnt: 5,
+		},
+		{
+			name: "删除失败",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().DeleteSuccessBefore(gomock.Any(), before, batchSize).
+					Return(int64(2), nil)
+				repo.EXPECT().DeleteSuccessBefore(gomock.Any(), before, batchSize).
+					Return(int64(0), errors.New("mock db error"))
+				return repo
+			},
+			wantCnt: 2,
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := &asyncSmsService{
+				repo:      tc.mock(ctrl),
+				batchSize: batchSize,
+			}
+			cnt, err := svc.CleanupSuccess(context.Background(), before)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCnt, cnt)
+		})
+	}
+}
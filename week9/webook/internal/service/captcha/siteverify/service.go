@@ -0,0 +1,67 @@
+package siteverify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// 常见的几个服务商的校验地址，它们的接口是兼容的
+	RecaptchaURL = "https://www.google.com/recaptcha/api/siteverify"
+	HCaptchaURL  = "https://api.hcaptcha.com/siteverify"
+	TurnstileURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// Service 适配 siteverify 风格的接口，也就是 POST secret、response 和 remoteip，
+// 返回 {"success": true}
+type Service struct {
+	client *http.Client
+	url    string
+	secret string
+}
+
+func NewService(client *http.Client, url string, secret string) *Service {
+	return &Service{
+		client: client,
+		url:    url,
+		secret: secret,
+	}
+}
+
+func (s *Service) Verify(ctx context.Context, token string, ip string) (bool, error) {
+	form := url.Values{}
+	form.Set("secret", s.secret)
+	form.Set("response", token)
+	if ip != "" {
+		form.Set("remoteip", ip)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("人机验证服务响应异常 %d", resp.StatusCode)
+	}
+	var res response
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return false, fmt.Errorf("解析人机验证响应失败 %w", err)
+	}
+	return res.Success, nil
+}
+
+type response struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
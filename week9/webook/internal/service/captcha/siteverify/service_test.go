@@ -0,0 +1,59 @@
+package siteverify
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestService_Verify(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		token   string
+
+		wantOk  bool
+		wantErr bool
+	}{
+		{
+			name: "验证通过",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, r.ParseForm())
+				assert.Equal(t, "my-secret", r.PostForm.Get("secret"))
+				assert.Equal(t, "token", r.PostForm.Get("response"))
+				assert.Equal(t, "127.0.0.1", r.PostForm.Get("remoteip"))
+				_, _ = w.Write([]byte(`{"success": true}`))
+			},
+			token:  "token",
+			wantOk: true,
+		},
+		{
+			name: "验证不通过",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+			},
+			token: "bad-token",
+		},
+		{
+			name: "服务异常",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			token:   "token",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+			svc := NewService(server.Client(), server.URL, "my-secret")
+			ok, err := svc.Verify(context.Background(), tc.token, "127.0.0.1")
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantOk, ok)
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package captcha
+
+import "context"
+
+// Service 人机验证，比如说滑块、点选之类的
+// 前端完成验证之后拿到一个 token，后端再拿 token 去验证
+//
+//go:generate mockgen -source=./types.go -package=captchamocks -destination=mocks/captcha.mock.go Service
+type Service interface {
+	// Verify ip 是用户的 IP，部分服务商会用来辅助判断
+	Verify(ctx context.Context, token string, ip string) (bool, error)
+}
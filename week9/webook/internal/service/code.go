@@ -2,63 +2,193 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
 	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
-	"math/rand"
+	"math/big"
+	"time"
 )
 
-var ErrCodeSendTooMany = repository.ErrCodeSendTooMany
+var (
+	ErrCodeSendTooMany    = repository.ErrCodeSendTooMany
+	ErrUnknownCodeChannel = errors.New("未知的验证码发送渠道")
+)
 
-const codeTplId = "1877556"
+const (
+	codeTplId = "1877556"
+	// CodeCharsetDigits 纯数字
+	CodeCharsetDigits = "0123456789"
+)
 
 //go:generate mockgen -source=./code.go -package=svcmocks -destination=mocks/code.mock.go CodeService
 type CodeService interface {
-	Send(ctx context.Context, biz string, phone string) error
-	Verify(ctx context.Context, biz string, phone string, inputCode string) (bool, error)
+	// Send target 是手机号码或者邮箱，取决于 biz 配置的发送渠道
+	Send(ctx context.Context, biz string, target string) error
+	Verify(ctx context.Context, biz string, target string, inputCode string) (bool, error)
 }
 
-// SMSCodeService 短信验证码的实现
-type SMSCodeService struct {
-	sms  sms.Service
-	repo repository.CodeRepository
+// CodeBizConfig 某个业务的验证码配置，没有配置的字段使用 DefaultCodeBizConfig 里面的值
+type CodeBizConfig struct {
+	Biz string `yaml:"biz"`
+	// Channel 发送渠道，对应 NewCodeService 里面 senders 的 key
+	Channel string `yaml:"channel"`
+	// TplId 短信或者语音验证码的模板
+	TplId string `yaml:"tplId"`
+	// Length 验证码的长度
+	Length int `yaml:"length"`
+	// Charset 验证码的字符集
+	Charset string `yaml:"charset"`
+	// TTL 验证码的有效期
+	TTL time.Duration `yaml:"ttl"`
+	// ResendInterval 多久之后才可以重新发送
+	ResendInterval time.Duration `yaml:"resendInterval"`
+	// MaxAttempts 最多可以验证几次
+	MaxAttempts int `yaml:"maxAttempts"`
 }
 
-func NewSMSCodeService(svc sms.Service, repo repository.CodeRepository) CodeService {
-	return &SMSCodeService{
-		sms:  svc,
-		repo: repo,
+// DefaultCodeBizConfig 六位数字的短信验证码，十分钟有效，一分钟之后可以重发，可以验证三次
+func DefaultCodeBizConfig() CodeBizConfig {
+	return CodeBizConfig{
+		Channel:        "sms",
+		TplId:          codeTplId,
+		Length:         6,
+		Charset:        CodeCharsetDigits,
+		TTL:            time.Minute * 10,
+		ResendInterval: time.Minute,
+		MaxAttempts:    3,
+	}
+}
+
+func (c CodeBizConfig) withDefaults() CodeBizConfig {
+	def := DefaultCodeBizConfig()
+	if c.Channel == "" {
+		c.Channel = def.Channel
+	}
+	if c.TplId == "" {
+		c.TplId = def.TplId
+	}
+	if c.Length <= 0 {
+		c.Length = def.Length
+	}
+	if c.Charset == "" {
+		c.Charset = def.Charset
+	}
+	if c.TTL <= 0 {
+		c.TTL = def.TTL
+	}
+	if c.ResendInterval <= 0 {
+		c.ResendInterval = def.ResendInterval
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = def.MaxAttempts
+	}
+	return c
+}
+
+func (c CodeBizConfig) policy() domain.CodePolicy {
+	return domain.CodePolicy{
+		TTL:            c.TTL,
+		ResendInterval: c.ResendInterval,
+		MaxAttempts:    c.MaxAttempts,
+	}
+}
+
+// ChannelCodeService 根据业务的配置，选择发送渠道
+type ChannelCodeService struct {
+	repo    repository.CodeRepository
+	senders map[string]CodeSender
+	cfgs    map[string]CodeBizConfig
+	// monitor 可以为 nil
+	monitor *CodeVerifyMonitor
+}
+
+// NewCodeService 没有配置的业务使用 DefaultCodeBizConfig，monitor 可以为 nil
+func NewCodeService(repo repository.CodeRepository,
+	senders map[string]CodeSender,
+	cfgs []CodeBizConfig,
+	monitor *CodeVerifyMonitor) CodeService {
+	m := make(map[string]CodeBizConfig, len(cfgs))
+	for _, cfg := range cfgs {
+		m[cfg.Biz] = cfg.withDefaults()
+	}
+	return &ChannelCodeService{
+		repo:    repo,
+		senders: senders,
+		cfgs:    m,
+		monitor: monitor,
 	}
 }
 
+// NewSMSCodeService 只有短信一个渠道，所有的业务都使用默认配置
+func NewSMSCodeService(svc sms.Service, repo repository.CodeRepository) CodeService {
+	return NewCodeService(repo, map[string]CodeSender{
+		"sms": NewSMSCodeSender(svc),
+	}, nil, nil)
+}
+
 // Send 生成一个随机验证码，并发送
-func (c *SMSCodeService) Send(ctx context.Context, biz string, phone string) error {
-	code := c.generate()
-	err := c.repo.Store(ctx, biz, phone, code)
+func (c *ChannelCodeService) Send(ctx context.Context, biz string, target string) error {
+	cfg := c.config(biz)
+	sender, ok := c.senders[cfg.Channel]
+	if !ok {
+		return fmt.Errorf("%w, biz %s, channel %s", ErrUnknownCodeChannel, biz, cfg.Channel)
+	}
+	code, err := c.generate(cfg)
 	if err != nil {
 		return err
 	}
-	err = c.sms.Send(ctx, codeTplId, []string{code}, phone)
-	return err
+	err = c.repo.Store(ctx, biz, target, code, cfg.policy())
+	if err != nil {
+		return err
+	}
+	return sender.Send(ctx, target, code, cfg)
 }
 
 // Verify 验证验证码
-func (c *SMSCodeService) Verify(ctx context.Context,
+func (c *ChannelCodeService) Verify(ctx context.Context,
 	biz string,
-	phone string,
+	target string,
 	inputCode string) (bool, error) {
-	ok, err := c.repo.Verify(ctx, biz, phone, inputCode)
+	ok, err := c.repo.Verify(ctx, biz, target, inputCode)
+	if err == repository.ErrCodeNotExist {
+		// 验证码过期了，对用户来说就是验证码不对
+		return false, nil
+	}
 	// 这里我们在 service 层面上对 RedisHandler 屏蔽了最为特殊的错误
 	if err == repository.ErrCodeVerifyTooManyTimes {
-		// 在接入了告警之后，这边要告警
-		// 因为这意味着有人在搞你
+		// 偶尔出现是用户手抖，大量出现就意味着有人在搞你
+		if c.monitor != nil {
+			c.monitor.Record(ctx, biz)
+		}
 		return false, nil
 	}
 	return ok, err
 }
 
-func (c *SMSCodeService) generate() string {
-	// 用随机数生成一个
-	num := rand.Intn(999999)
-	return fmt.Sprintf("%6d", num)
+func (c *ChannelCodeService) config(biz string) CodeBizConfig {
+	cfg, ok := c.cfgs[biz]
+	if !ok {
+		cfg = DefaultCodeBizConfig()
+		cfg.Biz = biz
+	}
+	return cfg
+}
+
+// generate 验证码是要防猜测的，所以用 crypto/rand
+// 不能用 fmt.Sprintf("%6d")，它补的是空格而不是 0
+func (c *ChannelCodeService) generate(cfg CodeBizConfig) (string, error) {
+	charset := []rune(cfg.Charset)
+	size := big.NewInt(int64(len(charset)))
+	res := make([]rune, cfg.Length)
+	for i := range res {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		res[i] = charset[n.Int64()]
+	}
+	return string(res), nil
 }
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service/captcha"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/ratelimit"
+	"regexp"
+)
+
+var (
+	ErrInvalidPhone    = errs.UserInvalidPhone
+	ErrCodeSendLimited = errs.UserCodeSendTooMany
+	ErrCaptchaRequired = errs.UserCaptchaRequired
+	ErrCaptchaFailed   = errs.UserCaptchaFailed
+)
+
+// CodeSendReq 发送验证码的请求，除了手机号码或者邮箱，还有防刷需要用到的信息
+type CodeSendReq struct {
+	Biz string
+	// Phone 和 Email 二选一，发邮件验证码的时候 Phone 为空
+	Phone string
+	// Email 格式由调用者校验
+	Email string
+	IP    string
+	// Device 设备指纹，由前端采集，可能为空。为空的时候要不要人机验证，看 RuleCodeGuard 的配置
+	Device string
+	// Captcha 人机验证的 token，只有要求人机验证的时候才需要
+	Captcha string
+}
+
+// Target 验证码发给谁
+func (req CodeSendReq) Target() string {
+	if req.Email != "" {
+		return req.Email
+	}
+	return req.Phone
+}
+
+// CodeGuard 发送验证码之前的防刷检查
+type CodeGuard interface {
+	// Check 返回 nil 说明可以发送
+	Check(ctx context.Context, req CodeSendReq) error
+}
+
+// CodeLimitRule 一条限流规则
+type CodeLimitRule struct {
+	// Name 用来拼接限流的 key，不同的规则不能重复
+	Name    string
+	Limiter ratelimit.Limiter
+	// Key 限流的对象，返回空字符串说明这个请求不适用这条规则
+	Key func(req CodeSendReq) string
+	// Challenge 为 true 的时候，触发了限流并不直接拒绝，而是要求人机验证
+	Challenge bool
+}
+
+// PhoneRule 同一个手机号码或者邮箱，比如说每天最多发十次
+func PhoneRule(limiter ratelimit.Limiter) CodeLimitRule {
+	return CodeLimitRule{
+		Name:    "phone",
+		Limiter: limiter,
+		Key: func(req CodeSendReq) string {
+			return req.Biz + ":" + req.Target()
+		},
+	}
+}
+
+// IPRule 同一个 IP，换着手机号码刷
+func IPRule(limiter ratelimit.Limiter) CodeLimitRule {
+	return CodeLimitRule{
+		Name:    "ip",
+		Limiter: limiter,
+		Key: func(req CodeSendReq) string {
+			return req.IP
+		},
+	}
+}
+
+// DeviceRule 同一个设备，换着 IP 和手机号码刷
+func DeviceRule(limiter ratelimit.Limiter) CodeLimitRule {
+	return CodeLimitRule{
+		Name:    "device",
+		Limiter: limiter,
+		Key: func(req CodeSendReq) string {
+			return req.Device
+		},
+	}
+}
+
+// SuspiciousIPRule 同一个 IP 短时间内请求比较多，但是还没到 IPRule 的阈值，
+// 这时候要求人机验证，而不是直接拒绝，因为可能是公司、学校这种共用出口 IP 的
+func SuspiciousIPRule(limiter ratelimit.Limiter) CodeLimitRule {
+	return CodeLimitRule{
+		Name:    "suspicious_ip",
+		Limiter: limiter,
+		Key: func(req CodeSendReq) string {
+			return req.IP
+		},
+		Challenge: true,
+	}
+}
+
+// RuleCodeGuard 依次检查手机号码格式、各条限流规则，手机号码和邮箱用的是同样的规则，可疑的请求要求人机验证。
+// 带了人机验证 token 的请求会先校验 token
+type RuleCodeGuard struct {
+	phoneExp *regexp.Regexp
+	rules    []CodeLimitRule
+	// captcha 为 nil 的时候，就不会要求人机验证
+	captcha captcha.Service
+	// noDeviceSuspicious 为 true 的时候，没有设备指纹的请求都要人机验证。
+	// 没有设备指纹的，大概率是脚本直接调用的接口，但是不采集设备指纹的客户端也会每次都要人机验证
+	noDeviceSuspicious bool
+	l                  logger.LoggerV1
+}
+
+func NewRuleCodeGuard(rules []CodeLimitRule,
+	captcha captcha.Service,
+	noDeviceSuspicious bool,
+	l logger.LoggerV1) CodeGuard {
+	return &RuleCodeGuard{
+		phoneExp:           regexp.MustCompile(ginx.PhoneRegexPattern),
+		rules:              rules,
+		captcha:            captcha,
+		noDeviceSuspicious: noDeviceSuspicious,
+		l:                  l,
+	}
+}
+
+func (g *RuleCodeGuard) Check(ctx context.Context, req CodeSendReq) error {
+	if req.Email == "" && !g.phoneExp.MatchString(req.Phone) {
+		return ErrInvalidPhone
+	}
+	// 先校验人机验证，没通过的请求不能消耗限流的额度
+	verified, err := g.verifyCaptcha(ctx, req)
+	if err != nil {
+		return err
+	}
+	suspicious := g.noDeviceSuspicious && req.Device == ""
+	for _, r := range g.rules {
+		key := r.Key(req)
+		if key == "" {
+			continue
+		}
+		limited, err := r.Limiter.Limit(ctx, fmt.Sprintf("code_send:%s:%s", r.Name, key))
+		if err != nil {
+			// 宁可拒绝，也不要被刷
+			return err
+		}
+		if !limited {
+			continue
+		}
+		if r.Challenge {
+			suspicious = true
+			continue
+		}
+		g.l.Warn("发送验证码触发限流",
+			logger.String("rule", r.Name),
+			logger.String("biz", req.Biz),
+			logger.String("ip", req.IP))
+		return ErrCodeSendLimited
+	}
+	if !suspicious || g.captcha == nil || verified {
+		return nil
+	}
+	return ErrCaptchaRequired
+}
+
+// verifyCaptcha 没有带人机验证的 token 返回 false
+func (g *RuleCodeGuard) verifyCaptcha(ctx context.Context, req CodeSendReq) (bool, error) {
+	if req.Captcha == "" || g.captcha == nil {
+		return false, nil
+	}
+	ok, err := g.captcha.Verify(ctx, req.Captcha, req.IP)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, ErrCaptchaFailed
+	}
+	return true, nil
+}
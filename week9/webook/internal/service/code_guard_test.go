@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/service/captcha"
+	captchamocks "gitee.com/geekbang/basic-go/webook/internal/service/captcha/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/ratelimit"
+	limitmocks "gitee.com/geekbang/basic-go/webook/pkg/ratelimit/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestRuleCodeGuard_Check(t *testing.T) {
+	testCases := []struct {
+		name string
+		// 依次是手机号码、IP、可疑 IP 的限流器
+		mock func(ctrl *gomock.Controller) (phone, ip, suspicious ratelimit.Limiter, c captcha.Service)
+		req  CodeSendReq
+		// 没有设备指纹的请求不当成可疑的
+		allowNoDevice bool
+
+		wantErr error
+	}{
+		{
+			name: "通过",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				phone := limitmocks.NewMockLimiter(ctrl)
+				phone.EXPECT().Limit(gomock.Any(), "code_send:phone:login:15212345678").Return(false, nil)
+				ip := limitmocks.NewMockLimiter(ctrl)
+				ip.EXPECT().Limit(gomock.Any(), "code_send:ip:127.0.0.1").Return(false, nil)
+				suspicious := limitmocks.NewMockLimiter(ctrl)
+				suspicious.EXPECT().Limit(gomock.Any(), "code_send:suspicious_ip:127.0.0.1").Return(false, nil)
+				return phone, ip, suspicious, captchamocks.NewMockService(ctrl)
+			},
+			req: CodeSendReq{Biz: "login", Phone: "15212345678", IP: "127.0.0.1", Device: "abc"},
+		},
+		{
+			name: "邮箱，同样的规则",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				phone := limitmocks.NewMockLimiter(ctrl)
+				phone.EXPECT().Limit(gomock.Any(), "code_send:phone:bind_email:123@qq.com").Return(false, nil)
+				ip := limitmocks.NewMockLimiter(ctrl)
+				ip.EXPECT().Limit(gomock.Any(), "code_send:ip:127.0.0.1").Return(true, nil)
+				return phone, ip, limitmocks.NewMockLimiter(ctrl), captchamocks.NewMockService(ctrl)
+			},
+			req:     CodeSendReq{Biz: "bind_email", Email: "123@qq.com", IP: "127.0.0.1", Device: "abc"},
+			wantErr: ErrCodeSendLimited,
+		},
+		{
+			name: "手机号码格式不对",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				return limitmocks.NewMockLimiter(ctrl), limitmocks.NewMockLimiter(ctrl),
+					limitmocks.NewMockLimiter(ctrl), captchamocks.NewMockService(ctrl)
+			},
+			req:     CodeSendReq{Biz: "login", Phone: "1521234567", IP: "127.0.0.1", Device: "abc"},
+			wantErr: ErrInvalidPhone,
+		},
+		{
+			name: "同一个 IP 发送太多",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				phone := limitmocks.NewMockLimiter(ctrl)
+				phone.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				ip := limitmocks.NewMockLimiter(ctrl)
+				ip.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(true, nil)
+				return phone, ip, limitmocks.NewMockLimiter(ctrl), captchamocks.NewMockService(ctrl)
+			},
+			req:     CodeSendReq{Biz: "login", Phone: "15212345678", IP: "127.0.0.1", Device: "abc"},
+			wantErr: ErrCodeSendLimited,
+		},
+		{
+			name: "限流出错",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				phone := limitmocks.NewMockLimiter(ctrl)
+				phone.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, errors.New("redis 错误"))
+				return phone, limitmocks.NewMockLimiter(ctrl),
+					limitmocks.NewMockLimiter(ctrl), captchamocks.NewMockService(ctrl)
+			},
+			req:     CodeSendReq{Biz: "login", Phone: "15212345678", IP: "127.0.0.1", Device: "abc"},
+			wantErr: errors.New("redis 错误"),
+		},
+		{
+			name: "可疑 IP，要求人机验证",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				phone := limitmocks.NewMockLimiter(ctrl)
+				phone.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				ip := limitmocks.NewMockLimiter(ctrl)
+				ip.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				suspicious := limitmocks.NewMockLimiter(ctrl)
+				suspicious.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(true, nil)
+				return phone, ip, suspicious, captchamocks.NewMockService(ctrl)
+			},
+			req:     CodeSendReq{Biz: "login", Phone: "15212345678", IP: "127.0.0.1", Device: "abc"},
+			wantErr: ErrCaptchaRequired,
+		},
+		{
+			name: "没有设备指纹，人机验证通过",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				phone := limitmocks.NewMockLimiter(ctrl)
+				phone.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				ip := limitmocks.NewMockLimiter(ctrl)
+				ip.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				suspicious := limitmocks.NewMockLimiter(ctrl)
+				suspicious.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				c := captchamocks.NewMockService(ctrl)
+				c.EXPECT().Verify(gomock.Any(), "captcha-token", "127.0.0.1").Return(true, nil)
+				return phone, ip, suspicious, c
+			},
+			req: CodeSendReq{Biz: "login", Phone: "15212345678", IP: "127.0.0.1",
+				Captcha: "captcha-token"},
+		},
+		{
+			name: "没有设备指纹，人机验证不通过，不消耗限流的额度",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				c := captchamocks.NewMockService(ctrl)
+				c.EXPECT().Verify(gomock.Any(), "captcha-token", "127.0.0.1").Return(false, nil)
+				return limitmocks.NewMockLimiter(ctrl), limitmocks.NewMockLimiter(ctrl),
+					limitmocks.NewMockLimiter(ctrl), c
+			},
+			req: CodeSendReq{Biz: "login", Phone: "15212345678", IP: "127.0.0.1",
+				Captcha: "captcha-token"},
+			wantErr: ErrCaptchaFailed,
+		},
+		{
+			name: "没有设备指纹，也没有带人机验证",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				phone := limitmocks.NewMockLimiter(ctrl)
+				phone.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				ip := limitmocks.NewMockLimiter(ctrl)
+				ip.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				suspicious := limitmocks.NewMockLimiter(ctrl)
+				suspicious.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				return phone, ip, suspicious, captchamocks.NewMockService(ctrl)
+			},
+			req:     CodeSendReq{Biz: "login", Phone: "15212345678", IP: "127.0.0.1"},
+			wantErr: ErrCaptchaRequired,
+		},
+		{
+			name: "没有设备指纹，配置了不当成可疑的",
+			mock: func(ctrl *gomock.Controller) (ratelimit.Limiter, ratelimit.Limiter, ratelimit.Limiter, captcha.Service) {
+				phone := limitmocks.NewMockLimiter(ctrl)
+				phone.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				ip := limitmocks.NewMockLimiter(ctrl)
+				ip.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				suspicious := limitmocks.NewMockLimiter(ctrl)
+				suspicious.EXPECT().Limit(gomock.Any(), gomock.Any()).Return(false, nil)
+				return phone, ip, suspicious, captchamocks.NewMockService(ctrl)
+			},
+			req:           CodeSendReq{Biz: "login", Phone: "15212345678", IP: "127.0.0.1"},
+			allowNoDevice: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			phone, ip, suspicious, c := tc.mock(ctrl)
+			// 设备这一条总是不限流，没有设备指纹的时候则不会调用
+			device := limitmocks.NewMockLimiter(ctrl)
+			device.EXPECT().Limit(gomock.Any(), "code_send:device:abc").
+				Return(false, nil).AnyTimes()
+			guard := NewRuleCodeGuard([]CodeLimitRule{
+				PhoneRule(phone),
+				IPRule(ip),
+				DeviceRule(device),
+				SuspiciousIPRule(suspicious),
+			}, c, !tc.allowNoDevice, logger.NewNoOpLogger())
+			err := guard.Check(context.Background(), tc.req)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"sync"
+	"time"
+)
+
+// CodeAlertFunc 验证次数耗尽的情况突增的时候回调，你可以接入自己公司的告警系统
+type CodeAlertFunc func(ctx context.Context, biz string, cnt int)
+
+// CodeVerifyMonitor 监控验证次数耗尽的情况，也就是 ErrCodeVerifyTooManyTimes
+// 偶尔出现是用户手抖，大量出现就意味着有人在暴力破解
+// window 内出现超过 threshold 次就告警，告警之后 window 内不再重复告警
+type CodeVerifyMonitor struct {
+	window    time.Duration
+	threshold int
+	alert     CodeAlertFunc
+	counter   *prometheus.CounterVec
+	l         logger.LoggerV1
+
+	lock sync.Mutex
+	// 每个业务最近出现的时间
+	events    map[string][]time.Time
+	lastAlert map[string]time.Time
+	// 为了测试，可以替换掉
+	now func() time.Time
+}
+
+func NewCodeVerifyMonitor(window time.Duration,
+	threshold int,
+	l logger.LoggerV1,
+	opts prometheus.CounterOpts) *CodeVerifyMonitor {
+	counter := prometheus.NewCounterVec(opts, []string{"biz"})
+	prometheus.MustRegister(counter)
+	m := &CodeVerifyMonitor{
+		window:    window,
+		threshold: threshold,
+		counter:   counter,
+		l:         l,
+		events:    make(map[string][]time.Time),
+		lastAlert: make(map[string]time.Time),
+		now:       time.Now,
+	}
+	// 默认就是打日志
+	m.alert = func(ctx context.Context, biz string, cnt int) {
+		l.Error("验证码验证次数耗尽的情况突增，可能有人在暴力破解",
+			logger.String("biz", biz),
+			logger.Int64("cnt", int64(cnt)))
+	}
+	return m
+}
+
+// SetAlertFunc 替换默认的告警方式
+func (m *CodeVerifyMonitor) SetAlertFunc(fn CodeAlertFunc) {
+	m.alert = fn
+}
+
+// Record 出现了一次验证次数耗尽
+func (m *CodeVerifyMonitor) Record(ctx context.Context, biz string) {
+	m.counter.WithLabelValues(biz).Inc()
+	m.lock.Lock()
+	now := m.now()
+	since := now.Add(-m.window)
+	events := m.events[biz]
+	// 移除过期的
+	idx := 0
+	for idx < len(events) && !events[idx].After(since) {
+		idx++
+	}
+	events = append(events[idx:], now)
+	m.events[biz] = events
+	cnt := len(events)
+	shouldAlert := cnt >= m.threshold && now.Sub(m.lastAlert[biz]) >= m.window
+	if shouldAlert {
+		m.lastAlert[biz] = now
+	}
+	m.lock.Unlock()
+	if shouldAlert {
+		m.alert(ctx, biz, cnt)
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	"time"
+)
+
+// CodeSender 验证码的发送渠道，比如说短信、语音、邮件
+type CodeSender interface {
+	Send(ctx context.Context, target string, code string, cfg CodeBizConfig) error
+}
+
+// SMSCodeSender 通过短信发送验证码
+// 语音验证码也是用这个实现，只需要传入语音服务商，并且配置语音的模板就可以
+type SMSCodeSender struct {
+	svc sms.Service
+}
+
+func NewSMSCodeSender(svc sms.Service) *SMSCodeSender {
+	return &SMSCodeSender{svc: svc}
+}
+
+func (s *SMSCodeSender) Send(ctx context.Context, target string, code string, cfg CodeBizConfig) error {
+	return s.svc.Send(ctx, cfg.TplId, []string{code}, target)
+}
+
+// SMSTemplateService 业务方先用 Issue 申请模板的 token，Send 的时候传 token 而不是模板 ID，
+// 见 auth.SMSService
+type SMSTemplateService interface {
+	sms.Service
+	Issue(ctx context.Context, biz string, tplId string) (string, error)
+}
+
+// TemplateSMSCodeSender 通过短信模板的 token 发送验证码，验证码的业务就是申请 token 的业务，
+// 所以哪些业务能用哪些模板、每天能发多少条，都由模板的配置来控制
+type TemplateSMSCodeSender struct {
+	svc SMSTemplateService
+}
+
+func NewTemplateSMSCodeSender(svc SMSTemplateService) *TemplateSMSCodeSender {
+	return &TemplateSMSCodeSender{svc: svc}
+}
+
+func (s *TemplateSMSCodeSender) Send(ctx context.Context, target string, code string, cfg CodeBizConfig) error {
+	// 签发 token 只是本地计算一下签名，所以每次都重新签发，不用缓存
+	token, err := s.svc.Issue(ctx, cfg.Biz, cfg.TplId)
+	if err != nil {
+		return err
+	}
+	return s.svc.Send(ctx, token, []string{code}, target)
+}
+
+// EmailCodeSender 通过邮件发送验证码
+type EmailCodeSender struct {
+	svc     email.Service
+	subject string
+}
+
+func NewEmailCodeSender(svc email.Service, subject string) *EmailCodeSender {
+	return &EmailCodeSender{
+		svc:     svc,
+		subject: subject,
+	}
+}
+
+func (s *EmailCodeSender) Send(ctx context.Context, target string, code string, cfg CodeBizConfig) error {
+	body := fmt.Sprintf("您的验证码是 %s，%d 分钟内有效，请不要泄露给他人。",
+		code, int64(cfg.TTL/time.Minute))
+	return s.svc.Send(ctx, target, s.subject, body)
+}
@@ -1,10 +1,220 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	smsmocks "gitee.com/geekbang/basic-go/webook/internal/service/sms/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 	"testing"
+	"time"
 )
 
 func TestFormat(t *testing.T) {
 	fmt.Printf("%06d", 123)
 }
+
+func TestChannelCodeService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.CodeRepository, map[string]CodeSender)
+		biz  string
+
+		wantErr error
+	}{
+		{
+			name: "没有配置的业务，默认用短信",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, map[string]CodeSender) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "login", "15212345678",
+					gomock.Any(), domain.CodePolicy{
+						TTL:            time.Minute * 10,
+						ResendInterval: time.Minute,
+						MaxAttempts:    3,
+					}).Return(nil)
+				sender := codeSenderFunc(func(ctx context.Context, target string, code string, cfg CodeBizConfig) error {
+					assert.Equal(t, "15212345678", target)
+					assert.Regexp(t, "^[0-9]{6}$", code)
+					assert.Equal(t, codeTplId, cfg.TplId)
+					return nil
+				})
+				return repo, map[string]CodeSender{"sms": sender}
+			},
+			biz: "login",
+		},
+		{
+			name: "按照业务的配置，用邮件发送",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, map[string]CodeSender) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "reset_password", "15212345678",
+					gomock.Any(), domain.CodePolicy{
+						TTL:            time.Minute * 30,
+						ResendInterval: time.Minute * 2,
+						MaxAttempts:    3,
+					}).Return(nil)
+				sender := codeSenderFunc(func(ctx context.Context, target string, code string, cfg CodeBizConfig) error {
+					assert.Regexp(t, "^[A-Z]{8}$", code)
+					return nil
+				})
+				return repo, map[string]CodeSender{
+					"sms":   noCodeSender(t),
+					"email": sender,
+				}
+			},
+			biz: "reset_password",
+		},
+		{
+			name: "发送太频繁",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, map[string]CodeSender) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "login", "15212345678",
+					gomock.Any(), gomock.Any()).Return(ErrCodeSendTooMany)
+				return repo, map[string]CodeSender{"sms": noCodeSender(t)}
+			},
+			biz:     "login",
+			wantErr: ErrCodeSendTooMany,
+		},
+		{
+			name: "没有对应的发送渠道",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, map[string]CodeSender) {
+				return repomocks.NewMockCodeRepository(ctrl), map[string]CodeSender{}
+			},
+			biz:     "login",
+			wantErr: ErrUnknownCodeChannel,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, senders := tc.mock(ctrl)
+			svc := NewCodeService(repo, senders, []CodeBizConfig{
+				{
+					Biz:            "reset_password",
+					Channel:        "email",
+					Length:         8,
+					Charset:        "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+					TTL:            time.Minute * 30,
+					ResendInterval: time.Minute * 2,
+				},
+			}, nil)
+			err := svc.Send(context.Background(), tc.biz, "15212345678")
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestChannelCodeService_generate(t *testing.T) {
+	svc := &ChannelCodeService{}
+	cfg := DefaultCodeBizConfig()
+	// 随机生成很多次，不会出现空格
+	for i := 0; i < 1000; i++ {
+		code, err := svc.generate(cfg)
+		require.NoError(t, err)
+		assert.Regexp(t, "^[0-9]{6}$", code)
+	}
+}
+
+type codeSenderFunc func(ctx context.Context, target string, code string, cfg CodeBizConfig) error
+
+func (f codeSenderFunc) Send(ctx context.Context, target string, code string, cfg CodeBizConfig) error {
+	return f(ctx, target, code, cfg)
+}
+
+// noCodeSender 不应该被调用的发送渠道
+func noCodeSender(t *testing.T) CodeSender {
+	return codeSenderFunc(func(ctx context.Context, target string, code string, cfg CodeBizConfig) error {
+		t.Fatal("不应该调用这个发送渠道")
+		return nil
+	})
+}
+
+func TestChannelCodeService_Verify(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockCodeRepository(ctrl)
+	repo.EXPECT().Verify(gomock.Any(), "login", "15212345678", "123456").
+		Return(false, repository.ErrCodeVerifyTooManyTimes).Times(4)
+	monitor := NewCodeVerifyMonitor(time.Minute, 3, logger.NewNoOpLogger(),
+		prometheus.CounterOpts{Name: "test_code_verify_too_many_times"})
+	now := time.Now()
+	monitor.now = func() time.Time {
+		return now
+	}
+	var alerts []int
+	monitor.SetAlertFunc(func(ctx context.Context, biz string, cnt int) {
+		alerts = append(alerts, cnt)
+	})
+	svc := NewCodeService(repo, nil, nil, monitor)
+	for i := 0; i < 4; i++ {
+		// 验证次数耗尽，对外表现为验证码不对
+		ok, err := svc.Verify(context.Background(), "login", "15212345678", "123456")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+	// 第三次的时候告警，第四次在冷却时间内，不重复告警
+	assert.Equal(t, []int{3}, alerts)
+
+	// 过了一个窗口，旧的都过期了，重新计数
+	now = now.Add(time.Minute)
+	monitor.Record(context.Background(), "login")
+	assert.Equal(t, []int{3}, alerts)
+}
+
+func TestTemplateSMSCodeSender_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) SMSTemplateService
+
+		wantErr error
+	}{
+		{
+			name: "用业务申请 token 再发送",
+			mock: func(ctrl *gomock.Controller) SMSTemplateService {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "token", []string{"123456"}, "15212345678").Return(nil)
+				return smsTemplateService{Service: svc, token: "token"}
+			},
+		},
+		{
+			name: "业务不能用这个模板",
+			mock: func(ctrl *gomock.Controller) SMSTemplateService {
+				return smsTemplateService{Service: smsmocks.NewMockService(ctrl),
+					err: errors.New("mock error")}
+			},
+			wantErr: errors.New("mock error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			sender := NewTemplateSMSCodeSender(tc.mock(ctrl))
+			err := sender.Send(context.Background(), "15212345678", "123456",
+				CodeBizConfig{Biz: "login", TplId: "login_code"})
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+// smsTemplateService 只允许 login 业务使用 login_code 模板
+type smsTemplateService struct {
+	sms.Service
+	token string
+	err   error
+}
+
+func (s smsTemplateService) Issue(ctx context.Context, biz string, tplId string) (string, error) {
+	if biz != "login" || tplId != "login_code" {
+		return "", fmt.Errorf("业务 %s 不能使用模板 %s", biz, tplId)
+	}
+	return s.token, s.err
+}
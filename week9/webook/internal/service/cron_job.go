@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
 	"time"
 )
@@ -43,28 +45,16 @@ func (s *cronJobService) Preempt(ctx context.Context) (domain.CronJob, error) {
 	if err != nil {
 		return domain.CronJob{}, err
 	}
-	ch := make(chan struct{})
-	go func() {
-		// 这边要启动一个 goroutine 开始续约，也就是在持续占有期间
-		// 假定说我们这里是十秒钟续约一次
-		ticker := time.NewTicker(s.refreshInterval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ch:
-				// 退出续约循环
-				return
-			case <-ticker.C:
-				s.refresh(j.Id)
-			}
-		}
-	}()
+	// 抢占到的任务本质上就是一个分布式锁，
+	// 所以续约直接复用 dlock 的机制，假定说我们这里是十秒钟续约一次
+	lease := dlock.AutoRefresh(&jobLock{repo: s.repo, job: j},
+		s.refreshInterval, time.Second)
+	j.Lost = lease.Lost()
 	// 只能调用一次，也就是放弃续约。这时候要把状态还原回去
 	j.CancelFunc = func() {
-		close(ch)
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 		defer cancel()
-		err := s.repo.Release(ctx, j.Id)
+		err := lease.Unlock(ctx)
 		if err != nil {
 			s.l.Error("释放任务失败",
 				logger.Error(err),
@@ -74,24 +64,44 @@ func (s *cronJobService) Preempt(ctx context.Context) (domain.CronJob, error) {
 	return j, nil
 }
 
-func (s *cronJobService) refresh(id int64) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	err := s.repo.UpdateUtime(ctx, id)
-	if err != nil {
-		s.l.Error("续约失败",
-			logger.Int64("jid", id),
-			logger.Error(err))
-	}
-}
-
 func (s *cronJobService) ResetNextTime(ctx context.Context,
 	jd domain.CronJob) error {
 	// 计算下一次的时间
 	t := jd.Next(time.Now())
 	// 我们认为这是不需要继续执行了
 	if !t.IsZero() {
-		return s.repo.UpdateNextTime(ctx, jd.Id, t)
+		return s.repo.UpdateNextTime(ctx, jd.Id, jd.Version, t)
 	}
 	return nil
 }
+
+// jobLock 把抢占到的任务适配成 dlock.Lock
+// 版本号就是 fencing token，续约和释放都要校验版本号
+type jobLock struct {
+	repo repository.CronJobRepository
+	job  domain.CronJob
+}
+
+func (j *jobLock) Key() string {
+	return fmt.Sprintf("cron_job:%d", j.job.Id)
+}
+
+func (j *jobLock) Token() int64 {
+	return j.job.Version
+}
+
+func (j *jobLock) Refresh(ctx context.Context) error {
+	err := j.repo.UpdateUtime(ctx, j.job.Id, j.job.Version)
+	if err == repository.ErrJobNotHold {
+		return dlock.ErrLockNotHold
+	}
+	return err
+}
+
+func (j *jobLock) Unlock(ctx context.Context) error {
+	err := j.repo.Release(ctx, j.job.Id, j.job.Version)
+	if err == repository.ErrJobNotHold {
+		return dlock.ErrLockNotHold
+	}
+	return err
+}
@@ -21,27 +21,53 @@ func TestCronJobService_Preempt(t *testing.T) {
 		wantErr  error
 		wantJob  domain.CronJob
 		interval time.Duration
+		// 续约过程中是否会失去抢占
+		wantLost bool
 	}{
 		{
 			name: "抢占并且续约",
 			mock: func(ctrl *gomock.Controller) repository.CronJobRepository {
 				repo := repomocks.NewMockCronJobRepository(ctrl)
 				repo.EXPECT().Preempt(gomock.Any()).Return(domain.CronJob{
-					Id: 1,
+					Id:      1,
+					Version: 2,
 				}, nil)
 				// interval 设置为三秒多，所以会续约三次
-				repo.EXPECT().UpdateUtime(gomock.Any(), int64(1)).Times(3).
+				repo.EXPECT().UpdateUtime(gomock.Any(), int64(1), int64(2)).Times(3).
 					Return(nil)
-				repo.EXPECT().Release(gomock.Any(), int64(1)).Return(nil)
+				repo.EXPECT().Release(gomock.Any(), int64(1), int64(2)).Return(nil)
 				return repo
 			},
 			// 多加 100 毫秒，规避边界条件
 			interval: time.Second*3 + time.Millisecond*100,
 			wantErr:  nil,
 			wantJob: domain.CronJob{
-				Id: 1,
+				Id:      1,
+				Version: 2,
 			},
 		},
+		{
+			name: "续约的时候发现任务被别人抢走了",
+			mock: func(ctrl *gomock.Controller) repository.CronJobRepository {
+				repo := repomocks.NewMockCronJobRepository(ctrl)
+				repo.EXPECT().Preempt(gomock.Any()).Return(domain.CronJob{
+					Id:      1,
+					Version: 2,
+				}, nil)
+				// 第一次续约就失败了，之后不会再续约
+				repo.EXPECT().UpdateUtime(gomock.Any(), int64(1), int64(2)).
+					Return(repository.ErrJobNotHold)
+				repo.EXPECT().Release(gomock.Any(), int64(1), int64(2)).
+					Return(repository.ErrJobNotHold)
+				return repo
+			},
+			interval: time.Second*3 + time.Millisecond*100,
+			wantJob: domain.CronJob{
+				Id:      1,
+				Version: 2,
+			},
+			wantLost: true,
+		},
 		{
 			name: "抢占失败",
 			mock: func(ctrl *gomock.Controller) repository.CronJobRepository {
@@ -57,6 +83,7 @@ func TestCronJobService_Preempt(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
+		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			ctrl := gomock.NewController(t)
@@ -70,11 +97,20 @@ func TestCronJobService_Preempt(t *testing.T) {
 				return
 			}
 			assert.NotNil(t, job.CancelFunc)
+			assert.NotNil(t, job.Lost)
 			cancelFunc := job.CancelFunc
+			lost := job.Lost
 			job.CancelFunc = nil
+			job.Lost = nil
 			assert.Equal(t, tc.wantJob, job)
 
 			time.Sleep(tc.interval)
+			select {
+			case <-lost:
+				assert.True(t, tc.wantLost)
+			default:
+				assert.False(t, tc.wantLost)
+			}
 			// 模拟运行之后取消续约
 			cancelFunc()
 			// 再次 sleep，借助 mock 确定真的退出了续约循环
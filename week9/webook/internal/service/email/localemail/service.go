@@ -0,0 +1,19 @@
+package localemail
+
+import (
+	"context"
+	"log"
+)
+
+// Service 输出到控制台，开发环境用
+type Service struct {
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+func (s *Service) Send(ctx context.Context, to string, subject string, body string) error {
+	log.Println("发送邮件", to, subject, body)
+	return nil
+}
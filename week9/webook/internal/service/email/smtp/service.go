@@ -0,0 +1,117 @@
+package smtp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// From 发件人，为空就使用 Username
+	From string `yaml:"from"`
+	// SSL 为 true 的时候一开始就用 TLS 连接，一般是 465 端口；
+	// 否则如果服务器支持，就用 STARTTLS 升级
+	SSL bool `yaml:"ssl"`
+}
+
+// Service 基于 SMTP 协议发送邮件
+// 没有用 smtp.SendMail，是因为它不支持 ctx，也没办法设置超时
+type Service struct {
+	cfg Config
+}
+
+func NewService(cfg Config) *Service {
+	if cfg.From == "" {
+		cfg.From = cfg.Username
+	}
+	return &Service{cfg: cfg}
+}
+
+func (s *Service) Send(ctx context.Context, to string, subject string, body string) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	// 连接建立之后，整个发送过程也要受 ctx 控制
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && !s.cfg.SSL {
+		err = client.StartTLS(&tls.Config{ServerName: s.cfg.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host))
+		if err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	if err = client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(s.message(to, subject, body))
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (s *Service) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(s.cfg.Host, fmt.Sprintf("%d", s.cfg.Port))
+	if s.cfg.SSL {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: s.cfg.Host}}
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// message 组装邮件，主题和正文都可能有中文，所以主题用 RFC 2047 编码，正文用 base64
+func (s *Service) message(to string, subject string, body string) []byte {
+	var sb strings.Builder
+	sb.WriteString("From: " + s.cfg.From + "\r\n")
+	sb.WriteString("To: " + to + "\r\n")
+	sb.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("Content-Transfer-Encoding: base64\r\n")
+	sb.WriteString("\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	// 每行不能超过 76 个字符
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded + "\r\n")
+	return []byte(sb.String())
+}
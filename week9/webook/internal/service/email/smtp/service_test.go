@@ -0,0 +1,147 @@
+package smtp
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		// 服务器对 AUTH 的响应
+		authResp string
+
+		wantErr bool
+	}{
+		{
+			name:     "发送成功",
+			authResp: "235 2.7.0 Authentication successful",
+		},
+		{
+			name:     "认证失败",
+			authResp: "535 5.7.8 Authentication failed",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newFakeServer(t, tc.authResp)
+			host, port, err := net.SplitHostPort(server.addr())
+			require.NoError(t, err)
+			p, err := strconv.Atoi(port)
+			require.NoError(t, err)
+			svc := NewService(Config{
+				Host:     host,
+				Port:     p,
+				Username: "webook@example.com",
+				Password: "123456",
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+			defer cancel()
+			err = svc.Send(ctx, "user@example.com", "验证码", "您的验证码是 123456")
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			mail := <-server.mails
+			assert.Equal(t, "webook@example.com", mail.from)
+			assert.Equal(t, "user@example.com", mail.to)
+			assert.Contains(t, mail.data, "Subject: =?UTF-8?b?6aqM6K+B56CB?=")
+			assert.Contains(t, mail.data,
+				base64.StdEncoding.EncodeToString([]byte("您的验证码是 123456")))
+		})
+	}
+}
+
+type fakeMail struct {
+	from string
+	to   string
+	data string
+}
+
+// fakeServer 只实现了发送一封邮件需要的命令
+type fakeServer struct {
+	ln       net.Listener
+	authResp string
+	mails    chan fakeMail
+}
+
+func newFakeServer(t *testing.T, authResp string) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{ln: ln, authResp: authResp, mails: make(chan fakeMail, 1)}
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	write := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	write("220 localhost ESMTP")
+	var mail fakeMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			write("250-localhost")
+			write("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH"):
+			write(s.authResp)
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			write("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			mail.to = strings.Trim(line[len("RCPT TO:"):], "<>")
+			write("250 OK")
+		case cmd == "DATA":
+			write("354 End data with <CR><LF>.<CR><LF>")
+			var sb strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				sb.WriteString(l)
+			}
+			mail.data = sb.String()
+			s.mails <- mail
+			write("250 OK")
+		case cmd == "QUIT":
+			write("221 Bye")
+			return
+		default:
+			write("250 OK")
+		}
+	}
+}
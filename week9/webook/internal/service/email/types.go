@@ -0,0 +1,11 @@
+package email
+
+import "context"
+
+// Service 发送邮件的抽象
+//
+//go:generate mockgen -source=./types.go -package=emailmocks -destination=mocks/email.mock.go Service
+type Service interface {
+	// Send body 是纯文本
+	Send(ctx context.Context, to string, subject string, body string) error
+}
@@ -0,0 +1,141 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./account.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./account.go -package=svcmocks -destination=mocks/account.mock.go AccountService
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAccountService is a mock of AccountService interface.
+type MockAccountService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountServiceMockRecorder
+}
+
+// MockAccountServiceMockRecorder is the mock recorder for MockAccountService.
+type MockAccountServiceMockRecorder struct {
+	mock *MockAccountService
+}
+
+// NewMockAccountService creates a new mock instance.
+func NewMockAccountService(ctrl *gomock.Controller) *MockAccountService {
+	mock := &MockAccountService{ctrl: ctrl}
+	mock.recorder = &MockAccountServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountService) EXPECT() *MockAccountServiceMockRecorder {
+	return m.recorder
+}
+
+// BindEmail mocks base method.
+func (m *MockAccountService) BindEmail(ctx context.Context, uid int64, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindEmail", ctx, uid, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindEmail indicates an expected call of BindEmail.
+func (mr *MockAccountServiceMockRecorder) BindEmail(ctx, uid, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindEmail", reflect.TypeOf((*MockAccountService)(nil).BindEmail), ctx, uid, email)
+}
+
+// BindPhone mocks base method.
+func (m *MockAccountService) BindPhone(ctx context.Context, uid int64, phone string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindPhone", ctx, uid, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindPhone indicates an expected call of BindPhone.
+func (mr *MockAccountServiceMockRecorder) BindPhone(ctx, uid, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindPhone", reflect.TypeOf((*MockAccountService)(nil).BindPhone), ctx, uid, phone)
+}
+
+// ChangeEmail mocks base method.
+func (m *MockAccountService) ChangeEmail(ctx context.Context, uid int64, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeEmail", ctx, uid, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangeEmail indicates an expected call of ChangeEmail.
+func (mr *MockAccountServiceMockRecorder) ChangeEmail(ctx, uid, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeEmail", reflect.TypeOf((*MockAccountService)(nil).ChangeEmail), ctx, uid, email)
+}
+
+// ChangePhone mocks base method.
+func (m *MockAccountService) ChangePhone(ctx context.Context, uid int64, phone string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePhone", ctx, uid, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePhone indicates an expected call of ChangePhone.
+func (mr *MockAccountServiceMockRecorder) ChangePhone(ctx, uid, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePhone", reflect.TypeOf((*MockAccountService)(nil).ChangePhone), ctx, uid, phone)
+}
+
+// MergeByEmail mocks base method.
+func (m *MockAccountService) MergeByEmail(ctx context.Context, uid int64, email string) (domain.AccountMergeLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeByEmail", ctx, uid, email)
+	ret0, _ := ret[0].(domain.AccountMergeLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeByEmail indicates an expected call of MergeByEmail.
+func (mr *MockAccountServiceMockRecorder) MergeByEmail(ctx, uid, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeByEmail", reflect.TypeOf((*MockAccountService)(nil).MergeByEmail), ctx, uid, email)
+}
+
+// MergeByPhone mocks base method.
+func (m *MockAccountService) MergeByPhone(ctx context.Context, uid int64, phone string) (domain.AccountMergeLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeByPhone", ctx, uid, phone)
+	ret0, _ := ret[0].(domain.AccountMergeLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeByPhone indicates an expected call of MergeByPhone.
+func (mr *MockAccountServiceMockRecorder) MergeByPhone(ctx, uid, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeByPhone", reflect.TypeOf((*MockAccountService)(nil).MergeByPhone), ctx, uid, phone)
+}
+
+// MergeLogs mocks base method.
+func (m *MockAccountService) MergeLogs(ctx context.Context, uid int64) ([]domain.AccountMergeLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeLogs", ctx, uid)
+	ret0, _ := ret[0].([]domain.AccountMergeLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeLogs indicates an expected call of MergeLogs.
+func (mr *MockAccountServiceMockRecorder) MergeLogs(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeLogs", reflect.TypeOf((*MockAccountService)(nil).MergeLogs), ctx, uid)
+}
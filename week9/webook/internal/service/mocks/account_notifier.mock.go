@@ -0,0 +1,53 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./account_notifier.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./account_notifier.go -package=svcmocks -destination=mocks/account_notifier.mock.go AccountNotifier
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAccountNotifier is a mock of AccountNotifier interface.
+type MockAccountNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountNotifierMockRecorder
+}
+
+// MockAccountNotifierMockRecorder is the mock recorder for MockAccountNotifier.
+type MockAccountNotifierMockRecorder struct {
+	mock *MockAccountNotifier
+}
+
+// NewMockAccountNotifier creates a new mock instance.
+func NewMockAccountNotifier(ctrl *gomock.Controller) *MockAccountNotifier {
+	mock := &MockAccountNotifier{ctrl: ctrl}
+	mock.recorder = &MockAccountNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountNotifier) EXPECT() *MockAccountNotifierMockRecorder {
+	return m.recorder
+}
+
+// ContactChanged mocks base method.
+func (m *MockAccountNotifier) ContactChanged(ctx context.Context, kind, oldContact, newContact string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContactChanged", ctx, kind, oldContact, newContact)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ContactChanged indicates an expected call of ContactChanged.
+func (mr *MockAccountNotifierMockRecorder) ContactChanged(ctx, kind, oldContact, newContact any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContactChanged", reflect.TypeOf((*MockAccountNotifier)(nil).ContactChanged), ctx, kind, oldContact, newContact)
+}
@@ -0,0 +1,115 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./async_sms.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./async_sms.go -package=svcmocks -destination=./mocks/async_sms.mock.go AsyncSmsService
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAsyncSmsService is a mock of AsyncSmsService interface.
+type MockAsyncSmsService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAsyncSmsServiceMockRecorder
+}
+
+// MockAsyncSmsServiceMockRecorder is the mock recorder for MockAsyncSmsService.
+type MockAsyncSmsServiceMockRecorder struct {
+	mock *MockAsyncSmsService
+}
+
+// NewMockAsyncSmsService creates a new mock instance.
+func NewMockAsyncSmsService(ctrl *gomock.Controller) *MockAsyncSmsService {
+	mock := &MockAsyncSmsService{ctrl: ctrl}
+	mock.recorder = &MockAsyncSmsServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAsyncSmsService) EXPECT() *MockAsyncSmsServiceMockRecorder {
+	return m.recorder
+}
+
+// Backlog mocks base method.
+func (m *MockAsyncSmsService) Backlog(ctx context.Context) (map[domain.AsyncSmsStatus]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Backlog", ctx)
+	ret0, _ := ret[0].(map[domain.AsyncSmsStatus]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Backlog indicates an expected call of Backlog.
+func (mr *MockAsyncSmsServiceMockRecorder) Backlog(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Backlog", reflect.TypeOf((*MockAsyncSmsService)(nil).Backlog), ctx)
+}
+
+// CleanupSuccess mocks base method.
+func (m *MockAsyncSmsService) CleanupSuccess(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CleanupSuccess", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CleanupSuccess indicates an expected call of CleanupSuccess.
+func (mr *MockAsyncSmsServiceMockRecorder) CleanupSuccess(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanupSuccess", reflect.TypeOf((*MockAsyncSmsService)(nil).CleanupSuccess), ctx, before)
+}
+
+// Detail mocks base method.
+func (m *MockAsyncSmsService) Detail(ctx context.Context, id int64) (domain.AsyncSms, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Detail", ctx, id)
+	ret0, _ := ret[0].(domain.AsyncSms)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Detail indicates an expected call of Detail.
+func (mr *MockAsyncSmsServiceMockRecorder) Detail(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Detail", reflect.TypeOf((*MockAsyncSmsService)(nil).Detail), ctx, id)
+}
+
+// List mocks base method.
+func (m *MockAsyncSmsService) List(ctx context.Context, status domain.AsyncSmsStatus, offset, limit int) ([]domain.AsyncSms, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, status, offset, limit)
+	ret0, _ := ret[0].([]domain.AsyncSms)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockAsyncSmsServiceMockRecorder) List(ctx, status, offset, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAsyncSmsService)(nil).List), ctx, status, offset, limit)
+}
+
+// Requeue mocks base method.
+func (m *MockAsyncSmsService) Requeue(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Requeue", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Requeue indicates an expected call of Requeue.
+func (mr *MockAsyncSmsServiceMockRecorder) Requeue(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Requeue", reflect.TypeOf((*MockAsyncSmsService)(nil).Requeue), ctx, id)
+}
@@ -39,6 +39,20 @@ func (m *MockCronJobService) EXPECT() *MockCronJobServiceMockRecorder {
 	return m.recorder
 }
 
+// AddJob mocks base method.
+func (m *MockCronJobService) AddJob(ctx context.Context, j domain.CronJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddJob", ctx, j)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddJob indicates an expected call of AddJob.
+func (mr *MockCronJobServiceMockRecorder) AddJob(ctx, j any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddJob", reflect.TypeOf((*MockCronJobService)(nil).AddJob), ctx, j)
+}
+
 // Preempt mocks base method.
 func (m *MockCronJobService) Preempt(ctx context.Context) (domain.CronJob, error) {
 	m.ctrl.T.Helper()
@@ -10,9 +10,9 @@ package svcmocks
 
 import (
 	context "context"
-	"gitee.com/geekbang/basic-go/webook/interactive/domain"
 	reflect "reflect"
 
+	domain "gitee.com/geekbang/basic-go/webook/interactive/domain"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -67,6 +67,21 @@ func (mr *MockInteractiveServiceMockRecorder) Collect(ctx, biz, bizId, cid, uid
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collect", reflect.TypeOf((*MockInteractiveService)(nil).Collect), ctx, biz, bizId, cid, uid)
 }
 
+// Collections mocks base method.
+func (m *MockInteractiveService) Collections(ctx context.Context, uid int64) ([]domain.UserBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Collections", ctx, uid)
+	ret0, _ := ret[0].([]domain.UserBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Collections indicates an expected call of Collections.
+func (mr *MockInteractiveServiceMockRecorder) Collections(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collections", reflect.TypeOf((*MockInteractiveService)(nil).Collections), ctx, uid)
+}
+
 // Get mocks base method.
 func (m *MockInteractiveService) Get(ctx context.Context, biz string, bizId, uid int64) (domain.Interactive, error) {
 	m.ctrl.T.Helper()
@@ -124,3 +139,18 @@ func (mr *MockInteractiveServiceMockRecorder) Like(ctx, biz, bizId, uid any) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Like", reflect.TypeOf((*MockInteractiveService)(nil).Like), ctx, biz, bizId, uid)
 }
+
+// Likes mocks base method.
+func (m *MockInteractiveService) Likes(ctx context.Context, uid int64) ([]domain.UserBiz, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Likes", ctx, uid)
+	ret0, _ := ret[0].([]domain.UserBiz)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Likes indicates an expected call of Likes.
+func (mr *MockInteractiveServiceMockRecorder) Likes(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Likes", reflect.TypeOf((*MockInteractiveService)(nil).Likes), ctx, uid)
+}
@@ -0,0 +1,98 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./oauth2.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./oauth2.go -package=svcmocks -destination=mocks/oauth2.mock.go OAuth2Service
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOAuth2Service is a mock of OAuth2Service interface.
+type MockOAuth2Service struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuth2ServiceMockRecorder
+}
+
+// MockOAuth2ServiceMockRecorder is the mock recorder for MockOAuth2Service.
+type MockOAuth2ServiceMockRecorder struct {
+	mock *MockOAuth2Service
+}
+
+// NewMockOAuth2Service creates a new mock instance.
+func NewMockOAuth2Service(ctrl *gomock.Controller) *MockOAuth2Service {
+	mock := &MockOAuth2Service{ctrl: ctrl}
+	mock.recorder = &MockOAuth2ServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuth2Service) EXPECT() *MockOAuth2ServiceMockRecorder {
+	return m.recorder
+}
+
+// Bind mocks base method.
+func (m *MockOAuth2Service) Bind(ctx context.Context, uid int64, info domain.OAuth2Info) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Bind", ctx, uid, info)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Bind indicates an expected call of Bind.
+func (mr *MockOAuth2ServiceMockRecorder) Bind(ctx, uid, info any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bind", reflect.TypeOf((*MockOAuth2Service)(nil).Bind), ctx, uid, info)
+}
+
+// Bindings mocks base method.
+func (m *MockOAuth2Service) Bindings(ctx context.Context, uid int64) ([]domain.OAuth2Binding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Bindings", ctx, uid)
+	ret0, _ := ret[0].([]domain.OAuth2Binding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Bindings indicates an expected call of Bindings.
+func (mr *MockOAuth2ServiceMockRecorder) Bindings(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bindings", reflect.TypeOf((*MockOAuth2Service)(nil).Bindings), ctx, uid)
+}
+
+// FindOrCreate mocks base method.
+func (m *MockOAuth2Service) FindOrCreate(ctx context.Context, info domain.OAuth2Info) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrCreate", ctx, info)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrCreate indicates an expected call of FindOrCreate.
+func (mr *MockOAuth2ServiceMockRecorder) FindOrCreate(ctx, info any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreate", reflect.TypeOf((*MockOAuth2Service)(nil).FindOrCreate), ctx, info)
+}
+
+// Unbind mocks base method.
+func (m *MockOAuth2Service) Unbind(ctx context.Context, uid int64, provider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unbind", ctx, uid, provider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unbind indicates an expected call of Unbind.
+func (mr *MockOAuth2ServiceMockRecorder) Unbind(ctx, uid, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unbind", reflect.TypeOf((*MockOAuth2Service)(nil).Unbind), ctx, uid, provider)
+}
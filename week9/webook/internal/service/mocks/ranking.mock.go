@@ -40,17 +40,17 @@ func (m *MockRankingService) EXPECT() *MockRankingServiceMockRecorder {
 }
 
 // RankTopN mocks base method.
-func (m *MockRankingService) RankTopN(ctx context.Context) error {
+func (m *MockRankingService) RankTopN(ctx context.Context, token int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RankTopN", ctx)
+	ret := m.ctrl.Call(m, "RankTopN", ctx, token)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RankTopN indicates an expected call of RankTopN.
-func (mr *MockRankingServiceMockRecorder) RankTopN(ctx any) *gomock.Call {
+func (mr *MockRankingServiceMockRecorder) RankTopN(ctx, token any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RankTopN", reflect.TypeOf((*MockRankingService)(nil).RankTopN), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RankTopN", reflect.TypeOf((*MockRankingService)(nil).RankTopN), ctx, token)
 }
 
 // TopN mocks base method.
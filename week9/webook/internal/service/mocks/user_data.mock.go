@@ -0,0 +1,129 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./user_data.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./user_data.go -package=svcmocks -destination=mocks/user_data.mock.go UserDataService
+//
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "gitee.com/geekbang/basic-go/webook/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserDataService is a mock of UserDataService interface.
+type MockUserDataService struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserDataServiceMockRecorder
+}
+
+// MockUserDataServiceMockRecorder is the mock recorder for MockUserDataService.
+type MockUserDataServiceMockRecorder struct {
+	mock *MockUserDataService
+}
+
+// NewMockUserDataService creates a new mock instance.
+func NewMockUserDataService(ctrl *gomock.Controller) *MockUserDataService {
+	mock := &MockUserDataService{ctrl: ctrl}
+	mock.recorder = &MockUserDataServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserDataService) EXPECT() *MockUserDataServiceMockRecorder {
+	return m.recorder
+}
+
+// CancelDeletion mocks base method.
+func (m *MockUserDataService) CancelDeletion(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelDeletion", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelDeletion indicates an expected call of CancelDeletion.
+func (mr *MockUserDataServiceMockRecorder) CancelDeletion(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelDeletion", reflect.TypeOf((*MockUserDataService)(nil).CancelDeletion), ctx, uid)
+}
+
+// LatestExport mocks base method.
+func (m *MockUserDataService) LatestExport(ctx context.Context, uid int64) (domain.DataExport, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatestExport", ctx, uid)
+	ret0, _ := ret[0].(domain.DataExport)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// LatestExport indicates an expected call of LatestExport.
+func (mr *MockUserDataServiceMockRecorder) LatestExport(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatestExport", reflect.TypeOf((*MockUserDataService)(nil).LatestExport), ctx, uid)
+}
+
+// RequestDeletion mocks base method.
+func (m *MockUserDataService) RequestDeletion(ctx context.Context, uid int64) (domain.AccountDeletion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestDeletion", ctx, uid)
+	ret0, _ := ret[0].(domain.AccountDeletion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestDeletion indicates an expected call of RequestDeletion.
+func (mr *MockUserDataServiceMockRecorder) RequestDeletion(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestDeletion", reflect.TypeOf((*MockUserDataService)(nil).RequestDeletion), ctx, uid)
+}
+
+// RequestExport mocks base method.
+func (m *MockUserDataService) RequestExport(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestExport", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestExport indicates an expected call of RequestExport.
+func (mr *MockUserDataServiceMockRecorder) RequestExport(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestExport", reflect.TypeOf((*MockUserDataService)(nil).RequestExport), ctx, uid)
+}
+
+// RunDeletions mocks base method.
+func (m *MockUserDataService) RunDeletions(ctx context.Context, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunDeletions", ctx, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunDeletions indicates an expected call of RunDeletions.
+func (mr *MockUserDataServiceMockRecorder) RunDeletions(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunDeletions", reflect.TypeOf((*MockUserDataService)(nil).RunDeletions), ctx, limit)
+}
+
+// RunExports mocks base method.
+func (m *MockUserDataService) RunExports(ctx context.Context, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunExports", ctx, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunExports indicates an expected call of RunExports.
+func (mr *MockUserDataServiceMockRecorder) RunExports(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunExports", reflect.TypeOf((*MockUserDataService)(nil).RunExports), ctx, limit)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+)
+
+var (
+	ErrOAuth2BoundByOther    = errs.UserOAuth2BoundByOther
+	ErrOAuth2AlreadyBound    = errs.UserOAuth2AlreadyBound
+	ErrOAuth2NotBound        = errs.UserOAuth2NotBound
+	ErrOAuth2LastLoginMethod = errs.UserOAuth2LastLoginMethod
+)
+
+// OAuth2Service 第三方账号登录和绑定，平台相关的部分在 oauth2.Provider 里面
+//
+//go:generate mockgen -source=./oauth2.go -package=svcmocks -destination=mocks/oauth2.mock.go OAuth2Service
+type OAuth2Service interface {
+	// FindOrCreate 用第三方账号登录，没有绑定过的就注册一个新用户
+	FindOrCreate(ctx context.Context, info domain.OAuth2Info) (domain.User, error)
+	// Bind 已经登录的用户绑定第三方账号
+	Bind(ctx context.Context, uid int64, info domain.OAuth2Info) error
+	// Unbind 解绑。如果这是用户唯一的登录方式，就不允许解绑
+	Unbind(ctx context.Context, uid int64, provider string) error
+	Bindings(ctx context.Context, uid int64) ([]domain.OAuth2Binding, error)
+}
+
+type oauth2Service struct {
+	repo     repository.OAuth2BindingRepository
+	userRepo repository.UserRepository
+}
+
+func NewOAuth2Service(repo repository.OAuth2BindingRepository,
+	userRepo repository.UserRepository) OAuth2Service {
+	return &oauth2Service{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (svc *oauth2Service) FindOrCreate(ctx context.Context,
+	info domain.OAuth2Info) (domain.User, error) {
+	// 类似于手机号的过程，大部分人只是扫码登录，也就是数据在我们这里是有的
+	b, err := svc.repo.FindByExternalId(ctx, info.Provider, info.ExternalId)
+	switch err {
+	case nil:
+		return svc.userRepo.FindById(ctx, b.Uid)
+	case repository.ErrOAuth2BindingNotFound:
+		uid, err := svc.repo.CreateWithUser(ctx, info)
+		if err == repository.ErrOAuth2BindingDuplicate {
+			// 同一个人并发回调，别人已经注册好了
+			// 主从模式下，这里要从主库中读取，暂时我们不需要考虑
+			b, err = svc.repo.FindByExternalId(ctx, info.Provider, info.ExternalId)
+			uid = b.Uid
+		}
+		if err != nil {
+			return domain.User{}, err
+		}
+		return svc.userRepo.FindById(ctx, uid)
+	default:
+		return domain.User{}, err
+	}
+}
+
+func (svc *oauth2Service) Bind(ctx context.Context, uid int64, info domain.OAuth2Info) error {
+	b, err := svc.repo.FindByExternalId(ctx, info.Provider, info.ExternalId)
+	switch err {
+	case nil:
+		if b.Uid == uid {
+			// 重复绑定，当成成功
+			return nil
+		}
+		return ErrOAuth2BoundByOther
+	case repository.ErrOAuth2BindingNotFound:
+	default:
+		return err
+	}
+	err = svc.repo.Create(ctx, domain.OAuth2Binding{
+		Uid:  uid,
+		Info: info,
+	})
+	if err == repository.ErrOAuth2BindingDuplicate {
+		// 前面已经确认了第三方账号没有被绑定，
+		// 所以冲突基本上就是这个用户已经绑定了这个平台的另外一个账号
+		return ErrOAuth2AlreadyBound
+	}
+	return err
+}
+
+func (svc *oauth2Service) Unbind(ctx context.Context, uid int64, provider string) error {
+	bs, err := svc.repo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, b := range bs {
+		if b.Info.Provider == provider {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrOAuth2NotBound
+	}
+	if len(bs) == 1 {
+		// 只剩下这一个第三方账号了，还要看看能不能用手机号码或者邮箱登录
+		u, err := svc.userRepo.FindById(ctx, uid)
+		if err != nil {
+			return err
+		}
+		if u.Phone == "" && u.Email == "" {
+			return ErrOAuth2LastLoginMethod
+		}
+	}
+	err = svc.repo.Delete(ctx, uid, provider)
+	if err == repository.ErrOAuth2BindingNotFound {
+		// 并发解绑
+		return ErrOAuth2NotBound
+	}
+	return err
+}
+
+func (svc *oauth2Service) Bindings(ctx context.Context, uid int64) ([]domain.OAuth2Binding, error) {
+	return svc.repo.FindByUid(ctx, uid)
+}
@@ -0,0 +1,125 @@
+package dingtalk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"net/http"
+	"net/url"
+)
+
+const ProviderName = "dingtalk"
+
+var defaultEndpoint = oauth2.Endpoint{
+	AuthURL:     "https://login.dingtalk.com/oauth2/auth",
+	TokenURL:    "https://api.dingtalk.com/v1.0/oauth2/userAccessToken",
+	UserInfoURL: "https://api.dingtalk.com/v1.0/contact/users/me",
+}
+
+type service struct {
+	clientId     string
+	clientSecret string
+	redirectURL  string
+	endpoint     oauth2.Endpoint
+	client       *http.Client
+}
+
+func NewService(cfg oauth2.Config, client *http.Client) oauth2.Provider {
+	return &service{
+		clientId:     cfg.ClientId,
+		clientSecret: cfg.ClientSecret,
+		redirectURL:  cfg.RedirectURL,
+		endpoint:     cfg.Endpoint.WithDefaults(defaultEndpoint),
+		client:       client,
+	}
+}
+
+func (s *service) Name() string {
+	return ProviderName
+}
+
+func (s *service) AuthURL(ctx context.Context, state string) (string, error) {
+	params := url.Values{}
+	params.Set("redirect_uri", s.redirectURL)
+	params.Set("response_type", "code")
+	params.Set("client_id", s.clientId)
+	params.Set("scope", "openid")
+	params.Set("state", state)
+	params.Set("prompt", "consent")
+	return s.endpoint.AuthURL + "?" + params.Encode(), nil
+}
+
+func (s *service) VerifyCode(ctx context.Context, code string) (domain.OAuth2Info, error) {
+	token, err := s.accessToken(ctx, code)
+	if err != nil {
+		return domain.OAuth2Info{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint.UserInfoURL, nil)
+	if err != nil {
+		return domain.OAuth2Info{}, err
+	}
+	req.Header.Set("x-acs-dingtalk-access-token", token)
+	var u userResult
+	err = oauth2.DoJSON(s.client, req, &u)
+	if err != nil {
+		return domain.OAuth2Info{}, err
+	}
+	if u.OpenId == "" {
+		return domain.OAuth2Info{}, errors.New("获取钉钉用户信息失败")
+	}
+	return domain.OAuth2Info{
+		Provider:   ProviderName,
+		ExternalId: u.OpenId,
+		UnionId:    u.UnionId,
+		Nickname:   u.Nick,
+	}, nil
+}
+
+// accessToken 钉钉出错的时候 HTTP 状态码是 4xx，DoJSON 会处理
+func (s *service) accessToken(ctx context.Context, code string) (string, error) {
+	body, err := json.Marshal(tokenReq{
+		ClientId:     s.clientId,
+		ClientSecret: s.clientSecret,
+		Code:         code,
+		GrantType:    "authorization_code",
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.endpoint.TokenURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var res tokenResult
+	err = oauth2.DoJSON(s.client, req, &res)
+	if err != nil {
+		return "", err
+	}
+	if res.AccessToken == "" {
+		return "", errors.New("换取 access_token 失败")
+	}
+	return res.AccessToken, nil
+}
+
+type tokenReq struct {
+	ClientId     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	Code         string `json:"code"`
+	GrantType    string `json:"grantType"`
+}
+
+type tokenResult struct {
+	AccessToken string `json:"accessToken"`
+	ExpireIn    int64  `json:"expireIn"`
+}
+
+type userResult struct {
+	Nick    string `json:"nick"`
+	UnionId string `json:"unionId"`
+	OpenId  string `json:"openId"`
+}
@@ -0,0 +1,79 @@
+package dingtalk
+
+import (
+	"context"
+	"encoding/json"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestService_VerifyCode(t *testing.T) {
+	testCases := []struct {
+		name string
+		code string
+
+		wantInfo domain.OAuth2Info
+		wantErr  bool
+	}{
+		{
+			name: "换取成功",
+			code: "good-code",
+			wantInfo: domain.OAuth2Info{
+				Provider:   "dingtalk",
+				ExternalId: "open-123",
+				UnionId:    "union-123",
+				Nickname:   "小明",
+			},
+		},
+		{
+			name:    "code 不对",
+			code:    "bad-code",
+			wantErr: true,
+		},
+	}
+	// 假的钉钉授权服务器
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/oauth2/userAccessToken", func(w http.ResponseWriter, r *http.Request) {
+		var req tokenReq
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "client-id", req.ClientId)
+		assert.Equal(t, "client-secret", req.ClientSecret)
+		assert.Equal(t, "authorization_code", req.GrantType)
+		if req.Code != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"invalidParameter","message":"code 无效"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"accessToken":"ding-token","expireIn":7200}`))
+	})
+	mux.HandleFunc("/v1.0/contact/users/me", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ding-token", r.Header.Get("x-acs-dingtalk-access-token"))
+		_, _ = w.Write([]byte(`{"nick":"小明","unionId":"union-123","openId":"open-123"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	svc := NewService(oauth2.Config{
+		ClientId:     "client-id",
+		ClientSecret: "client-secret",
+		Endpoint: oauth2.Endpoint{
+			TokenURL:    server.URL + "/v1.0/oauth2/userAccessToken",
+			UserInfoURL: server.URL + "/v1.0/contact/users/me",
+		},
+	}, server.Client())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := svc.VerifyCode(context.Background(), tc.code)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantInfo, info)
+		})
+	}
+}
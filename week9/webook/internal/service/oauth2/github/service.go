@@ -0,0 +1,124 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const ProviderName = "github"
+
+var defaultEndpoint = oauth2.Endpoint{
+	AuthURL:     "https://github.com/login/oauth/authorize",
+	TokenURL:    "https://github.com/login/oauth/access_token",
+	UserInfoURL: "https://api.github.com/user",
+}
+
+type service struct {
+	clientId     string
+	clientSecret string
+	redirectURL  string
+	endpoint     oauth2.Endpoint
+	client       *http.Client
+}
+
+func NewService(cfg oauth2.Config, client *http.Client) oauth2.Provider {
+	return &service{
+		clientId:     cfg.ClientId,
+		clientSecret: cfg.ClientSecret,
+		redirectURL:  cfg.RedirectURL,
+		endpoint:     cfg.Endpoint.WithDefaults(defaultEndpoint),
+		client:       client,
+	}
+}
+
+func (s *service) Name() string {
+	return ProviderName
+}
+
+func (s *service) AuthURL(ctx context.Context, state string) (string, error) {
+	params := url.Values{}
+	params.Set("client_id", s.clientId)
+	params.Set("redirect_uri", s.redirectURL)
+	// 只需要读取公开的用户信息
+	params.Set("scope", "read:user")
+	params.Set("state", state)
+	return s.endpoint.AuthURL + "?" + params.Encode(), nil
+}
+
+func (s *service) VerifyCode(ctx context.Context, code string) (domain.OAuth2Info, error) {
+	token, err := s.accessToken(ctx, code)
+	if err != nil {
+		return domain.OAuth2Info{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint.UserInfoURL, nil)
+	if err != nil {
+		return domain.OAuth2Info{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	var u userResult
+	err = oauth2.DoJSON(s.client, req, &u)
+	if err != nil {
+		return domain.OAuth2Info{}, err
+	}
+	if u.Id == 0 {
+		return domain.OAuth2Info{}, errors.New("获取 GitHub 用户信息失败")
+	}
+	nickname := u.Name
+	if nickname == "" {
+		nickname = u.Login
+	}
+	return domain.OAuth2Info{
+		Provider: ProviderName,
+		// login 是可以改的，所以要用 id
+		ExternalId: strconv.FormatInt(u.Id, 10),
+		Nickname:   nickname,
+	}, nil
+}
+
+func (s *service) accessToken(ctx context.Context, code string) (string, error) {
+	params := url.Values{}
+	params.Set("client_id", s.clientId)
+	params.Set("client_secret", s.clientSecret)
+	params.Set("code", code)
+	params.Set("redirect_uri", s.redirectURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.endpoint.TokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// 不设置的话，GitHub 返回的是 form 格式
+	req.Header.Set("Accept", "application/json")
+	var res tokenResult
+	err = oauth2.DoJSON(s.client, req, &res)
+	if err != nil {
+		return "", err
+	}
+	// code 不对的时候，HTTP 状态码也是 200
+	if res.Error != "" {
+		return "", fmt.Errorf("换取 access_token 失败 %s, %s", res.Error, res.ErrorDescription)
+	}
+	return res.AccessToken, nil
+}
+
+type tokenResult struct {
+	AccessToken      string `json:"access_token"`
+	Scope            string `json:"scope"`
+	TokenType        string `json:"token_type"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type userResult struct {
+	Id    int64  `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
@@ -0,0 +1,117 @@
+package github
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestService_VerifyCode(t *testing.T) {
+	testCases := []struct {
+		name string
+		code string
+		// GitHub 用户接口的响应
+		userResp   string
+		userStatus int
+
+		wantInfo domain.OAuth2Info
+		wantErr  bool
+	}{
+		{
+			name:       "换取成功",
+			code:       "good-code",
+			userResp:   `{"id":123,"login":"octocat","name":"The Octocat"}`,
+			userStatus: http.StatusOK,
+			wantInfo: domain.OAuth2Info{
+				Provider:   "github",
+				ExternalId: "123",
+				Nickname:   "The Octocat",
+			},
+		},
+		{
+			name:       "没有名字就用 login",
+			code:       "good-code",
+			userResp:   `{"id":123,"login":"octocat"}`,
+			userStatus: http.StatusOK,
+			wantInfo: domain.OAuth2Info{
+				Provider:   "github",
+				ExternalId: "123",
+				Nickname:   "octocat",
+			},
+		},
+		{
+			name:    "code 不对",
+			code:    "bad-code",
+			wantErr: true,
+		},
+		{
+			name:       "获取用户信息失败",
+			code:       "good-code",
+			userResp:   `{"message":"Bad credentials"}`,
+			userStatus: http.StatusUnauthorized,
+			wantErr:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// 假的 GitHub 授权服务器
+			mux := http.NewServeMux()
+			mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Accept"))
+				require.NoError(t, r.ParseForm())
+				assert.Equal(t, "client-id", r.PostForm.Get("client_id"))
+				assert.Equal(t, "client-secret", r.PostForm.Get("client_secret"))
+				if r.PostForm.Get("code") != "good-code" {
+					_, _ = w.Write([]byte(`{"error":"bad_verification_code","error_description":"The code passed is incorrect or expired."}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"access_token":"gho_token","token_type":"bearer"}`))
+			})
+			mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer gho_token", r.Header.Get("Authorization"))
+				w.WriteHeader(tc.userStatus)
+				_, _ = w.Write([]byte(tc.userResp))
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+			svc := NewService(oauth2.Config{
+				ClientId:     "client-id",
+				ClientSecret: "client-secret",
+				Endpoint: oauth2.Endpoint{
+					TokenURL:    server.URL + "/login/oauth/access_token",
+					UserInfoURL: server.URL + "/user",
+				},
+			}, server.Client())
+			info, err := svc.VerifyCode(context.Background(), tc.code)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantInfo, info)
+		})
+	}
+}
+
+func TestService_AuthURL(t *testing.T) {
+	svc := NewService(oauth2.Config{
+		ClientId:    "client-id",
+		RedirectURL: "https://webook.com/oauth2/github/callback",
+	}, http.DefaultClient)
+	u, err := svc.AuthURL(context.Background(), "my-state")
+	require.NoError(t, err)
+	parsed, err := url.Parse(u)
+	require.NoError(t, err)
+	assert.Equal(t, "github.com", parsed.Host)
+	q := parsed.Query()
+	assert.Equal(t, "client-id", q.Get("client_id"))
+	assert.Equal(t, "https://webook.com/oauth2/github/callback", q.Get("redirect_uri"))
+	assert.Equal(t, "my-state", q.Get("state"))
+}
@@ -0,0 +1,24 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DoJSON 发送请求，并且把响应解析为 JSON。HTTP 状态码不是 2xx 的都认为是错误
+func DoJSON(client *http.Client, req *http.Request, val any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// 错误信息一般很短，读一部分用来排查问题就可以
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("请求 %s 失败，状态码 %d，响应 %s",
+			req.URL.Path, resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(val)
+}
@@ -1,4 +1,4 @@
-package wechat
+package oauth2
 
 import (
 	"context"
@@ -9,11 +9,11 @@ import (
 
 // PrometheusDecorator 利用组合来避免需要实现所有的接口
 type PrometheusDecorator struct {
-	Service
+	Provider
 	sum prometheus.Summary
 }
 
-func NewPrometheusDecorator(svc Service,
+func NewPrometheusDecorator(p Provider,
 	namespace string,
 	subsystem string,
 	instanceId string,
@@ -24,6 +24,7 @@ func NewPrometheusDecorator(svc Service,
 		Subsystem: subsystem,
 		ConstLabels: map[string]string{
 			"instance_id": instanceId,
+			"provider":    p.Name(),
 		},
 		Objectives: map[float64]float64{
 			0.5:   0.01,
@@ -35,17 +36,17 @@ func NewPrometheusDecorator(svc Service,
 	})
 	prometheus.MustRegister(sum)
 	return &PrometheusDecorator{
-		Service: svc,
-		sum:     sum,
+		Provider: p,
+		sum:      sum,
 	}
 }
 
 // VerifyCode 因为 AuthURL 过于简单，没有监控的必要
-func (p *PrometheusDecorator) VerifyCode(ctx context.Context, code string) (domain.WechatInfo, error) {
+func (p *PrometheusDecorator) VerifyCode(ctx context.Context, code string) (domain.OAuth2Info, error) {
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
 		p.sum.Observe(float64(duration.Milliseconds()))
 	}()
-	return p.Service.VerifyCode(ctx, code)
+	return p.Provider.VerifyCode(ctx, code)
 }
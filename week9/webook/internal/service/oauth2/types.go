@@ -0,0 +1,77 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+)
+
+var ErrUnknownProvider = errors.New("未知的 OAuth2 平台")
+
+// Provider 一个第三方登录平台。目前大部分公司的 OAuth2 平台都差不多的设计：
+// 先跳转到平台的授权页面，用户同意之后回调我们，带上 code，我们再拿 code 换用户信息
+//
+//go:generate mockgen -source=./types.go -package=oauth2mocks -destination=mocks/provider.mock.go Provider
+type Provider interface {
+	// Name 平台的名字，也是路由 /oauth2/:provider 里面的 provider
+	Name() string
+	AuthURL(ctx context.Context, state string) (string, error)
+	// VerifyCode 用回调拿到的 code 换取用户在平台上的信息
+	VerifyCode(ctx context.Context, code string) (domain.OAuth2Info, error)
+}
+
+// Config 各个平台通用的配置
+type Config struct {
+	// Name 对应 Provider.Name，同时决定了用哪个平台的实现
+	Name         string `yaml:"name"`
+	ClientId     string `yaml:"clientId"`
+	ClientSecret string `yaml:"clientSecret"`
+	// RedirectURL 回调地址，也就是 /oauth2/:provider/callback 对外的完整地址
+	RedirectURL string `yaml:"redirectURL"`
+	// Endpoint 没有配置的地址使用平台默认的，测试的时候可以换成 httptest 的地址
+	Endpoint Endpoint `yaml:"endpoint"`
+}
+
+type Endpoint struct {
+	// AuthURL 授权页面
+	AuthURL string `yaml:"authURL"`
+	// TokenURL 用 code 换取 access token
+	TokenURL string `yaml:"tokenURL"`
+	// UserInfoURL 用 access token 获取用户信息，有些平台换 token 的时候就返回了，那就不需要
+	UserInfoURL string `yaml:"userInfoURL"`
+}
+
+// WithDefaults 用 def 填充没有配置的地址
+func (e Endpoint) WithDefaults(def Endpoint) Endpoint {
+	if e.AuthURL == "" {
+		e.AuthURL = def.AuthURL
+	}
+	if e.TokenURL == "" {
+		e.TokenURL = def.TokenURL
+	}
+	if e.UserInfoURL == "" {
+		e.UserInfoURL = def.UserInfoURL
+	}
+	return e
+}
+
+// Providers 按照名字查找 Provider
+type Providers struct {
+	providers map[string]Provider
+}
+
+func NewProviders(providers ...Provider) *Providers {
+	m := make(map[string]Provider, len(providers))
+	for _, p := range providers {
+		m[p.Name()] = p
+	}
+	return &Providers{providers: m}
+}
+
+func (p *Providers) Get(name string) (Provider, error) {
+	res, ok := p.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return res, nil
+}
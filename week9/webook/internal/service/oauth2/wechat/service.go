@@ -1,78 +1,88 @@
 package wechat
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
-	"golang.org/x/net/context"
 	"net/http"
 	"net/url"
 )
 
-const authURLPattern = "https://open.weixin.qq.com/connect/qrconnect?appid=%s&redirect_uri=%s&response_type=code&scope=snsapi_login&state=%s#wechat_redire"
+const ProviderName = "wechat"
 
-var redirectURL = url.PathEscape("https://meoying.com/oauth2/wechat/callback")
+var defaultEndpoint = oauth2.Endpoint{
+	AuthURL:  "https://open.weixin.qq.com/connect/qrconnect",
+	TokenURL: "https://api.weixin.qq.com/sns/oauth2/access_token",
+}
 
 type service struct {
-	appId     string
-	appSecret string
-	client    *http.Client
-	logger    logger.LoggerV1
+	appId       string
+	appSecret   string
+	redirectURL string
+	endpoint    oauth2.Endpoint
+	client      *http.Client
+	logger      logger.LoggerV1
 }
 
-func NewService(appId, appSecret string,
-	logger logger.LoggerV1) Service {
+// NewService 微信换 access token 的时候就会返回 openid 和 unionid，所以不需要 UserInfoURL
+func NewService(cfg oauth2.Config,
+	client *http.Client,
+	logger logger.LoggerV1) oauth2.Provider {
 	return &service{
-		appId:     appId,
-		appSecret: appSecret,
-		client:    http.DefaultClient,
-		logger:    logger,
+		appId:       cfg.ClientId,
+		appSecret:   cfg.ClientSecret,
+		redirectURL: cfg.RedirectURL,
+		endpoint:    cfg.Endpoint.WithDefaults(defaultEndpoint),
+		client:      client,
+		logger:      logger,
 	}
 }
 
-func (s *service) VerifyCode(ctx context.Context, code string) (domain.WechatInfo, error) {
-	const baseURL = "https://api.weixin.qq.com/sns/oauth2/access_token"
+func (s *service) Name() string {
+	return ProviderName
+}
+
+func (s *service) VerifyCode(ctx context.Context, code string) (domain.OAuth2Info, error) {
 	// 这是另外一种写法
 	queryParams := url.Values{}
 	queryParams.Set("appid", s.appId)
 	queryParams.Set("secret", s.appSecret)
 	queryParams.Set("code", code)
 	queryParams.Set("grant_type", "authorization_code")
-	accessTokenURL := baseURL + "?" + queryParams.Encode()
-	req, err := http.NewRequest("GET", accessTokenURL, nil)
+	accessTokenURL := s.endpoint.TokenURL + "?" + queryParams.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, accessTokenURL, nil)
 	if err != nil {
-		return domain.WechatInfo{}, err
+		return domain.OAuth2Info{}, err
 	}
-	req = req.WithContext(ctx)
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return domain.WechatInfo{}, err
-	}
-	defer resp.Body.Close()
 	var res Result
-
-	err = json.NewDecoder(resp.Body).Decode(&res)
+	err = oauth2.DoJSON(s.client, req, &res)
 	if err != nil {
-		return domain.WechatInfo{}, err
+		return domain.OAuth2Info{}, err
 	}
+	// 微信出错的时候，HTTP 状态码也是 200
 	if res.ErrCode != 0 {
-		return domain.WechatInfo{}, errors.New("换取 access_token 失败")
+		return domain.OAuth2Info{},
+			fmt.Errorf("换取 access_token 失败 %d, %s", res.ErrCode, res.ErrMsg)
 	}
-	return domain.WechatInfo{
-		OpenId:  res.OpenId,
-		UnionId: res.UnionId,
+	return domain.OAuth2Info{
+		Provider:   ProviderName,
+		ExternalId: res.OpenId,
+		UnionId:    res.UnionId,
 	}, nil
 }
 
+// AuthURL 微信要求参数的顺序不能变，所以这里没有用 url.Values
 func (s *service) AuthURL(ctx context.Context, state string) (string, error) {
-	return fmt.Sprintf(authURLPattern, s.appId, redirectURL, state), nil
+	const pattern = "%s?appid=%s&redirect_uri=%s&response_type=code&scope=snsapi_login&state=%s#wechat_redirect"
+	return fmt.Sprintf(pattern, s.endpoint.AuthURL, s.appId,
+		url.QueryEscape(s.redirectURL), url.QueryEscape(state)), nil
 }
 
 type Result struct {
 	ErrCode int64  `json:"errcode"`
-	ErrMsg  string `json:"errMsg"`
+	ErrMsg  string `json:"errmsg"`
 
 	Scope string `json:"scope"`
 
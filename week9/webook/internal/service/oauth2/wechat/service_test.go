@@ -0,0 +1,81 @@
+package wechat
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestService_VerifyCode(t *testing.T) {
+	testCases := []struct {
+		name string
+		code string
+
+		wantInfo domain.OAuth2Info
+		wantErr  bool
+	}{
+		{
+			name: "换取成功",
+			code: "good-code",
+			wantInfo: domain.OAuth2Info{
+				Provider:   "wechat",
+				ExternalId: "open-123",
+				UnionId:    "union-123",
+			},
+		},
+		{
+			name:    "code 不对",
+			code:    "bad-code",
+			wantErr: true,
+		},
+	}
+	// 假的微信授权服务器
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "app-id", q.Get("appid"))
+		assert.Equal(t, "app-secret", q.Get("secret"))
+		assert.Equal(t, "authorization_code", q.Get("grant_type"))
+		if q.Get("code") != "good-code" {
+			_, _ = w.Write([]byte(`{"errcode":40029,"errmsg":"invalid code"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"token","openid":"open-123","unionid":"union-123"}`))
+	}))
+	defer server.Close()
+	svc := NewService(oauth2.Config{
+		ClientId:     "app-id",
+		ClientSecret: "app-secret",
+		Endpoint: oauth2.Endpoint{
+			TokenURL: server.URL + "/sns/oauth2/access_token",
+		},
+	}, server.Client(), logger.NewNoOpLogger())
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := svc.VerifyCode(context.Background(), tc.code)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantInfo, info)
+		})
+	}
+}
+
+func TestService_AuthURL(t *testing.T) {
+	svc := NewService(oauth2.Config{
+		ClientId:    "app-id",
+		RedirectURL: "https://webook.com/oauth2/wechat/callback",
+	}, http.DefaultClient, logger.NewNoOpLogger())
+	u, err := svc.AuthURL(context.Background(), "my-state")
+	require.NoError(t, err)
+	assert.Equal(t, "https://open.weixin.qq.com/connect/qrconnect?appid=app-id"+
+		"&redirect_uri=https%3A%2F%2Fwebook.com%2Foauth2%2Fwechat%2Fcallback"+
+		"&response_type=code&scope=snsapi_login&state=my-state#wechat_redirect", u)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestOAuth2Service_FindOrCreate(t *testing.T) {
+	info := domain.OAuth2Info{Provider: "github", ExternalId: "123", Nickname: "octocat"}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository)
+
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "已经绑定过",
+			mock: func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository) {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByExternalId(gomock.Any(), "github", "123").
+					Return(domain.OAuth2Binding{Uid: 1, Info: info}, nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				return repo, userRepo
+			},
+			wantUser: domain.User{Id: 1},
+		},
+		{
+			name: "没有绑定过，注册新用户",
+			mock: func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository) {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByExternalId(gomock.Any(), "github", "123").
+					Return(domain.OAuth2Binding{}, repository.ErrOAuth2BindingNotFound)
+				repo.EXPECT().CreateWithUser(gomock.Any(), info).Return(int64(2), nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.User{Id: 2}, nil)
+				return repo, userRepo
+			},
+			wantUser: domain.User{Id: 2},
+		},
+		{
+			name: "并发注册，别人已经注册好了",
+			mock: func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository) {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByExternalId(gomock.Any(), "github", "123").
+					Return(domain.OAuth2Binding{}, repository.ErrOAuth2BindingNotFound)
+				repo.EXPECT().CreateWithUser(gomock.Any(), info).
+					Return(int64(0), repository.ErrOAuth2BindingDuplicate)
+				repo.EXPECT().FindByExternalId(gomock.Any(), "github", "123").
+					Return(domain.OAuth2Binding{Uid: 3, Info: info}, nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.User{Id: 3}, nil)
+				return repo, userRepo
+			},
+			wantUser: domain.User{Id: 3},
+		},
+		{
+			name: "查询绑定出错",
+			mock: func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository) {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByExternalId(gomock.Any(), "github", "123").
+					Return(domain.OAuth2Binding{}, errors.New("mock db error"))
+				return repo, repomocks.NewMockUserRepository(ctrl)
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewOAuth2Service(tc.mock(ctrl))
+			u, err := svc.FindOrCreate(context.Background(), info)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
+
+func TestOAuth2Service_Bind(t *testing.T) {
+	info := domain.OAuth2Info{Provider: "github", ExternalId: "123"}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.OAuth2BindingRepository
+
+		wantErr error
+	}{
+		{
+			name: "绑定成功",
+			mock: func(ctrl *gomock.Controller) repository.OAuth2BindingRepository {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByExternalId(gomock.Any(), "github", "123").
+					Return(domain.OAuth2Binding{}, repository.ErrOAuth2BindingNotFound)
+				repo.EXPECT().Create(gomock.Any(), domain.OAuth2Binding{Uid: 1, Info: info}).Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "重复绑定",
+			mock: func(ctrl *gomock.Controller) repository.OAuth2BindingRepository {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByExternalId(gomock.Any(), "github", "123").
+					Return(domain.OAuth2Binding{Uid: 1, Info: info}, nil)
+				return repo
+			},
+		},
+		{
+			name: "第三方账号绑定了别的用户",
+			mock: func(ctrl *gomock.Controller) repository.OAuth2BindingRepository {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByExternalId(gomock.Any(), "github", "123").
+					Return(domain.OAuth2Binding{Uid: 2, Info: info}, nil)
+				return repo
+			},
+			wantErr: ErrOAuth2BoundByOther,
+		},
+		{
+			name: "已经绑定了这个平台的别的账号",
+			mock: func(ctrl *gomock.Controller) repository.OAuth2BindingRepository {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByExternalId(gomock.Any(), "github", "123").
+					Return(domain.OAuth2Binding{}, repository.ErrOAuth2BindingNotFound)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					Return(repository.ErrOAuth2BindingDuplicate)
+				return repo
+			},
+			wantErr: ErrOAuth2AlreadyBound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewOAuth2Service(tc.mock(ctrl), repomocks.NewMockUserRepository(ctrl))
+			err := svc.Bind(context.Background(), 1, info)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestOAuth2Service_Unbind(t *testing.T) {
+	github := domain.OAuth2Binding{Uid: 1, Info: domain.OAuth2Info{Provider: "github", ExternalId: "123"}}
+	wechat := domain.OAuth2Binding{Uid: 1, Info: domain.OAuth2Info{Provider: "wechat", ExternalId: "abc"}}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository)
+
+		wantErr error
+	}{
+		{
+			name: "还有别的第三方账号",
+			mock: func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository) {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return([]domain.OAuth2Binding{github, wechat}, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(1), "github").Return(nil)
+				return repo, repomocks.NewMockUserRepository(ctrl)
+			},
+		},
+		{
+			name: "最后一个第三方账号，但是有手机号码",
+			mock: func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository) {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return([]domain.OAuth2Binding{github}, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(1), "github").Return(nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "15212345678"}, nil)
+				return repo, userRepo
+			},
+		},
+		{
+			name: "唯一的登录方式",
+			mock: func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository) {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return([]domain.OAuth2Binding{github}, nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1}, nil)
+				return repo, userRepo
+			},
+			wantErr: ErrOAuth2LastLoginMethod,
+		},
+		{
+			name: "没有绑定",
+			mock: func(ctrl *gomock.Controller) (repository.OAuth2BindingRepository, repository.UserRepository) {
+				repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return([]domain.OAuth2Binding{wechat}, nil)
+				return repo, repomocks.NewMockUserRepository(ctrl)
+			},
+			wantErr: ErrOAuth2NotBound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewOAuth2Service(tc.mock(ctrl))
+			err := svc.Unbind(context.Background(), 1, "github")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
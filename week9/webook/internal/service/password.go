@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordResetByPhone = "phone"
+	PasswordResetByEmail = "email"
+)
+
+var (
+	// ErrWrongPassword 和登录的用户名或者密码不对共用一个错误码，在 web 里面转成错误码
+	ErrWrongPassword = errors.New("原密码不对")
+	// ErrPasswordNotSet 短信或者第三方登录注册的用户没有密码，只能走重置密码
+	ErrPasswordNotSet = errs.UserPasswordNotSet
+	// ErrPasswordAccountNotFound 没有注册的不会发验证码，所以对外表现为验证码错误
+	ErrPasswordAccountNotFound  = errors.New("账号不存在")
+	ErrUnknownPasswordResetType = errors.New("未知的重置密码方式")
+)
+
+// PasswordService 忘记密码和修改密码
+// 重置密码之前要先验证过手机号码或者邮箱是这个人的，这里不会再验证
+//
+//go:generate mockgen -source=./password.go -package=svcmocks -destination=mocks/password.mock.go PasswordService
+type PasswordService interface {
+	// AccountExists 发送重置验证码之前检查一下，没有注册的就不发了。
+	// by 是 PasswordResetByPhone 或者 PasswordResetByEmail
+	AccountExists(ctx context.Context, by string, target string) (bool, error)
+	// Reset 重置密码，用户所有的会话都会失效，包括别人拿着旧密码登录的
+	Reset(ctx context.Context, by string, target string, password string) error
+	// Change 修改密码，除了 keepSsid 也就是当前的会话，别的会话都会失效
+	Change(ctx context.Context, uid int64, keepSsid string, oldPassword string, password string) error
+}
+
+type passwordService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	l           logger.LoggerV1
+}
+
+func NewPasswordService(userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	l logger.LoggerV1) PasswordService {
+	return &passwordService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		l:           l,
+	}
+}
+
+func (svc *passwordService) AccountExists(ctx context.Context, by string, target string) (bool, error) {
+	_, err := svc.findUser(ctx, by, target)
+	switch err {
+	case nil:
+		return true, nil
+	case ErrPasswordAccountNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (svc *passwordService) Reset(ctx context.Context, by string, target string, password string) error {
+	u, err := svc.findUser(ctx, by, target)
+	if err != nil {
+		return err
+	}
+	err = svc.updatePassword(ctx, u.Id, password)
+	if err != nil {
+		return err
+	}
+	// 忘记密码也可能是被盗号了，所以全部踢下线
+	err = svc.sessionRepo.DeleteByUid(ctx, u.Id)
+	if err != nil {
+		// 密码已经改了，这里只记录日志，会话最多七天之后也会过期
+		svc.l.Error("重置密码之后清除会话失败",
+			logger.Int64("uid", u.Id), logger.Error(err))
+	}
+	return nil
+}
+
+func (svc *passwordService) Change(ctx context.Context, uid int64, keepSsid string,
+	oldPassword string, password string) error {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.Password == "" {
+		return ErrPasswordNotSet
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(oldPassword))
+	if err != nil {
+		return ErrWrongPassword
+	}
+	err = svc.updatePassword(ctx, uid, password)
+	if err != nil {
+		return err
+	}
+	err = svc.sessionRepo.DeleteOthers(ctx, uid, keepSsid)
+	if err != nil {
+		svc.l.Error("修改密码之后清除别的会话失败",
+			logger.Int64("uid", uid), logger.Error(err))
+	}
+	return nil
+}
+
+func (svc *passwordService) updatePassword(ctx context.Context, uid int64, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return svc.userRepo.UpdatePassword(ctx, uid, string(hash))
+}
+
+func (svc *passwordService) findUser(ctx context.Context, by string, target string) (domain.User, error) {
+	var (
+		u   domain.User
+		err error
+	)
+	switch by {
+	case PasswordResetByPhone:
+		u, err = svc.userRepo.FindByPhone(ctx, target)
+	case PasswordResetByEmail:
+		u, err = svc.userRepo.FindByEmail(ctx, target)
+	default:
+		return domain.User{}, ErrUnknownPasswordResetType
+	}
+	if err == repository.ErrUserNotFound {
+		return domain.User{}, ErrPasswordAccountNotFound
+	}
+	return u, err
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestPasswordService_Change(t *testing.T) {
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("hello#world123"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.SessionRepository)
+
+		oldPassword string
+		wantErr     error
+	}{
+		{
+			name: "修改成功",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Password: string(oldHash)}, nil)
+				userRepo.EXPECT().UpdatePassword(gomock.Any(), int64(1), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, id int64, hash string) error {
+						return bcrypt.CompareHashAndPassword([]byte(hash), []byte("hello#world456"))
+					})
+				sessRepo := repomocks.NewMockSessionRepository(ctrl)
+				sessRepo.EXPECT().DeleteOthers(gomock.Any(), int64(1), "ssid").Return(nil)
+				return userRepo, sessRepo
+			},
+			oldPassword: "hello#world123",
+		},
+		{
+			name: "原密码不对",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Password: string(oldHash)}, nil)
+				return userRepo, repomocks.NewMockSessionRepository(ctrl)
+			},
+			oldPassword: "hello#world000",
+			wantErr:     ErrWrongPassword,
+		},
+		{
+			name: "短信注册的没有密码",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "15212345678"}, nil)
+				return userRepo, repomocks.NewMockSessionRepository(ctrl)
+			},
+			wantErr: ErrPasswordNotSet,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, sessRepo := tc.mock(ctrl)
+			svc := NewPasswordService(userRepo, sessRepo, logger.NewNoOpLogger())
+			err := svc.Change(context.Background(), 1, "ssid", tc.oldPassword, "hello#world456")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestPasswordService_Reset(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.SessionRepository)
+		by   string
+
+		wantErr error
+	}{
+		{
+			name: "用手机号码重置",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "15212345678").
+					Return(domain.User{Id: 1}, nil)
+				userRepo.EXPECT().UpdatePassword(gomock.Any(), int64(1), gomock.Any()).Return(nil)
+				sessRepo := repomocks.NewMockSessionRepository(ctrl)
+				sessRepo.EXPECT().DeleteByUid(gomock.Any(), int64(1)).Return(nil)
+				return userRepo, sessRepo
+			},
+			by: PasswordResetByPhone,
+		},
+		{
+			name: "账号不存在",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.SessionRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByPhone(gomock.Any(), "15212345678").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				return userRepo, repomocks.NewMockSessionRepository(ctrl)
+			},
+			by:      PasswordResetByPhone,
+			wantErr: ErrPasswordAccountNotFound,
+		},
+		{
+			name: "未知的重置方式",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.SessionRepository) {
+				return repomocks.NewMockUserRepository(ctrl), repomocks.NewMockSessionRepository(ctrl)
+			},
+			by:      "wechat",
+			wantErr: ErrUnknownPasswordResetType,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, sessRepo := tc.mock(ctrl)
+			svc := NewPasswordService(userRepo, sessRepo, logger.NewNoOpLogger())
+			err := svc.Reset(context.Background(), tc.by, "15212345678", "hello#world456")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
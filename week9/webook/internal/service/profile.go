@@ -0,0 +1,172 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/interactive/service"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/pkg/imagex"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/google/uuid"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// MaxAvatarSize 头像文件最大 2MB
+	MaxAvatarSize = 2 << 20
+	// maxAvatarPixels 避免一个很小的文件解码出一张巨大的图片
+	maxAvatarPixels = 4096 * 4096
+	avatarSize      = 512
+	avatarThumbSize = 128
+	// latestArticleCnt 作者主页展示的最近文章数量
+	latestArticleCnt = 10
+	statsBatchSize   = 100
+)
+
+var (
+	// ErrInvalidAvatar 不是支持的图片格式，或者图片太大
+	ErrInvalidAvatar = errs.UserInvalidAvatar
+	ErrUserNotFound  = errs.UserNotFound
+)
+
+// ProfileService 个人资料里面除了基本信息以外的部分，包括头像、作者主页和统计数据
+//
+//go:generate mockgen -source=./profile.go -package=svcmocks -destination=mocks/profile.mock.go ProfileService
+type ProfileService interface {
+	// PublicProfile 用户不存在的时候返回 ErrUserNotFound
+	PublicProfile(ctx context.Context, uid int64) (domain.PublicProfile, error)
+	Stats(ctx context.Context, uid int64) (domain.UserStats, error)
+	// UpdateAvatar data 是上传的原图，支持 JPEG、PNG 和 GIF，
+	// 返回裁剪缩放之后的头像和缩略图的 URL
+	UpdateAvatar(ctx context.Context, uid int64, data []byte) (string, string, error)
+}
+
+type profileService struct {
+	userRepo   repository.UserRepository
+	artRepo    repository.ArticleRepository
+	avatarRepo repository.AvatarRepository
+	intrSvc    service.InteractiveService
+	l          logger.LoggerV1
+}
+
+func NewProfileService(userRepo repository.UserRepository,
+	artRepo repository.ArticleRepository,
+	avatarRepo repository.AvatarRepository,
+	intrSvc service.InteractiveService,
+	l logger.LoggerV1) ProfileService {
+	return &profileService{
+		userRepo:   userRepo,
+		artRepo:    artRepo,
+		avatarRepo: avatarRepo,
+		intrSvc:    intrSvc,
+		l:          l,
+	}
+}
+
+func (svc *profileService) PublicProfile(ctx context.Context, uid int64) (domain.PublicProfile, error) {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err == repository.ErrUserNotFound {
+		return domain.PublicProfile{}, ErrUserNotFound
+	}
+	if err != nil {
+		return domain.PublicProfile{}, err
+	}
+	cnt, err := svc.artRepo.CountPubByAuthor(ctx, uid)
+	if err != nil {
+		return domain.PublicProfile{}, err
+	}
+	arts, err := svc.artRepo.ListPubByAuthor(ctx, uid, 0, latestArticleCnt)
+	if err != nil {
+		return domain.PublicProfile{}, err
+	}
+	return domain.PublicProfile{
+		Id:          u.Id,
+		Nickname:    u.Nickname,
+		Avatar:      u.Avatar,
+		AvatarThumb: u.AvatarThumb,
+		AboutMe:     u.AboutMe,
+		ArticleCnt:  cnt,
+		Articles:    arts,
+	}, nil
+}
+
+// Stats 分批查询已发表的文章，再汇总它们的阅读、点赞和收藏数
+// 文章很多的作者会比较慢，到时候可以考虑在 interactive 里面按照作者维护计数
+func (svc *profileService) Stats(ctx context.Context, uid int64) (domain.UserStats, error) {
+	var res domain.UserStats
+	for offset := 0; ; offset += statsBatchSize {
+		arts, err := svc.artRepo.ListPubByAuthor(ctx, uid, offset, statsBatchSize)
+		if err != nil {
+			return domain.UserStats{}, err
+		}
+		if len(arts) == 0 {
+			break
+		}
+		ids := make([]int64, 0, len(arts))
+		for _, art := range arts {
+			ids = append(ids, art.Id)
+		}
+		intrs, err := svc.intrSvc.GetByIds(ctx, "article", ids)
+		if err != nil {
+			return domain.UserStats{}, err
+		}
+		res.ArticleCnt += int64(len(arts))
+		for _, intr := range intrs {
+			res.ReadCnt += intr.ReadCnt
+			res.LikeCnt += intr.LikeCnt
+			res.CollectCnt += intr.CollectCnt
+		}
+		if len(arts) < statsBatchSize {
+			break
+		}
+	}
+	return res, nil
+}
+
+func (svc *profileService) UpdateAvatar(ctx context.Context, uid int64, data []byte) (string, string, error) {
+	if len(data) > MaxAvatarSize {
+		return "", "", ErrInvalidAvatar
+	}
+	// 先看一下尺寸，不合适的就不用解码了
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 ||
+		cfg.Width*cfg.Height > maxAvatarPixels {
+		return "", "", ErrInvalidAvatar
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", "", ErrInvalidAvatar
+	}
+	// 每次上传都用新的 key，这样 CDN 上旧的缓存不会有影响
+	prefix := fmt.Sprintf("avatars/%d/%s", uid, uuid.New().String())
+	avatar, err := svc.upload(ctx, prefix, img, avatarSize)
+	if err != nil {
+		return "", "", err
+	}
+	thumb, err := svc.upload(ctx, prefix, img, avatarThumbSize)
+	if err != nil {
+		return "", "", err
+	}
+	err = svc.userRepo.UpdateAvatar(ctx, uid, avatar, thumb)
+	if err != nil {
+		return "", "", err
+	}
+	return avatar, thumb, nil
+}
+
+func (svc *profileService) upload(ctx context.Context, prefix string,
+	img image.Image, size int) (string, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, imagex.Thumbnail(img, size), &jpeg.Options{Quality: 85})
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%s_%d.jpg", prefix, size)
+	return svc.avatarRepo.Upload(ctx, key, buf.Bytes(), "image/jpeg")
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	domain2 "gitee.com/geekbang/basic-go/webook/interactive/domain"
+	"gitee.com/geekbang/basic-go/webook/interactive/service"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestProfileService_Stats(t *testing.T) {
+	// 第一批是满的，所以还会再查一次
+	fullBatch := make([]domain.Article, 0, statsBatchSize)
+	fullIds := make([]int64, 0, statsBatchSize)
+	for i := 1; i <= statsBatchSize; i++ {
+		fullBatch = append(fullBatch, domain.Article{Id: int64(i)})
+		fullIds = append(fullIds, int64(i))
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.ArticleRepository, service.InteractiveService)
+
+		wantStats domain.UserStats
+		wantErr   error
+	}{
+		{
+			name: "只有一批",
+			mock: func(ctrl *gomock.Controller) (repository.ArticleRepository, service.InteractiveService) {
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				artRepo.EXPECT().ListPubByAuthor(gomock.Any(), int64(1), 0, statsBatchSize).
+					Return([]domain.Article{{Id: 1}, {Id: 2}}, nil)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{1, 2}).
+					Return(map[int64]domain2.Interactive{
+						1: {BizId: 1, ReadCnt: 10, LikeCnt: 2, CollectCnt: 1},
+						2: {BizId: 2, ReadCnt: 5, LikeCnt: 1},
+					}, nil)
+				return artRepo, intrSvc
+			},
+			wantStats: domain.UserStats{ArticleCnt: 2, ReadCnt: 15, LikeCnt: 3, CollectCnt: 1},
+		},
+		{
+			name: "多批",
+			mock: func(ctrl *gomock.Controller) (repository.ArticleRepository, service.InteractiveService) {
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				artRepo.EXPECT().ListPubByAuthor(gomock.Any(), int64(1), 0, statsBatchSize).
+					Return(fullBatch, nil)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", fullIds).
+					Return(map[int64]domain2.Interactive{
+						1: {BizId: 1, ReadCnt: 10, LikeCnt: 2},
+					}, nil)
+				artRepo.EXPECT().ListPubByAuthor(gomock.Any(), int64(1), statsBatchSize, statsBatchSize).
+					Return([]domain.Article{}, nil)
+				return artRepo, intrSvc
+			},
+			wantStats: domain.UserStats{ArticleCnt: statsBatchSize, ReadCnt: 10, LikeCnt: 2},
+		},
+		{
+			name: "没有文章",
+			mock: func(ctrl *gomock.Controller) (repository.ArticleRepository, service.InteractiveService) {
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				artRepo.EXPECT().ListPubByAuthor(gomock.Any(), int64(1), 0, statsBatchSize).
+					Return(nil, nil)
+				return artRepo, intrSvc
+			},
+		},
+		{
+			name: "查询计数失败",
+			mock: func(ctrl *gomock.Controller) (repository.ArticleRepository, service.InteractiveService) {
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				intrSvc := svcmocks.NewMockInteractiveService(ctrl)
+				artRepo.EXPECT().ListPubByAuthor(gomock.Any(), int64(1), 0, statsBatchSize).
+					Return([]domain.Article{{Id: 1}}, nil)
+				intrSvc.EXPECT().GetByIds(gomock.Any(), "article", []int64{1}).
+					Return(nil, errors.New("mock error"))
+				return artRepo, intrSvc
+			},
+			wantErr: errors.New("mock error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			artRepo, intrSvc := tc.mock(ctrl)
+			svc := NewProfileService(nil, artRepo, nil, intrSvc, logger.NewNoOpLogger())
+			stats, err := svc.Stats(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantStats, stats)
+		})
+	}
+}
+
+func TestProfileService_UpdateAvatar(t *testing.T) {
+	encodePNG := func(w, h int) []byte {
+		var buf bytes.Buffer
+		err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+		assert.NoError(t, err)
+		return buf.Bytes()
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.AvatarRepository)
+		data []byte
+
+		wantAvatar string
+		wantThumb  string
+		wantErr    error
+	}{
+		{
+			name: "上传成功",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.AvatarRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				avatarRepo := repomocks.NewMockAvatarRepository(ctrl)
+				avatarRepo.EXPECT().Upload(gomock.Any(), gomock.Any(), gomock.Any(), "image/jpeg").
+					Return("avatar_512.jpg", nil)
+				avatarRepo.EXPECT().Upload(gomock.Any(), gomock.Any(), gomock.Any(), "image/jpeg").
+					Return("avatar_128.jpg", nil)
+				userRepo.EXPECT().UpdateAvatar(gomock.Any(), int64(1), "avatar_512.jpg", "avatar_128.jpg").
+					Return(nil)
+				return userRepo, avatarRepo
+			},
+			data:       encodePNG(600, 400),
+			wantAvatar: "avatar_512.jpg",
+			wantThumb:  "avatar_128.jpg",
+		},
+		{
+			name: "不是图片",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.AvatarRepository) {
+				return nil, nil
+			},
+			data:    []byte("hello"),
+			wantErr: ErrInvalidAvatar,
+		},
+		{
+			name: "尺寸太大",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.AvatarRepository) {
+				return nil, nil
+			},
+			data:    encodePNG(5000, 5000),
+			wantErr: ErrInvalidAvatar,
+		},
+		{
+			name: "上传失败",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.AvatarRepository) {
+				avatarRepo := repomocks.NewMockAvatarRepository(ctrl)
+				avatarRepo.EXPECT().Upload(gomock.Any(), gomock.Any(), gomock.Any(), "image/jpeg").
+					Return("", errors.New("mock error"))
+				return nil, avatarRepo
+			},
+			data:    encodePNG(10, 10),
+			wantErr: errors.New("mock error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, avatarRepo := tc.mock(ctrl)
+			svc := NewProfileService(userRepo, nil, avatarRepo, nil, logger.NewNoOpLogger())
+			avatar, thumb, err := svc.UpdateAvatar(context.Background(), 1, tc.data)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantAvatar, avatar)
+			assert.Equal(t, tc.wantThumb, thumb)
+		})
+	}
+}
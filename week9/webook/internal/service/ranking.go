@@ -14,7 +14,8 @@ import (
 //go:generate mockgen -source=./ranking.go -package=svcmocks -destination=./mocks/ranking.mock.go RankingService
 type RankingService interface {
 	// RankTopN 计算 TopN
-	RankTopN(ctx context.Context) error
+	// token 是调用者持有的分布式锁的 fencing token，写入结果的时候会用来拒绝旧的持有者
+	RankTopN(ctx context.Context, token int64) error
 	// TopN 返回业务的 ID
 	TopN(ctx context.Context) ([]domain.Article, error)
 }
@@ -46,13 +47,13 @@ func NewBatchRankingService(
 	return res
 }
 
-func (a *BatchRankingService) RankTopN(ctx context.Context) error {
+func (a *BatchRankingService) RankTopN(ctx context.Context, token int64) error {
 	arts, err := a.rankTopN(ctx)
 	if err != nil {
 		return err
 	}
 	// 准备放到缓存里面
-	return a.repo.ReplaceTopN(ctx, arts)
+	return a.repo.ReplaceTopN(ctx, token, arts)
 }
 
 func (a *BatchRankingService) rankTopN(ctx context.Context) ([]domain.Article, error) {
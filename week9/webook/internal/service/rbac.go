@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+)
+
+// PermissionAll 拥有所有的权限，预置的 admin 角色就是这个
+const PermissionAll = "*"
+
+var (
+	ErrRoleNotFound     = errs.RBACRoleNotFound
+	ErrUserRoleNotFound = errs.RBACUserRoleNotFound
+)
+
+// RBACService 角色和权限，同时也是 rbac 中间件的 PermissionChecker
+//
+//go:generate mockgen -source=./rbac.go -package=svcmocks -destination=mocks/rbac.mock.go RBACService
+type RBACService interface {
+	HasPermission(ctx context.Context, uid int64, permission string) (bool, error)
+	Roles(ctx context.Context, uid int64) ([]domain.Role, error)
+	// AssignRole 重复分配不会报错
+	AssignRole(ctx context.Context, uid int64, role string) error
+	RevokeRole(ctx context.Context, uid int64, role string) error
+}
+
+type rbacService struct {
+	repo repository.RBACRepository
+}
+
+func NewRBACService(repo repository.RBACRepository) RBACService {
+	return &rbacService{
+		repo: repo,
+	}
+}
+
+func (svc *rbacService) HasPermission(ctx context.Context, uid int64, permission string) (bool, error) {
+	perms, err := svc.repo.FindPermissions(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if p == PermissionAll || p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (svc *rbacService) Roles(ctx context.Context, uid int64) ([]domain.Role, error) {
+	return svc.repo.FindRoles(ctx, uid)
+}
+
+func (svc *rbacService) AssignRole(ctx context.Context, uid int64, role string) error {
+	err := svc.repo.AddUserRole(ctx, uid, role)
+	switch err {
+	case nil, repository.ErrUserRoleDuplicate:
+		return nil
+	case repository.ErrRoleNotFound:
+		return ErrRoleNotFound
+	default:
+		return err
+	}
+}
+
+func (svc *rbacService) RevokeRole(ctx context.Context, uid int64, role string) error {
+	err := svc.repo.RemoveUserRole(ctx, uid, role)
+	if err == repository.ErrRoleNotFound {
+		return ErrUserRoleNotFound
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestRBACService_HasPermission(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.RBACRepository
+
+		wantOk  bool
+		wantErr error
+	}{
+		{
+			name: "有这个权限",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().FindPermissions(gomock.Any(), int64(1)).
+					Return([]string{"sms:read", "sms:manage"}, nil)
+				return repo
+			},
+			wantOk: true,
+		},
+		{
+			name: "管理员",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().FindPermissions(gomock.Any(), int64(1)).
+					Return([]string{PermissionAll}, nil)
+				return repo
+			},
+			wantOk: true,
+		},
+		{
+			name: "没有这个权限",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().FindPermissions(gomock.Any(), int64(1)).
+					Return([]string{"sms:read"}, nil)
+				return repo
+			},
+		},
+		{
+			name: "查询出错",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().FindPermissions(gomock.Any(), int64(1)).
+					Return(nil, errors.New("mock error"))
+				return repo
+			},
+			wantErr: errors.New("mock error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewRBACService(tc.mock(ctrl))
+			ok, err := svc.HasPermission(context.Background(), 1, "sms:manage")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantOk, ok)
+		})
+	}
+}
+
+func TestRBACService_AssignRole(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.RBACRepository
+
+		wantErr error
+	}{
+		{
+			name: "分配成功",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().AddUserRole(gomock.Any(), int64(1), "admin").Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "重复分配",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().AddUserRole(gomock.Any(), int64(1), "admin").
+					Return(repository.ErrUserRoleDuplicate)
+				return repo
+			},
+		},
+		{
+			name: "角色不存在",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().AddUserRole(gomock.Any(), int64(1), "admin").
+					Return(repository.ErrRoleNotFound)
+				return repo
+			},
+			wantErr: ErrRoleNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewRBACService(tc.mock(ctrl))
+			err := svc.AssignRole(context.Background(), 1, "admin")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrSessionRevoked 退出登录、被踢下线或者已经过期
+	ErrSessionRevoked  = errors.New("会话已经失效")
+	ErrSessionNotFound = errs.UserSessionNotFound
+	// ErrRefreshTokenReused 旧的长 token 又被拿来刷新，整个会话已经作废了
+	ErrRefreshTokenReused = errors.New("长 token 被重复使用")
+)
+
+// SessionService 登录会话，也就是用户在哪些设备上登录了
+//
+//go:generate mockgen -source=./session.go -package=svcmocks -destination=mocks/session.mock.go SessionService
+type SessionService interface {
+	// Create 登录的时候创建会话，调用者要填好 Ssid、Uid、设备信息和过期时间
+	Create(ctx context.Context, s domain.Session) error
+	// Check 会话还有效的时候返回 nil，同时会顺便更新最后活跃时间
+	Check(ctx context.Context, uid int64, ssid string) error
+	// Rotate 刷新的时候换一个新的长 token，旧的长 token 就失效了。
+	// 再用旧的长 token 来刷新，会返回 ErrRefreshTokenReused，并且整个会话都会失效
+	Rotate(ctx context.Context, uid int64, ssid string, oldRid string, newRid string) error
+	// List 按照最后活跃时间倒序
+	List(ctx context.Context, uid int64) ([]domain.Session, error)
+	// Revoke 踢下线，只能踢自己的会话
+	Revoke(ctx context.Context, uid int64, ssid string) error
+	// RevokeAll 退出所有设备
+	RevokeAll(ctx context.Context, uid int64) error
+}
+
+type sessionService struct {
+	repo repository.SessionRepository
+}
+
+func NewSessionService(repo repository.SessionRepository) SessionService {
+	return &sessionService{
+		repo: repo,
+	}
+}
+
+func (svc *sessionService) Create(ctx context.Context, s domain.Session) error {
+	now := time.Now()
+	s.Ctime = now
+	s.Utime = now
+	return svc.repo.Create(ctx, s)
+}
+
+func (svc *sessionService) Check(ctx context.Context, uid int64, ssid string) error {
+	ok, err := svc.repo.Touch(ctx, uid, ssid)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+func (svc *sessionService) Rotate(ctx context.Context, uid int64, ssid string, oldRid string, newRid string) error {
+	err := svc.repo.Rotate(ctx, uid, ssid, oldRid, newRid)
+	switch err {
+	case repository.ErrSessionNotFound:
+		return ErrSessionRevoked
+	case repository.ErrRefreshTokenReused:
+		return ErrRefreshTokenReused
+	default:
+		return err
+	}
+}
+
+func (svc *sessionService) List(ctx context.Context, uid int64) ([]domain.Session, error) {
+	ss, err := svc.repo.FindByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ss, func(i, j int) bool {
+		return ss[i].Utime.After(ss[j].Utime)
+	})
+	return ss, nil
+}
+
+func (svc *sessionService) Revoke(ctx context.Context, uid int64, ssid string) error {
+	err := svc.repo.Delete(ctx, uid, ssid)
+	if err == repository.ErrSessionNotFound {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+func (svc *sessionService) RevokeAll(ctx context.Context, uid int64) error {
+	return svc.repo.DeleteByUid(ctx, uid)
+}
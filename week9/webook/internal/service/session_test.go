@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestSessionService_Check(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.SessionRepository
+
+		wantErr error
+	}{
+		{
+			name: "会话有效",
+			mock: func(ctrl *gomock.Controller) repository.SessionRepository {
+				repo := repomocks.NewMockSessionRepository(ctrl)
+				repo.EXPECT().Touch(gomock.Any(), int64(1), "ssid").Return(true, nil)
+				return repo
+			},
+		},
+		{
+			name: "已经被踢下线",
+			mock: func(ctrl *gomock.Controller) repository.SessionRepository {
+				repo := repomocks.NewMockSessionRepository(ctrl)
+				repo.EXPECT().Touch(gomock.Any(), int64(1), "ssid").Return(false, nil)
+				return repo
+			},
+			wantErr: ErrSessionRevoked,
+		},
+		{
+			name: "Redis 出错",
+			mock: func(ctrl *gomock.Controller) repository.SessionRepository {
+				repo := repomocks.NewMockSessionRepository(ctrl)
+				repo.EXPECT().Touch(gomock.Any(), int64(1), "ssid").Return(false, errors.New("mock error"))
+				return repo
+			},
+			wantErr: errors.New("mock error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewSessionService(tc.mock(ctrl))
+			err := svc.Check(context.Background(), 1, "ssid")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestSessionService_Revoke(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.SessionRepository
+
+		wantErr error
+	}{
+		{
+			name: "踢下线成功",
+			mock: func(ctrl *gomock.Controller) repository.SessionRepository {
+				repo := repomocks.NewMockSessionRepository(ctrl)
+				repo.EXPECT().Delete(gomock.Any(), int64(1), "ssid").Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "会话不存在或者不是自己的",
+			mock: func(ctrl *gomock.Controller) repository.SessionRepository {
+				repo := repomocks.NewMockSessionRepository(ctrl)
+				repo.EXPECT().Delete(gomock.Any(), int64(1), "ssid").
+					Return(repository.ErrSessionNotFound)
+				return repo
+			},
+			wantErr: ErrSessionNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewSessionService(tc.mock(ctrl))
+			err := svc.Revoke(context.Background(), 1, "ssid")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestSessionService_Rotate(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.SessionRepository
+
+		wantErr error
+	}{
+		{
+			name: "刷新成功",
+			mock: func(ctrl *gomock.Controller) repository.SessionRepository {
+				repo := repomocks.NewMockSessionRepository(ctrl)
+				repo.EXPECT().Rotate(gomock.Any(), int64(1), "ssid", "old", "new").Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "会话已经失效",
+			mock: func(ctrl *gomock.Controller) repository.SessionRepository {
+				repo := repomocks.NewMockSessionRepository(ctrl)
+				repo.EXPECT().Rotate(gomock.Any(), int64(1), "ssid", "old", "new").
+					Return(repository.ErrSessionNotFound)
+				return repo
+			},
+			wantErr: ErrSessionRevoked,
+		},
+		{
+			name: "旧的长 token 被重复使用",
+			mock: func(ctrl *gomock.Controller) repository.SessionRepository {
+				repo := repomocks.NewMockSessionRepository(ctrl)
+				repo.EXPECT().Rotate(gomock.Any(), int64(1), "ssid", "old", "new").
+					Return(repository.ErrRefreshTokenReused)
+				return repo
+			},
+			wantErr: ErrRefreshTokenReused,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewSessionService(tc.mock(ctrl))
+			err := svc.Rotate(context.Background(), 1, "ssid", "old", "new")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
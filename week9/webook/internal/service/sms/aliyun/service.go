@@ -0,0 +1,172 @@
+package aliyun
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnknownTemplate 阿里云的模板参数是具名的，所以必须提前配置好参数名
+var ErrUnknownTemplate = errors.New("阿里云短信模板未配置参数名")
+
+const defaultEndpoint = "https://dysmsapi.aliyuncs.com/"
+
+// Service 阿里云短信
+// 没有引入阿里云的 SDK，而是直接按照 RPC 风格的 API 拼请求，
+// 一来 SDK 依赖太重，二来方便用 httptest 测试
+type Service struct {
+	client       *http.Client
+	endpoint     string
+	accessKeyId  string
+	accessSecret string
+	signName     string
+	regionId     string
+	// 阿里云的模板参数是 JSON 对象，而我们的 args 是按照位置传的
+	// 所以要有一个模板 ID 到参数名的映射
+	templates map[string][]string
+}
+
+type Config struct {
+	// Endpoint 为空则使用默认的 dysmsapi.aliyuncs.com
+	Endpoint     string     `yaml:"endpoint"`
+	AccessKeyId  string     `yaml:"accessKeyId"`
+	AccessSecret string     `yaml:"accessSecret"`
+	SignName     string     `yaml:"signName"`
+	RegionId     string     `yaml:"regionId"`
+	Templates    []Template `yaml:"templates"`
+}
+
+// Template 模板的参数名，顺序要和调用 Send 时候的 args 一致
+// 不用 map 是因为 viper 会把 map 的 key 都转成小写，而模板 ID 是区分大小写的
+type Template struct {
+	Id     string   `yaml:"id"`
+	Params []string `yaml:"params"`
+}
+
+func NewService(client *http.Client, cfg Config) *Service {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	regionId := cfg.RegionId
+	if regionId == "" {
+		regionId = "cn-hangzhou"
+	}
+	templates := make(map[string][]string, len(cfg.Templates))
+	for _, tpl := range cfg.Templates {
+		templates[tpl.Id] = tpl.Params
+	}
+	return &Service{
+		client:       client,
+		endpoint:     endpoint,
+		accessKeyId:  cfg.AccessKeyId,
+		accessSecret: cfg.AccessSecret,
+		signName:     cfg.SignName,
+		regionId:     regionId,
+		templates:    templates,
+	}
+}
+
+func (s *Service) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	names, ok := s.templates[tplId]
+	if !ok || len(names) != len(args) {
+		return fmt.Errorf("%w, 模板 %s", ErrUnknownTemplate, tplId)
+	}
+	tplParam := make(map[string]string, len(args))
+	for i, name := range names {
+		tplParam[name] = args[i]
+	}
+	paramBytes, err := json.Marshal(tplParam)
+	if err != nil {
+		return err
+	}
+	params := map[string]string{
+		"AccessKeyId":      s.accessKeyId,
+		"Action":           "SendSms",
+		"Format":           "JSON",
+		"PhoneNumbers":     strings.Join(numbers, ","),
+		"RegionId":         s.regionId,
+		"SignName":         sms.SignName(ctx, s.signName),
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   uuid.New().String(),
+		"SignatureVersion": "1.0",
+		"TemplateCode":     tplId,
+		"TemplateParam":    string(paramBytes),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"Version":          "2017-05-25",
+	}
+	params["Signature"] = sign(http.MethodPost, params, s.accessSecret)
+
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		s.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var res response
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return fmt.Errorf("解析阿里云短信响应失败 %w", err)
+	}
+	if res.Code != "OK" {
+		return fmt.Errorf("发送失败，code: %s, 原因：%s, request id: %s",
+			res.Code, res.Message, res.RequestId)
+	}
+	return nil
+}
+
+type response struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	BizId     string `json:"BizId"`
+	RequestId string `json:"RequestId"`
+}
+
+// sign 阿里云 RPC 风格 API 的签名算法，也就是 HMAC-SHA1
+// 参数按照 key 排序之后，拼成 query string，再整个编码一次
+func sign(method string, params map[string]string, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params[k]))
+	}
+	stringToSign := method + "&" + percentEncode("/") + "&" +
+		percentEncode(strings.Join(pairs, "&"))
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// percentEncode 阿里云要求的是 RFC3986 的编码，和 url.QueryEscape 有几个字符不同
+func percentEncode(s string) string {
+	res := url.QueryEscape(s)
+	res = strings.ReplaceAll(res, "+", "%20")
+	res = strings.ReplaceAll(res, "*", "%2A")
+	res = strings.ReplaceAll(res, "%7E", "~")
+	return res
+}
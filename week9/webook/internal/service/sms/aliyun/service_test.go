@@ -0,0 +1,120 @@
+package aliyun
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestService_Send(t *testing.T) {
+	const secret = "testsecret"
+	testCases := []struct {
+		name string
+		// 模拟阿里云的服务端
+		handler func(t *testing.T) http.HandlerFunc
+
+		tplId   string
+		args    []string
+		numbers []string
+
+		wantErr error
+		// 有些错误是 fmt.Errorf 拼出来的，只能判断是不是出错了
+		wantAnyErr bool
+	}{
+		{
+			name: "发送成功",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					require.NoError(t, r.ParseForm())
+					assert.Equal(t, http.MethodPost, r.Method)
+					assert.Equal(t, "SendSms", r.PostForm.Get("Action"))
+					assert.Equal(t, "SMS_123", r.PostForm.Get("TemplateCode"))
+					assert.Equal(t, `{"code":"123456"}`, r.PostForm.Get("TemplateParam"))
+					assert.Equal(t, "15212345678,15212345679", r.PostForm.Get("PhoneNumbers"))
+					assert.Equal(t, "妙影科技", r.PostForm.Get("SignName"))
+					assert.Equal(t, "ak", r.PostForm.Get("AccessKeyId"))
+					// 服务端重新算一遍签名
+					params := make(map[string]string, len(r.PostForm))
+					for k := range r.PostForm {
+						if k != "Signature" {
+							params[k] = r.PostForm.Get(k)
+						}
+					}
+					assert.Equal(t, sign(http.MethodPost, params, secret),
+						r.PostForm.Get("Signature"))
+					_, _ = w.Write([]byte(`{"Code":"OK","Message":"OK","BizId":"1","RequestId":"abc"}`))
+				}
+			},
+			tplId:   "SMS_123",
+			args:    []string{"123456"},
+			numbers: []string{"15212345678", "15212345679"},
+		},
+		{
+			name: "阿里云返回错误码",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write([]byte(`{"Code":"isv.BUSINESS_LIMIT_CONTROL","Message":"触发流控","RequestId":"abc"}`))
+				}
+			},
+			tplId:      "SMS_123",
+			args:       []string{"123456"},
+			numbers:    []string{"15212345678"},
+			wantAnyErr: true,
+		},
+		{
+			name: "响应不是 JSON",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusBadGateway)
+					_, _ = w.Write([]byte(`bad gateway`))
+				}
+			},
+			tplId:      "SMS_123",
+			args:       []string{"123456"},
+			numbers:    []string{"15212345678"},
+			wantAnyErr: true,
+		},
+		{
+			name: "模板没有配置参数名",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					t.Fatal("不应该发出请求")
+				}
+			},
+			tplId:   "SMS_unknown",
+			args:    []string{"123456"},
+			numbers: []string{"15212345678"},
+			wantErr: ErrUnknownTemplate,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler(t))
+			defer server.Close()
+			svc := NewService(server.Client(), Config{
+				Endpoint:     server.URL,
+				AccessKeyId:  "ak",
+				AccessSecret: secret,
+				SignName:     "妙影科技",
+				Templates: []Template{
+					{Id: "SMS_123", Params: []string{"code"}},
+				},
+			})
+			err := svc.Send(context.Background(), tc.tplId, tc.args, tc.numbers...)
+			if tc.wantAnyErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tc.wantErr))
+		})
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	assert.Equal(t, "a%20b%2A~%2F", percentEncode("a b*~/"))
+}
@@ -0,0 +1,223 @@
+package async
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Policy 判定要不要转异步的策略
+type Policy struct {
+	// Window 统计的滑动窗口大小
+	Window time.Duration `yaml:"window"`
+	// Buckets 窗口切分成多少个桶，桶越多，窗口滑动越平滑
+	Buckets int `yaml:"buckets"`
+	// MinRequests 窗口内至少要有这么多请求才做判定，避免样本太少误判
+	MinRequests int64 `yaml:"minRequests"`
+	// LatencyThreshold 平均响应时间超过这个值就转异步
+	LatencyThreshold time.Duration `yaml:"latencyThreshold"`
+	// TrendRatio 当前这个桶的平均响应时间比上一个桶增长超过这个比例，就转异步
+	// 比如说 0.5 就是增长了 50%。0 表示不启用
+	TrendRatio float64 `yaml:"trendRatio"`
+	// ErrorRate 错误率超过这个值就转异步
+	ErrorRate float64 `yaml:"errorRate"`
+	// RecoverAfter 进入异步这么久之后，尝试退出异步
+	RecoverAfter time.Duration `yaml:"recoverAfter"`
+	// ProbeRatio 异步期间保留多少比例的流量继续同步发送，用来判定服务商有没有恢复
+	ProbeRatio float64 `yaml:"probeRatio"`
+}
+
+// DefaultPolicy 默认的策略，具体数值你可以根据服务商的 SLA 来调整
+func DefaultPolicy() Policy {
+	return Policy{
+		Window:           time.Second * 10,
+		Buckets:          10,
+		MinRequests:      10,
+		LatencyThreshold: time.Millisecond * 500,
+		TrendRatio:       1,
+		ErrorRate:        0.2,
+		RecoverAfter:     time.Minute * 3,
+		ProbeRatio:       0.01,
+	}
+}
+
+// Decider 根据同步发送的响应时间和错误率，决定要不要转异步
+// 每一个服务商应该有一个自己的 Decider
+type Decider struct {
+	policy Policy
+	lock   sync.Mutex
+	window *slidingWindow
+	async  bool
+	// 进入异步的时间
+	asyncSince time.Time
+
+	// 为了测试，可以替换掉
+	now  func() time.Time
+	rand func() float64
+	// 切换模式的时候回调
+	onSwitch func(async bool)
+}
+
+func NewDecider(p Policy) *Decider {
+	return &Decider{
+		policy: p,
+		window: newSlidingWindow(p.Window, p.Buckets),
+		now:    time.Now,
+		rand:   rand.Float64,
+	}
+}
+
+// NeedAsync 异步模式下，依旧会放 ProbeRatio 的流量走同步
+func (d *Decider) NeedAsync() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if !d.async {
+		return false
+	}
+	now := d.now()
+	if now.Sub(d.asyncSince) >= d.policy.RecoverAfter {
+		st := d.window.stats(now)
+		// 探测流量不够，或者探测流量表明服务商已经恢复了
+		if st.cnt < d.policy.MinRequests || !d.unhealthy(st) {
+			d.switchMode(false, now)
+			return false
+		}
+		// 还没恢复，再等一个周期
+		d.asyncSince = now
+	}
+	return d.rand() >= d.policy.ProbeRatio
+}
+
+// Report 上报一次同步发送的结果
+func (d *Decider) Report(duration time.Duration, err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	now := d.now()
+	d.window.add(now, duration, err != nil)
+	if d.async {
+		// 异步模式下的数据只用来判定要不要退出异步
+		return
+	}
+	st := d.window.stats(now)
+	if st.cnt >= d.policy.MinRequests && d.unhealthy(st) {
+		d.switchMode(true, now)
+	}
+}
+
+// Async 当前是否处于异步模式
+func (d *Decider) Async() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.async
+}
+
+func (d *Decider) unhealthy(st windowStats) bool {
+	// 1. 平均响应时间超过了绝对阈值
+	if st.avgLatency() > d.policy.LatencyThreshold {
+		return true
+	}
+	// 2. 错误率太高
+	if float64(st.errCnt) > float64(st.cnt)*d.policy.ErrorRate {
+		return true
+	}
+	// 3. 响应时间的变化趋势
+	if d.policy.TrendRatio > 0 && st.last.cnt > 0 && st.prev.cnt > 0 {
+		prev := st.prev.avgLatency()
+		return prev > 0 &&
+			float64(st.last.avgLatency()-prev) > float64(prev)*d.policy.TrendRatio
+	}
+	return false
+}
+
+func (d *Decider) switchMode(async bool, now time.Time) {
+	d.async = async
+	d.asyncSince = now
+	// 切换之后，旧的数据就没有参考价值了
+	d.window.reset()
+	if d.onSwitch != nil {
+		d.onSwitch(async)
+	}
+}
+
+type bucket struct {
+	// 桶的编号，也就是 UnixNano / 桶的宽度
+	idx     int64
+	cnt     int64
+	errCnt  int64
+	latency time.Duration
+}
+
+func (b bucket) avgLatency() time.Duration {
+	if b.cnt == 0 {
+		return 0
+	}
+	return b.latency / time.Duration(b.cnt)
+}
+
+type windowStats struct {
+	bucket
+	// 当前这个桶
+	last bucket
+	// 上一个桶
+	prev bucket
+}
+
+// slidingWindow 环形数组实现的滑动窗口，非线程安全，由 Decider 加锁
+type slidingWindow struct {
+	buckets []bucket
+	width   int64
+}
+
+func newSlidingWindow(window time.Duration, n int) *slidingWindow {
+	if n <= 0 {
+		n = 1
+	}
+	width := int64(window) / int64(n)
+	if width <= 0 {
+		width = 1
+	}
+	return &slidingWindow{
+		buckets: make([]bucket, n),
+		width:   width,
+	}
+}
+
+func (w *slidingWindow) add(now time.Time, duration time.Duration, failed bool) {
+	idx := now.UnixNano() / w.width
+	b := &w.buckets[idx%int64(len(w.buckets))]
+	if b.idx != idx {
+		// 这个位置上是一个已经滑出窗口的桶
+		*b = bucket{idx: idx}
+	}
+	b.cnt++
+	b.latency += duration
+	if failed {
+		b.errCnt++
+	}
+}
+
+func (w *slidingWindow) stats(now time.Time) windowStats {
+	cur := now.UnixNano() / w.width
+	var res windowStats
+	for _, b := range w.buckets {
+		if b.idx <= cur-int64(len(w.buckets)) || b.idx > cur {
+			continue
+		}
+		res.cnt += b.cnt
+		res.errCnt += b.errCnt
+		res.latency += b.latency
+		switch b.idx {
+		case cur:
+			res.last = b
+		case cur - 1:
+			res.prev = b
+		}
+	}
+	return res
+}
+
+func (w *slidingWindow) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+}
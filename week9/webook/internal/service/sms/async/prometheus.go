@@ -0,0 +1,38 @@
+package async
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ModeMetrics 上报每个服务商当前处于同步还是异步模式
+// 全局创建一个就可以，多个服务商共用，避免重复注册
+type ModeMetrics struct {
+	vector *prometheus.GaugeVec
+}
+
+func NewModeMetrics(namespace string,
+	subsystem string,
+	instanceId string,
+	name string) *ModeMetrics {
+	vector := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      "短信服务商当前的发送模式，1 是异步，0 是同步",
+		ConstLabels: map[string]string{
+			"instance_id": instanceId,
+		},
+	}, []string{"provider"})
+	prometheus.MustRegister(vector)
+	return &ModeMetrics{
+		vector: vector,
+	}
+}
+
+func (m *ModeMetrics) Set(provider string, async bool) {
+	val := 0.0
+	if async {
+		val = 1
+	}
+	m.vector.WithLabelValues(provider).Set(val)
+}
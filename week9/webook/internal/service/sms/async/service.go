@@ -6,6 +6,9 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
 	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"golang.org/x/sync/errgroup"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -14,22 +17,110 @@ type Service struct {
 	// 转异步，存储发短信请求的 repository
 	repo repository.AsyncSmsRepository
 	l    logger.LoggerV1
+	cfg  Config
+	// 判定要不要转异步
+	decider *Decider
+}
+
+// Config 异步发送相关的配置
+type Config struct {
+	// Provider 服务商的名字，用在日志和监控里面
+	Provider string `yaml:"provider"`
+	// Policy 判定要不要转异步的策略
+	Policy Policy `yaml:"policy"`
+	// BatchSize 一次抢占多少条
+	BatchSize int `yaml:"batchSize"`
+	// Concurrency 最多同时有多少个 goroutine 在发送
+	Concurrency int `yaml:"concurrency"`
+	// IdleInterval 没有抢占到任务的时候，睡眠多久再抢
+	IdleInterval time.Duration `yaml:"idleInterval"`
+	// SendTimeout 单条短信的发送超时时间
+	SendTimeout time.Duration `yaml:"sendTimeout"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Provider:     "default",
+		Policy:       DefaultPolicy(),
+		BatchSize:    20,
+		Concurrency:  5,
+		IdleInterval: time.Second,
+		SendTimeout:  time.Second,
+	}
+}
+
+// withDefaults 没有配置的字段，使用默认值
+func (c Config) withDefaults() Config {
+	def := DefaultConfig()
+	if c.Provider == "" {
+		c.Provider = def.Provider
+	}
+	if c.Policy == (Policy{}) {
+		c.Policy = def.Policy
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = def.BatchSize
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = def.Concurrency
+	}
+	if c.IdleInterval <= 0 {
+		c.IdleInterval = def.IdleInterval
+	}
+	if c.SendTimeout <= 0 {
+		c.SendTimeout = def.SendTimeout
+	}
+	return c
 }
 
 func NewService(svc sms.Service,
 	repo repository.AsyncSmsRepository,
 	l logger.LoggerV1) *Service {
-	res := &Service{
-		svc:  svc,
-		repo: repo,
-		l:    l,
-	}
+	return NewAdaptiveService(svc, repo, l, DefaultConfig(), nil)
+}
+
+// NewAdaptiveService metrics 可以为 nil，也就是不上报当前模式
+func NewAdaptiveService(svc sms.Service,
+	repo repository.AsyncSmsRepository,
+	l logger.LoggerV1,
+	cfg Config,
+	metrics *ModeMetrics) *Service {
+	cfg = cfg.withDefaults()
+	res := newService(svc, repo, l, cfg, NewDecider(cfg.Policy), metrics)
 	go func() {
 		res.StartAsyncCycle()
 	}()
 	return res
 }
 
+func newService(svc sms.Service,
+	repo repository.AsyncSmsRepository,
+	l logger.LoggerV1,
+	cfg Config,
+	decider *Decider,
+	metrics *ModeMetrics) *Service {
+	res := &Service{
+		svc:     svc,
+		repo:    repo,
+		l:       l,
+		cfg:     cfg.withDefaults(),
+		decider: decider,
+	}
+	provider := res.cfg.Provider
+	if metrics != nil {
+		metrics.Set(provider, false)
+	}
+	decider.onSwitch = func(async bool) {
+		l.Warn("短信发送模式切换",
+			logger.String("provider", provider),
+			logger.Bool("async", async))
+		if metrics != nil {
+			metrics.Set(provider, async)
+		}
+	}
+	return res
+}
+
 // StartAsyncCycle 异步发送消息
 // 这里我们没有设计退出机制，是因为没啥必要
 // 因为程序停止的时候，它自然就停止了
@@ -40,50 +131,75 @@ func (s *Service) StartAsyncCycle() {
 	}
 }
 
+// AsyncSend 抢占一批，并发发送，再批量上报结果
 func (s *Service) AsyncSend() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	// 抢占一个异步发送的消息，确保在非常多个实例
-	// 比如 k8s 部署了三个 pod，一个请求，只有一个实例能拿到
-	as, err := s.repo.PreemptWaitingSMS(ctx)
+	// 抢占一批异步发送的消息，确保在非常多个实例
+	// 比如 k8s 部署了三个 pod，一条消息只有一个实例能拿到
+	batch, err := s.repo.PreemptWaitingSMSBatch(ctx, s.cfg.BatchSize)
 	cancel()
-	switch err {
-	case nil:
-		// 执行发送
-		// 这个也可以做成配置的
-		ctx, cancel = context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
-		err = s.svc.Send(ctx, as.TplId, as.Args, as.Numbers...)
-		if err != nil {
-			// 啥也不需要干
-			s.l.Error("执行异步发送短信失败",
-				logger.Error(err),
-				logger.Int64("id", as.Id))
-		}
-		res := err == nil
-		// 通知 repository 我这一次的执行结果
-		err = s.repo.ReportScheduleResult(ctx, as.Id, res)
-		if err != nil {
-			s.l.Error("执行异步发送短信成功，但是标记数据库失败",
-				logger.Error(err),
-				logger.Bool("res", res),
-				logger.Int64("id", as.Id))
-		}
-	case repository.ErrWaitingSMSNotFound:
-		// 睡一秒。这个你可以自己决定
-		time.Sleep(time.Second)
-	default:
+	if err != nil {
 		// 正常来说应该是数据库那边出了问题，
 		// 但是为了尽量运行，还是要继续的
-		// 你可以稍微睡眠，也可以不睡眠
 		// 睡眠的话可以帮你规避掉短时间的网络抖动问题
 		s.l.Error("抢占异步发送短信任务失败",
 			logger.Error(err))
-		time.Sleep(time.Second)
+		time.Sleep(s.cfg.IdleInterval)
+		return
+	}
+	if len(batch) == 0 {
+		// 没有任务，睡一会。这个你可以自己决定
+		time.Sleep(s.cfg.IdleInterval)
+		return
+	}
+
+	var (
+		lock       sync.Mutex
+		successIds = make([]int64, 0, len(batch))
+		failedIds  = make([]int64, 0, len(batch))
+		eg         errgroup.Group
+	)
+	// 控制住并发度，避免一下子把服务商打爆
+	eg.SetLimit(s.cfg.Concurrency)
+	for _, as := range batch {
+		as := as
+		eg.Go(func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.SendTimeout)
+			defer cancel()
+			err := s.svc.Send(ctx, as.TplId, as.Args, as.Numbers...)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				// 啥也不需要干，到了时间会重试
+				s.l.Error("执行异步发送短信失败",
+					logger.Error(err),
+					logger.Int64("id", as.Id))
+				failedIds = append(failedIds, as.Id)
+				return nil
+			}
+			successIds = append(successIds, as.Id)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+	// 按照 ID 排序，更新数据库的时候加锁顺序一致，也方便排查问题
+	sort.Slice(successIds, func(i, j int) bool { return successIds[i] < successIds[j] })
+	sort.Slice(failedIds, func(i, j int) bool { return failedIds[i] < failedIds[j] })
+
+	// 通知 repository 这一批的执行结果
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = s.repo.ReportScheduleResults(ctx, successIds, failedIds)
+	if err != nil {
+		s.l.Error("执行异步发送短信完毕，但是标记数据库失败",
+			logger.Error(err),
+			logger.Int64("success", int64(len(successIds))),
+			logger.Int64("failed", int64(len(failedIds))))
 	}
 }
 
 func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
-	if s.needAsync() {
+	if s.decider.NeedAsync() {
 		// 需要异步发送，直接转储到数据库
 		err := s.repo.Add(ctx, domain.AsyncSms{
 			TplId:   tplId,
@@ -94,20 +210,8 @@ func (s *Service) Send(ctx context.Context, tplId string, args []string, numbers
 		})
 		return err
 	}
-	return s.svc.Send(ctx, tplId, args, numbers...)
-}
-
-// 提前引导你们，开始思考系统容错问题
-// 你们面试装逼，赢得竞争优势就靠这一类的东西
-func (s *Service) needAsync() bool {
-	// 这边就是你要设计的，各种判定要不要触发异步的方案
-	// 1. 基于响应时间的，平均响应时间
-	// 1.1 使用绝对阈值，比如说直接发送的时候，（连续一段时间，或者连续N个请求）响应时间超过了 500ms，然后后续请求转异步
-	// 1.2 变化趋势，比如说当前一秒钟内的所有请求的响应时间比上一秒钟增长了 X%，就转异步
-	// 2. 基于错误率：一段时间内，收到 err 的请求比率大于 X%，转异步
-
-	// 什么时候退出异步
-	// 1. 进入异步 N 分钟后
-	// 2. 保留 1% 的流量（或者更少），继续同步发送，判定响应时间/错误率
-	return true
+	start := s.decider.now()
+	err := s.svc.Send(ctx, tplId, args, numbers...)
+	s.decider.Report(s.decider.now().Sub(start), err)
+	return err
 }
@@ -0,0 +1,294 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	smsmocks "gitee.com/geekbang/basic-go/webook/internal/service/sms/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestService_Send(t *testing.T) {
+	policy := Policy{
+		Window:           time.Second * 10,
+		Buckets:          10,
+		MinRequests:      5,
+		LatencyThreshold: time.Millisecond * 500,
+		TrendRatio:       1,
+		ErrorRate:        0.2,
+		RecoverAfter:     time.Minute,
+		ProbeRatio:       0.01,
+	}
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.AsyncSmsRepository
+		// 用来控制概率，决定探测流量
+		rand float64
+		// 模拟发送的过程
+		before func(svc *Service, clock *fakeClock, fake *fakeSmsService)
+
+		wantErr   error
+		wantAsync bool
+		// 总共有多少请求落到了同步发送上
+		wantSyncCnt int
+	}{
+		{
+			name: "响应时间正常，同步发送",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				return repomocks.NewMockAsyncSmsRepository(ctrl)
+			},
+			before: func(svc *Service, clock *fakeClock, fake *fakeSmsService) {
+				fake.latency = time.Millisecond * 100
+				sendN(svc, 10)
+			},
+			wantSyncCnt: 11,
+		},
+		{
+			name: "平均响应时间超过阈值，转异步",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			rand: 0.5,
+			before: func(svc *Service, clock *fakeClock, fake *fakeSmsService) {
+				fake.latency = time.Second
+				sendN(svc, 5)
+			},
+			wantAsync:   true,
+			wantSyncCnt: 5,
+		},
+		{
+			name: "样本太少，不转异步",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				return repomocks.NewMockAsyncSmsRepository(ctrl)
+			},
+			rand: 0.5,
+			before: func(svc *Service, clock *fakeClock, fake *fakeSmsService) {
+				fake.latency = time.Second
+				sendN(svc, 3)
+			},
+			wantSyncCnt: 4,
+		},
+		{
+			name: "错误率过高，转异步",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			rand: 0.5,
+			before: func(svc *Service, clock *fakeClock, fake *fakeSmsService) {
+				fake.latency = time.Millisecond * 10
+				sendN(svc, 4)
+				fake.err = errors.New("服务商崩了")
+				sendN(svc, 2)
+			},
+			wantAsync:   true,
+			wantSyncCnt: 6,
+		},
+		{
+			name: "响应时间增长过快，转异步",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			rand: 0.5,
+			before: func(svc *Service, clock *fakeClock, fake *fakeSmsService) {
+				fake.latency = time.Millisecond * 50
+				sendN(svc, 5)
+				// 进入下一个桶
+				clock.Add(time.Second)
+				// 虽然没有超过绝对阈值，但是翻了好几倍
+				fake.latency = time.Millisecond * 200
+				sendN(svc, 1)
+			},
+			wantAsync:   true,
+			wantSyncCnt: 6,
+		},
+		{
+			name: "异步期间，保留探测流量",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				return repomocks.NewMockAsyncSmsRepository(ctrl)
+			},
+			// 命中了探测流量
+			rand: 0.001,
+			before: func(svc *Service, clock *fakeClock, fake *fakeSmsService) {
+				fake.latency = time.Second
+				sendN(svc, 5)
+			},
+			wantAsync:   true,
+			wantSyncCnt: 6,
+		},
+		{
+			name: "进入异步一段时间后，恢复同步",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			rand: 0.5,
+			before: func(svc *Service, clock *fakeClock, fake *fakeSmsService) {
+				fake.latency = time.Second
+				sendN(svc, 5)
+				sendN(svc, 1)
+				fake.latency = time.Millisecond * 10
+				clock.Add(time.Minute)
+			},
+			wantSyncCnt: 6,
+		},
+		{
+			name: "探测流量表明没有恢复，继续异步",
+			mock: func(ctrl *gomock.Controller) repository.AsyncSmsRepository {
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().Add(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			rand: 0.001,
+			before: func(svc *Service, clock *fakeClock, fake *fakeSmsService) {
+				fake.latency = time.Second
+				sendN(svc, 5)
+				// 快到恢复时间的时候，探测流量依旧很慢
+				clock.Add(time.Minute - time.Second*6)
+				sendN(svc, 5)
+				clock.Add(time.Second)
+				// 后面的请求不再走探测流量
+				svc.decider.rand = func() float64 {
+					return 0.5
+				}
+			},
+			wantAsync:   true,
+			wantSyncCnt: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			clock := &fakeClock{now: time.UnixMilli(1000000)}
+			fake := &fakeSmsService{clock: clock}
+			decider := NewDecider(policy)
+			decider.now = clock.Now
+			decider.rand = func() float64 {
+				return tc.rand
+			}
+			svc := newService(fake, tc.mock(ctrl), logger.NewNoOpLogger(),
+				Config{Provider: "fake"}, decider, nil)
+			tc.before(svc, clock, fake)
+			err := svc.Send(context.Background(), "123", []string{"123456"}, "15212345678")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantAsync, svc.decider.Async())
+			assert.Equal(t, tc.wantSyncCnt, fake.cnt)
+		})
+	}
+}
+
+func sendN(svc *Service, n int) {
+	for i := 0; i < n; i++ {
+		_ = svc.Send(context.Background(), "123", []string{"123456"}, "15212345678")
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeSmsService 模拟服务商，可以注入响应时间和错误
+// 响应时间是通过拨动时钟来模拟的，所以测试不需要真的睡眠
+type fakeSmsService struct {
+	clock   *fakeClock
+	latency time.Duration
+	err     error
+	cnt     int
+}
+
+func (f *fakeSmsService) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	f.cnt++
+	f.clock.Add(f.latency)
+	return f.err
+}
+
+func TestService_AsyncSend(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSmsRepository)
+	}{
+		{
+			name: "批量发送，部分失败",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSmsRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().PreemptWaitingSMSBatch(gomock.Any(), 10).
+					Return([]domain.AsyncSms{
+						{Id: 1, TplId: "123", Args: []string{"1"}, Numbers: []string{"152"}},
+						{Id: 2, TplId: "123", Args: []string{"2"}, Numbers: []string{"152"}},
+						{Id: 3, TplId: "123", Args: []string{"3"}, Numbers: []string{"152"}},
+					}, nil)
+				svc.EXPECT().Send(gomock.Any(), "123", []string{"1"}, "152").Return(nil)
+				svc.EXPECT().Send(gomock.Any(), "123", []string{"2"}, "152").
+					Return(errors.New("发送失败"))
+				svc.EXPECT().Send(gomock.Any(), "123", []string{"3"}, "152").Return(nil)
+				repo.EXPECT().ReportScheduleResults(gomock.Any(),
+					[]int64{1, 3}, []int64{2}).Return(nil)
+				return svc, repo
+			},
+		},
+		{
+			name: "没有任务",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSmsRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().PreemptWaitingSMSBatch(gomock.Any(), 10).
+					Return([]domain.AsyncSms{}, nil)
+				return svc, repo
+			},
+		},
+		{
+			name: "抢占失败",
+			mock: func(ctrl *gomock.Controller) (sms.Service, repository.AsyncSmsRepository) {
+				svc := smsmocks.NewMockService(ctrl)
+				repo := repomocks.NewMockAsyncSmsRepository(ctrl)
+				repo.EXPECT().PreemptWaitingSMSBatch(gomock.Any(), 10).
+					Return(nil, errors.New("mock db error"))
+				return svc, repo
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, repo := tc.mock(ctrl)
+			cfg := Config{
+				Provider:     "mock",
+				Policy:       DefaultPolicy(),
+				BatchSize:    10,
+				Concurrency:  2,
+				IdleInterval: time.Millisecond,
+				SendTimeout:  time.Second,
+			}
+			s := newService(svc, repo, logger.NewNoOpLogger(), cfg,
+				NewDecider(cfg.Policy), nil)
+			s.AsyncSend()
+		})
+	}
+}
@@ -0,0 +1,18 @@
+-- 额度的 key，也就是 sms:quota:业务:模板:日期
+local key = KEYS[1]
+-- 这一次要发送的条数
+local cnt = tonumber(ARGV[1])
+local quota = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local used = tonumber(redis.call("get", key) or "0")
+
+if used + cnt > quota then
+    -- 额度不够了
+    return -1
+end
+redis.call("incrby", key, cnt)
+if used == 0 then
+    -- 第一次用，设置过期时间
+    redis.call("expire", key, ttl)
+end
+return 0
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+)
+
+// ProviderService 装饰具体的服务商
+// 把我们内部的模板 ID 转换为这个服务商的模板 ID，并且带上模板的签名
+type ProviderService struct {
+	svc      sms.Service
+	provider string
+	tpls     *Templates
+}
+
+func NewProviderService(svc sms.Service, provider string, tpls *Templates) *ProviderService {
+	return &ProviderService{
+		svc:      svc,
+		provider: provider,
+		tpls:     tpls,
+	}
+}
+
+func (p *ProviderService) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	tpl, ok := p.tpls.Get(tplId)
+	if !ok {
+		// 没有注册的模板，认为调用者传的就是服务商的模板 ID
+		return p.svc.Send(ctx, tplId, args, numbers...)
+	}
+	if tpl.SignName != "" {
+		ctx = sms.WithSignName(ctx, tpl.SignName)
+	}
+	return p.svc.Send(ctx, tpl.ProviderTplId(p.provider), args, numbers...)
+}
@@ -2,13 +2,76 @@ package auth
 
 import (
 	"context"
+	_ "embed"
+	"errors"
+	"fmt"
 	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"time"
 )
 
+var (
+	//go:embed lua/quota.lua
+	luaQuota string
+
+	ErrInvalidToken    = errors.New("非法的短信模板 token")
+	ErrTokenExpired    = errors.New("短信模板 token 已经过期")
+	ErrUnknownTemplate = errors.New("未知的短信模板")
+	ErrBizNotAllowed   = errors.New("业务无权使用该短信模板")
+	ErrQuotaExceeded   = errors.New("短信模板今天的额度已经用完")
+)
+
+// quotaTTL 额度按天计算，多留一个小时，避免跨天的时候 key 提前过期
+const quotaTTL = time.Hour * 25
+
+// SMSService 业务方不能直接使用模板 ID，而是要先调用 Issue 申请一个 token，
+// 发送的时候传 token，这样就可以控制哪些业务能用哪些模板，以及每天能发多少
 type SMSService struct {
-	svc sms.Service
-	key []byte
+	svc  sms.Service
+	tpls *Templates
+	cmd  redis.Cmdable
+	key  []byte
+	// token 的有效期
+	expiration time.Duration
+	// 为了测试，可以替换掉
+	now func() time.Time
+}
+
+func NewSMSService(svc sms.Service,
+	tpls *Templates,
+	cmd redis.Cmdable,
+	key []byte,
+	expiration time.Duration) *SMSService {
+	return &SMSService{
+		svc:        svc,
+		tpls:       tpls,
+		cmd:        cmd,
+		key:        key,
+		expiration: expiration,
+		now:        time.Now,
+	}
+}
+
+// Issue 给业务方签发使用 tplId 的 token
+func (s *SMSService) Issue(ctx context.Context, biz string, tplId string) (string, error) {
+	tpl, ok := s.tpls.Get(tplId)
+	if !ok {
+		return "", fmt.Errorf("%w, 模板 %s", ErrUnknownTemplate, tplId)
+	}
+	if !tpl.Allow(biz) {
+		return "", fmt.Errorf("%w, 业务 %s, 模板 %s", ErrBizNotAllowed, biz, tplId)
+	}
+	now := s.now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.expiration)),
+		},
+		Tpl: tplId,
+		Biz: biz,
+	})
+	return token.SignedString(s.key)
 }
 
 func (s *SMSService) Send(ctx context.Context,
@@ -17,14 +80,48 @@ func (s *SMSService) Send(ctx context.Context,
 	var c Claims
 	_, err := jwt.ParseWithClaims(tplToken, &c, func(token *jwt.Token) (interface{}, error) {
 		return s.key, nil
-	})
+	}, jwt.WithTimeFunc(s.now), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case err != nil:
+		return fmt.Errorf("%w, %w", ErrInvalidToken, err)
+	}
+	// 模板的配置可能在签发 token 之后变了，所以要重新检查
+	tpl, ok := s.tpls.Get(c.Tpl)
+	if !ok {
+		return fmt.Errorf("%w, 模板 %s", ErrUnknownTemplate, c.Tpl)
+	}
+	if !tpl.Allow(c.Biz) {
+		return fmt.Errorf("%w, 业务 %s, 模板 %s", ErrBizNotAllowed, c.Biz, c.Tpl)
+	}
+	if tpl.DailyQuota <= 0 {
+		return s.svc.Send(ctx, tpl.Id, args, numbers...)
+	}
+	key := s.quotaKey(c.Biz, tpl.Id)
+	cnt := int64(len(numbers))
+	res, err := s.cmd.Eval(ctx, luaQuota, []string{key},
+		cnt, tpl.DailyQuota, int64(quotaTTL/time.Second)).Int()
 	if err != nil {
 		return err
 	}
-	return s.svc.Send(ctx, c.Tpl, args, numbers...)
+	if res == -1 {
+		return ErrQuotaExceeded
+	}
+	err = s.svc.Send(ctx, tpl.Id, args, numbers...)
+	if err != nil {
+		// 没发出去，把额度还回去，还失败了也没关系，顶多少发几条
+		_ = s.cmd.DecrBy(ctx, key, cnt).Err()
+	}
+	return err
+}
+
+func (s *SMSService) quotaKey(biz, tplId string) string {
+	return fmt.Sprintf("sms:quota:%s:%s:%s", biz, tplId, s.now().Format("20060102"))
 }
 
 type Claims struct {
 	jwt.RegisteredClaims
 	Tpl string
+	Biz string
 }
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache/redismocks"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	smsmocks "gitee.com/geekbang/basic-go/webook/internal/service/sms/mocks"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestSMSService_Send(t *testing.T) {
+	key := []byte("k6CswdUm77WKcbM68UQUuxVsHSpTCwgK")
+	now := time.Date(2023, 12, 1, 10, 0, 0, 0, time.Local)
+	tpls := NewTemplates([]Template{
+		{Id: "login_code", Bizs: []string{"login"}},
+		{Id: "notify", Bizs: []string{"notify"}, DailyQuota: 100},
+	})
+	quotaKey := "sms:quota:notify:notify:20231201"
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable)
+		// 签发 token 用的
+		biz   string
+		tplId string
+		// 签发之后过了多久才发送
+		after time.Duration
+		// 篡改 token
+		token func(token string) string
+
+		wantIssueErr error
+		wantErr      error
+	}{
+		{
+			name: "发送成功，不限额度",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "login_code",
+					[]string{"123456"}, "15212345678").Return(nil)
+				return svc, redismocks.NewMockCmdable(ctrl)
+			},
+			biz:   "login",
+			tplId: "login_code",
+		},
+		{
+			name: "发送成功，扣减额度",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "notify",
+					[]string{"123456"}, "15212345678").Return(nil)
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaQuota, []string{quotaKey},
+					int64(1), int64(100), int64(90000)).
+					Return(redis.NewCmdResult(int64(0), nil))
+				return svc, cmd
+			},
+			biz:   "notify",
+			tplId: "notify",
+		},
+		{
+			name: "发送失败，额度还回去",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable) {
+				svc := smsmocks.NewMockService(ctrl)
+				svc.EXPECT().Send(gomock.Any(), "notify",
+					[]string{"123456"}, "15212345678").Return(errors.New("发送失败"))
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaQuota, []string{quotaKey},
+					int64(1), int64(100), int64(90000)).
+					Return(redis.NewCmdResult(int64(0), nil))
+				cmd.EXPECT().DecrBy(gomock.Any(), quotaKey, int64(1)).
+					Return(redis.NewIntResult(0, nil))
+				return svc, cmd
+			},
+			biz:     "notify",
+			tplId:   "notify",
+			wantErr: errors.New("发送失败"),
+		},
+		{
+			name: "额度用完了",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable) {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cmd.EXPECT().Eval(gomock.Any(), luaQuota, []string{quotaKey},
+					int64(1), int64(100), int64(90000)).
+					Return(redis.NewCmdResult(int64(-1), nil))
+				return smsmocks.NewMockService(ctrl), cmd
+			},
+			biz:     "notify",
+			tplId:   "notify",
+			wantErr: ErrQuotaExceeded,
+		},
+		{
+			name: "token 过期",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable) {
+				return smsmocks.NewMockService(ctrl), redismocks.NewMockCmdable(ctrl)
+			},
+			biz:     "login",
+			tplId:   "login_code",
+			after:   time.Hour,
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name: "token 被篡改",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable) {
+				return smsmocks.NewMockService(ctrl), redismocks.NewMockCmdable(ctrl)
+			},
+			biz:   "login",
+			tplId: "login_code",
+			token: func(token string) string {
+				return token + "a"
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "未知的模板",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable) {
+				return smsmocks.NewMockService(ctrl), redismocks.NewMockCmdable(ctrl)
+			},
+			biz:          "login",
+			tplId:        "unknown",
+			wantIssueErr: ErrUnknownTemplate,
+		},
+		{
+			name: "业务无权使用模板",
+			mock: func(ctrl *gomock.Controller) (sms.Service, redis.Cmdable) {
+				return smsmocks.NewMockService(ctrl), redismocks.NewMockCmdable(ctrl)
+			},
+			biz:          "login",
+			tplId:        "notify",
+			wantIssueErr: ErrBizNotAllowed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, cmd := tc.mock(ctrl)
+			authSvc := NewSMSService(svc, tpls, cmd, key, time.Minute*30)
+			authSvc.now = func() time.Time {
+				return now
+			}
+			token, err := authSvc.Issue(context.Background(), tc.biz, tc.tplId)
+			assert.ErrorIs(t, err, tc.wantIssueErr)
+			if err != nil {
+				return
+			}
+			if tc.token != nil {
+				token = tc.token(token)
+			}
+			authSvc.now = func() time.Time {
+				return now.Add(tc.after)
+			}
+			err = authSvc.Send(context.Background(), token, []string{"123456"}, "15212345678")
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			if errors.Is(err, tc.wantErr) {
+				return
+			}
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestProviderService_Send(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	tpls := NewTemplates([]Template{
+		{
+			Id:        "login_code",
+			SignName:  "妙影科技",
+			Providers: []ProviderTemplate{{Provider: "tencent", TplId: "1877556"}},
+		},
+	})
+	svc := smsmocks.NewMockService(ctrl)
+	svc.EXPECT().Send(gomock.Any(), "1877556", []string{"123456"}, "15212345678").
+		DoAndReturn(func(ctx context.Context, tplId string, args []string, numbers ...string) error {
+			assert.Equal(t, "妙影科技", sms.SignName(ctx, "默认签名"))
+			return nil
+		})
+	// 没有注册的模板，原样传下去
+	svc.EXPECT().Send(gomock.Any(), "other", []string{"123456"}, "15212345678").
+		DoAndReturn(func(ctx context.Context, tplId string, args []string, numbers ...string) error {
+			assert.Equal(t, "默认签名", sms.SignName(ctx, "默认签名"))
+			return nil
+		})
+	p := NewProviderService(svc, "tencent", tpls)
+	require.NoError(t, p.Send(context.Background(), "login_code", []string{"123456"}, "15212345678"))
+	require.NoError(t, p.Send(context.Background(), "other", []string{"123456"}, "15212345678"))
+}
@@ -0,0 +1,55 @@
+package auth
+
+import "github.com/ecodeclub/ekit/slice"
+
+// Template 短信模板
+// Id 是我们内部的模板 ID，和具体的服务商无关
+type Template struct {
+	Id string `yaml:"id"`
+	// Providers 各个服务商那边的模板 ID，没有配置的服务商就直接用 Id
+	Providers []ProviderTemplate `yaml:"providers"`
+	// SignName 签名，为空就使用服务商自己配置的签名
+	SignName string `yaml:"signName"`
+	// Bizs 允许使用这个模板的业务
+	Bizs []string `yaml:"bizs"`
+	// DailyQuota 每个业务每天最多可以发送多少条，0 就是不限制
+	DailyQuota int64 `yaml:"dailyQuota"`
+}
+
+// ProviderTemplate 不用 map 是因为 viper 会把 map 的 key 都转成小写
+type ProviderTemplate struct {
+	Provider string `yaml:"provider"`
+	TplId    string `yaml:"tplId"`
+}
+
+func (t Template) Allow(biz string) bool {
+	return slice.Contains[string](t.Bizs, biz)
+}
+
+// ProviderTplId 在 provider 那边的模板 ID
+func (t Template) ProviderTplId(provider string) string {
+	for _, p := range t.Providers {
+		if p.Provider == provider {
+			return p.TplId
+		}
+	}
+	return t.Id
+}
+
+// Templates 模板的注册中心，模板都是配置好的，初始化之后就不会再修改，所以不需要加锁
+type Templates struct {
+	tpls map[string]Template
+}
+
+func NewTemplates(tpls []Template) *Templates {
+	res := make(map[string]Template, len(tpls))
+	for _, tpl := range tpls {
+		res[tpl.Id] = tpl
+	}
+	return &Templates{tpls: res}
+}
+
+func (t *Templates) Get(id string) (Template, bool) {
+	tpl, ok := t.tpls[id]
+	return tpl, ok
+}
@@ -0,0 +1,142 @@
+package failover
+
+import (
+	"sort"
+	"time"
+)
+
+type breakerState int32
+
+const (
+	// stateClosed 正常状态，请求都可以过去
+	stateClosed breakerState = iota
+	// stateHalfOpen 熔断一段时间后，放少量探测请求过去
+	stateHalfOpen
+	// stateOpen 熔断，请求不会发过去
+	stateOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateClosed:
+		return "closed"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
+
+// sample 一次请求的结果
+type sample struct {
+	latency time.Duration
+	failed  bool
+	// 请求结束的时间，过期的样本不参与计算
+	at time.Time
+}
+
+// breaker 每个服务商一个，同时记录了健康度的统计数据
+// 非线程安全，由外面加锁
+type breaker struct {
+	state breakerState
+	// 进入 open 状态的时间
+	openedAt time.Time
+	// half-open 状态下，已经放出去还没有结果的探测请求
+	probing int
+	// half-open 状态下，连续成功的探测请求
+	probeSuccess int
+
+	// 最近的请求结果，环形数组
+	samples []sample
+	next    int
+	full    bool
+}
+
+func newBreaker(windowSize int) *breaker {
+	return &breaker{
+		samples: make([]sample, windowSize),
+	}
+}
+
+func (b *breaker) record(s sample) {
+	b.samples[b.next] = s
+	b.next = (b.next + 1) % len(b.samples)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// window 返回 since 之后的样本
+// 健康度低的服务商分不到流量，样本也就不会更新，
+// 所以要让旧样本过期，这样过一段时间之后它就又有机会分到流量了
+func (b *breaker) window(since time.Time) []sample {
+	all := b.samples[:b.next]
+	if b.full {
+		all = b.samples
+	}
+	res := make([]sample, 0, len(all))
+	for _, s := range all {
+		if s.at.After(since) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func (b *breaker) reset() {
+	b.next = 0
+	b.full = false
+	b.probing = 0
+	b.probeSuccess = 0
+}
+
+// errorRate 窗口内的错误率，以及样本数量
+func errorRate(w []sample) (float64, int) {
+	if len(w) == 0 {
+		return 0, 0
+	}
+	failed := 0
+	for _, s := range w {
+		if s.failed {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(w)), len(w)
+}
+
+// percentile 窗口内响应时间的分位数，p 的取值是 (0, 1]
+func percentile(w []sample, p float64) time.Duration {
+	if len(w) == 0 {
+		return 0
+	}
+	latencies := make([]time.Duration, len(w))
+	for i, s := range w {
+		latencies[i] = s.latency
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	idx := int(float64(len(latencies))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(latencies) {
+		idx = len(latencies) - 1
+	}
+	return latencies[idx]
+}
+
+// score 健康度，取值是 [0, 1]，越大越健康
+// 成功率 × 响应时间的惩罚系数，p90 超过了 target 就按比例打折
+func score(w []sample, target time.Duration) float64 {
+	rate, cnt := errorRate(w)
+	if cnt == 0 {
+		// 没有数据的，认为是健康的，这样新加入的服务商也能分到流量
+		return 1
+	}
+	res := 1 - rate
+	if p90 := percentile(w, 0.9); p90 > target && target > 0 {
+		res = res * float64(target) / float64(p90)
+	}
+	return res
+}
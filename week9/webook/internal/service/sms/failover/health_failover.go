@@ -0,0 +1,233 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNoAvailableProvider = errors.New("所有服务商都被熔断了")
+
+// Provider 带名字的服务商，名字用在日志和监控里面
+type Provider struct {
+	Name string
+	Svc  sms.Service
+}
+
+type HealthConfig struct {
+	// WindowSize 根据最近多少次请求来计算健康度
+	WindowSize int `yaml:"windowSize"`
+	// SampleTTL 超过这个时间的请求不再参与计算
+	SampleTTL time.Duration `yaml:"sampleTTL"`
+	// MinRequests 至少要有这么多次请求才会触发熔断
+	MinRequests int `yaml:"minRequests"`
+	// ErrorRate 错误率达到这个值就熔断
+	ErrorRate float64 `yaml:"errorRate"`
+	// LatencyTarget p90 响应时间超过这个值，健康度就按比例打折
+	LatencyTarget time.Duration `yaml:"latencyTarget"`
+	// OpenTimeout 熔断之后，过多久进入 half-open 状态
+	OpenTimeout time.Duration `yaml:"openTimeout"`
+	// HalfOpenProbes half-open 状态下，连续成功这么多次探测请求，就恢复
+	HalfOpenProbes int `yaml:"halfOpenProbes"`
+}
+
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		WindowSize:     100,
+		SampleTTL:      time.Minute,
+		MinRequests:    10,
+		ErrorRate:      0.5,
+		LatencyTarget:  time.Millisecond * 500,
+		OpenTimeout:    time.Second * 30,
+		HalfOpenProbes: 3,
+	}
+}
+
+// WithDefaults 没有配置的字段，使用 DefaultHealthConfig 里面的值
+func (c HealthConfig) WithDefaults() HealthConfig {
+	def := DefaultHealthConfig()
+	if c.WindowSize == 0 {
+		c.WindowSize = def.WindowSize
+	}
+	if c.SampleTTL == 0 {
+		c.SampleTTL = def.SampleTTL
+	}
+	if c.MinRequests == 0 {
+		c.MinRequests = def.MinRequests
+	}
+	if c.ErrorRate == 0 {
+		c.ErrorRate = def.ErrorRate
+	}
+	if c.LatencyTarget == 0 {
+		c.LatencyTarget = def.LatencyTarget
+	}
+	if c.OpenTimeout == 0 {
+		c.OpenTimeout = def.OpenTimeout
+	}
+	if c.HalfOpenProbes == 0 {
+		c.HalfOpenProbes = def.HalfOpenProbes
+	}
+	return c
+}
+
+// HealthFailoverSMSService 根据健康度来挑选服务商，每个服务商都有自己的熔断器
+// 1. 熔断器关闭的服务商，按照健康度从高到低依次尝试；
+// 2. 熔断器 half-open 的服务商，会优先放一个请求过去探测，探测失败了也还会继续尝试别的服务商；
+// 3. 熔断器打开的服务商，不会有请求。
+type HealthFailoverSMSService struct {
+	providers []Provider
+	breakers  []*breaker
+	cfg       HealthConfig
+	l         logger.LoggerV1
+	metrics   *BreakerMetrics
+	// 熔断器的状态都是在内存里面的，一把锁保护起来就可以了
+	lock sync.Mutex
+	// 为了测试，可以替换掉
+	now func() time.Time
+}
+
+// NewHealthFailoverSMSService metrics 可以为 nil
+func NewHealthFailoverSMSService(providers []Provider,
+	cfg HealthConfig,
+	l logger.LoggerV1,
+	metrics *BreakerMetrics) *HealthFailoverSMSService {
+	breakers := make([]*breaker, len(providers))
+	for i, p := range providers {
+		breakers[i] = newBreaker(cfg.WindowSize)
+		if metrics != nil {
+			metrics.init(p.Name)
+		}
+	}
+	return &HealthFailoverSMSService{
+		providers: providers,
+		breakers:  breakers,
+		cfg:       cfg,
+		l:         l,
+		metrics:   metrics,
+		now:       time.Now,
+	}
+}
+
+func (h *HealthFailoverSMSService) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	candidates := h.pick()
+	if len(candidates) == 0 {
+		return ErrNoAvailableProvider
+	}
+	var err error
+	for _, idx := range candidates {
+		start := h.now()
+		err = h.providers[idx].Svc.Send(ctx, tplId, args, numbers...)
+		h.report(idx, h.now().Sub(start), err)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			// 调用者设置的超时时间到了，或者调用者主动取消了，HTTP 的服务商会把它们包装起来
+			return err
+		}
+		h.l.Warn("短信服务商发送失败，尝试下一个",
+			logger.String("provider", h.providers[idx].Name),
+			logger.Error(err))
+	}
+	return err
+}
+
+// pick 返回这一次请求要依次尝试的服务商的下标
+func (h *HealthFailoverSMSService) pick() []int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	now := h.now()
+	var probes []int
+	closed := make([]int, 0, len(h.providers))
+	for i, b := range h.breakers {
+		if b.state == stateOpen && now.Sub(b.openedAt) >= h.cfg.OpenTimeout {
+			h.transit(i, stateHalfOpen)
+		}
+		switch b.state {
+		case stateClosed:
+			closed = append(closed, i)
+		case stateHalfOpen:
+			// 同一时刻只放一个探测请求
+			if b.probing == 0 {
+				b.probing++
+				probes = append(probes, i)
+			}
+		}
+	}
+	scores := make([]float64, len(h.breakers))
+	since := now.Add(-h.cfg.SampleTTL)
+	for _, i := range closed {
+		scores[i] = score(h.breakers[i].window(since), h.cfg.LatencyTarget)
+	}
+	sort.SliceStable(closed, func(i, j int) bool {
+		return scores[closed[i]] > scores[closed[j]]
+	})
+	return append(probes, closed...)
+}
+
+func (h *HealthFailoverSMSService) report(idx int, latency time.Duration, err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	b := h.breakers[idx]
+	if errors.Is(err, context.Canceled) {
+		// 调用者主动取消，不算服务商的问题
+		if b.state == stateHalfOpen && b.probing > 0 {
+			b.probing--
+		}
+		return
+	}
+	failed := err != nil
+	switch b.state {
+	case stateHalfOpen:
+		if b.probing > 0 {
+			b.probing--
+		}
+		if failed {
+			h.transit(idx, stateOpen)
+			return
+		}
+		b.probeSuccess++
+		if b.probeSuccess >= h.cfg.HalfOpenProbes {
+			h.transit(idx, stateClosed)
+		}
+	case stateClosed:
+		now := h.now()
+		b.record(sample{latency: latency, failed: failed, at: now})
+		rate, cnt := errorRate(b.window(now.Add(-h.cfg.SampleTTL)))
+		if cnt >= h.cfg.MinRequests && rate >= h.cfg.ErrorRate {
+			h.transit(idx, stateOpen)
+		}
+	default:
+		// open 状态下不会有请求，除非是在熔断前就发出去的，忽略就可以
+	}
+}
+
+func (h *HealthFailoverSMSService) transit(idx int, state breakerState) {
+	b := h.breakers[idx]
+	from := b.state
+	b.state = state
+	switch state {
+	case stateOpen:
+		b.openedAt = h.now()
+		b.probing = 0
+		b.probeSuccess = 0
+	case stateHalfOpen:
+		b.probing = 0
+		b.probeSuccess = 0
+	case stateClosed:
+		// 之前的数据已经没有参考价值了
+		b.reset()
+	}
+	name := h.providers[idx].Name
+	h.l.Warn("短信服务商熔断器状态变更",
+		logger.String("provider", name),
+		logger.String("from", from.String()),
+		logger.String("to", state.String()))
+	if h.metrics != nil {
+		h.metrics.set(name, state)
+	}
+}
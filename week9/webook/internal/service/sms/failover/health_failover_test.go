@@ -0,0 +1,210 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHealthFailoverSMSService_Send(t *testing.T) {
+	cfg := HealthConfig{
+		WindowSize:     10,
+		SampleTTL:      time.Minute,
+		MinRequests:    4,
+		ErrorRate:      0.5,
+		LatencyTarget:  time.Millisecond * 500,
+		OpenTimeout:    time.Second * 30,
+		HalfOpenProbes: 2,
+	}
+	errSend := errors.New("发送失败")
+	errWrappedTimeout := fmt.Errorf("发送短信请求失败 %w", context.DeadlineExceeded)
+	testCases := []struct {
+		name string
+		// 模拟前面的请求
+		before func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider)
+
+		wantErr error
+		// 最后一次请求，各个服务商被调用的次数
+		wantA int
+		wantB int
+		// 最后 A 的熔断器状态
+		wantState breakerState
+	}{
+		{
+			name: "优先使用第一个",
+			before: func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider) {
+			},
+			wantA: 1,
+		},
+		{
+			name: "响应时间太长，健康度下降，流量切到另一个",
+			before: func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider) {
+				a.latency = time.Second
+				sendN(svc, 1)
+				a.reset()
+				b.reset()
+			},
+			wantB: 1,
+		},
+		{
+			name: "失败之后，流量切到另一个",
+			before: func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider) {
+				a.err = errSend
+				sendN(svc, 1)
+				a.reset()
+				b.reset()
+			},
+			wantB: 1,
+		},
+		{
+			name: "失败的样本过期之后，重新分到流量",
+			before: func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider) {
+				a.err = errSend
+				sendN(svc, 1)
+				clock.Add(cfg.SampleTTL)
+				a.err = nil
+				a.reset()
+				b.reset()
+			},
+			wantA: 1,
+		},
+		{
+			name: "熔断一段时间之后，探测成功，恢复",
+			before: func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider) {
+				svc.transit(0, stateOpen)
+				// 还没到时间，不会探测
+				sendN(svc, 1)
+				clock.Add(cfg.OpenTimeout)
+				// 第一次探测
+				sendN(svc, 1)
+				a.reset()
+				b.reset()
+			},
+			// 第二次探测也成功了，熔断器关闭
+			wantA:     1,
+			wantState: stateClosed,
+		},
+		{
+			name: "熔断一段时间之后，探测失败，继续熔断",
+			before: func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider) {
+				svc.transit(0, stateOpen)
+				clock.Add(cfg.OpenTimeout)
+				a.err = errSend
+			},
+			// 探测请求失败之后，还是会继续尝试 B
+			wantA:     1,
+			wantB:     1,
+			wantState: stateOpen,
+		},
+		{
+			name: "错误率太高，全部熔断",
+			before: func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider) {
+				a.err = errSend
+				b.err = errSend
+				sendN(svc, 4)
+				a.reset()
+				b.reset()
+			},
+			wantErr:   ErrNoAvailableProvider,
+			wantState: stateOpen,
+		},
+		{
+			name: "调用者超时，不再尝试别的",
+			before: func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider) {
+				a.err = context.DeadlineExceeded
+			},
+			wantErr: context.DeadlineExceeded,
+			wantA:   1,
+		},
+		{
+			name: "调用者超时，服务商包装了错误，不再尝试别的",
+			before: func(svc *HealthFailoverSMSService, clock *fakeClock, a, b *fakeProvider) {
+				a.err = errWrappedTimeout
+			},
+			wantErr: errWrappedTimeout,
+			wantA:   1,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.UnixMilli(1000000)}
+			a := &fakeProvider{clock: clock}
+			b := &fakeProvider{clock: clock}
+			svc := NewHealthFailoverSMSService([]Provider{
+				{Name: "a", Svc: a},
+				{Name: "b", Svc: b},
+			}, cfg, logger.NewNoOpLogger(), nil)
+			svc.now = clock.Now
+			tc.before(svc, clock, a, b)
+			err := svc.Send(context.Background(), "123", []string{"123456"}, "15212345678")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantA, a.cnt)
+			assert.Equal(t, tc.wantB, b.cnt)
+			assert.Equal(t, tc.wantState, svc.breakers[0].state)
+		})
+	}
+}
+
+func TestBreaker_score(t *testing.T) {
+	now := time.UnixMilli(1000000)
+	b := newBreaker(10)
+	// 没有数据，认为是健康的
+	assert.Equal(t, 1.0, score(b.window(now), time.Millisecond*100))
+	// 这个会被挤出窗口
+	b.record(sample{latency: time.Second, failed: true, at: now})
+	for i := 0; i < 8; i++ {
+		b.record(sample{latency: time.Millisecond * 50, at: now})
+	}
+	b.record(sample{latency: time.Millisecond * 200, failed: true, at: now})
+	b.record(sample{latency: time.Millisecond * 400, at: now})
+	w := b.window(now.Add(-time.Minute))
+	assert.Equal(t, time.Millisecond*200, percentile(w, 0.9))
+	// 错误率 10%，p90 是 200ms，超过了 100ms 的目标，打五折
+	assert.InDelta(t, 0.45, score(w, time.Millisecond*100), 0.0001)
+	// 目标足够宽松，就只看错误率
+	assert.InDelta(t, 0.9, score(w, time.Second), 0.0001)
+	// 样本都过期了
+	assert.Empty(t, b.window(now))
+}
+
+func sendN(svc *HealthFailoverSMSService, n int) {
+	for i := 0; i < n; i++ {
+		_ = svc.Send(context.Background(), "123", []string{"123456"}, "15212345678")
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeProvider 模拟服务商，响应时间通过拨动时钟来模拟
+type fakeProvider struct {
+	clock   *fakeClock
+	latency time.Duration
+	err     error
+	cnt     int
+}
+
+func (f *fakeProvider) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
+	f.cnt++
+	f.clock.Add(f.latency)
+	return f.err
+}
+
+// reset 只清空调用次数
+func (f *fakeProvider) reset() {
+	f.cnt = 0
+}
@@ -0,0 +1,49 @@
+package failover
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BreakerMetrics 上报每个服务商熔断器的状态，0 是 closed，1 是 half-open，2 是 open
+// 以及状态变更的次数。全局创建一个就可以
+type BreakerMetrics struct {
+	state  *prometheus.GaugeVec
+	change *prometheus.CounterVec
+}
+
+func NewBreakerMetrics(namespace string,
+	subsystem string,
+	instanceId string,
+	name string) *BreakerMetrics {
+	labels := map[string]string{
+		"instance_id": instanceId,
+	}
+	state := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   subsystem,
+		Name:        name,
+		Help:        "短信服务商熔断器的状态，0 是 closed，1 是 half-open，2 是 open",
+		ConstLabels: labels,
+	}, []string{"provider"})
+	change := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   namespace,
+		Subsystem:   subsystem,
+		Name:        name + "_change",
+		Help:        "短信服务商熔断器状态变更次数",
+		ConstLabels: labels,
+	}, []string{"provider", "state"})
+	prometheus.MustRegister(state, change)
+	return &BreakerMetrics{
+		state:  state,
+		change: change,
+	}
+}
+
+func (m *BreakerMetrics) init(provider string) {
+	m.state.WithLabelValues(provider).Set(float64(stateClosed))
+}
+
+func (m *BreakerMetrics) set(provider string, state breakerState) {
+	m.state.WithLabelValues(provider).Set(float64(state))
+	m.change.WithLabelValues(provider, state.String()).Inc()
+}
@@ -0,0 +1,123 @@
+package httpsms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// Service 通用的 HTTP 短信服务，用来对接各种 webhook 或者没有 SDK 的小服务商
+// URL 和请求体都是 text/template 模板，可以引用 .TplId、.Args 和 .Numbers
+// 另外提供了 json 和 join 两个函数，比如说：
+//
+//	{"tpl": {{json .TplId}}, "to": {{json .Numbers}}, "code": {{json (index .Args 0)}}}
+type Service struct {
+	client  *http.Client
+	method  string
+	url     *template.Template
+	body    *template.Template
+	headers map[string]string
+
+	successField string
+	successValue string
+}
+
+type Config struct {
+	// Method 默认是 POST
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// Body 为空就不带请求体
+	Body string `yaml:"body"`
+	// SuccessField 响应 JSON 里面表示成功与否的字段，为空则只看 HTTP 状态码
+	SuccessField string `yaml:"successField"`
+	// SuccessValue SuccessField 的值等于它，才认为是发送成功
+	SuccessValue string `yaml:"successValue"`
+}
+
+// 模板里面可以用的数据
+type tplData struct {
+	TplId   string
+	Args    []string
+	Numbers []string
+}
+
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		val, err := json.Marshal(v)
+		return string(val), err
+	},
+	"join": strings.Join,
+}
+
+func NewService(client *http.Client, cfg Config) (*Service, error) {
+	urlTpl, err := template.New("url").Funcs(funcs).Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("解析 URL 模板失败 %w", err)
+	}
+	bodyTpl, err := template.New("body").Funcs(funcs).Parse(cfg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("解析请求体模板失败 %w", err)
+	}
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	return &Service{
+		client:       client,
+		method:       method,
+		url:          urlTpl,
+		body:         bodyTpl,
+		headers:      cfg.Headers,
+		successField: cfg.SuccessField,
+		successValue: cfg.SuccessValue,
+	}, nil
+}
+
+func (s *Service) Send(ctx context.Context, tplId string,
+	args []string, numbers ...string) error {
+	data := tplData{TplId: tplId, Args: args, Numbers: numbers}
+	var urlBuf, bodyBuf bytes.Buffer
+	if err := s.url.Execute(&urlBuf, data); err != nil {
+		return fmt.Errorf("渲染 URL 失败 %w", err)
+	}
+	if err := s.body.Execute(&bodyBuf, data); err != nil {
+		return fmt.Errorf("渲染请求体失败 %w", err)
+	}
+	var body io.Reader
+	if bodyBuf.Len() > 0 {
+		body = &bodyBuf
+	}
+	req, err := http.NewRequestWithContext(ctx, s.method, urlBuf.String(), body)
+	if err != nil {
+		return err
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("发送失败，HTTP 状态码 %d", resp.StatusCode)
+	}
+	if s.successField == "" {
+		return nil
+	}
+	var res map[string]any
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("解析响应失败 %w", err)
+	}
+	// 数字和字符串都统一转成字符串来比较
+	if val := fmt.Sprint(res[s.successField]); val != s.successValue {
+		return fmt.Errorf("发送失败，%s: %s", s.successField, val)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package httpsms
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestService_Send(t *testing.T) {
+	testCases := []struct {
+		name string
+		// 模拟服务商
+		handler func(t *testing.T) http.HandlerFunc
+		// URL 模板里面会拼上 httptest 的地址
+		cfg func(addr string) Config
+
+		wantErr bool
+	}{
+		{
+			name: "POST JSON 发送成功",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, http.MethodPost, r.Method)
+					assert.Equal(t, "/send/tpl_1", r.URL.Path)
+					assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+					body, err := io.ReadAll(r.Body)
+					require.NoError(t, err)
+					assert.JSONEq(t,
+						`{"to":["15212345678","15212345679"],"code":"123456"}`,
+						string(body))
+					_, _ = w.Write([]byte(`{"code":0,"msg":"ok"}`))
+				}
+			},
+			cfg: func(addr string) Config {
+				return Config{
+					URL: addr + "/send/{{.TplId}}",
+					Headers: map[string]string{
+						"Authorization": "Bearer token",
+						"Content-Type":  "application/json",
+					},
+					Body:         `{"to":{{json .Numbers}},"code":{{json (index .Args 0)}}}`,
+					SuccessField: "code",
+					SuccessValue: "0",
+				}
+			},
+		},
+		{
+			name: "GET，参数在 URL 里面",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, http.MethodGet, r.Method)
+					assert.Equal(t, "15212345678,15212345679", r.URL.Query().Get("to"))
+					assert.Equal(t, "123456", r.URL.Query().Get("args"))
+				}
+			},
+			cfg: func(addr string) Config {
+				return Config{
+					Method: http.MethodGet,
+					URL:    addr + `/send?to={{join .Numbers "," | urlquery}}&args={{join .Args "," | urlquery}}`,
+				}
+			},
+		},
+		{
+			name: "HTTP 状态码不对",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			},
+			cfg: func(addr string) Config {
+				return Config{URL: addr + "/send"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "业务码表示失败",
+			handler: func(t *testing.T) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write([]byte(`{"code":1001,"msg":"余额不足"}`))
+				}
+			},
+			cfg: func(addr string) Config {
+				return Config{
+					URL:          addr + "/send",
+					SuccessField: "code",
+					SuccessValue: "0",
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler(t))
+			defer server.Close()
+			svc, err := NewService(server.Client(), tc.cfg(server.URL))
+			require.NoError(t, err)
+			err = svc.Send(context.Background(), "tpl_1",
+				[]string{"123456"}, "15212345678", "15212345679")
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestNewService(t *testing.T) {
+	_, err := NewService(http.DefaultClient, Config{URL: "{{.TplId"})
+	assert.Error(t, err)
+}
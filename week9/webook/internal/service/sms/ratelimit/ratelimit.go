@@ -16,17 +16,25 @@ var errLimited = errors.New("短信服务触发限流")
 type RatelimitSMSService struct {
 	svc     sms.Service
 	limiter ratelimit.Limiter
+	key     string
 }
 
 func NewRatelimitSMSService(svc sms.Service, limiter ratelimit.Limiter) *RatelimitSMSService {
+	return NewRatelimitSMSServiceV1(svc, limiter, key)
+}
+
+// NewRatelimitSMSServiceV1 有多个服务商的时候，每个服务商要用自己的 key 来限流
+func NewRatelimitSMSServiceV1(svc sms.Service, limiter ratelimit.Limiter,
+	key string) *RatelimitSMSService {
 	return &RatelimitSMSService{
 		svc:     svc,
 		limiter: limiter,
+		key:     key,
 	}
 }
 
 func (r *RatelimitSMSService) Send(ctx context.Context, tplId string, args []string, numbers ...string) error {
-	limited, err := r.limiter.Limit(ctx, key)
+	limited, err := r.limiter.Limit(ctx, r.key)
 	if err != nil {
 		return fmt.Errorf("短信服务判断是否限流异常 %w", err)
 	}
@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/aliyun"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/httpsms"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/localsms"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/tencent"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
+	tencentSMS "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"net/http"
+	"os"
+	"time"
+)
+
+func newMemoryService(cfg ProviderConfig) (sms.Service, error) {
+	return localsms.NewService(), nil
+}
+
+func newTencentService(cfg ProviderConfig) (sms.Service, error) {
+	type Config struct {
+		// 密钥不建议放在配置文件里面，为空的话就从环境变量里面读取
+		SecretId  string `yaml:"secretId"`
+		SecretKey string `yaml:"secretKey"`
+		Region    string `yaml:"region"`
+		AppId     string `yaml:"appId"`
+		SignName  string `yaml:"signName"`
+	}
+	c := Config{
+		SecretId:  os.Getenv("SMS_SECRET_ID"),
+		SecretKey: os.Getenv("SMS_SECRET_KEY"),
+		Region:    "ap-nanjing",
+	}
+	if err := cfg.Decode(&c); err != nil {
+		return nil, err
+	}
+	if c.SecretId == "" || c.SecretKey == "" {
+		return nil, errors.New("没有找到腾讯云短信的密钥")
+	}
+	client, err := tencentSMS.NewClient(common.NewCredential(c.SecretId, c.SecretKey),
+		c.Region, profile.NewClientProfile())
+	if err != nil {
+		return nil, err
+	}
+	return tencent.NewService(client, c.AppId, c.SignName), nil
+}
+
+func newAliyunService(cfg ProviderConfig) (sms.Service, error) {
+	c := aliyun.Config{
+		AccessKeyId:  os.Getenv("ALIYUN_ACCESS_KEY_ID"),
+		AccessSecret: os.Getenv("ALIYUN_ACCESS_KEY_SECRET"),
+	}
+	if err := cfg.Decode(&c); err != nil {
+		return nil, err
+	}
+	if c.AccessKeyId == "" || c.AccessSecret == "" {
+		return nil, errors.New("没有找到阿里云短信的密钥")
+	}
+	return aliyun.NewService(&http.Client{Timeout: time.Second * 3}, c), nil
+}
+
+func newHTTPService(cfg ProviderConfig) (sms.Service, error) {
+	var c httpsms.Config
+	if err := cfg.Decode(&c); err != nil {
+		return nil, err
+	}
+	return httpsms.NewService(&http.Client{Timeout: time.Second * 3}, c)
+}
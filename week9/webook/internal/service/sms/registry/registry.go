@@ -0,0 +1,202 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/async"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/auth"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/failover"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/metric"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/otel"
+	smsratelimit "gitee.com/geekbang/basic-go/webook/internal/service/sms/ratelimit"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/ratelimit"
+	"github.com/mitchellh/mapstructure"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+var (
+	ErrNoProvider = errors.New("没有配置任何短信服务商")
+	// ErrAsyncWithoutRepo 创建 Registry 的时候没有传异步短信的 repository，不能配置 async
+	ErrAsyncWithoutRepo = errors.New("没有异步短信的存储，不能配置 async")
+	// ErrInvalidWindowSize 健康度的统计窗口必须大于 0
+	ErrInvalidWindowSize = errors.New("failover.health.windowSize 必须大于 0")
+)
+
+// Factory 根据配置创建一个服务商的实现
+type Factory func(cfg ProviderConfig) (sms.Service, error)
+
+type ProviderConfig struct {
+	// Name 服务商的名字，同一个类型可以配置多个，比如说两个不同账号的腾讯云
+	Name string `yaml:"name"`
+	// Type 对应 Registry 里面注册的 Factory
+	Type string `yaml:"type"`
+	// Options 服务商自己的配置，由对应的 Factory 解析
+	Options map[string]any `yaml:"options"`
+}
+
+// Decode 把 Options 解析到 val 里面，字段按照 yaml 标签来匹配
+func (p ProviderConfig) Decode(val any) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          "yaml",
+		WeaklyTypedInput: true,
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		Result:           val,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(p.Options)
+}
+
+// Config 整个短信服务的配置
+// 装饰器从里到外依次是：服务商 → 模板转换 → 限流 → failover → 监控 → 链路追踪 → 异步
+// 除了服务商，其余都是可选的，为 nil 或者 false 就不启用
+type Config struct {
+	Providers []ProviderConfig `yaml:"providers"`
+	// Templates 配置了的话，发送的时候会把模板 ID 转换成各个服务商自己的模板 ID
+	Templates []auth.Template  `yaml:"templates"`
+	Ratelimit *RatelimitConfig `yaml:"ratelimit"`
+	Failover  FailoverConfig   `yaml:"failover"`
+	Metrics   *MetricsConfig   `yaml:"metrics"`
+	Otel      bool             `yaml:"otel"`
+	Async     *async.Config    `yaml:"async"`
+}
+
+// RatelimitConfig 每个服务商单独限流
+type RatelimitConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	Rate     int           `yaml:"rate"`
+}
+
+type FailoverConfig struct {
+	// Type 为 health 的时候，根据健康度挑选服务商，并且每个服务商都有熔断器；
+	// 为 timeout 的时候，连续超时 Threshold 次就切换服务商；
+	// 否则就是按照顺序轮流尝试
+	Type      string `yaml:"type"`
+	Threshold int32  `yaml:"threshold"`
+	// Health 没有配置的字段使用默认值
+	Health failover.HealthConfig `yaml:"health"`
+}
+
+type MetricsConfig struct {
+	Namespace  string `yaml:"namespace"`
+	Subsystem  string `yaml:"subsystem"`
+	InstanceId string `yaml:"instanceId"`
+	Name       string `yaml:"name"`
+}
+
+// Registry 服务商的注册中心，根据配置组装出完整的短信服务
+type Registry struct {
+	factories map[string]Factory
+	// 限流需要
+	cmd redis.Cmdable
+	// 异步需要
+	repo repository.AsyncSmsRepository
+	l    logger.LoggerV1
+}
+
+// NewRegistry 已经注册好了内置的服务商，你可以继续调用 Register 注册别的
+func NewRegistry(cmd redis.Cmdable,
+	repo repository.AsyncSmsRepository,
+	l logger.LoggerV1) *Registry {
+	r := &Registry{
+		factories: make(map[string]Factory, 4),
+		cmd:       cmd,
+		repo:      repo,
+		l:         l,
+	}
+	r.Register("memory", newMemoryService)
+	r.Register("tencent", newTencentService)
+	r.Register("aliyun", newAliyunService)
+	r.Register("http", newHTTPService)
+	return r
+}
+
+// Register 同名的会覆盖
+func (r *Registry) Register(typ string, f Factory) {
+	r.factories[typ] = f
+}
+
+func (r *Registry) Build(cfg Config) (sms.Service, error) {
+	if len(cfg.Providers) == 0 {
+		return nil, ErrNoProvider
+	}
+	if cfg.Async != nil && r.repo == nil {
+		return nil, ErrAsyncWithoutRepo
+	}
+	tpls := auth.NewTemplates(cfg.Templates)
+	svcs := make([]failover.Provider, 0, len(cfg.Providers))
+	for _, pc := range cfg.Providers {
+		f, ok := r.factories[pc.Type]
+		if !ok {
+			return nil, fmt.Errorf("未知的短信服务商类型 %s", pc.Type)
+		}
+		svc, err := f(pc)
+		if err != nil {
+			return nil, fmt.Errorf("初始化短信服务商 %s 失败 %w", pc.Name, err)
+		}
+		if len(cfg.Templates) > 0 {
+			svc = auth.NewProviderService(svc, pc.Name, tpls)
+		}
+		if cfg.Ratelimit != nil {
+			limiter := ratelimit.NewRedisSlidingWindowLimiter(r.cmd,
+				cfg.Ratelimit.Interval, cfg.Ratelimit.Rate)
+			svc = smsratelimit.NewRatelimitSMSServiceV1(svc, limiter, "sms_"+pc.Name)
+		}
+		svcs = append(svcs, failover.Provider{Name: pc.Name, Svc: svc})
+	}
+
+	res, err := r.buildFailover(svcs, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Metrics != nil {
+		res = metric.NewPrometheusDecorator(res, cfg.Metrics.Namespace,
+			cfg.Metrics.Subsystem, cfg.Metrics.InstanceId, cfg.Metrics.Name)
+	}
+	if cfg.Otel {
+		res = otel.NewService(res)
+	}
+	if cfg.Async != nil {
+		var modeMetrics *async.ModeMetrics
+		if cfg.Metrics != nil {
+			modeMetrics = async.NewModeMetrics(cfg.Metrics.Namespace,
+				cfg.Metrics.Subsystem, cfg.Metrics.InstanceId, cfg.Metrics.Name+"_async_mode")
+		}
+		res = async.NewAdaptiveService(res, r.repo, r.l, *cfg.Async, modeMetrics)
+	}
+	return res, nil
+}
+
+func (r *Registry) buildFailover(providers []failover.Provider, cfg Config) (sms.Service, error) {
+	if len(providers) == 1 {
+		// 只有一个服务商，也就没什么可以 failover 的
+		return providers[0].Svc, nil
+	}
+	svcs := make([]sms.Service, 0, len(providers))
+	for _, p := range providers {
+		svcs = append(svcs, p.Svc)
+	}
+	switch cfg.Failover.Type {
+	case "health":
+		healthCfg := cfg.Failover.Health.WithDefaults()
+		if healthCfg.WindowSize <= 0 {
+			return nil, ErrInvalidWindowSize
+		}
+		var metrics *failover.BreakerMetrics
+		if cfg.Metrics != nil {
+			metrics = failover.NewBreakerMetrics(cfg.Metrics.Namespace,
+				cfg.Metrics.Subsystem, cfg.Metrics.InstanceId, cfg.Metrics.Name+"_breaker")
+		}
+		return failover.NewHealthFailoverSMSService(providers, healthCfg, r.l, metrics), nil
+	case "timeout":
+		return failover.NewTimeoutFailoverSMSService(svcs, cfg.Failover.Threshold), nil
+	default:
+		return failover.NewFailoverSMSService(svcs), nil
+	}
+}
@@ -0,0 +1,190 @@
+package registry
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/async"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/auth"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/failover"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/httpsms"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/localsms"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/otel"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Build(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  Config
+
+		wantErr bool
+		// 检查组装出来的装饰器链
+		check func(t *testing.T, svc sms.Service)
+	}{
+		{
+			name: "只有一个服务商",
+			cfg: Config{
+				Providers: []ProviderConfig{{Name: "memory", Type: "memory"}},
+			},
+			check: func(t *testing.T, svc sms.Service) {
+				assert.IsType(t, &localsms.Service{}, svc)
+			},
+		},
+		{
+			name: "多个服务商，failover 加链路追踪",
+			cfg: Config{
+				Providers: []ProviderConfig{
+					{Name: "memory", Type: "memory"},
+					{Name: "webhook", Type: "http", Options: map[string]any{
+						"url":          "http://localhost/sms",
+						"successField": "code",
+						"successValue": "0",
+					}},
+				},
+				Failover: FailoverConfig{Type: "timeout", Threshold: 3},
+				Otel:     true,
+			},
+			check: func(t *testing.T, svc sms.Service) {
+				assert.IsType(t, &otel.Service{}, svc)
+			},
+		},
+		{
+			name: "配置了模板",
+			cfg: Config{
+				Providers: []ProviderConfig{{Name: "memory", Type: "memory"}},
+				Templates: []auth.Template{{Id: "login_code"}},
+			},
+			check: func(t *testing.T, svc sms.Service) {
+				assert.IsType(t, &auth.ProviderService{}, svc)
+			},
+		},
+		{
+			name: "根据健康度 failover",
+			cfg: Config{
+				Providers: []ProviderConfig{
+					{Name: "memory1", Type: "memory"},
+					{Name: "memory2", Type: "memory"},
+				},
+				Failover: FailoverConfig{Type: "health"},
+			},
+			check: func(t *testing.T, svc sms.Service) {
+				assert.IsType(t, &failover.HealthFailoverSMSService{}, svc)
+				assert.NoError(t, svc.Send(context.Background(), "123", []string{"1"}, "152"))
+			},
+		},
+		{
+			name: "根据健康度 failover，只配置了部分字段",
+			cfg: Config{
+				Providers: []ProviderConfig{
+					{Name: "memory1", Type: "memory"},
+					{Name: "memory2", Type: "memory"},
+				},
+				Failover: FailoverConfig{Type: "health",
+					Health: failover.HealthConfig{ErrorRate: 0.5}},
+			},
+			check: func(t *testing.T, svc sms.Service) {
+				assert.IsType(t, &failover.HealthFailoverSMSService{}, svc)
+				assert.NoError(t, svc.Send(context.Background(), "123", []string{"1"}, "152"))
+			},
+		},
+		{
+			name: "健康度的统计窗口小于 0",
+			cfg: Config{
+				Providers: []ProviderConfig{
+					{Name: "memory1", Type: "memory"},
+					{Name: "memory2", Type: "memory"},
+				},
+				Failover: FailoverConfig{Type: "health",
+					Health: failover.HealthConfig{WindowSize: -1}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "自定义的服务商",
+			cfg: Config{
+				Providers: []ProviderConfig{
+					{Name: "mine", Type: "custom"},
+					{Name: "memory", Type: "memory"},
+				},
+			},
+			check: func(t *testing.T, svc sms.Service) {
+				assert.IsType(t, &failover.FailoverSMSService{}, svc)
+				assert.NoError(t, svc.Send(context.Background(), "123", []string{"1"}, "152"))
+			},
+		},
+		{
+			name: "未知的服务商",
+			cfg: Config{
+				Providers: []ProviderConfig{{Name: "abc", Type: "abc"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "服务商配置有误",
+			cfg: Config{
+				Providers: []ProviderConfig{{Name: "webhook", Type: "http", Options: map[string]any{
+					"url": "{{.TplId",
+				}}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "没有服务商",
+			wantErr: true,
+		},
+		{
+			name: "没有异步短信的存储，但是配置了 async",
+			cfg: Config{
+				Providers: []ProviderConfig{{Name: "memory", Type: "memory"}},
+				Async:     &async.Config{},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRegistry(nil, nil, logger.NewNoOpLogger())
+			r.Register("custom", func(cfg ProviderConfig) (sms.Service, error) {
+				return localsms.NewService(), nil
+			})
+			svc, err := r.Build(tc.cfg)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.check(t, svc)
+		})
+	}
+}
+
+func TestProviderConfig_Decode(t *testing.T) {
+	pc := ProviderConfig{Options: map[string]any{
+		"method":       "GET",
+		"url":          "http://localhost",
+		"successvalue": 0,
+		"headers": map[string]any{
+			"Authorization": "abc",
+		},
+	}}
+	var c httpsms.Config
+	require.NoError(t, pc.Decode(&c))
+	assert.Equal(t, httpsms.Config{
+		Method:       "GET",
+		URL:          "http://localhost",
+		SuccessValue: "0",
+		Headers:      map[string]string{"Authorization": "abc"},
+	}, c)
+
+	var d struct {
+		Timeout time.Duration `yaml:"timeout"`
+	}
+	pc = ProviderConfig{Options: map[string]any{"timeout": "3s"}}
+	require.NoError(t, pc.Decode(&d))
+	assert.Equal(t, time.Second*3, d.Timeout)
+}
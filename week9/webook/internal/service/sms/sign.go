@@ -0,0 +1,20 @@
+package sms
+
+import "context"
+
+type signNameKey struct{}
+
+// WithSignName 指定这一次发送使用的签名
+// 不同的模板可能要用不同的签名，而 Service 的接口里面没有签名，所以只能通过 ctx 传下去
+func WithSignName(ctx context.Context, signName string) context.Context {
+	return context.WithValue(ctx, signNameKey{}, signName)
+}
+
+// SignName 返回 ctx 里面的签名，没有的话就用 def
+func SignName(ctx context.Context, def string) string {
+	val, ok := ctx.Value(signNameKey{}).(string)
+	if !ok || val == "" {
+		return def
+	}
+	return val
+}
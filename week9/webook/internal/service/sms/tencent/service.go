@@ -3,6 +3,7 @@ package tencent
 import (
 	"context"
 	"fmt"
+	mysms "gitee.com/geekbang/basic-go/webook/internal/service/sms"
 	"github.com/ecodeclub/ekit"
 	"github.com/ecodeclub/ekit/slice"
 	sms "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
@@ -33,7 +34,8 @@ func (s *Service) Send(ctx context.Context, tplId string,
 	req.SetContext(ctx)
 	req.TemplateParamSet = toStringPtrSlice(args)
 	req.TemplateId = ekit.ToPtr[string](tplId)
-	req.SignName = s.signName
+	// 模板可能指定了自己的签名
+	req.SignName = ekit.ToPtr[string](mysms.SignName(ctx, *s.signName))
 	resp, err := s.client.SendSms(req)
 	zap.L().Debug("调用腾讯短信服务",
 		zap.Any("req", req),
@@ -2,15 +2,15 @@ package service
 
 import (
 	"context"
-	"errors"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var ErrUserDuplicateEmail = repository.ErrUserDuplicate
-var ErrInvalidUserOrPassword = errors.New("邮箱或者密码不正确")
+var ErrUserDuplicateEmail = errs.UserDuplicateEmail
+var ErrInvalidUserOrPassword = errs.UserInvalidOrPassword
 
 // UserService 用户相关服务
 //
@@ -23,10 +23,6 @@ type UserService interface {
 	// UpdateNonSensitiveInfo 更新非敏感数据
 	// 你可以在这里进一步补充究竟哪些数据会被更新
 	UpdateNonSensitiveInfo(ctx context.Context, user domain.User) error
-
-	// FindOrCreateByWechat 查找或者初始化
-	// 随着业务增长，这边可以考虑拆分出去作为一个新的 Service
-	FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (domain.User, error)
 }
 
 type userService struct {
@@ -62,7 +58,11 @@ func (svc *userService) Signup(ctx context.Context, u domain.User) error {
 		return err
 	}
 	u.Password = string(hash)
-	return svc.repo.Create(ctx, u)
+	err = svc.repo.Create(ctx, u)
+	if err == repository.ErrUserDuplicate {
+		return ErrUserDuplicateEmail
+	}
+	return err
 }
 
 // FindOrCreate 如果手机号不存在，那么会初始化一个用户
@@ -100,33 +100,6 @@ func (svc *userService) Login(ctx context.Context,
 	return u, nil
 }
 
-func (svc *userService) FindOrCreateByWechat(ctx context.Context,
-	info domain.WechatInfo) (domain.User, error) {
-	// 类似于手机号的过程，大部分人只是扫码登录，也就是数据在我们这里是有的
-	u, err := svc.repo.FindByWechat(ctx, info.OpenId)
-	if err != repository.ErrUserNotFound {
-		return u, err
-	}
-	// 直接使用包变量
-	//zap.L().Info("微信用户未注册，注册新用户",
-	//	zap.Any("wechat_info", info))
-
-	// 使用注入的 logger
-	//svc.logger.Info("微信用户未注册，注册新用户",
-	//	zap.Any("wechat_info", info))
-
-	// 自定义的 logger
-	//logger.Logger.Info("微信用户未注册，注册新用户",
-	//	zap.Any("wechat_info", info))
-
-	// 要执行注册
-	err = svc.repo.Create(ctx, domain.User{
-		WechatInfo: info,
-	})
-	// 主从模式下，这里要从主库中读取，暂时我们不需要考虑
-	return svc.repo.FindByWechat(ctx, info.OpenId)
-}
-
 func (svc *userService) Profile(ctx context.Context,
 	id int64) (domain.User, error) {
 	// 在系统内部，基本上都是用 ID 的。
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	intrSvc "gitee.com/geekbang/basic-go/webook/interactive/service"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/events/user"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"time"
+)
+
+var (
+	ErrExportInProgress = errs.UserExportInProgress
+	ErrExportNotFound   = errs.UserExportNotFound
+	// ErrDeletionNotFound 没有申请注销，或者冷静期已经过了，不能撤销了
+	ErrDeletionNotFound = errs.UserDeletionNotFound
+)
+
+// UserDataService 导出个人数据和注销账号，真正的导出和删除都是定时任务来执行的
+//
+//go:generate mockgen -source=./user_data.go -package=svcmocks -destination=mocks/user_data.mock.go UserDataService
+type UserDataService interface {
+	// RequestExport 申请导出，上一次还没有完成的时候返回 ErrExportInProgress
+	RequestExport(ctx context.Context, uid int64) error
+	// LatestExport 最近一次导出，完成了的话会带上下载链接
+	LatestExport(ctx context.Context, uid int64) (domain.DataExport, string, error)
+	// RunExports 执行等待中的导出，返回成功的数量
+	RunExports(ctx context.Context, limit int) (int, error)
+
+	// RequestDeletion 申请注销，冷静期过了之后才会执行，重复申请不会重置冷静期
+	RequestDeletion(ctx context.Context, uid int64) (domain.AccountDeletion, error)
+	CancelDeletion(ctx context.Context, uid int64) error
+	// RunDeletions 执行冷静期已经过了的注销，返回成功的数量
+	RunDeletions(ctx context.Context, limit int) (int, error)
+}
+
+type userDataService struct {
+	repo        repository.UserDataRepository
+	userRepo    repository.UserRepository
+	oauth2Repo  repository.OAuth2BindingRepository
+	sessionRepo repository.SessionRepository
+	artRepo     repository.ArticleRepository
+	intrSvc     intrSvc.InteractiveService
+	producer    user.Producer
+	l           logger.LoggerV1
+	// gracePeriod 注销的冷静期
+	gracePeriod time.Duration
+	// urlExpiration 下载链接的有效期
+	urlExpiration time.Duration
+}
+
+func NewUserDataService(repo repository.UserDataRepository,
+	userRepo repository.UserRepository,
+	oauth2Repo repository.OAuth2BindingRepository,
+	sessionRepo repository.SessionRepository,
+	artRepo repository.ArticleRepository,
+	intrSvc intrSvc.InteractiveService,
+	producer user.Producer,
+	l logger.LoggerV1,
+	gracePeriod time.Duration) UserDataService {
+	return &userDataService{
+		repo:          repo,
+		userRepo:      userRepo,
+		oauth2Repo:    oauth2Repo,
+		sessionRepo:   sessionRepo,
+		artRepo:       artRepo,
+		intrSvc:       intrSvc,
+		producer:      producer,
+		l:             l,
+		gracePeriod:   gracePeriod,
+		urlExpiration: time.Minute * 15,
+	}
+}
+
+func (svc *userDataService) RequestExport(ctx context.Context, uid int64) error {
+	e, err := svc.repo.FindLatestExport(ctx, uid)
+	switch err {
+	case nil:
+		if e.Status == domain.DataExportStatusPending ||
+			e.Status == domain.DataExportStatusRunning {
+			return ErrExportInProgress
+		}
+	case repository.ErrUserDataNotFound:
+	default:
+		return err
+	}
+	_, err = svc.repo.CreateExport(ctx, uid)
+	return err
+}
+
+func (svc *userDataService) LatestExport(ctx context.Context, uid int64) (domain.DataExport, string, error) {
+	e, err := svc.repo.FindLatestExport(ctx, uid)
+	if err == repository.ErrUserDataNotFound {
+		return domain.DataExport{}, "", ErrExportNotFound
+	}
+	if err != nil || e.Status != domain.DataExportStatusDone {
+		return e, "", err
+	}
+	url, err := svc.repo.SignExportURL(ctx, e.Key, svc.urlExpiration)
+	return e, url, err
+}
+
+func (svc *userDataService) RunExports(ctx context.Context, limit int) (int, error) {
+	es, err := svc.repo.FindPendingExports(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+	cnt := 0
+	for _, e := range es {
+		ok, err := svc.repo.PreemptExport(ctx, e.Id)
+		if err != nil {
+			return cnt, err
+		}
+		if !ok {
+			// 被别的实例抢走了
+			continue
+		}
+		key := fmt.Sprintf("exports/%d/%d.zip", e.Uid, e.Id)
+		err = svc.export(ctx, e.Uid, key)
+		if err != nil {
+			svc.l.Error("导出个人数据失败",
+				logger.Int64("uid", e.Uid),
+				logger.Int64("id", e.Id),
+				logger.Error(err))
+			err = svc.failExport(e.Id)
+		} else {
+			err = svc.repo.CompleteExport(ctx, e.Id, key)
+			cnt++
+		}
+		if err != nil {
+			return cnt, err
+		}
+	}
+	return cnt, nil
+}
+
+// failExport 导出失败有可能就是因为 ctx 超时了，所以要用新的 ctx。
+// 这一步也失败了的话，任务会停在 running，等超时之后被重新抢占
+func (svc *userDataService) failExport(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	return svc.repo.FailExport(ctx, id)
+}
+
+// export 打包成一个 zip，里面每一类数据是一个 JSON 文件。
+// 阅读历史目前还没有落库，所以没有导出
+func (svc *userDataService) export(ctx context.Context, uid int64, key string) error {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	bindings, err := svc.oauth2Repo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	arts, err := svc.articles(ctx, uid)
+	if err != nil {
+		return err
+	}
+	likes, err := svc.intrSvc.Likes(ctx, uid)
+	if err != nil {
+		return err
+	}
+	collections, err := svc.intrSvc.Collections(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	profile := exportProfile{
+		Id:       u.Id,
+		Email:    u.Email,
+		Phone:    u.Phone,
+		Nickname: u.Nickname,
+		AboutMe:  u.AboutMe,
+		Ctime:    u.Ctime,
+	}
+	if !u.Birthday.IsZero() {
+		profile.Birthday = u.Birthday.Format(time.DateOnly)
+	}
+	for _, b := range bindings {
+		profile.OAuth2 = append(profile.OAuth2, exportOAuth2{
+			Provider: b.Info.Provider,
+			Nickname: b.Info.Nickname,
+			Ctime:    b.Ctime,
+		})
+	}
+	exportArts := make([]exportArticle, 0, len(arts))
+	for _, art := range arts {
+		exportArts = append(exportArts, exportArticle{
+			Id:      art.Id,
+			Title:   art.Title,
+			Content: art.Content,
+			Status:  art.Status.ToUint8(),
+			Ctime:   art.Ctime,
+			Utime:   art.Utime,
+		})
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name string
+		val  any
+	}{
+		{name: "profile.json", val: profile},
+		{name: "articles.json", val: exportArts},
+		{name: "likes.json", val: likes},
+		{name: "collections.json", val: collections},
+	} {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(f.val)
+		if err != nil {
+			return err
+		}
+	}
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+	return svc.repo.SaveExportFile(ctx, key, buf.Bytes())
+}
+
+// articles 制作库里面所有的文章
+func (svc *userDataService) articles(ctx context.Context, uid int64) ([]domain.Article, error) {
+	// 不能用 100，第一页的缓存里面没有完整的内容
+	const limit = 50
+	var res []domain.Article
+	for offset := 0; ; offset += limit {
+		arts, err := svc.artRepo.List(ctx, uid, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, arts...)
+		if len(arts) < limit {
+			return res, nil
+		}
+	}
+}
+
+func (svc *userDataService) RequestDeletion(ctx context.Context, uid int64) (domain.AccountDeletion, error) {
+	d, err := svc.repo.FindDeletion(ctx, uid)
+	switch err {
+	case nil:
+		if d.Status == domain.AccountDeletionStatusPending ||
+			d.Status == domain.AccountDeletionStatusRunning {
+			return d, nil
+		}
+	case repository.ErrUserDataNotFound:
+	default:
+		return domain.AccountDeletion{}, err
+	}
+	d = domain.AccountDeletion{
+		Uid:      uid,
+		Status:   domain.AccountDeletionStatusPending,
+		ExecTime: time.Now().Add(svc.gracePeriod),
+	}
+	return d, svc.repo.SaveDeletion(ctx, d)
+}
+
+func (svc *userDataService) CancelDeletion(ctx context.Context, uid int64) error {
+	ok, err := svc.repo.UpdateDeletionStatus(ctx, uid,
+		domain.AccountDeletionStatusPending, domain.AccountDeletionStatusCancelled)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDeletionNotFound
+	}
+	return nil
+}
+
+func (svc *userDataService) RunDeletions(ctx context.Context, limit int) (int, error) {
+	ds, err := svc.repo.FindDueDeletions(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+	cnt := 0
+	for _, d := range ds {
+		if d.Status == domain.AccountDeletionStatusPending {
+			ok, err := svc.repo.UpdateDeletionStatus(ctx, d.Uid,
+				domain.AccountDeletionStatusPending, domain.AccountDeletionStatusRunning)
+			if err != nil {
+				return cnt, err
+			}
+			if !ok {
+				// 刚刚撤销了，或者被别的实例抢走了
+				continue
+			}
+		}
+		err = svc.deleteUser(ctx, d.Uid)
+		if err != nil {
+			// 停在 running，下一次再执行，每一步都是幂等的
+			svc.l.Error("注销账号失败", logger.Int64("uid", d.Uid), logger.Error(err))
+			continue
+		}
+		_, err = svc.repo.UpdateDeletionStatus(ctx, d.Uid,
+			domain.AccountDeletionStatusRunning, domain.AccountDeletionStatusDone)
+		if err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+	return cnt, nil
+}
+
+// deleteUser 删除本模块的数据，别的模块的数据通过消息通知它们自己删
+func (svc *userDataService) deleteUser(ctx context.Context, uid int64) error {
+	err := svc.sessionRepo.DeleteByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	bindings, err := svc.oauth2Repo.FindByUid(ctx, uid)
+	if err != nil {
+		return err
+	}
+	for _, b := range bindings {
+		err = svc.oauth2Repo.Delete(ctx, uid, b.Info.Provider)
+		if err != nil && err != repository.ErrOAuth2BindingNotFound {
+			return err
+		}
+	}
+	err = svc.artRepo.DeleteByAuthor(ctx, uid)
+	if err != nil {
+		return err
+	}
+	err = svc.userRepo.Anonymize(ctx, uid)
+	if err != nil {
+		return err
+	}
+	return svc.producer.ProduceUserDeletedEvent(user.UserDeletedEvent{Uid: uid})
+}
+
+type exportProfile struct {
+	Id       int64          `json:"id"`
+	Email    string         `json:"email,omitempty"`
+	Phone    string         `json:"phone,omitempty"`
+	Nickname string         `json:"nickname,omitempty"`
+	AboutMe  string         `json:"aboutMe,omitempty"`
+	Birthday string         `json:"birthday,omitempty"`
+	OAuth2   []exportOAuth2 `json:"oauth2,omitempty"`
+	Ctime    time.Time      `json:"ctime"`
+}
+
+type exportOAuth2 struct {
+	Provider string    `json:"provider"`
+	Nickname string    `json:"nickname,omitempty"`
+	Ctime    time.Time `json:"ctime"`
+}
+
+type exportArticle struct {
+	Id      int64     `json:"id"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Status  uint8     `json:"status"`
+	Ctime   time.Time `json:"ctime"`
+	Utime   time.Time `json:"utime"`
+}
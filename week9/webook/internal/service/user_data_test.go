@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/events/user"
+	evtmocks "gitee.com/geekbang/basic-go/webook/internal/events/user/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	repomocks "gitee.com/geekbang/basic-go/webook/internal/repository/mocks"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestUserDataService_RequestExport(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserDataRepository
+
+		wantErr error
+	}{
+		{
+			name: "第一次导出",
+			mock: func(ctrl *gomock.Controller) repository.UserDataRepository {
+				repo := repomocks.NewMockUserDataRepository(ctrl)
+				repo.EXPECT().FindLatestExport(gomock.Any(), int64(1)).
+					Return(domain.DataExport{}, repository.ErrUserDataNotFound)
+				repo.EXPECT().CreateExport(gomock.Any(), int64(1)).Return(int64(10), nil)
+				return repo
+			},
+		},
+		{
+			name: "上一次已经完成",
+			mock: func(ctrl *gomock.Controller) repository.UserDataRepository {
+				repo := repomocks.NewMockUserDataRepository(ctrl)
+				repo.EXPECT().FindLatestExport(gomock.Any(), int64(1)).
+					Return(domain.DataExport{Id: 9, Status: domain.DataExportStatusDone}, nil)
+				repo.EXPECT().CreateExport(gomock.Any(), int64(1)).Return(int64(10), nil)
+				return repo
+			},
+		},
+		{
+			name: "上一次还没有完成",
+			mock: func(ctrl *gomock.Controller) repository.UserDataRepository {
+				repo := repomocks.NewMockUserDataRepository(ctrl)
+				repo.EXPECT().FindLatestExport(gomock.Any(), int64(1)).
+					Return(domain.DataExport{Id: 9, Status: domain.DataExportStatusRunning}, nil)
+				return repo
+			},
+			wantErr: ErrExportInProgress,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserDataService(tc.mock(ctrl), nil, nil, nil, nil, nil, nil,
+				logger.NewNoOpLogger(), time.Hour)
+			err := svc.RequestExport(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestUserDataService_RunExports(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockUserDataRepository(ctrl)
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	ctx, cancel := context.WithCancel(context.Background())
+	repo.EXPECT().FindPendingExports(gomock.Any(), 10).
+		Return([]domain.DataExport{{Id: 9, Uid: 1, Status: domain.DataExportStatusRunning}}, nil)
+	repo.EXPECT().PreemptExport(gomock.Any(), int64(9)).Return(true, nil)
+	// 导出到一半 ctx 超时了
+	userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+		DoAndReturn(func(ctx context.Context, id int64) (domain.User, error) {
+			cancel()
+			return domain.User{}, ctx.Err()
+		})
+	repo.EXPECT().FailExport(gomock.Any(), int64(9)).
+		DoAndReturn(func(ctx context.Context, id int64) error {
+			// 标记失败不能用已经超时了的 ctx
+			return ctx.Err()
+		})
+	svc := NewUserDataService(repo, userRepo, nil, nil, nil, nil, nil,
+		logger.NewNoOpLogger(), time.Hour)
+	cnt, err := svc.RunExports(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cnt)
+}
+
+func TestUserDataService_RunDeletions(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserDataRepository,
+			repository.UserRepository, repository.OAuth2BindingRepository,
+			repository.SessionRepository, repository.ArticleRepository, user.Producer)
+
+		wantCnt int
+		wantErr error
+	}{
+		{
+			name: "冷静期过了",
+			mock: func(ctrl *gomock.Controller) (repository.UserDataRepository,
+				repository.UserRepository, repository.OAuth2BindingRepository,
+				repository.SessionRepository, repository.ArticleRepository, user.Producer) {
+				repo := repomocks.NewMockUserDataRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				oauth2Repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				sessionRepo := repomocks.NewMockSessionRepository(ctrl)
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				repo.EXPECT().FindDueDeletions(gomock.Any(), gomock.Any(), 10).
+					Return([]domain.AccountDeletion{
+						{Uid: 1, Status: domain.AccountDeletionStatusPending, ExecTime: now},
+					}, nil)
+				repo.EXPECT().UpdateDeletionStatus(gomock.Any(), int64(1),
+					domain.AccountDeletionStatusPending, domain.AccountDeletionStatusRunning).
+					Return(true, nil)
+				sessionRepo.EXPECT().DeleteByUid(gomock.Any(), int64(1)).Return(nil)
+				oauth2Repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return([]domain.OAuth2Binding{{Uid: 1, Info: domain.OAuth2Info{Provider: "wechat"}}}, nil)
+				oauth2Repo.EXPECT().Delete(gomock.Any(), int64(1), "wechat").Return(nil)
+				artRepo.EXPECT().DeleteByAuthor(gomock.Any(), int64(1)).Return(nil)
+				userRepo.EXPECT().Anonymize(gomock.Any(), int64(1)).Return(nil)
+				producer.EXPECT().ProduceUserDeletedEvent(user.UserDeletedEvent{Uid: 1}).Return(nil)
+				repo.EXPECT().UpdateDeletionStatus(gomock.Any(), int64(1),
+					domain.AccountDeletionStatusRunning, domain.AccountDeletionStatusDone).
+					Return(true, nil)
+				return repo, userRepo, oauth2Repo, sessionRepo, artRepo, producer
+			},
+			wantCnt: 1,
+		},
+		{
+			name: "刚刚撤销了",
+			mock: func(ctrl *gomock.Controller) (repository.UserDataRepository,
+				repository.UserRepository, repository.OAuth2BindingRepository,
+				repository.SessionRepository, repository.ArticleRepository, user.Producer) {
+				repo := repomocks.NewMockUserDataRepository(ctrl)
+				repo.EXPECT().FindDueDeletions(gomock.Any(), gomock.Any(), 10).
+					Return([]domain.AccountDeletion{
+						{Uid: 1, Status: domain.AccountDeletionStatusPending, ExecTime: now},
+					}, nil)
+				repo.EXPECT().UpdateDeletionStatus(gomock.Any(), int64(1),
+					domain.AccountDeletionStatusPending, domain.AccountDeletionStatusRunning).
+					Return(false, nil)
+				return repo, nil, nil, nil, nil, nil
+			},
+		},
+		{
+			name: "上一次失败了，发消息又失败了",
+			mock: func(ctrl *gomock.Controller) (repository.UserDataRepository,
+				repository.UserRepository, repository.OAuth2BindingRepository,
+				repository.SessionRepository, repository.ArticleRepository, user.Producer) {
+				repo := repomocks.NewMockUserDataRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				oauth2Repo := repomocks.NewMockOAuth2BindingRepository(ctrl)
+				sessionRepo := repomocks.NewMockSessionRepository(ctrl)
+				artRepo := repomocks.NewMockArticleRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				// running 的不需要再抢占
+				repo.EXPECT().FindDueDeletions(gomock.Any(), gomock.Any(), 10).
+					Return([]domain.AccountDeletion{
+						{Uid: 1, Status: domain.AccountDeletionStatusRunning, ExecTime: now},
+					}, nil)
+				sessionRepo.EXPECT().DeleteByUid(gomock.Any(), int64(1)).Return(nil)
+				oauth2Repo.EXPECT().FindByUid(gomock.Any(), int64(1)).Return(nil, nil)
+				artRepo.EXPECT().DeleteByAuthor(gomock.Any(), int64(1)).Return(nil)
+				userRepo.EXPECT().Anonymize(gomock.Any(), int64(1)).Return(nil)
+				producer.EXPECT().ProduceUserDeletedEvent(user.UserDeletedEvent{Uid: 1}).
+					Return(errors.New("mock error"))
+				return repo, userRepo, oauth2Repo, sessionRepo, artRepo, producer
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, userRepo, oauth2Repo, sessionRepo, artRepo, producer := tc.mock(ctrl)
+			svc := NewUserDataService(repo, userRepo, oauth2Repo, sessionRepo, artRepo,
+				nil, producer, logger.NewNoOpLogger(), time.Hour)
+			cnt, err := svc.RunDeletions(context.Background(), 10)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCnt, cnt)
+		})
+	}
+}
+
+func TestUserDataService_CancelDeletion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := repomocks.NewMockUserDataRepository(ctrl)
+	repo.EXPECT().UpdateDeletionStatus(gomock.Any(), int64(1),
+		domain.AccountDeletionStatusPending, domain.AccountDeletionStatusCancelled).
+		Return(false, nil)
+	svc := NewUserDataService(repo, nil, nil, nil, nil, nil, nil,
+		logger.NewNoOpLogger(), time.Hour)
+	err := svc.CancelDeletion(context.Background(), 1)
+	assert.Equal(t, ErrDeletionNotFound, err)
+}
@@ -0,0 +1,353 @@
+package web
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	perrs "gitee.com/geekbang/basic-go/webook/pkg/errs"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"time"
+)
+
+const (
+	bizBindPhone = "bind_phone"
+	bizBindEmail = "bind_email"
+	// 换绑，验证码发给新的手机号码或者邮箱
+	bizChangePhone = "change_phone"
+	bizChangeEmail = "change_email"
+
+	mergeKindPhone = "phone"
+	mergeKindEmail = "email"
+)
+
+var _ handler = &AccountHandler{}
+
+// AccountHandler 登录之后绑定、换绑手机号码和邮箱，以及合并账号
+// 绑定微信之类的第三方账号，走的是 /oauth2/:provider/bind/authurl
+type AccountHandler struct {
+	svc            service.AccountService
+	codeSvc        service.CodeService
+	codeGuard      service.CodeGuard
+	mergeTicketKey []byte
+	// mergeTicketExpiration 验证完手机号码或者邮箱之后，多久之内可以合并
+	mergeTicketExpiration time.Duration
+}
+
+// NewAccountHandler mergeTicketKey 是合并账号凭证的签名密钥
+func NewAccountHandler(svc service.AccountService,
+	codeSvc service.CodeService,
+	codeGuard service.CodeGuard,
+	mergeTicketKey []byte) *AccountHandler {
+	return &AccountHandler{
+		svc:                   svc,
+		codeSvc:               codeSvc,
+		codeGuard:             codeGuard,
+		mergeTicketKey:        mergeTicketKey,
+		mergeTicketExpiration: time.Minute * 10,
+	}
+}
+
+func (h *AccountHandler) RegisterRoutes(server *gin.Engine) {
+	ug := server.Group("/users")
+	ginx.HandleReq(ug, http.MethodPost, "/bind/phone/code/send", h.SendBindPhoneCode)
+	// 已经注册过的话，data 是合并账号的凭证
+	ginx.HandleClaimsAndReq(ug, http.MethodPost, "/bind/phone", h.BindPhone, ginx.WithResp[string]())
+	ginx.HandleReq(ug, http.MethodPost, "/bind/email/code/send", h.SendBindEmailCode)
+	ginx.HandleClaimsAndReq(ug, http.MethodPost, "/bind/email", h.BindEmail, ginx.WithResp[string]())
+	ginx.HandleReq(ug, http.MethodPost, "/change/phone/code/send", h.SendChangePhoneCode)
+	ginx.HandleClaimsAndReq(ug, http.MethodPost, "/change/phone", h.ChangePhone)
+	ginx.HandleReq(ug, http.MethodPost, "/change/email/code/send", h.SendChangeEmailCode)
+	ginx.HandleClaimsAndReq(ug, http.MethodPost, "/change/email", h.ChangeEmail)
+	ginx.HandleClaimsAndReq(ug, http.MethodPost, "/merge", h.Merge, ginx.WithResp[AccountMergeLogVO]())
+	ginx.Handle(ug, http.MethodGet, "/merge/logs", ginx.WrapClaims(h.MergeLogs),
+		ginx.WithResp[[]AccountMergeLogVO]())
+}
+
+type SendPhoneCodeReq struct {
+	Phone string `json:"phone" binding:"required,phone"`
+	// Captcha 要求人机验证之后才需要
+	Captcha string `json:"captcha"`
+}
+
+func (req SendPhoneCodeReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+type SendEmailCodeReq struct {
+	Email   string `json:"email" binding:"required,email"`
+	Captcha string `json:"captcha"`
+}
+
+func (req SendEmailCodeReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+func (h *AccountHandler) SendBindPhoneCode(ctx *gin.Context, req SendPhoneCodeReq) (ginx.Result, error) {
+	return h.sendPhoneCode(ctx, bizBindPhone, req)
+}
+
+func (h *AccountHandler) SendBindEmailCode(ctx *gin.Context, req SendEmailCodeReq) (ginx.Result, error) {
+	return h.sendEmailCode(ctx, bizBindEmail, req)
+}
+
+// SendChangePhoneCode 验证码发给新的手机号码
+func (h *AccountHandler) SendChangePhoneCode(ctx *gin.Context, req SendPhoneCodeReq) (ginx.Result, error) {
+	return h.sendPhoneCode(ctx, bizChangePhone, req)
+}
+
+// SendChangeEmailCode 验证码发给新的邮箱
+func (h *AccountHandler) SendChangeEmailCode(ctx *gin.Context, req SendEmailCodeReq) (ginx.Result, error) {
+	return h.sendEmailCode(ctx, bizChangeEmail, req)
+}
+
+func (h *AccountHandler) sendPhoneCode(ctx *gin.Context, biz string, req SendPhoneCodeReq) (ginx.Result, error) {
+	return h.sendCode(ctx, service.CodeSendReq{
+		Biz:     biz,
+		Phone:   req.Phone,
+		IP:      ctx.ClientIP(),
+		Device:  ctx.GetHeader(deviceFingerprintHeader),
+		Captcha: req.Captcha,
+	})
+}
+
+// sendEmailCode 邮件也可以被拿来刷，所以也要经过防刷检查
+func (h *AccountHandler) sendEmailCode(ctx *gin.Context, biz string, req SendEmailCodeReq) (ginx.Result, error) {
+	return h.sendCode(ctx, service.CodeSendReq{
+		Biz:     biz,
+		Email:   req.Email,
+		IP:      ctx.ClientIP(),
+		Device:  ctx.GetHeader(deviceFingerprintHeader),
+		Captcha: req.Captcha,
+	})
+}
+
+func (h *AccountHandler) sendCode(ctx *gin.Context, req service.CodeSendReq) (ginx.Result, error) {
+	// 和登录一样的防刷规则
+	err := h.codeGuard.Check(ctx, req)
+	switch err {
+	case nil:
+	case service.ErrInvalidPhone, service.ErrCodeSendLimited,
+		service.ErrCaptchaRequired, service.ErrCaptchaFailed:
+		return Result{}, err
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(err)
+	}
+	err = h.codeSvc.Send(ctx, req.Biz, req.Target())
+	switch err {
+	case nil:
+		return Result{Msg: "发送成功"}, nil
+	case service.ErrCodeSendTooMany:
+		return Result{}, errs.UserCodeSendTooMany
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(err)
+	}
+}
+
+type PhoneCodeReq struct {
+	Phone string `json:"phone" binding:"required,phone"`
+	Code  string `json:"code" binding:"required"`
+}
+
+func (req PhoneCodeReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+type EmailCodeReq struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required"`
+}
+
+func (req EmailCodeReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+func (h *AccountHandler) BindPhone(ctx *gin.Context, req PhoneCodeReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	if err := h.verifyCode(ctx, bizBindPhone, req.Phone, req.Code); err != nil {
+		return Result{}, err
+	}
+	err := h.svc.BindPhone(ctx, uc.Id, req.Phone)
+	switch err {
+	case nil:
+		return Result{Msg: "绑定成功"}, nil
+	case service.ErrPhoneBoundByOther:
+		// 已经验证过手机号码了，所以可以直接合并，不用再发一次验证码
+		return h.mergeTicket(ctx, errs.UserPhoneBoundByOther.WithMsg("该手机号码已经注册过账号，是否合并",
+			"the phone number is registered by another account, merge it?"),
+			MergeClaims{Uid: uc.Id, Kind: mergeKindPhone, Target: req.Phone})
+	case service.ErrAccountPhoneExists:
+		return Result{}, errs.UserContactExists.WithMsg("已经绑定了别的手机号码",
+			"another phone number is already bound")
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("绑定手机号码失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+func (h *AccountHandler) BindEmail(ctx *gin.Context, req EmailCodeReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	if err := h.verifyCode(ctx, bizBindEmail, req.Email, req.Code); err != nil {
+		return Result{}, err
+	}
+	err := h.svc.BindEmail(ctx, uc.Id, req.Email)
+	switch err {
+	case nil:
+		return Result{Msg: "绑定成功"}, nil
+	case service.ErrEmailBoundByOther:
+		return h.mergeTicket(ctx, errs.UserEmailBoundByOther.WithMsg("该邮箱已经注册过账号，是否合并",
+			"the email is registered by another account, merge it?"),
+			MergeClaims{Uid: uc.Id, Kind: mergeKindEmail, Target: req.Email})
+	case service.ErrAccountEmailExists:
+		return Result{}, errs.UserContactExists.WithMsg("已经绑定了别的邮箱",
+			"another email is already bound")
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("绑定邮箱失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+// ChangePhone 换绑手机号码，验证码是发给新的手机号码的
+func (h *AccountHandler) ChangePhone(ctx *gin.Context, req PhoneCodeReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	if err := h.verifyCode(ctx, bizChangePhone, req.Phone, req.Code); err != nil {
+		return Result{}, err
+	}
+	err := h.svc.ChangePhone(ctx, uc.Id, req.Phone)
+	switch err {
+	case nil:
+		return Result{Msg: "换绑成功"}, nil
+	case service.ErrPhoneBoundByOther:
+		// 换绑不走合并，要合并的话用绑定的接口
+		return Result{}, err
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("换绑手机号码失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+// ChangeEmail 换绑邮箱，验证码是发给新的邮箱的
+func (h *AccountHandler) ChangeEmail(ctx *gin.Context, req EmailCodeReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	if err := h.verifyCode(ctx, bizChangeEmail, req.Email, req.Code); err != nil {
+		return Result{}, err
+	}
+	err := h.svc.ChangeEmail(ctx, uc.Id, req.Email)
+	switch err {
+	case nil:
+		return Result{Msg: "换绑成功"}, nil
+	case service.ErrEmailBoundByOther:
+		return Result{}, err
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("换绑邮箱失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+// verifyCode 验证码不对或者校验失败都返回带错误码的 error
+func (h *AccountHandler) verifyCode(ctx *gin.Context, biz, target, code string) error {
+	ok, err := h.codeSvc.Verify(ctx, biz, target, code)
+	if err != nil {
+		return errs.UserInternalServerError.Wrap(fmt.Errorf("校验验证码失败 biz %s, %w", biz, err))
+	}
+	if !ok {
+		return errs.UserInvalidCode
+	}
+	return nil
+}
+
+// mergeTicket 合并账号的凭证，证明用户刚刚验证过另外一个账号的手机号码或者邮箱。
+// 错误码是 e，data 是凭证
+func (h *AccountHandler) mergeTicket(ctx *gin.Context, e error, mc MergeClaims) (ginx.Result, error) {
+	mc.ExpiresAt = jwt.NewNumericDate(time.Now().Add(h.mergeTicketExpiration))
+	ticket, err := jwt.NewWithClaims(jwt.SigningMethodHS256, mc).SignedString(h.mergeTicketKey)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(err)
+	}
+	res := ginx.ErrResult(ctx, e)
+	res.Data = ticket
+	return res, nil
+}
+
+type MergeReq struct {
+	Ticket string `json:"ticket" binding:"required"`
+}
+
+func (req MergeReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+// Merge 把另外一个账号合并到当前登录的账号，另外一个账号会被删除
+func (h *AccountHandler) Merge(ctx *gin.Context, req MergeReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	var mc MergeClaims
+	token, err := jwt.ParseWithClaims(req.Ticket, &mc, func(token *jwt.Token) (interface{}, error) {
+		return h.mergeTicketKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	// 凭证只能由申请的人使用
+	if err != nil || !token.Valid || mc.Uid != uc.Id {
+		return Result{}, errs.UserInvalidMergeTicket
+	}
+	var log domain.AccountMergeLog
+	switch mc.Kind {
+	case mergeKindPhone:
+		log, err = h.svc.MergeByPhone(ctx, uc.Id, mc.Target)
+	case mergeKindEmail:
+		log, err = h.svc.MergeByEmail(ctx, uc.Id, mc.Target)
+	default:
+		return Result{}, errs.UserInvalidMergeTicket
+	}
+	switch err {
+	case nil:
+		return Result{Msg: "合并成功", Data: h.toMergeLogVO(log)}, nil
+	case service.ErrAccountMergeConflict:
+		return Result{}, err
+	case service.ErrAccountNotFound, service.ErrAccountMergeSelf:
+		return Result{}, errs.UserInvalidMergeTicket.Wrap(err)
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("合并账号失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+func (h *AccountHandler) MergeLogs(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	logs, err := h.svc.MergeLogs(ctx, uc.Id)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(err)
+	}
+	res := make([]AccountMergeLogVO, 0, len(logs))
+	for _, l := range logs {
+		res = append(res, h.toMergeLogVO(l))
+	}
+	return Result{Data: res}, nil
+}
+
+func (h *AccountHandler) toMergeLogVO(l domain.AccountMergeLog) AccountMergeLogVO {
+	return AccountMergeLogVO{
+		SourceUid:   l.SourceUid,
+		TargetUid:   l.TargetUid,
+		Articles:    l.Articles,
+		Likes:       l.Likes,
+		Collections: l.Collections,
+		Bindings:    l.Bindings,
+		Ctime:       l.Ctime.Format(time.DateTime),
+	}
+}
+
+type MergeClaims struct {
+	// Uid 申请合并的用户，也就是保留下来的账号
+	Uid int64
+	// Kind 用什么证明了另外一个账号是自己的，phone 或者 email
+	Kind   string
+	Target string
+	jwt.RegisteredClaims
+}
+
+type AccountMergeLogVO struct {
+	SourceUid   int64  `json:"sourceUid"`
+	TargetUid   int64  `json:"targetUid"`
+	Articles    int64  `json:"articles"`
+	Likes       int64  `json:"likes"`
+	Collections int64  `json:"collections"`
+	Bindings    int64  `json:"bindings"`
+	Ctime       string `json:"ctime"`
+}
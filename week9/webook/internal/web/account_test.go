@@ -0,0 +1,235 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestAccountHandler_BindAndMerge 绑定手机号码的时候发现已经注册过，拿着凭证合并
+func TestAccountHandler_BindAndMerge(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.AccountService, service.CodeService)
+		// 合并的时候登录的用户
+		mergeUid int64
+
+		wantResult Result
+	}{
+		{
+			name: "合并成功",
+			mock: func(ctrl *gomock.Controller) (service.AccountService, service.CodeService) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizBindPhone, "15212345678", "123456").
+					Return(true, nil)
+				svc := svcmocks.NewMockAccountService(ctrl)
+				svc.EXPECT().BindPhone(gomock.Any(), int64(1), "15212345678").
+					Return(service.ErrPhoneBoundByOther)
+				svc.EXPECT().MergeByPhone(gomock.Any(), int64(1), "15212345678").
+					Return(domain.AccountMergeLog{SourceUid: 2, TargetUid: 1, Articles: 3}, nil)
+				return svc, codeSvc
+			},
+			mergeUid: 1,
+			wantResult: Result{Msg: "合并成功", Data: map[string]any{
+				"sourceUid": float64(2), "targetUid": float64(1), "articles": float64(3),
+				"likes": float64(0), "collections": float64(0), "bindings": float64(0),
+				"ctime": "0001-01-01 00:00:00",
+			}},
+		},
+		{
+			name: "凭证不是自己的",
+			mock: func(ctrl *gomock.Controller) (service.AccountService, service.CodeService) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizBindPhone, "15212345678", "123456").
+					Return(true, nil)
+				svc := svcmocks.NewMockAccountService(ctrl)
+				svc.EXPECT().BindPhone(gomock.Any(), int64(1), "15212345678").
+					Return(service.ErrPhoneBoundByOther)
+				return svc, codeSvc
+			},
+			mergeUid:   3,
+			wantResult: Result{Code: errs.UserInvalidMergeTicket.Code, Msg: "请重新验证"},
+		},
+		{
+			name: "信息冲突",
+			mock: func(ctrl *gomock.Controller) (service.AccountService, service.CodeService) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizBindPhone, "15212345678", "123456").
+					Return(true, nil)
+				svc := svcmocks.NewMockAccountService(ctrl)
+				svc.EXPECT().BindPhone(gomock.Any(), int64(1), "15212345678").
+					Return(service.ErrPhoneBoundByOther)
+				svc.EXPECT().MergeByPhone(gomock.Any(), int64(1), "15212345678").
+					Return(domain.AccountMergeLog{}, service.ErrAccountMergeConflict)
+				return svc, codeSvc
+			},
+			mergeUid: 1,
+			wantResult: Result{Code: errs.UserMergeConflict.Code,
+				Msg: "两个账号绑定了不同的手机号码、邮箱或者同一个平台的第三方账号，请先解绑"},
+		},
+		{
+			name: "合并同一个账号",
+			mock: func(ctrl *gomock.Controller) (service.AccountService, service.CodeService) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizBindPhone, "15212345678", "123456").
+					Return(true, nil)
+				svc := svcmocks.NewMockAccountService(ctrl)
+				svc.EXPECT().BindPhone(gomock.Any(), int64(1), "15212345678").
+					Return(service.ErrPhoneBoundByOther)
+				svc.EXPECT().MergeByPhone(gomock.Any(), int64(1), "15212345678").
+					Return(domain.AccountMergeLog{}, service.ErrAccountMergeSelf)
+				return svc, codeSvc
+			},
+			mergeUid:   1,
+			wantResult: Result{Code: errs.UserInvalidMergeTicket.Code, Msg: "请重新验证"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, codeSvc := tc.mock(ctrl)
+			hdl := NewAccountHandler(svc, codeSvc, nil, []byte("merge-ticket-key"))
+			uid := int64(1)
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Id: uid})
+			})
+			hdl.RegisterRoutes(server)
+
+			res := doJSON(t, server, "/users/bind/phone",
+				`{"phone":"15212345678","code":"123456"}`)
+			require.Equal(t, errs.UserPhoneBoundByOther.Code, res.Code)
+			ticket, ok := res.Data.(string)
+			require.True(t, ok)
+
+			uid = tc.mergeUid
+			res = doJSON(t, server, "/users/merge", `{"ticket":"`+ticket+`"}`)
+			assert.Equal(t, tc.wantResult, res)
+		})
+	}
+}
+
+func doJSON(t *testing.T, server *gin.Engine, path string, body string) Result {
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var res Result
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+	return res
+}
+
+func TestAccountHandler_SendBindEmailCode(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.CodeGuard, service.CodeService)
+		body string
+
+		wantResult Result
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) (service.CodeGuard, service.CodeService) {
+				guard := codeGuardFunc(func(ctx context.Context, req service.CodeSendReq) error {
+					// 邮件也要经过防刷检查
+					if req.Biz != bizBindEmail || req.Target() != "123@qq.com" {
+						return errors.New("防刷检查的请求不对")
+					}
+					return nil
+				})
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Send(gomock.Any(), bizBindEmail, "123@qq.com").Return(nil)
+				return guard, codeSvc
+			},
+			body:       `{"email":"123@qq.com"}`,
+			wantResult: Result{Msg: "发送成功"},
+		},
+		{
+			name: "邮箱格式不对",
+			mock: func(ctrl *gomock.Controller) (service.CodeGuard, service.CodeService) {
+				return nil, svcmocks.NewMockCodeService(ctrl)
+			},
+			body: `{"email":"123"}`,
+			wantResult: Result{Code: errs.UserInvalidInput.Code, Msg: "邮箱格式不对",
+				Data: []any{map[string]any{"field": "email", "tag": "email", "msg": "邮箱格式不对"}}},
+		},
+		{
+			name: "触发防刷规则",
+			mock: func(ctrl *gomock.Controller) (service.CodeGuard, service.CodeService) {
+				guard := codeGuardFunc(func(ctx context.Context, req service.CodeSendReq) error {
+					return service.ErrCodeSendLimited
+				})
+				return guard, svcmocks.NewMockCodeService(ctrl)
+			},
+			body:       `{"email":"123@qq.com"}`,
+			wantResult: Result{Code: errs.UserCodeSendTooMany.Code, Msg: "发送太频繁，请稍后再试"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			guard, codeSvc := tc.mock(ctrl)
+			hdl := NewAccountHandler(nil, codeSvc, guard, []byte("merge-ticket-key"))
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Id: 1})
+			})
+			hdl.RegisterRoutes(server)
+			res := doJSON(t, server, "/users/bind/email/code/send", tc.body)
+			assert.Equal(t, tc.wantResult, res)
+		})
+	}
+}
+
+type codeGuardFunc func(ctx context.Context, req service.CodeSendReq) error
+
+func (f codeGuardFunc) Check(ctx context.Context, req service.CodeSendReq) error {
+	return f(ctx, req)
+}
+
+// TestAccountHandler_MergeTicketAlg 只接受 HS256 签名的凭证
+func TestAccountHandler_MergeTicketAlg(t *testing.T) {
+	key := []byte("merge-ticket-key")
+	ticket, err := jwt.NewWithClaims(jwt.SigningMethodHS512, MergeClaims{
+		Uid:    1,
+		Kind:   mergeKindPhone,
+		Target: "15212345678",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}).SignedString(key)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// 凭证校验不通过，不会调用 service
+	hdl := NewAccountHandler(svcmocks.NewMockAccountService(ctrl),
+		svcmocks.NewMockCodeService(ctrl), nil, key)
+	server := gin.New()
+	server.Use(func(ctx *gin.Context) {
+		ctx.Set("user", ijwt.UserClaims{Id: 1})
+	})
+	hdl.RegisterRoutes(server)
+
+	res := doJSON(t, server, "/users/merge", `{"ticket":"`+ticket+`"}`)
+	assert.Equal(t, Result{Code: errs.UserInvalidMergeTicket.Code, Msg: "请重新验证"}, res)
+}
@@ -5,6 +5,7 @@ import (
 	domain2 "gitee.com/geekbang/basic-go/webook/interactive/domain"
 	service2 "gitee.com/geekbang/basic-go/webook/interactive/service"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	"gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
@@ -41,96 +42,49 @@ func (a *ArticleHandler) RegisterRoutes(s *gin.Engine) {
 	g := s.Group("/articles")
 	// 在有 list 等路由的时候，无法这样注册
 	// g.GET("/:id", a.Detail)
-	g.GET("/detail/:id", a.Detail)
+	ginx.Handle(g, http.MethodGet, "/detail/:id", a.Detail, ginx.WithResp[ArticleVo]())
 	// 理论上来说应该用 GET的，但是我实在不耐烦处理类型转化
 	// 直接 POST，JSON 转一了百了。
-	g.POST("/list", a.List)
+	ginx.HandleClaimsAndReq(g, http.MethodPost, "/list", a.List, ginx.WithResp[[]ArticleVo]())
 
-	g.POST("/edit", a.Edit)
-	g.POST("/publish", a.Publish)
-	g.POST("/withdraw", a.Withdraw)
+	ginx.HandleClaimsAndReq(g, http.MethodPost, "/edit", a.Edit, ginx.WithResp[int64]())
+	ginx.HandleClaimsAndReq(g, http.MethodPost, "/publish", a.Publish, ginx.WithResp[int64]())
+	ginx.HandleClaimsAndReq(g, http.MethodPost, "/withdraw", a.Withdraw)
 
 	pub := g.Group("/pub")
 	//pub.GET("/pub", a.PubList)
-	pub.GET("/:id", ginx.WrapClaims(a.PubDetail))
-	pub.POST("/like", ginx.WrapClaimsAndReq[LikeReq](a.Like))
-	pub.POST("/collect", ginx.WrapClaimsAndReq[CollectReq](a.Collect))
+	// 匿名用户也可以看，登录了的才有点赞和收藏的状态
+	ginx.Handle(pub, http.MethodGet, "/:id", ginx.WrapOptionalClaims(a.PubDetail),
+		ginx.WithResp[ArticleVo]())
+	ginx.HandleClaimsAndReq(pub, http.MethodPost, "/like", a.Like)
+	ginx.HandleClaimsAndReq(pub, http.MethodPost, "/collect", a.Collect)
 }
 
-func (a *ArticleHandler) Withdraw(ctx *gin.Context) {
-	var req ArticleReq
-	if err := ctx.Bind(&req); err != nil {
-		a.l.Error("反序列化请求失败", logger.Error(err))
-		return
-	}
-	usr, ok := ctx.MustGet("user").(jwt.UserClaims)
-	if !ok {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		a.l.Error("获得用户会话信息失败")
-		return
-	}
+func (a *ArticleHandler) Withdraw(ctx *gin.Context, req ArticleReq, usr jwt.UserClaims) (ginx.Result, error) {
 	if err := a.svc.Withdraw(ctx, usr.Id, req.Id); err != nil {
-		a.l.Error("设置为尽自己可见失败", logger.Error(err),
-			logger.Field{Key: "id", Value: req.Id})
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		return
+		return Result{}, errs.ArticleInternalServerError.Wrap(
+			fmt.Errorf("设置为尽自己可见失败 id %d, %w", req.Id, err))
 	}
-	ctx.JSON(http.StatusOK, Result{
+	return Result{
 		Msg: "OK",
-	})
+	}, nil
 }
 
-func (a *ArticleHandler) List(ctx *gin.Context) {
-	type Req struct {
-		Offset int `json:"offset"`
-		Limit  int `json:"limit"`
-	}
-
-	var req Req
-	if err := ctx.Bind(&req); err != nil {
-		a.l.Error("反序列化请求失败", logger.Error(err))
-		return
-	}
-
+func (a *ArticleHandler) List(ctx *gin.Context, req ArticleListReq, usr jwt.UserClaims) (ginx.Result, error) {
 	// 对于批量接口来说，要小心批次大小
 	if req.Limit > 100 {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 4,
-			// 我会倾向于不告诉前端批次太大
-			// 因为一般你和前端一起完成任务的时候
-			// 你们是协商好了的，所以会进来这个分支
-			// 就表明是有人跟你过不去
-			Msg: "请求有误",
-		})
+		// 我会倾向于不告诉前端批次太大
+		// 因为一般你和前端一起完成任务的时候
+		// 你们是协商好了的，所以会进来这个分支
+		// 就表明是有人跟你过不去
 		a.l.Error("获得用户会话信息失败")
-		return
-	}
-
-	usr, ok := ctx.MustGet("user").(jwt.UserClaims)
-	if !ok {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		a.l.Error("获得用户会话信息失败")
-		return
+		return Result{}, errs.ArticleInvalidInput
 	}
 	arts, err := a.svc.List(ctx, usr.Id, req.Offset, req.Limit)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		a.l.Error("获得用户会话信息失败")
-		return
+		return Result{}, errs.ArticleInternalServerError.Wrap(err)
 	}
-	ctx.JSON(http.StatusOK, Result{
+	return Result{
 		Data: slice.Map[domain.Article, ArticleVo](arts,
 			func(idx int, src domain.Article) ArticleVo {
 				return ArticleVo{
@@ -146,45 +100,33 @@ func (a *ArticleHandler) List(ctx *gin.Context) {
 					Utime: src.Utime.Format(time.DateTime),
 				}
 			}),
-	})
+	}, nil
 }
 
 func (a *ArticleHandler) Detail(ctx *gin.Context) {
 	idstr := ctx.Param("id")
 	id, err := strconv.ParseInt(idstr, 10, 64)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 4,
-			Msg:  "参数错误",
-		})
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.ArticleInvalidInput))
 		a.l.Error("前端输入的 ID 不对", logger.Error(err))
 		return
 	}
 	usr, ok := ctx.MustGet("user").(jwt.UserClaims)
 	if !ok {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.ArticleInternalServerError))
 		a.l.Error("获得用户会话信息失败")
 		return
 	}
 	art, err := a.svc.GetById(ctx, id)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.ArticleInternalServerError))
 		a.l.Error("获得文章信息失败", logger.Error(err))
 		return
 	}
 	// 这是不借助数据库查询来判定的方法
 	if art.Author.Id != usr.Id {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 4,
-			// 也不需要告诉前端究竟发生了什么
-			Msg: "输入有误",
-		})
+		// 也不需要告诉前端究竟发生了什么
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.ArticleInvalidInput))
 		// 如果公司有风控系统，这个时候就要上报这种非法访问的用户了。
 		a.l.Error("非法访问文章，创作者 ID 不匹配",
 			logger.Int64("uid", usr.Id))
@@ -206,62 +148,24 @@ func (a *ArticleHandler) Detail(ctx *gin.Context) {
 	})
 }
 
-func (a *ArticleHandler) Publish(ctx *gin.Context) {
-	var req ArticleReq
-	if err := ctx.Bind(&req); err != nil {
-		a.l.Error("反序列化请求失败", logger.Error(err))
-		return
-	}
-	usr, ok := ctx.MustGet("user").(jwt.UserClaims)
-	if !ok {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		a.l.Error("获得用户会话信息失败")
-		return
-	}
+func (a *ArticleHandler) Publish(ctx *gin.Context, req ArticleReq, usr jwt.UserClaims) (ginx.Result, error) {
 	id, err := a.svc.Publish(ctx, req.toDomain(usr.Id))
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		a.l.Error("发表失败", logger.Error(err))
-		return
+		return Result{}, errs.ArticleInternalServerError.Wrap(fmt.Errorf("发表失败 %w", err))
 	}
-	ctx.JSON(http.StatusOK, Result{
+	return Result{
 		Data: id,
-	})
+	}, nil
 }
 
-func (a *ArticleHandler) Edit(ctx *gin.Context) {
-	var req ArticleReq
-	if err := ctx.Bind(&req); err != nil {
-		a.l.Error("反序列化请求失败", logger.Error(err))
-		return
-	}
-	usr, ok := ctx.MustGet("user").(jwt.UserClaims)
-	if !ok {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		a.l.Error("获得用户会话信息失败")
-		return
-	}
+func (a *ArticleHandler) Edit(ctx *gin.Context, req ArticleReq, usr jwt.UserClaims) (ginx.Result, error) {
 	id, err := a.svc.Save(ctx, req.toDomain(usr.Id))
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Code: 5,
-			Msg:  "系统错误",
-		})
-		a.l.Error("保存数据失败", logger.Field{Key: "error", Value: err})
-		return
+		return Result{}, errs.ArticleInternalServerError.Wrap(fmt.Errorf("保存数据失败 %w", err))
 	}
-	ctx.JSON(http.StatusOK, Result{
+	return Result{
 		Data: id,
-	})
+	}, nil
 }
 
 func (a *ArticleHandler) PubDetail(ctx *gin.Context, uc ginx.UserClaims) (Result, error) {
@@ -269,10 +173,8 @@ func (a *ArticleHandler) PubDetail(ctx *gin.Context, uc ginx.UserClaims) (Result
 	id, err := strconv.ParseInt(idstr, 10, 64)
 	if err != nil {
 		a.l.Error("前端输入的 ID 不对", logger.Error(err))
-		return Result{
-			Code: 4,
-			Msg:  "参数错误",
-		}, fmt.Errorf("查询文章详情的 ID %s 不正确, %w", idstr, err)
+		return Result{}, errs.ArticleInvalidInput.Wrap(
+			fmt.Errorf("查询文章详情的 ID %s 不正确, %w", idstr, err))
 	}
 
 	// 使用 error group 来同时查询数据
@@ -296,10 +198,8 @@ func (a *ArticleHandler) PubDetail(ctx *gin.Context, uc ginx.UserClaims) (Result
 	err = eg.Wait()
 
 	if err != nil {
-		return Result{
-			Code: 5,
-			Msg:  "系统错误",
-		}, fmt.Errorf("获取文章信息失败 %w", err)
+		return Result{}, errs.ArticleInternalServerError.Wrap(
+			fmt.Errorf("获取文章信息失败 %w", err))
 	}
 
 	// 直接异步操作，在确定我们获取到了数据之后再来操作
@@ -338,10 +238,7 @@ func (a *ArticleHandler) Like(ctx *gin.Context, req LikeReq, uc jwt.UserClaims)
 	}
 
 	if err != nil {
-		return Result{
-			Code: 5,
-			Msg:  "系统错误",
-		}, err
+		return Result{}, errs.ArticleInternalServerError.Wrap(err)
 	}
 	return Result{Msg: "OK"}, nil
 }
@@ -352,10 +249,7 @@ func (a *ArticleHandler) Collect(
 	uc jwt.UserClaims) (Result, error) {
 	err := a.intrSvc.Collect(ctx, a.biz, req.Id, req.Cid, uc.Id)
 	if err != nil {
-		return Result{
-			Code: 5,
-			Msg:  "系统错误",
-		}, err
+		return Result{}, errs.ArticleInternalServerError.Wrap(err)
 	}
 	return Result{Msg: "OK"}, nil
 }
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
 	"gitee.com/geekbang/basic-go/webook/internal/web/jwt"
@@ -97,7 +98,7 @@ func TestArticleHandler_Publish(t *testing.T) {
 }`,
 			wantCode: 200,
 			wantRes: Result{
-				Code: 5,
+				Code: errs.ArticleInternalServerError.Code,
 				Msg:  "系统错误",
 			},
 		},
@@ -112,7 +113,11 @@ func TestArticleHandler_Publish(t *testing.T) {
 	"title":"我的标题",
 	"cont
 }`,
-			wantCode: http.StatusBadRequest,
+			wantCode: http.StatusOK,
+			wantRes: Result{
+				Code: errs.ArticleInvalidInput.Code,
+				Msg:  "参数错误",
+			},
 		},
 	}
 	for _, tc := range testCases {
@@ -1,17 +1,29 @@
 package web
 
-import "gitee.com/geekbang/basic-go/webook/internal/domain"
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	perrs "gitee.com/geekbang/basic-go/webook/pkg/errs"
+)
 
 type LikeReq struct {
 	Id   int64 `json:"id"`
 	Like bool  `json:"like"`
 }
 
+func (req LikeReq) InvalidInput() *perrs.Error {
+	return errs.ArticleInvalidInput
+}
+
 type CollectReq struct {
 	Id  int64 `json:"id"`
 	Cid int64 `json:"cid"`
 }
 
+func (req CollectReq) InvalidInput() *perrs.Error {
+	return errs.ArticleInvalidInput
+}
+
 type ArticleVo struct {
 	Id    int64  `json:"id"`
 	Title string `json:"title"`
@@ -40,6 +52,20 @@ type ArticleReq struct {
 	Content string `json:"content"`
 }
 
+func (req ArticleReq) InvalidInput() *perrs.Error {
+	return errs.ArticleInvalidInput
+}
+
+// ArticleListReq 创作者查看自己的文章列表
+type ArticleListReq struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+func (req ArticleListReq) InvalidInput() *perrs.Error {
+	return errs.ArticleInvalidInput
+}
+
 func (req ArticleReq) toDomain(uid int64) domain.Article {
 	return domain.Article{
 		Id:      req.Id,
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	perrs "gitee.com/geekbang/basic-go/webook/pkg/errs"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+var _ handler = (*AsyncSmsHandler)(nil)
+
+// AsyncSmsHandler 管理后台查看和处理异步短信
+type AsyncSmsHandler struct {
+	svc service.AsyncSmsService
+}
+
+func NewAsyncSmsHandler(svc service.AsyncSmsService) *AsyncSmsHandler {
+	return &AsyncSmsHandler{
+		svc: svc,
+	}
+}
+
+func (h *AsyncSmsHandler) RegisterRoutes(s *gin.Engine) {
+	g := s.Group("/admin/async_sms")
+	ginx.HandleReq(g, http.MethodPost, "/list", h.List, ginx.WithResp[[]AsyncSmsVo](),
+		ginx.WithPermission("sms:read"))
+	ginx.HandleReq(g, http.MethodPost, "/detail", h.Detail, ginx.WithResp[AsyncSmsVo](),
+		ginx.WithPermission("sms:read"))
+	ginx.HandleReq(g, http.MethodPost, "/requeue", h.Requeue, ginx.WithPermission("sms:manage"))
+}
+
+func (h *AsyncSmsHandler) List(ctx *gin.Context, req AsyncSmsListReq) (ginx.Result, error) {
+	if req.Limit <= 0 || req.Limit > 100 {
+		return ginx.Result{}, errs.AsyncSmsInvalidInput
+	}
+	res, err := h.svc.List(ctx, domain.AsyncSmsStatus(req.Status), req.Offset, req.Limit)
+	if err != nil {
+		return ginx.Result{}, errs.AsyncSmsInternalServerError.Wrap(err)
+	}
+	return ginx.Result{
+		Data: slice.Map(res, func(idx int, src domain.AsyncSms) AsyncSmsVo {
+			return h.toVo(src)
+		}),
+	}, nil
+}
+
+func (h *AsyncSmsHandler) Detail(ctx *gin.Context, req AsyncSmsReq) (ginx.Result, error) {
+	as, err := h.svc.Detail(ctx, req.Id)
+	if err != nil {
+		return ginx.Result{}, errs.AsyncSmsInternalServerError.Wrap(err)
+	}
+	return ginx.Result{
+		Data: h.toVo(as),
+	}, nil
+}
+
+func (h *AsyncSmsHandler) Requeue(ctx *gin.Context, req AsyncSmsReq) (ginx.Result, error) {
+	err := h.svc.Requeue(ctx, req.Id)
+	switch err {
+	case nil:
+		return ginx.Result{
+			Msg: "OK",
+		}, nil
+	case service.ErrAsyncSmsNotRequeueable:
+		return ginx.Result{}, err
+	default:
+		return ginx.Result{}, errs.AsyncSmsInternalServerError.Wrap(err)
+	}
+}
+
+func (h *AsyncSmsHandler) toVo(as domain.AsyncSms) AsyncSmsVo {
+	return AsyncSmsVo{
+		Id:       as.Id,
+		TplId:    as.TplId,
+		Args:     as.Args,
+		Numbers:  as.Numbers,
+		RetryCnt: as.RetryCnt,
+		RetryMax: as.RetryMax,
+		Status:   as.Status.ToUint8(),
+		NextTime: as.NextTime.Format(time.DateTime),
+		Ctime:    as.Ctime.Format(time.DateTime),
+		Utime:    as.Utime.Format(time.DateTime),
+	}
+}
+
+type AsyncSmsListReq struct {
+	Status uint8 `json:"status"`
+	Offset int   `json:"offset"`
+	Limit  int   `json:"limit"`
+}
+
+func (req AsyncSmsListReq) InvalidInput() *perrs.Error {
+	return errs.AsyncSmsInvalidInput
+}
+
+type AsyncSmsReq struct {
+	Id int64 `json:"id"`
+}
+
+func (req AsyncSmsReq) InvalidInput() *perrs.Error {
+	return errs.AsyncSmsInvalidInput
+}
+
+type AsyncSmsVo struct {
+	Id       int64    `json:"id"`
+	TplId    string   `json:"tplId"`
+	Args     []string `json:"args"`
+	Numbers  []string `json:"numbers"`
+	RetryCnt int      `json:"retryCnt"`
+	RetryMax int      `json:"retryMax"`
+	Status   uint8    `json:"status"`
+	NextTime string   `json:"nextTime"`
+	Ctime    string   `json:"ctime"`
+	Utime    string   `json:"utime"`
+}
@@ -0,0 +1,31 @@
+package web
+
+import (
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+var _ handler = (*JWKSHandler)(nil)
+
+// JWKSHandler 公开短 token 的公钥，别的服务拿到之后就可以自己校验 token，
+// 不需要回调我们。长 token 只有我们自己用，所以不公开
+type JWKSHandler struct {
+	keys *ijwt.Keys
+}
+
+func NewJWKSHandler(keys *ijwt.Keys) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+func (h *JWKSHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/.well-known/jwks.json", h.JWKS)
+}
+
+func (h *JWKSHandler) JWKS(ctx *gin.Context) {
+	// 轮换的时候新公钥要先发布出去，所以缓存时间不能太长
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.keys.Access.JWKS())
+}
@@ -0,0 +1,274 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+)
+
+var (
+	ErrUnknownKid = errors.New("未知的 kid")
+	ErrNoSignKey  = errors.New("没有可以用来签名的密钥")
+)
+
+// KeyConfig 一个密钥。HS256 用 secretFile，secret 不要写在配置文件里面，只给测试用；
+// RS256 和 EdDSA 用 PEM 格式的文件。
+// 只配置了 publicKeyFile 的，是轮换下来的旧密钥，只用来校验
+type KeyConfig struct {
+	Kid            string
+	Alg            string
+	Secret         string
+	SecretFile     string
+	PrivateKeyFile string
+	PublicKeyFile  string
+}
+
+// KeySetConfig 轮换的时候，先把新密钥加进来，等所有实例都能校验了，
+// 再把 signKid 切换过去，等旧 token 都过期了，再把旧密钥删掉
+type KeySetConfig struct {
+	// SignKid 用哪个密钥签名，其它的只用来校验
+	SignKid string
+	Keys    []KeyConfig
+}
+
+type KeysConfig struct {
+	Access  KeySetConfig
+	Refresh KeySetConfig
+}
+
+// Keys 长短 token 用不同的密钥，这样短 token 的公钥可以公开出去给别的服务校验
+type Keys struct {
+	Access  *KeySet
+	Refresh *KeySet
+}
+
+func NewKeys(cfg KeysConfig) (*Keys, error) {
+	access, err := NewKeySet(cfg.Access)
+	if err != nil {
+		return nil, fmt.Errorf("短 token 密钥 %w", err)
+	}
+	refresh, err := NewKeySet(cfg.Refresh)
+	if err != nil {
+		return nil, fmt.Errorf("长 token 密钥 %w", err)
+	}
+	return &Keys{Access: access, Refresh: refresh}, nil
+}
+
+type key struct {
+	kid    string
+	method jwt.SigningMethod
+	// signKey 只用来校验的旧密钥没有
+	signKey   any
+	verifyKey any
+}
+
+// KeySet 签名的时候在头部带上 kid，校验的时候按照 kid 找密钥
+type KeySet struct {
+	signer *key
+	// keys 保持配置里面的顺序，JWKS 的输出也是这个顺序
+	keys    []*key
+	kids    map[string]*key
+	methods []string
+}
+
+func NewKeySet(cfg KeySetConfig) (*KeySet, error) {
+	ks := &KeySet{
+		kids: make(map[string]*key, len(cfg.Keys)),
+	}
+	for _, kc := range cfg.Keys {
+		k, err := newKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("kid %s %w", kc.Kid, err)
+		}
+		if _, ok := ks.kids[k.kid]; ok {
+			return nil, fmt.Errorf("kid %s 重复了", k.kid)
+		}
+		ks.keys = append(ks.keys, k)
+		ks.kids[k.kid] = k
+		ks.addMethod(k.method.Alg())
+	}
+	signer, ok := ks.kids[cfg.SignKid]
+	if !ok || signer.signKey == nil {
+		return nil, fmt.Errorf("%w, signKid: %s", ErrNoSignKey, cfg.SignKid)
+	}
+	ks.signer = signer
+	return ks, nil
+}
+
+func (ks *KeySet) addMethod(alg string) {
+	for _, m := range ks.methods {
+		if m == alg {
+			return
+		}
+	}
+	ks.methods = append(ks.methods, alg)
+}
+
+// Sign 用当前的签名密钥签名
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signer.method, claims)
+	token.Header["kid"] = ks.signer.kid
+	return token.SignedString(ks.signer.signKey)
+}
+
+// Parse 校验 token 并且解析到 claims 里面
+func (ks *KeySet) Parse(tokenStr string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenStr, claims, ks.keyfunc,
+		jwt.WithValidMethods(ks.methods))
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("token 不合法")
+	}
+	return nil
+}
+
+func (ks *KeySet) keyfunc(token *jwt.Token) (any, error) {
+	k := ks.signer
+	// 没有 kid 的是引入密钥管理之前签发的，只能是当前的签名密钥
+	if kid, ok := token.Header["kid"]; ok {
+		kidStr, _ := kid.(string)
+		k, ok = ks.kids[kidStr]
+		if !ok {
+			return nil, fmt.Errorf("%w %v", ErrUnknownKid, kid)
+		}
+	}
+	// 防止拿着 RSA 公钥当作 HMAC 的密钥来伪造 token
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("kid %s 不能用 %s 算法", k.kid, token.Method.Alg())
+	}
+	return k.verifyKey, nil
+}
+
+// JWKS 公开所有的公钥，HS256 的密钥不能公开
+func (ks *KeySet) JWKS() JWKS {
+	res := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, k := range ks.keys {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			res.Keys = append(res.Keys, JWK{
+				Kty: "RSA",
+				Kid: k.kid,
+				Use: "sig",
+				Alg: k.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			res.Keys = append(res.Keys, JWK{
+				Kty: "OKP",
+				Kid: k.kid,
+				Use: "sig",
+				Alg: k.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return res
+}
+
+// JWKS 参考 RFC 7517
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA 才有
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 才有
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func newKey(cfg KeyConfig) (*key, error) {
+	if cfg.Kid == "" {
+		return nil, errors.New("kid 不能为空")
+	}
+	k := &key{kid: cfg.Kid}
+	switch cfg.Alg {
+	case jwt.SigningMethodHS256.Alg():
+		k.method = jwt.SigningMethodHS256
+		secret := []byte(cfg.Secret)
+		if cfg.SecretFile != "" {
+			var err error
+			secret, err = os.ReadFile(cfg.SecretFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(secret) == 0 {
+			return nil, errors.New("没有配置 secret")
+		}
+		k.signKey, k.verifyKey = secret, secret
+	case jwt.SigningMethodRS256.Alg():
+		k.method = jwt.SigningMethodRS256
+		err := k.loadPEM(cfg, func(data []byte) (any, any, error) {
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			return priv, &priv.PublicKey, nil
+		}, func(data []byte) (any, error) {
+			return jwt.ParseRSAPublicKeyFromPEM(data)
+		})
+		if err != nil {
+			return nil, err
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		k.method = jwt.SigningMethodEdDSA
+		err := k.loadPEM(cfg, func(data []byte) (any, any, error) {
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			edPriv, ok := priv.(ed25519.PrivateKey)
+			if !ok {
+				return nil, nil, errors.New("不是 Ed25519 私钥")
+			}
+			return edPriv, edPriv.Public(), nil
+		}, func(data []byte) (any, error) {
+			return jwt.ParseEdPublicKeyFromPEM(data)
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("不支持的算法 %s", cfg.Alg)
+	}
+	return k, nil
+}
+
+// loadPEM 有私钥的，公钥从私钥里面算出来，不需要再配置公钥
+func (k *key) loadPEM(cfg KeyConfig,
+	parsePriv func(data []byte) (any, any, error),
+	parsePub func(data []byte) (any, error)) error {
+	if cfg.PrivateKeyFile != "" {
+		data, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return err
+		}
+		k.signKey, k.verifyKey, err = parsePriv(data)
+		return err
+	}
+	if cfg.PublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return err
+		}
+		k.verifyKey, err = parsePub(data)
+		return err
+	}
+	return errors.New("没有配置 privateKeyFile 或者 publicKeyFile")
+}
@@ -0,0 +1,154 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeySet_Rotate(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPriv := writePEM(t, dir, "rs.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	rsaPubDer, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	rsaPub := writePEM(t, dir, "rs.pub.pem", "PUBLIC KEY", rsaPubDer)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDer, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	edPriv := writePEM(t, dir, "ed.pem", "PRIVATE KEY", edDer)
+
+	// 轮换之前，用 RS256 签名
+	before, err := NewKeySet(KeySetConfig{
+		SignKid: "rs",
+		Keys: []KeyConfig{
+			{Kid: "rs", Alg: "RS256", PrivateKeyFile: rsaPriv},
+			{Kid: "hs", Alg: "HS256", Secret: "secret"},
+		},
+	})
+	require.NoError(t, err)
+	oldToken, err := before.Sign(testClaims(1))
+	require.NoError(t, err)
+
+	// 轮换之后，用 EdDSA 签名，RS256 只剩下公钥用来校验
+	after, err := NewKeySet(KeySetConfig{
+		SignKid: "ed",
+		Keys: []KeyConfig{
+			{Kid: "ed", Alg: "EdDSA", PrivateKeyFile: edPriv},
+			{Kid: "rs", Alg: "RS256", PublicKeyFile: rsaPub},
+		},
+	})
+	require.NoError(t, err)
+	newToken, err := after.Sign(testClaims(2))
+	require.NoError(t, err)
+
+	var uc UserClaims
+	require.NoError(t, after.Parse(oldToken, &uc))
+	assert.Equal(t, int64(1), uc.Id)
+	require.NoError(t, after.Parse(newToken, &uc))
+	assert.Equal(t, int64(2), uc.Id)
+	// 轮换之前的实例不认识新的密钥
+	assert.Error(t, before.Parse(newToken, &uc))
+
+	jwks := after.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, JWK{Kty: "OKP", Kid: "ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+		X: jwks.Keys[0].X}, jwks.Keys[0])
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+	// HS256 的密钥不能公开
+	assert.Len(t, before.JWKS().Keys, 1)
+}
+
+func TestKeySet_Parse(t *testing.T) {
+	ks, err := NewKeySet(KeySetConfig{
+		SignKid: "hs",
+		Keys:    []KeyConfig{{Kid: "hs", Alg: "HS256", Secret: "secret"}},
+	})
+	require.NoError(t, err)
+	testCases := []struct {
+		name  string
+		token func(t *testing.T) string
+
+		wantErr bool
+	}{
+		{
+			name: "引入 kid 之前签发的",
+			token: func(t *testing.T) string {
+				token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims(1)).
+					SignedString([]byte("secret"))
+				require.NoError(t, err)
+				return token
+			},
+		},
+		{
+			name: "kid 对但是算法不对",
+			token: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS384, testClaims(1))
+				token.Header["kid"] = "hs"
+				str, err := token.SignedString([]byte("secret"))
+				require.NoError(t, err)
+				return str
+			},
+			wantErr: true,
+		},
+		{
+			name: "密钥不对",
+			token: func(t *testing.T) string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims(1))
+				token.Header["kid"] = "hs"
+				str, err := token.SignedString([]byte("another"))
+				require.NoError(t, err)
+				return str
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var uc UserClaims
+			err := ks.Parse(tc.token(t), &uc)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestNewKeySet(t *testing.T) {
+	_, err := NewKeySet(KeySetConfig{
+		SignKid: "rs",
+		Keys:    []KeyConfig{{Kid: "rs", Alg: "RS256", PublicKeyFile: "not_exist.pem"}},
+	})
+	assert.Error(t, err)
+	_, err = NewKeySet(KeySetConfig{
+		SignKid: "other",
+		Keys:    []KeyConfig{{Kid: "hs", Alg: "HS256", Secret: "secret"}},
+	})
+	assert.ErrorIs(t, err, ErrNoSignKey)
+}
+
+func testClaims(uid int64) UserClaims {
+	return UserClaims{
+		Id: uid,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
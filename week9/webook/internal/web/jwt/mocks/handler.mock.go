@@ -11,6 +11,7 @@ package jwtmocks
 import (
 	reflect "reflect"
 
+	jwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	gin "github.com/gin-gonic/gin"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,17 +40,17 @@ func (m *MockHandler) EXPECT() *MockHandlerMockRecorder {
 }
 
 // CheckSession mocks base method.
-func (m *MockHandler) CheckSession(ctx *gin.Context, ssid string) error {
+func (m *MockHandler) CheckSession(ctx *gin.Context, uid int64, ssid string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckSession", ctx, ssid)
+	ret := m.ctrl.Call(m, "CheckSession", ctx, uid, ssid)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CheckSession indicates an expected call of CheckSession.
-func (mr *MockHandlerMockRecorder) CheckSession(ctx, ssid any) *gomock.Call {
+func (mr *MockHandlerMockRecorder) CheckSession(ctx, uid, ssid any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSession", reflect.TypeOf((*MockHandler)(nil).CheckSession), ctx, ssid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSession", reflect.TypeOf((*MockHandler)(nil).CheckSession), ctx, uid, ssid)
 }
 
 // ClearToken mocks base method.
@@ -80,6 +81,50 @@ func (mr *MockHandlerMockRecorder) ExtractTokenString(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractTokenString", reflect.TypeOf((*MockHandler)(nil).ExtractTokenString), ctx)
 }
 
+// ParseAccessToken mocks base method.
+func (m *MockHandler) ParseAccessToken(tokenStr string) (jwt.UserClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseAccessToken", tokenStr)
+	ret0, _ := ret[0].(jwt.UserClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseAccessToken indicates an expected call of ParseAccessToken.
+func (mr *MockHandlerMockRecorder) ParseAccessToken(tokenStr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseAccessToken", reflect.TypeOf((*MockHandler)(nil).ParseAccessToken), tokenStr)
+}
+
+// ParseRefreshToken mocks base method.
+func (m *MockHandler) ParseRefreshToken(tokenStr string) (jwt.RefreshClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseRefreshToken", tokenStr)
+	ret0, _ := ret[0].(jwt.RefreshClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseRefreshToken indicates an expected call of ParseRefreshToken.
+func (mr *MockHandlerMockRecorder) ParseRefreshToken(tokenStr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseRefreshToken", reflect.TypeOf((*MockHandler)(nil).ParseRefreshToken), tokenStr)
+}
+
+// RotateToken mocks base method.
+func (m *MockHandler) RotateToken(ctx *gin.Context, rc jwt.RefreshClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateToken", ctx, rc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateToken indicates an expected call of RotateToken.
+func (mr *MockHandlerMockRecorder) RotateToken(ctx, rc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateToken", reflect.TypeOf((*MockHandler)(nil).RotateToken), ctx, rc)
+}
+
 // SetJWTToken mocks base method.
 func (m *MockHandler) SetJWTToken(ctx *gin.Context, ssid string, uid int64) error {
 	m.ctrl.T.Helper()
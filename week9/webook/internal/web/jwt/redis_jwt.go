@@ -1,30 +1,27 @@
 package jwt
 
 import (
-	"errors"
-	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 	"strings"
 	"time"
 )
 
-// AccessTokenKey 因为 JWT Key 不太可能变，所以可以直接写成常量
-// 也可以考虑做成依赖注入
-var AccessTokenKey = []byte("moyn8y9abnd7q4zkq2m73yw8tu9j5ixm")
-var RefreshTokenKey = []byte("moyn8y9abnd7q4zkq2m73yw8tu9j5ixA")
-
+// RedisHandler 会话记录在 Redis 里面，登录的时候创建，退出登录或者被踢下线的时候删除
 type RedisHandler struct {
-	cmd redis.Cmdable
+	sessSvc service.SessionService
+	keys    *Keys
 	// 长 token 的过期时间
 	rtExpiration time.Duration
 }
 
-func NewRedisHandler(cmd redis.Cmdable) Handler {
+func NewRedisHandler(sessSvc service.SessionService, keys *Keys) Handler {
 	return &RedisHandler{
-		cmd:          cmd,
+		sessSvc:      sessSvc,
+		keys:         keys,
 		rtExpiration: time.Hour * 24 * 7,
 	}
 }
@@ -32,7 +29,7 @@ func NewRedisHandler(cmd redis.Cmdable) Handler {
 func (h *RedisHandler) SetJWTToken(ctx *gin.Context,
 	ssid string,
 	uid int64) error {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaims{
+	tokenStr, err := h.keys.Access.Sign(UserClaims{
 		Id:        uid,
 		Ssid:      ssid,
 		UserAgent: ctx.GetHeader("User-Agent"),
@@ -43,7 +40,6 @@ func (h *RedisHandler) SetJWTToken(ctx *gin.Context,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * 30)),
 		},
 	})
-	tokenStr, err := token.SignedString(AccessTokenKey)
 	if err != nil {
 		return err
 	}
@@ -59,38 +55,67 @@ func (h *RedisHandler) ClearToken(ctx *gin.Context) error {
 	ctx.Header("x-refresh-token", "")
 	// 这里不可能拿不到
 	uc := ctx.MustGet("user").(UserClaims)
-	return h.cmd.Set(ctx, h.key(uc.Ssid),
-		"", h.rtExpiration).Err()
-}
-
-func (h *RedisHandler) key(ssid string) string {
-	return fmt.Sprintf("users:Ssid:%s", ssid)
+	err := h.sessSvc.Revoke(ctx, uc.Id, uc.Ssid)
+	if err == service.ErrSessionNotFound {
+		// 已经被别的设备踢下线了
+		return nil
+	}
+	return err
 }
 
 // SetLoginToken 设置登录后的 token
 func (h *RedisHandler) SetLoginToken(ctx *gin.Context, uid int64) error {
 	ssid := uuid.New().String()
-	err := h.SetJWTToken(ctx, ssid, uid)
+	rid := uuid.New().String()
+	err := h.sessSvc.Create(ctx, domain.Session{
+		Ssid:      ssid,
+		Uid:       uid,
+		UserAgent: ctx.GetHeader("User-Agent"),
+		IP:        ctx.ClientIP(),
+		Expire:    time.Now().Add(h.rtExpiration),
+		RefreshId: rid,
+	})
 	if err != nil {
 		return err
 	}
-	err = h.setRefreshToken(ctx, ssid, uid)
+	err = h.SetJWTToken(ctx, ssid, uid)
+	if err != nil {
+		return err
+	}
+	err = h.setRefreshToken(ctx, ssid, rid, uid)
 	return err
 }
 
+// RotateToken 每次刷新都换一个新的长 token。
+// 如果旧的长 token 又被拿来刷新，说明长 token 很可能泄露了，整个会话都会失效，
+// 拿着偷来的长 token 的人和用户自己都要重新登录
+func (h *RedisHandler) RotateToken(ctx *gin.Context, rc RefreshClaims) error {
+	rid := uuid.New().String()
+	err := h.sessSvc.Rotate(ctx, rc.Id, rc.Ssid, rc.Rid, rid)
+	if err != nil {
+		return err
+	}
+	err = h.SetJWTToken(ctx, rc.Ssid, rc.Id)
+	if err != nil {
+		return err
+	}
+	// 新的长 token 并不会延长会话，会话还是登录之后七天过期
+	return h.setRefreshToken(ctx, rc.Ssid, rid, rc.Id)
+}
+
 func (h *RedisHandler) setRefreshToken(ctx *gin.Context,
-	ssid string,
+	ssid string, rid string,
 	uid int64) error {
 	rc := RefreshClaims{
 		Id:   uid,
 		Ssid: ssid,
+		Rid:  rid,
 		RegisteredClaims: jwt.RegisteredClaims{
-			// 设置为七天过期
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * 7)),
+			// 设置为七天过期，和会话一起过期
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(h.rtExpiration)),
 		},
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, rc)
-	refreshTokenStr, err := refreshToken.SignedString(RefreshTokenKey)
+	refreshTokenStr, err := h.keys.Refresh.Sign(rc)
 	if err != nil {
 		return err
 	}
@@ -98,16 +123,21 @@ func (h *RedisHandler) setRefreshToken(ctx *gin.Context,
 	return nil
 }
 
-func (h *RedisHandler) CheckSession(ctx *gin.Context, ssid string) error {
-	logout, err := h.cmd.Exists(ctx,
-		fmt.Sprintf("users:Ssid:%s", ssid)).Result()
-	if err != nil {
-		return err
-	}
-	if logout > 0 {
-		return errors.New("用户已经退出登录")
-	}
-	return nil
+func (h *RedisHandler) ParseAccessToken(tokenStr string) (UserClaims, error) {
+	var uc UserClaims
+	err := h.keys.Access.Parse(tokenStr, &uc)
+	return uc, err
+}
+
+func (h *RedisHandler) ParseRefreshToken(tokenStr string) (RefreshClaims, error) {
+	var rc RefreshClaims
+	err := h.keys.Refresh.Parse(tokenStr, &rc)
+	return rc, err
+}
+
+// CheckSession 会话不存在，就是退出登录了、被踢下线了或者已经过期了
+func (h *RedisHandler) CheckSession(ctx *gin.Context, uid int64, ssid string) error {
+	return h.sessSvc.Check(ctx, uid, ssid)
 }
 
 func (h *RedisHandler) ExtractTokenString(ctx *gin.Context) string {
@@ -11,13 +11,20 @@ type Handler interface {
 	ClearToken(ctx *gin.Context) error
 	SetLoginToken(ctx *gin.Context, uid int64) error
 	SetJWTToken(ctx *gin.Context, ssid string, uid int64) error
-	CheckSession(ctx *gin.Context, ssid string) error
+	// RotateToken 用长 token 刷新，同时下发新的短 token 和长 token，旧的长 token 失效
+	RotateToken(ctx *gin.Context, rc RefreshClaims) error
+	CheckSession(ctx *gin.Context, uid int64, ssid string) error
+	// ParseAccessToken 按照 token 头部的 kid 找密钥校验
+	ParseAccessToken(tokenStr string) (UserClaims, error)
+	ParseRefreshToken(tokenStr string) (RefreshClaims, error)
 	ExtractTokenString(ctx *gin.Context) string
 }
 
 type RefreshClaims struct {
 	Id   int64
 	Ssid string
+	// Rid 每个长 token 都不一样，用来发现旧的长 token 被重复使用
+	Rid string
 	jwt.RegisteredClaims
 }
 
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AuthPolicy 一个路由要不要登录
+type AuthPolicy string
+
+const (
+	// AuthRequired 必须登录，没有匹配上任何配置的路由都是这个
+	AuthRequired AuthPolicy = "required"
+	// AuthPublic 不需要登录，带了 token 也不会解析
+	AuthPublic AuthPolicy = "public"
+	// AuthOptional 可以不登录。带了 token 的话，token 必须是合法的，
+	// 这样前端才知道要刷新 token，否则登录了的用户就变成了匿名用户
+	AuthOptional AuthPolicy = "optional"
+)
+
+// AuthRoute 路径是 gin 风格的模式，:name 匹配一段，*name 匹配后面所有的。
+// Method 为空的时候匹配所有的 HTTP 方法
+type AuthRoute struct {
+	Method string
+	Path   string
+	Policy AuthPolicy
+}
+
+// CheckAuthRoutes 启动的时候检查配置，免得配错了之后变成需要登录
+func CheckAuthRoutes(routes []AuthRoute) error {
+	for _, r := range routes {
+		switch r.Policy {
+		case AuthRequired, AuthPublic, AuthOptional:
+		default:
+			return fmt.Errorf("路由 %s 的 policy %s 不对", r.Path, r.Policy)
+		}
+		if !strings.HasPrefix(r.Path, "/") {
+			return fmt.Errorf("路由 %s 要以 / 开头", r.Path)
+		}
+	}
+	return nil
+}
+
+type authRoute struct {
+	method   string
+	segments []string
+	policy   AuthPolicy
+}
+
+// authRoutes 按照配置的顺序匹配，第一个匹配上的生效，
+// 所以更具体的路由要放在通配的路由前面
+type authRoutes []authRoute
+
+func newAuthRoutes(routes []AuthRoute) authRoutes {
+	res := make(authRoutes, 0, len(routes))
+	for _, r := range routes {
+		res = append(res, authRoute{
+			method:   strings.ToUpper(r.Method),
+			segments: strings.Split(r.Path, "/"),
+			policy:   r.Policy,
+		})
+	}
+	return res
+}
+
+func (rs authRoutes) policy(method, path string) AuthPolicy {
+	segments := strings.Split(path, "/")
+	for _, r := range rs {
+		if r.method != "" && r.method != method {
+			continue
+		}
+		if r.match(segments) {
+			return r.policy
+		}
+	}
+	return AuthRequired
+}
+
+func (r authRoute) match(segments []string) bool {
+	for i, seg := range r.segments {
+		if strings.HasPrefix(seg, "*") {
+			return len(segments) >= i
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if strings.HasPrefix(seg, ":") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if seg != segments[i] {
+			return false
+		}
+	}
+	return len(segments) == len(r.segments)
+}
+
+// PolicyFunc 给生成接口文档之类的地方用，path 也可以是 gin 风格的模式
+func PolicyFunc(routes []AuthRoute) func(method, path string) AuthPolicy {
+	return newAuthRoutes(routes).policy
+}
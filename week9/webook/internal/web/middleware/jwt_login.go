@@ -2,46 +2,39 @@ package middleware
 
 import (
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
-	"github.com/ecodeclub/ekit/set"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"net/http"
 	"time"
 )
 
 type JWTLoginMiddlewareBuilder struct {
-	publicPaths set.Set[string]
+	routes authRoutes
 	ijwt.Handler
 }
 
-func NewJWTLoginMiddlewareBuilder(hdl ijwt.Handler) *JWTLoginMiddlewareBuilder {
-	s := set.NewMapSet[string](3)
-	s.Add("/users/signup")
-	s.Add("/users/login_sms/code/send")
-	s.Add("/users/login_sms")
-	s.Add("/users/refresh_token")
-	s.Add("/users/login")
-	s.Add("/oauth2/wechat/authurl")
-	s.Add("/oauth2/wechat/callback")
-	s.Add("/test/random")
+// NewJWTLoginMiddlewareBuilder routes 里面没有的路由都需要登录
+func NewJWTLoginMiddlewareBuilder(hdl ijwt.Handler, routes []AuthRoute) *JWTLoginMiddlewareBuilder {
 	return &JWTLoginMiddlewareBuilder{
-		publicPaths: s,
-		Handler:     hdl,
+		routes:  newAuthRoutes(routes),
+		Handler: hdl,
 	}
 }
+
 func (j *JWTLoginMiddlewareBuilder) Build() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		// 不需要校验
-		if j.publicPaths.Exist(ctx.Request.URL.Path) {
+		policy := j.routes.policy(ctx.Request.Method, ctx.Request.URL.Path)
+		if policy == AuthPublic {
+			// 不需要校验
 			return
 		}
 		// 如果是空字符串，你可以预期后面 Parse 就会报错
 		tokenStr := j.ExtractTokenString(ctx)
-		uc := ijwt.UserClaims{}
-		token, err := jwt.ParseWithClaims(tokenStr, &uc, func(token *jwt.Token) (interface{}, error) {
-			return ijwt.AccessTokenKey, nil
-		})
-		if err != nil || !token.Valid {
+		if tokenStr == "" && policy == AuthOptional {
+			// 匿名用户
+			return
+		}
+		uc, err := j.ParseAccessToken(tokenStr)
+		if err != nil {
 			// 不正确的 token
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
@@ -65,7 +58,7 @@ func (j *JWTLoginMiddlewareBuilder) Build() gin.HandlerFunc {
 			return
 		}
 
-		err = j.CheckSession(ctx, uc.Ssid)
+		err = j.CheckSession(ctx, uc.Id, uc.Ssid)
 		if err != nil {
 			// 系统错误或者用户已经主动退出登录了
 			// 这里也可以考虑说，如果在 Redis 已经崩溃的时候，
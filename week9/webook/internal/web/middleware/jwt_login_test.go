@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"errors"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	jwtmocks "gitee.com/geekbang/basic-go/webook/internal/web/jwt/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWTLoginMiddlewareBuilder_Build(t *testing.T) {
+	uc := ijwt.UserClaims{
+		Id:        123,
+		Ssid:      "ssid",
+		UserAgent: "test",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	routes := []AuthRoute{
+		{Path: "/users/signup", Policy: AuthPublic},
+		{Path: "/oauth2/:provider/callback", Policy: AuthPublic},
+		// 更具体的放前面
+		{Path: "/static/private/*filepath", Policy: AuthRequired},
+		{Path: "/static/*filepath", Policy: AuthPublic},
+		{Method: http.MethodGet, Path: "/articles/pub/:id", Policy: AuthOptional},
+	}
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		mock   func(ctrl *gomock.Controller) ijwt.Handler
+
+		wantCode int
+		wantUid  int64
+	}{
+		{
+			name:   "公开的路由",
+			method: http.MethodPost,
+			path:   "/users/signup",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "公开的带参数的路由",
+			method: http.MethodGet,
+			path:   "/oauth2/wechat/callback",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "通配的公开路由",
+			method: http.MethodGet,
+			path:   "/static/js/app.js",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				return jwtmocks.NewMockHandler(ctrl)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "通配路由前面更具体的需要登录",
+			method: http.MethodGet,
+			path:   "/static/private/a.js",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractTokenString(gomock.Any()).Return("")
+				hdl.EXPECT().ParseAccessToken("").Return(ijwt.UserClaims{}, errors.New("mock error"))
+				return hdl
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "可以不登录，没有 token",
+			method: http.MethodGet,
+			path:   "/articles/pub/1",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractTokenString(gomock.Any()).Return("")
+				return hdl
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:   "可以不登录，带了合法的 token",
+			method: http.MethodGet,
+			path:   "/articles/pub/1",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractTokenString(gomock.Any()).Return("token")
+				hdl.EXPECT().ParseAccessToken("token").Return(uc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), int64(123), "ssid").Return(nil)
+				return hdl
+			},
+			wantCode: http.StatusOK,
+			wantUid:  123,
+		},
+		{
+			name:   "可以不登录，但是 token 过期了",
+			method: http.MethodGet,
+			path:   "/articles/pub/1",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractTokenString(gomock.Any()).Return("token")
+				hdl.EXPECT().ParseAccessToken("token").Return(ijwt.UserClaims{}, errors.New("mock error"))
+				return hdl
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "方法不对，需要登录",
+			method: http.MethodPost,
+			path:   "/articles/pub/1",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractTokenString(gomock.Any()).Return("")
+				hdl.EXPECT().ParseAccessToken("").Return(ijwt.UserClaims{}, errors.New("mock error"))
+				return hdl
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "需要登录",
+			method: http.MethodPost,
+			path:   "/users/edit",
+			mock: func(ctrl *gomock.Controller) ijwt.Handler {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ExtractTokenString(gomock.Any()).Return("token")
+				hdl.EXPECT().ParseAccessToken("token").Return(uc, nil)
+				hdl.EXPECT().CheckSession(gomock.Any(), int64(123), "ssid").Return(nil)
+				return hdl
+			},
+			wantCode: http.StatusOK,
+			wantUid:  123,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			gin.SetMode(gin.ReleaseMode)
+			server := gin.New()
+			server.Use(NewJWTLoginMiddlewareBuilder(tc.mock(ctrl), routes).Build())
+			var uid int64
+			server.Any("/*path", func(ctx *gin.Context) {
+				if val, ok := ctx.Get("user"); ok {
+					uid = val.(ijwt.UserClaims).Id
+				}
+				ctx.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("User-Agent", "test")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+			assert.Equal(t, tc.wantUid, uid)
+		})
+	}
+}
+
+func TestCheckAuthRoutes(t *testing.T) {
+	assert.NoError(t, CheckAuthRoutes([]AuthRoute{{Path: "/users/login", Policy: AuthPublic}}))
+	assert.Error(t, CheckAuthRoutes([]AuthRoute{{Path: "/users/login", Policy: "Public"}}))
+	assert.Error(t, CheckAuthRoutes([]AuthRoute{{Path: "users/login", Policy: AuthPublic}}))
+}
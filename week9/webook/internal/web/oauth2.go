@@ -0,0 +1,235 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	uuid "github.com/lithammer/shortuuid/v4"
+	"net/http"
+	"time"
+)
+
+var _ handler = (*OAuth2Handler)(nil)
+
+// OAuth2Handler 所有第三方平台共用的登录、绑定流程，平台之间的差异在 oauth2.Provider 里面
+type OAuth2Handler struct {
+	providers       *oauth2.Providers
+	svc             service.OAuth2Service
+	stateCookieName string
+	stateTokenKey   []byte
+	ijwt.Handler
+}
+
+func NewOAuth2Handler(providers *oauth2.Providers,
+	svc service.OAuth2Service,
+	jwthdl ijwt.Handler) *OAuth2Handler {
+	return &OAuth2Handler{
+		providers: providers,
+		svc:       svc,
+		// 万一后续我们要改，也可以做成可配置的。
+		stateCookieName: "jwt-state",
+		stateTokenKey:   []byte("moyn8y9abnd7q4zkq2m73yw8tu9j5ixB"),
+		Handler:         jwthdl,
+	}
+}
+
+func (h *OAuth2Handler) RegisterRoutes(s *gin.Engine) {
+	g := s.Group("/oauth2")
+	ginx.Handle(g, http.MethodGet, "/:provider/authurl", h.OAuth2URL, ginx.WithResp[string]())
+	// 已经登录的用户绑定第三方账号，和登录共用一个回调
+	ginx.Handle(g, http.MethodGet, "/:provider/bind/authurl", ginx.WrapClaims(h.BindURL),
+		ginx.WithResp[string]())
+	// 这边用 Any 万无一失
+	g.Any("/:provider/callback", h.Callback)
+	ginx.Handle(g, http.MethodPost, "/:provider/unbind", ginx.WrapClaims(h.Unbind))
+	ginx.Handle(g, http.MethodGet, "/bindings", ginx.WrapClaims(h.Bindings),
+		ginx.WithResp[[]OAuth2BindingVO]())
+}
+
+// OAuth2URL 登录用的授权地址
+// 不管有没有登录都是登录用的，所以不用 WrapOptionalClaims
+func (h *OAuth2Handler) OAuth2URL(ctx *gin.Context) {
+	res, err := h.authURL(ctx, 0)
+	if err != nil {
+		res = ginx.ErrResult(ctx, err)
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// BindURL 绑定用的授权地址，把用户 ID 放进 state 里面，回调的时候就知道是绑定
+func (h *OAuth2Handler) BindURL(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	return h.authURL(ctx, uc.Id)
+}
+
+func (h *OAuth2Handler) authURL(ctx *gin.Context, uid int64) (ginx.Result, error) {
+	p, err := h.providers.Get(ctx.Param("provider"))
+	if err != nil {
+		return Result{}, errs.UserInvalidInput.WithMsg("不支持的登录方式", "unsupported login method")
+	}
+	state := uuid.New()
+	url, err := p.AuthURL(ctx, state)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(err)
+	}
+	err = h.setStateCookie(ctx, StateClaims{
+		State:    state,
+		Provider: p.Name(),
+		Uid:      uid,
+	})
+	if err != nil {
+		// 理论上你也可以考虑忽略这个错误，不影响扫码登录
+		return Result{}, errs.UserInternalServerError.Wrap(err)
+	}
+	return Result{
+		Data: url,
+	}, nil
+}
+
+func (h *OAuth2Handler) Callback(ctx *gin.Context) {
+	p, err := h.providers.Get(ctx.Param("provider"))
+	if err != nil {
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInvalidInput.WithMsg("不支持的登录方式", "unsupported login method")))
+		return
+	}
+	// 验证 state
+	sc, err := h.verifyState(ctx, p.Name())
+	if err != nil {
+		// 实际上，但凡进来这里，就说明有人搞你，
+		// 因此这边要做好监控和告警
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx,
+			errs.UserInternalServerError.WithMsg("系统异常，请重试", "system error, please try again")))
+		return
+	}
+
+	info, err := p.VerifyCode(ctx, ctx.Query("code"))
+	if err != nil {
+		// 实际上这个错误，也有可能是 code 不对
+		// 但是给前端的信息没有太大的必要区分究竟是代码不对还是系统本身有问题
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+		return
+	}
+	if sc.Uid > 0 {
+		h.bind(ctx, sc.Uid, info)
+		return
+	}
+	// 这里就是登录成功
+	// 所以你需要设置 JWT
+	u, err := h.svc.FindOrCreate(ctx, info)
+	if err != nil {
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+		return
+	}
+	err = h.SetLoginToken(ctx, u.Id)
+	if err != nil {
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Msg: "登录成功",
+	})
+}
+
+func (h *OAuth2Handler) bind(ctx *gin.Context, uid int64, info domain.OAuth2Info) {
+	err := h.svc.Bind(ctx, uid, info)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{Msg: "绑定成功"})
+	case service.ErrOAuth2BoundByOther:
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserOAuth2BoundByOther))
+	case service.ErrOAuth2AlreadyBound:
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserOAuth2AlreadyBound))
+	default:
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+	}
+}
+
+func (h *OAuth2Handler) Unbind(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.Unbind(ctx, uc.Id, ctx.Param("provider"))
+	switch err {
+	case nil:
+		return Result{Msg: "解绑成功"}, nil
+	case service.ErrOAuth2NotBound:
+		return Result{}, errs.UserOAuth2NotBound
+	case service.ErrOAuth2LastLoginMethod:
+		return Result{}, errs.UserOAuth2LastLoginMethod
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(err)
+	}
+}
+
+func (h *OAuth2Handler) Bindings(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	bs, err := h.svc.Bindings(ctx, uc.Id)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(err)
+	}
+	res := make([]OAuth2BindingVO, 0, len(bs))
+	for _, b := range bs {
+		res = append(res, OAuth2BindingVO{
+			Provider: b.Info.Provider,
+			Nickname: b.Info.Nickname,
+			Ctime:    b.Ctime.Format(time.DateTime),
+		})
+	}
+	return Result{Data: res}, nil
+}
+
+func (h *OAuth2Handler) verifyState(ctx *gin.Context, provider string) (StateClaims, error) {
+	state := ctx.Query("state")
+	ck, err := ctx.Cookie(h.stateCookieName)
+	if err != nil {
+		// 基本上，如果进来这里，就可以认为是有人在搞鬼。
+		return StateClaims{}, fmt.Errorf("%w, 无法获得 cookie", err)
+	}
+	var sc StateClaims
+	_, err = jwt.ParseWithClaims(ck, &sc, func(token *jwt.Token) (interface{}, error) {
+		return h.stateTokenKey, nil
+	})
+	if err != nil {
+		return StateClaims{}, fmt.Errorf("%w, cookie 不是合法 JWT token", err)
+	}
+	if sc.State != state {
+		return StateClaims{}, errors.New("state 被篡改了")
+	}
+	if sc.Provider != provider {
+		return StateClaims{}, errors.New("state 不是这个平台的")
+	}
+	return sc, nil
+}
+
+func (h *OAuth2Handler) setStateCookie(ctx *gin.Context, sc StateClaims) error {
+	const maxAge = 600
+	sc.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Second * maxAge))
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, sc)
+	tokenStr, err := token.SignedString(h.stateTokenKey)
+	if err != nil {
+		return err
+	}
+	ctx.SetCookie(h.stateCookieName, tokenStr,
+		maxAge,
+		// 限制在只能在这里生效。
+		"/oauth2/"+sc.Provider+"/callback",
+		// 这边把 HTTPS 协议禁止了。不过在生产环境中要开启。
+		"", false, true)
+	return nil
+}
+
+type StateClaims struct {
+	State    string
+	Provider string
+	// Uid 大于 0 说明是已经登录的用户在绑定第三方账号
+	Uid int64
+	jwt.RegisteredClaims
+}
+
+type OAuth2BindingVO struct {
+	Provider string `json:"provider"`
+	Nickname string `json:"nickname"`
+	Ctime    string `json:"ctime"`
+}
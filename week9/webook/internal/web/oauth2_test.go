@@ -0,0 +1,174 @@
+package web
+
+import (
+	"encoding/json"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2/github"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	jwtmocks "gitee.com/geekbang/basic-go/webook/internal/web/jwt/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOAuth2Handler_Callback(t *testing.T) {
+	// 假的 GitHub 授权服务器，只认 good-code
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" {
+			_, _ = w.Write([]byte(`{"error":"bad_verification_code"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"gho_token"}`))
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":123,"login":"octocat"}`))
+	})
+	authServer := httptest.NewServer(mux)
+	defer authServer.Close()
+	providers := oauth2.NewProviders(github.NewService(oauth2.Config{
+		ClientId:    "client-id",
+		RedirectURL: "http://localhost/oauth2/github/callback",
+		Endpoint: oauth2.Endpoint{
+			AuthURL:     authServer.URL + "/login/oauth/authorize",
+			TokenURL:    authServer.URL + "/login/oauth/access_token",
+			UserInfoURL: authServer.URL + "/user",
+		},
+	}, authServer.Client()))
+	info := domain.OAuth2Info{Provider: "github", ExternalId: "123", Nickname: "octocat"}
+
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.OAuth2Service, ijwt.Handler)
+		// 获取授权地址的路径，绑定的时候需要登录
+		authPath string
+		uid      int64
+		code     string
+		// 篡改 state
+		state string
+
+		wantResult Result
+	}{
+		{
+			name: "登录成功",
+			mock: func(ctrl *gomock.Controller) (service.OAuth2Service, ijwt.Handler) {
+				svc := svcmocks.NewMockOAuth2Service(ctrl)
+				svc.EXPECT().FindOrCreate(gomock.Any(), info).Return(domain.User{Id: 1}, nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().SetLoginToken(gomock.Any(), int64(1)).Return(nil)
+				return svc, hdl
+			},
+			authPath:   "/oauth2/github/authurl",
+			code:       "good-code",
+			wantResult: Result{Msg: "登录成功"},
+		},
+		{
+			name: "绑定成功",
+			mock: func(ctrl *gomock.Controller) (service.OAuth2Service, ijwt.Handler) {
+				svc := svcmocks.NewMockOAuth2Service(ctrl)
+				svc.EXPECT().Bind(gomock.Any(), int64(2), info).Return(nil)
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			authPath:   "/oauth2/github/bind/authurl",
+			uid:        2,
+			code:       "good-code",
+			wantResult: Result{Msg: "绑定成功"},
+		},
+		{
+			name: "绑定了别的用户",
+			mock: func(ctrl *gomock.Controller) (service.OAuth2Service, ijwt.Handler) {
+				svc := svcmocks.NewMockOAuth2Service(ctrl)
+				svc.EXPECT().Bind(gomock.Any(), int64(2), info).Return(service.ErrOAuth2BoundByOther)
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			authPath: "/oauth2/github/bind/authurl",
+			uid:      2,
+			code:     "good-code",
+			wantResult: Result{Code: errs.UserOAuth2BoundByOther.Code,
+				Msg: "该账号已经绑定了别的用户"},
+		},
+		{
+			name: "code 不对",
+			mock: func(ctrl *gomock.Controller) (service.OAuth2Service, ijwt.Handler) {
+				return svcmocks.NewMockOAuth2Service(ctrl), jwtmocks.NewMockHandler(ctrl)
+			},
+			authPath:   "/oauth2/github/authurl",
+			code:       "bad-code",
+			wantResult: Result{Code: errs.UserInternalServerError.Code, Msg: "系统错误"},
+		},
+		{
+			name: "state 被篡改",
+			mock: func(ctrl *gomock.Controller) (service.OAuth2Service, ijwt.Handler) {
+				return svcmocks.NewMockOAuth2Service(ctrl), jwtmocks.NewMockHandler(ctrl)
+			},
+			authPath:   "/oauth2/github/authurl",
+			code:       "good-code",
+			state:      "bad-state",
+			wantResult: Result{Code: errs.UserInternalServerError.Code, Msg: "系统异常，请重试"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, jwtHdl := tc.mock(ctrl)
+			hdl := NewOAuth2Handler(providers, svc, jwtHdl)
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				// 模拟登录
+				ctx.Set("user", ijwt.UserClaims{Id: tc.uid})
+			})
+			hdl.RegisterRoutes(server)
+
+			// 先拿授权地址，授权地址里面带着 state
+			req, err := http.NewRequest(http.MethodGet, tc.authPath, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			var res Result
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			authURL, err := url.Parse(res.Data.(string))
+			require.NoError(t, err)
+			state := authURL.Query().Get("state")
+			if tc.state != "" {
+				state = tc.state
+			}
+			cookies := recorder.Result().Cookies()
+			require.Len(t, cookies, 1)
+
+			// 用户同意授权之后，平台回调我们
+			req, err = http.NewRequest(http.MethodGet,
+				"/oauth2/github/callback?code="+tc.code+"&state="+url.QueryEscape(state), nil)
+			require.NoError(t, err)
+			req.AddCookie(cookies[0])
+			recorder = httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			res = Result{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantResult, res)
+		})
+	}
+}
+
+func TestOAuth2Handler_UnknownProvider(t *testing.T) {
+	hdl := NewOAuth2Handler(oauth2.NewProviders(), nil, nil)
+	server := gin.New()
+	hdl.RegisterRoutes(server)
+	req, err := http.NewRequest(http.MethodGet, "/oauth2/qq/authurl", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	var res Result
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+	assert.Equal(t, errs.UserInvalidInput.Code, res.Code)
+}
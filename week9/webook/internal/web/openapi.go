@@ -0,0 +1,39 @@
+package web
+
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx/middleware/rbac"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+var _ handler = (*OpenAPIHandler)(nil)
+
+// OpenAPIHandler 给前端看的接口文档，从注册的路由生成。
+// 登录要求和权限用的是和中间件一样的配置，所以不会和实际的行为不一致
+type OpenAPIHandler struct {
+	builder *openapi.Builder
+}
+
+func NewOpenAPIHandler(authRoutes []middleware.AuthRoute, routes *rbac.Routes) *OpenAPIHandler {
+	policy := middleware.PolicyFunc(authRoutes)
+	return &OpenAPIHandler{
+		builder: openapi.NewBuilder("webook", "v1").
+			Auth(func(method, path string) openapi.Auth {
+				switch policy(method, path) {
+				case middleware.AuthPublic:
+					return openapi.AuthPublic
+				case middleware.AuthOptional:
+					return openapi.AuthOptional
+				default:
+					return openapi.AuthRequired
+				}
+			}).
+			Permission(routes.Permission),
+	}
+}
+
+// RegisterRoutes 文档是第一次请求的时候生成的，所以注册的顺序没有关系
+func (h *OpenAPIHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/openapi.json", h.builder.Handler(server))
+}
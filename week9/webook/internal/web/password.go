@@ -0,0 +1,204 @@
+package web
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	perrs "gitee.com/geekbang/basic-go/webook/pkg/errs"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const (
+	bizResetPasswordPhone = "reset_password_phone"
+	bizResetPasswordEmail = "reset_password_email"
+)
+
+var _ handler = &PasswordHandler{}
+
+// PasswordHandler 忘记密码和修改密码
+type PasswordHandler struct {
+	svc       service.PasswordService
+	codeSvc   service.CodeService
+	codeGuard service.CodeGuard
+}
+
+func NewPasswordHandler(svc service.PasswordService,
+	codeSvc service.CodeService,
+	codeGuard service.CodeGuard) *PasswordHandler {
+	return &PasswordHandler{
+		svc:       svc,
+		codeSvc:   codeSvc,
+		codeGuard: codeGuard,
+	}
+}
+
+func (h *PasswordHandler) RegisterRoutes(server *gin.Engine) {
+	pg := server.Group("/users/password")
+	// 忘记密码，不需要登录
+	ginx.HandleReq(pg, http.MethodPost, "/reset/code", h.SendResetCode)
+	ginx.HandleReq(pg, http.MethodPost, "/reset", h.Reset)
+	ginx.HandleClaimsAndReq(pg, http.MethodPost, "/change", h.Change)
+}
+
+// ResetTarget 手机号码和邮箱二选一
+type ResetTarget struct {
+	Phone string `json:"phone"`
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+func (t ResetTarget) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+func (t ResetTarget) Validate() error {
+	if t.Phone == "" && t.Email == "" {
+		return errs.UserInvalidInput.WithMsg("请输入手机号码或者邮箱",
+			"please enter a phone number or email")
+	}
+	return nil
+}
+
+// target 返回 biz、重置方式和手机号码或者邮箱
+func (t ResetTarget) target() (string, string, string) {
+	if t.Phone != "" {
+		return bizResetPasswordPhone, service.PasswordResetByPhone, t.Phone
+	}
+	return bizResetPasswordEmail, service.PasswordResetByEmail, t.Email
+}
+
+type SendResetCodeReq struct {
+	ResetTarget
+	Captcha string `json:"captcha"`
+}
+
+func (h *PasswordHandler) SendResetCode(ctx *gin.Context, req SendResetCodeReq) (ginx.Result, error) {
+	biz, by, target := req.target()
+	// 邮件也可以被拿来刷，所以手机号码和邮箱都要经过防刷检查
+	guardReq := service.CodeSendReq{
+		Biz:     biz,
+		IP:      ctx.ClientIP(),
+		Device:  ctx.GetHeader(deviceFingerprintHeader),
+		Captcha: req.Captcha,
+	}
+	if by == service.PasswordResetByPhone {
+		guardReq.Phone = target
+	} else {
+		guardReq.Email = target
+	}
+	err := h.checkGuard(ctx, guardReq)
+	if err != nil {
+		return Result{}, err
+	}
+	exists, err := h.svc.AccountExists(ctx, by, target)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(fmt.Errorf("查找重置密码的账号失败 %w", err))
+	}
+	if !exists {
+		// 不告诉前端这个手机号码或者邮箱有没有注册，免得被人拿来试探
+		return Result{Msg: "发送成功"}, nil
+	}
+	err = h.codeSvc.Send(ctx, biz, target)
+	switch err {
+	case nil:
+		return Result{Msg: "发送成功"}, nil
+	case service.ErrCodeSendTooMany:
+		return Result{}, errs.UserCodeSendTooMany
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("发送重置密码验证码失败 biz %s, %w", biz, err))
+	}
+}
+
+type ResetPasswordReq struct {
+	ResetTarget
+	Code            string `json:"code" binding:"required"`
+	Password        string `json:"password" binding:"required,password"`
+	ConfirmPassword string `json:"confirmPassword" binding:"required"`
+}
+
+func (req ResetPasswordReq) Validate() error {
+	if err := req.ResetTarget.Validate(); err != nil {
+		return err
+	}
+	return checkConfirmPassword(req.Password, req.ConfirmPassword)
+}
+
+func (h *PasswordHandler) Reset(ctx *gin.Context, req ResetPasswordReq) (ginx.Result, error) {
+	biz, by, target := req.target()
+	ok, err := h.codeSvc.Verify(ctx, biz, target, req.Code)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("校验重置密码验证码失败 biz %s, %w", biz, err))
+	}
+	if !ok {
+		return Result{}, errs.UserInvalidCode
+	}
+	err = h.svc.Reset(ctx, by, target, req.Password)
+	switch err {
+	case nil:
+		return Result{Msg: "重置成功，请重新登录"}, nil
+	case service.ErrPasswordAccountNotFound:
+		// 没有注册的不会发验证码，一般走不到这里
+		return Result{}, errs.UserInvalidCode.Wrap(err)
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(fmt.Errorf("重置密码失败 %w", err))
+	}
+}
+
+type ChangePasswordReq struct {
+	OldPassword     string `json:"oldPassword" binding:"required"`
+	Password        string `json:"password" binding:"required,password"`
+	ConfirmPassword string `json:"confirmPassword" binding:"required"`
+}
+
+func (req ChangePasswordReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+func (req ChangePasswordReq) Validate() error {
+	return checkConfirmPassword(req.Password, req.ConfirmPassword)
+}
+
+func (h *PasswordHandler) Change(ctx *gin.Context, req ChangePasswordReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.Change(ctx, uc.Id, uc.Ssid, req.OldPassword, req.Password)
+	switch err {
+	case nil:
+		return Result{Msg: "修改成功"}, nil
+	case service.ErrWrongPassword:
+		return Result{}, errs.UserInvalidOrPassword.WithMsg("原密码不对", "wrong password")
+	case service.ErrPasswordNotSet:
+		return Result{}, err
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("修改密码失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+// checkGuard 和登录一样的防刷规则，req 里面是手机号码或者邮箱
+func (h *PasswordHandler) checkGuard(ctx *gin.Context, req service.CodeSendReq) error {
+	err := h.codeGuard.Check(ctx, req)
+	switch err {
+	case nil:
+		return nil
+	case service.ErrInvalidPhone, service.ErrCodeSendLimited,
+		service.ErrCaptchaRequired, service.ErrCaptchaFailed:
+		return err
+	default:
+		return errs.UserInternalServerError.Wrap(fmt.Errorf("发送验证码防刷检查失败 %w", err))
+	}
+}
+
+// checkConfirmPassword 两次输入的密码要一样
+func checkConfirmPassword(password, confirmPassword string) error {
+	if password != confirmPassword {
+		return ginx.FieldError{
+			Field: "confirmPassword",
+			Tag:   "eqfield",
+			Err:   errs.UserInvalidInput.WithMsg("两次输入密码不对", "the two passwords do not match"),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestPasswordHandler(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.PasswordService, service.CodeService)
+		// guard 不设置就是不限制
+		guard codeGuardFunc
+		path  string
+		body  string
+
+		wantResult Result
+	}{
+		{
+			name: "没有注册的邮箱不发验证码",
+			mock: func(ctrl *gomock.Controller) (service.PasswordService, service.CodeService) {
+				svc := svcmocks.NewMockPasswordService(ctrl)
+				svc.EXPECT().AccountExists(gomock.Any(), service.PasswordResetByEmail, "123@qq.com").
+					Return(false, nil)
+				return svc, svcmocks.NewMockCodeService(ctrl)
+			},
+			path:       "/users/password/reset/code",
+			body:       `{"email":"123@qq.com"}`,
+			wantResult: Result{Msg: "发送成功"},
+		},
+		{
+			name: "邮箱触发防刷规则",
+			mock: func(ctrl *gomock.Controller) (service.PasswordService, service.CodeService) {
+				return svcmocks.NewMockPasswordService(ctrl), svcmocks.NewMockCodeService(ctrl)
+			},
+			guard: func(ctx context.Context, req service.CodeSendReq) error {
+				if req.Biz != bizResetPasswordEmail || req.Target() != "123@qq.com" {
+					return errors.New("防刷检查的请求不对")
+				}
+				return service.ErrCodeSendLimited
+			},
+			path:       "/users/password/reset/code",
+			body:       `{"email":"123@qq.com"}`,
+			wantResult: Result{Code: errs.UserCodeSendTooMany.Code, Msg: "发送太频繁，请稍后再试"},
+		},
+		{
+			name: "用邮箱重置",
+			mock: func(ctrl *gomock.Controller) (service.PasswordService, service.CodeService) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizResetPasswordEmail, "123@qq.com", "12345678").
+					Return(true, nil)
+				svc := svcmocks.NewMockPasswordService(ctrl)
+				svc.EXPECT().Reset(gomock.Any(), service.PasswordResetByEmail, "123@qq.com", "hello#world123").
+					Return(nil)
+				return svc, codeSvc
+			},
+			path: "/users/password/reset",
+			body: `{"email":"123@qq.com","code":"12345678",` +
+				`"password":"hello#world123","confirmPassword":"hello#world123"}`,
+			wantResult: Result{Msg: "重置成功，请重新登录"},
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) (service.PasswordService, service.CodeService) {
+				codeSvc := svcmocks.NewMockCodeService(ctrl)
+				codeSvc.EXPECT().Verify(gomock.Any(), bizResetPasswordPhone, "15212345678", "123456").
+					Return(false, nil)
+				return svcmocks.NewMockPasswordService(ctrl), codeSvc
+			},
+			path: "/users/password/reset",
+			body: `{"phone":"15212345678","code":"123456",` +
+				`"password":"hello#world123","confirmPassword":"hello#world123"}`,
+			wantResult: Result{Code: errs.UserInvalidCode.Code, Msg: "验证码错误"},
+		},
+		{
+			name: "修改密码保留当前会话",
+			mock: func(ctrl *gomock.Controller) (service.PasswordService, service.CodeService) {
+				svc := svcmocks.NewMockPasswordService(ctrl)
+				svc.EXPECT().Change(gomock.Any(), int64(1), "ssid", "hello#world000", "hello#world123").
+					Return(nil)
+				return svc, svcmocks.NewMockCodeService(ctrl)
+			},
+			path: "/users/password/change",
+			body: `{"oldPassword":"hello#world000",` +
+				`"password":"hello#world123","confirmPassword":"hello#world123"}`,
+			wantResult: Result{Msg: "修改成功"},
+		},
+		{
+			name: "原密码不对",
+			mock: func(ctrl *gomock.Controller) (service.PasswordService, service.CodeService) {
+				svc := svcmocks.NewMockPasswordService(ctrl)
+				svc.EXPECT().Change(gomock.Any(), int64(1), "ssid", "hello#world000", "hello#world123").
+					Return(service.ErrWrongPassword)
+				return svc, svcmocks.NewMockCodeService(ctrl)
+			},
+			path: "/users/password/change",
+			body: `{"oldPassword":"hello#world000",` +
+				`"password":"hello#world123","confirmPassword":"hello#world123"}`,
+			wantResult: Result{Code: errs.UserInvalidOrPassword.Code, Msg: "原密码不对"},
+		},
+		{
+			name: "新密码太简单",
+			mock: func(ctrl *gomock.Controller) (service.PasswordService, service.CodeService) {
+				return svcmocks.NewMockPasswordService(ctrl), svcmocks.NewMockCodeService(ctrl)
+			},
+			path: "/users/password/change",
+			body: `{"oldPassword":"hello#world000","password":"123","confirmPassword":"123"}`,
+			wantResult: Result{Code: errs.UserInvalidInput.Code,
+				Msg: "密码必须包含数字、特殊字符，并且长度不能小于 8 位",
+				Data: []any{map[string]any{"field": "password", "tag": "password",
+					"msg": "密码必须包含数字、特殊字符，并且长度不能小于 8 位"}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, codeSvc := tc.mock(ctrl)
+			guard := tc.guard
+			if guard == nil {
+				guard = func(ctx context.Context, req service.CodeSendReq) error {
+					return nil
+				}
+			}
+			hdl := NewPasswordHandler(svc, codeSvc, guard)
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Id: 1, Ssid: "ssid"})
+			})
+			hdl.RegisterRoutes(server)
+
+			res := doJSON(t, server, tc.path, tc.body)
+			assert.Equal(t, tc.wantResult, res)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	perrs "gitee.com/geekbang/basic-go/webook/pkg/errs"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/ecodeclub/ekit/slice"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const permissionRBACManage = "rbac:manage"
+
+var _ handler = (*RBACHandler)(nil)
+
+// RBACHandler 管理后台给用户分配角色
+type RBACHandler struct {
+	svc service.RBACService
+}
+
+func NewRBACHandler(svc service.RBACService) *RBACHandler {
+	return &RBACHandler{
+		svc: svc,
+	}
+}
+
+func (h *RBACHandler) RegisterRoutes(s *gin.Engine) {
+	g := s.Group("/admin/rbac")
+	ginx.HandleReq(g, http.MethodPost, "/roles", h.Roles, ginx.WithResp[[]RoleVO](),
+		ginx.WithPermission(permissionRBACManage))
+	ginx.HandleReq(g, http.MethodPost, "/assign", h.Assign, ginx.WithPermission(permissionRBACManage))
+	ginx.HandleReq(g, http.MethodPost, "/revoke", h.Revoke, ginx.WithPermission(permissionRBACManage))
+}
+
+func (h *RBACHandler) Roles(ctx *gin.Context, req UserRolesReq) (ginx.Result, error) {
+	if req.Uid <= 0 {
+		return ginx.Result{}, errs.RBACInvalidInput
+	}
+	roles, err := h.svc.Roles(ctx, req.Uid)
+	if err != nil {
+		return ginx.Result{}, errs.RBACInternalServerError.Wrap(err)
+	}
+	return ginx.Result{
+		Data: slice.Map(roles, func(idx int, src domain.Role) RoleVO {
+			return RoleVO{
+				Name:        src.Name,
+				Description: src.Description,
+				Permissions: src.Permissions,
+			}
+		}),
+	}, nil
+}
+
+func (h *RBACHandler) Assign(ctx *gin.Context, req UserRoleReq) (ginx.Result, error) {
+	if req.Uid <= 0 || req.Role == "" {
+		return ginx.Result{}, errs.RBACInvalidInput
+	}
+	err := h.svc.AssignRole(ctx, req.Uid, req.Role)
+	switch err {
+	case nil:
+		return ginx.Result{Msg: "OK"}, nil
+	case service.ErrRoleNotFound:
+		return ginx.Result{}, err
+	default:
+		return ginx.Result{}, errs.RBACInternalServerError.Wrap(err)
+	}
+}
+
+func (h *RBACHandler) Revoke(ctx *gin.Context, req UserRoleReq) (ginx.Result, error) {
+	if req.Uid <= 0 || req.Role == "" {
+		return ginx.Result{}, errs.RBACInvalidInput
+	}
+	err := h.svc.RevokeRole(ctx, req.Uid, req.Role)
+	switch err {
+	case nil:
+		return ginx.Result{Msg: "OK"}, nil
+	case service.ErrUserRoleNotFound:
+		return ginx.Result{}, err
+	default:
+		return ginx.Result{}, errs.RBACInternalServerError.Wrap(err)
+	}
+}
+
+type UserRolesReq struct {
+	Uid int64 `json:"uid"`
+}
+
+func (req UserRolesReq) InvalidInput() *perrs.Error {
+	return errs.RBACInvalidInput
+}
+
+type UserRoleReq struct {
+	Uid  int64  `json:"uid"`
+	Role string `json:"role"`
+}
+
+func (req UserRoleReq) InvalidInput() *perrs.Error {
+	return errs.RBACInvalidInput
+}
+
+type RoleVO struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
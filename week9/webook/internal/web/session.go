@@ -0,0 +1,101 @@
+package web
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+var _ handler = (*SessionHandler)(nil)
+
+// SessionHandler 查看自己在哪些设备上登录了，以及把设备踢下线
+type SessionHandler struct {
+	svc service.SessionService
+	ijwt.Handler
+}
+
+func NewSessionHandler(svc service.SessionService, jwthdl ijwt.Handler) *SessionHandler {
+	return &SessionHandler{
+		svc:     svc,
+		Handler: jwthdl,
+	}
+}
+
+func (h *SessionHandler) RegisterRoutes(server *gin.Engine) {
+	ug := server.Group("/users/sessions")
+	ginx.Handle(ug, http.MethodGet, "", ginx.WrapClaims(h.List), ginx.WithResp[[]SessionVO]())
+	ginx.Handle(ug, http.MethodDelete, "/:ssid", ginx.WrapClaims(h.Revoke))
+	// 退出所有设备
+	ginx.Handle(ug, http.MethodDelete, "", ginx.WrapClaims(h.RevokeAll))
+}
+
+func (h *SessionHandler) List(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	ss, err := h.svc.List(ctx, uc.Id)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("查询会话失败 uid %d, %w", uc.Id, err))
+	}
+	res := make([]SessionVO, 0, len(ss))
+	for _, s := range ss {
+		res = append(res, SessionVO{
+			Ssid:      s.Ssid,
+			UserAgent: s.UserAgent,
+			IP:        s.IP,
+			Ctime:     s.Ctime.Format(time.DateTime),
+			Utime:     s.Utime.Format(time.DateTime),
+			Current:   s.Ssid == uc.Ssid,
+		})
+	}
+	return Result{Data: res}, nil
+}
+
+func (h *SessionHandler) Revoke(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	ssid := ctx.Param("ssid")
+	if ssid == uc.Ssid {
+		// 踢自己就是退出登录，顺便把 token 清掉
+		return h.logout(ctx)
+	}
+	err := h.svc.Revoke(ctx, uc.Id, ssid)
+	switch err {
+	case nil:
+		return Result{Msg: "OK"}, nil
+	case service.ErrSessionNotFound:
+		return Result{}, errs.UserSessionNotFound
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("踢下线失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+func (h *SessionHandler) RevokeAll(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.RevokeAll(ctx, uc.Id)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("退出所有设备失败 uid %d, %w", uc.Id, err))
+	}
+	return h.logout(ctx)
+}
+
+func (h *SessionHandler) logout(ctx *gin.Context) (ginx.Result, error) {
+	err := h.ClearToken(ctx)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(err)
+	}
+	return Result{Msg: "OK"}, nil
+}
+
+type SessionVO struct {
+	Ssid      string `json:"ssid"`
+	UserAgent string `json:"userAgent"`
+	IP        string `json:"ip"`
+	Ctime     string `json:"ctime"`
+	// Utime 最后活跃时间
+	Utime string `json:"utime"`
+	// Current 是不是当前正在用的这个会话
+	Current bool `json:"current"`
+}
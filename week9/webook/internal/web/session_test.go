@@ -0,0 +1,118 @@
+package web
+
+import (
+	"encoding/json"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	jwtmocks "gitee.com/geekbang/basic-go/webook/internal/web/jwt/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionHandler(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) (service.SessionService, ijwt.Handler)
+		method string
+		path   string
+
+		wantResult Result
+	}{
+		{
+			name: "查询会话",
+			mock: func(ctrl *gomock.Controller) (service.SessionService, ijwt.Handler) {
+				svc := svcmocks.NewMockSessionService(ctrl)
+				svc.EXPECT().List(gomock.Any(), int64(1)).Return([]domain.Session{
+					{Ssid: "current", UserAgent: "chrome", IP: "127.0.0.1"},
+					{Ssid: "other", UserAgent: "safari", IP: "127.0.0.2"},
+				}, nil)
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			method: http.MethodGet,
+			path:   "/users/sessions",
+			wantResult: Result{Data: []any{
+				map[string]any{"ssid": "current", "userAgent": "chrome", "ip": "127.0.0.1",
+					"ctime": "0001-01-01 00:00:00", "utime": "0001-01-01 00:00:00", "current": true},
+				map[string]any{"ssid": "other", "userAgent": "safari", "ip": "127.0.0.2",
+					"ctime": "0001-01-01 00:00:00", "utime": "0001-01-01 00:00:00", "current": false},
+			}},
+		},
+		{
+			name: "踢别的设备下线",
+			mock: func(ctrl *gomock.Controller) (service.SessionService, ijwt.Handler) {
+				svc := svcmocks.NewMockSessionService(ctrl)
+				svc.EXPECT().Revoke(gomock.Any(), int64(1), "other").Return(nil)
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			method:     http.MethodDelete,
+			path:       "/users/sessions/other",
+			wantResult: Result{Msg: "OK"},
+		},
+		{
+			name: "踢自己下线就是退出登录",
+			mock: func(ctrl *gomock.Controller) (service.SessionService, ijwt.Handler) {
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ClearToken(gomock.Any()).Return(nil)
+				return svcmocks.NewMockSessionService(ctrl), hdl
+			},
+			method:     http.MethodDelete,
+			path:       "/users/sessions/current",
+			wantResult: Result{Msg: "OK"},
+		},
+		{
+			name: "会话不存在",
+			mock: func(ctrl *gomock.Controller) (service.SessionService, ijwt.Handler) {
+				svc := svcmocks.NewMockSessionService(ctrl)
+				svc.EXPECT().Revoke(gomock.Any(), int64(1), "other").
+					Return(service.ErrSessionNotFound)
+				return svc, jwtmocks.NewMockHandler(ctrl)
+			},
+			method:     http.MethodDelete,
+			path:       "/users/sessions/other",
+			wantResult: Result{Code: errs.UserSessionNotFound.Code, Msg: "会话不存在"},
+		},
+		{
+			name: "退出所有设备",
+			mock: func(ctrl *gomock.Controller) (service.SessionService, ijwt.Handler) {
+				svc := svcmocks.NewMockSessionService(ctrl)
+				svc.EXPECT().RevokeAll(gomock.Any(), int64(1)).Return(nil)
+				hdl := jwtmocks.NewMockHandler(ctrl)
+				hdl.EXPECT().ClearToken(gomock.Any()).Return(nil)
+				return svc, hdl
+			},
+			method:     http.MethodDelete,
+			path:       "/users/sessions",
+			wantResult: Result{Msg: "OK"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, jwtHdl := tc.mock(ctrl)
+			hdl := NewSessionHandler(svc, jwtHdl)
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Id: 1, Ssid: "current"})
+			})
+			hdl.RegisterRoutes(server)
+
+			req, err := http.NewRequest(tc.method, tc.path, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantResult, res)
+		})
+	}
+}
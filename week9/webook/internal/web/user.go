@@ -1,48 +1,48 @@
 package web
 
 import (
+	"fmt"
 	"gitee.com/geekbang/basic-go/webook/internal/domain"
 	"gitee.com/geekbang/basic-go/webook/internal/errs"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	perrs "gitee.com/geekbang/basic-go/webook/pkg/errs"
 	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
-	regexp "github.com/dlclark/regexp2"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"net/http"
 	"time"
 )
 
 const (
-	emailRegexPattern = "^\\w+([-+.]\\w+)*@\\w+([-.]\\w+)*\\.\\w+([-.]\\w+)*$"
-	// 和上面比起来，用 ` 看起来就比较清爽
-	passwordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,}$`
-
 	userIdKey = "userId"
 	bizLogin  = "login"
+	// deviceFingerprintHeader 前端采集的设备指纹放在这个 header 里面
+	deviceFingerprintHeader = "X-Device-Fingerprint"
 )
 
 var _ handler = &UserHandler{}
 
 type UserHandler struct {
-	svc              service.UserService
-	codeSvc          service.CodeService
-	emailRegexExp    *regexp.Regexp
-	passwordRegexExp *regexp.Regexp
+	svc        service.UserService
+	profileSvc service.ProfileService
+	codeSvc    service.CodeService
+	codeGuard  service.CodeGuard
 	ijwt.Handler
 }
 
 func NewUserHandler(svc service.UserService,
-	codeSvc service.CodeService, jwthdl ijwt.Handler) *UserHandler {
+	profileSvc service.ProfileService,
+	codeSvc service.CodeService,
+	codeGuard service.CodeGuard,
+	jwthdl ijwt.Handler) *UserHandler {
 	return &UserHandler{
-		svc:              svc,
-		codeSvc:          codeSvc,
-		emailRegexExp:    regexp.MustCompile(emailRegexPattern, regexp.None),
-		passwordRegexExp: regexp.MustCompile(passwordRegexPattern, regexp.None),
-		Handler:          jwthdl,
+		svc:        svc,
+		profileSvc: profileSvc,
+		codeSvc:    codeSvc,
+		codeGuard:  codeGuard,
+		Handler:    jwthdl,
 	}
 }
 
@@ -55,35 +55,44 @@ func (c *UserHandler) RegisterRoutes(server *gin.Engine) {
 
 	// 分组注册
 	ug := server.Group("/users")
-	ug.POST("/signup", ginx.WrapReq[SignUpReq](c.SignUp))
+	ginx.HandleReq(ug, http.MethodPost, "/signup", c.SignUp)
 	// session 机制
 	//ug.POST("/login", c.Login)
 	// JWT 机制
-	ug.POST("/login", c.LoginJWT)
+	ginx.HandleReq(ug, http.MethodPost, "/login", c.LoginJWT)
 	ug.POST("/logout", c.Logout)
-	ug.POST("/edit", c.Edit)
+	ginx.HandleClaimsAndReq(ug, http.MethodPost, "/edit", c.Edit)
 	//ug.GET("/profile", c.Profile)
-	ug.GET("/profile", c.ProfileJWT)
-	ug.POST("/login_sms/code/send", c.SendSMSLoginCode)
-	ug.POST("/login_sms", c.LoginSMS)
+	// 个人资料没有用 Result 包起来
+	ginx.Handle(ug, http.MethodGet, "/profile", c.ProfileJWT, ginx.WithRawResp[ProfileVo]())
+	// 上传头像是 multipart 的，没有 JSON 请求体
+	ginx.Handle(ug, http.MethodPost, "/avatar", c.UploadAvatar, ginx.WithResp[AvatarVo]())
+	// 作者主页，不需要登录
+	ginx.Handle(ug, http.MethodGet, "/:id/public", c.PublicProfile, ginx.WithResp[PublicProfileVo]())
+	ginx.HandleReq(ug, http.MethodPost, "/login_sms/code/send", c.SendSMSLoginCode)
+	ginx.HandleReq(ug, http.MethodPost, "/login_sms", c.LoginSMS)
 	ug.POST("/refresh_token", c.RefreshToken)
 }
 
 func (c *UserHandler) RefreshToken(ctx *gin.Context) {
 	// 假定长 token 也放在这里
 	tokenStr := c.ExtractTokenString(ctx)
-	var rc ijwt.RefreshClaims
-	token, err := jwt.ParseWithClaims(tokenStr, &rc, func(token *jwt.Token) (interface{}, error) {
-		return ijwt.RefreshTokenKey, nil
-	})
+	rc, err := c.ParseRefreshToken(tokenStr)
 	// 这边要保持和登录校验一直的逻辑，即返回 401 响应
-	if err != nil || token == nil || !token.Valid {
-		ctx.JSON(http.StatusUnauthorized, Result{Code: 4, Msg: "请登录"})
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, ginx.ErrResult(ctx, perrs.Unauthorized))
 		return
 	}
 
-	// 校验 ssid
-	err = c.CheckSession(ctx, rc.Ssid)
+	// 校验 ssid，同时换一个新的长 token
+	err = c.RotateToken(ctx, rc)
+	if err == service.ErrRefreshTokenReused {
+		// 旧的长 token 又被用了，整个会话已经作废
+		zap.L().Warn("长 token 被重复使用，可能已经泄露",
+			zap.Int64("uid", rc.Id), zap.String("ssid", rc.Ssid))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
 		// 系统错误或者用户已经主动退出登录了
 		// 这里也可以考虑说，如果在 Redis 已经崩溃的时候，
@@ -91,174 +100,154 @@ func (c *UserHandler) RefreshToken(ctx *gin.Context) {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
-
-	err = c.SetJWTToken(ctx, rc.Ssid, rc.Id)
-	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, Result{Code: 4, Msg: "请登录"})
-		return
-	}
 	ctx.JSON(http.StatusOK, Result{Msg: "刷新成功"})
 }
 
-func (c *UserHandler) LoginSMS(ctx *gin.Context) {
-	type Req struct {
-		Phone string `json:"phone"`
-		Code  string `json:"code"`
-	}
-	var req Req
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
+type LoginSMSReq struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+func (req LoginSMSReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+func (c *UserHandler) LoginSMS(ctx *gin.Context, req LoginSMSReq) (ginx.Result, error) {
 	ok, err := c.codeSvc.Verify(ctx, bizLogin, req.Phone, req.Code)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统异常"})
-		zap.L().Error("用户手机号码登录失败", zap.Error(err))
-		return
+		return Result{}, errs.UserInternalServerError.Wrap(fmt.Errorf("用户手机号码登录失败 %w", err))
 	}
 	if !ok {
-		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "验证码错误"})
-		return
+		return Result{}, errs.UserInvalidCode
 	}
 
 	// 验证码是对的
 	// 登录或者注册用户
 	u, err := c.svc.FindOrCreate(ctx, req.Phone)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "系统错误"})
-		return
+		return Result{}, errs.UserInternalServerError.Wrap(fmt.Errorf("手机号码登录查找或者创建用户失败 %w", err))
 	}
-	// 用 uuid 来标识这一次会话
-	ssid := uuid.New().String()
-	err = c.SetJWTToken(ctx, ssid, u.Id)
+	err = c.SetLoginToken(ctx, u.Id)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{Msg: "系统错误"})
-		return
+		return Result{}, errs.UserInternalServerError.Wrap(fmt.Errorf("设置登录 token 失败 uid %d, %w", u.Id, err))
 	}
-	ctx.JSON(http.StatusOK, Result{Msg: "登录成功"})
+	return Result{Msg: "登录成功"}, nil
+}
+
+type SMSLoginCodeReq struct {
+	Phone string `json:"phone"`
+	// Captcha 要求人机验证之后才需要
+	Captcha string `json:"captcha"`
+}
+
+func (req SMSLoginCodeReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
 }
 
 // SendSMSLoginCode 发送短信验证码
-func (c *UserHandler) SendSMSLoginCode(ctx *gin.Context) {
-	type Req struct {
-		Phone string `json:"phone"`
-	}
-	var req Req
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
-	// 你也可以用正则表达式校验是不是合法的手机号
+// 除了 1 分钟内不能重发，还要防止有人换着手机号码刷我们的短信
+func (c *UserHandler) SendSMSLoginCode(ctx *gin.Context, req SMSLoginCodeReq) (ginx.Result, error) {
 	if req.Phone == "" {
-		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "请输入手机号码"})
-		return
+		return Result{}, errs.UserInvalidPhone.WithMsg("请输入手机号码",
+			"please enter a phone number")
+	}
+	err := c.codeGuard.Check(ctx, service.CodeSendReq{
+		Biz:     bizLogin,
+		Phone:   req.Phone,
+		IP:      ctx.ClientIP(),
+		Device:  ctx.GetHeader(deviceFingerprintHeader),
+		Captcha: req.Captcha,
+	})
+	switch err {
+	case nil:
+	case service.ErrInvalidPhone, service.ErrCodeSendLimited,
+		service.ErrCaptchaRequired, service.ErrCaptchaFailed:
+		return Result{}, err
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(fmt.Errorf("发送验证码防刷检查失败 %w", err))
 	}
-	err := c.codeSvc.Send(ctx, bizLogin, req.Phone)
+	err = c.codeSvc.Send(ctx, bizLogin, req.Phone)
 	switch err {
 	case nil:
-		ctx.JSON(http.StatusOK, Result{Msg: "发送成功"})
+		return Result{Msg: "发送成功"}, nil
 	case service.ErrCodeSendTooMany:
-		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "短信发送太频繁，请稍后再试"})
+		return Result{}, errs.UserCodeSendTooMany
 	default:
-		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
-		// 要打印日志
-		return
+		return Result{}, errs.UserInternalServerError.Wrap(fmt.Errorf("发送登录验证码失败 %w", err))
 	}
 }
 
 type SignUpReq struct {
-	Email           string `json:"email"`
-	Password        string `json:"password"`
-	ConfirmPassword string `json:"confirmPassword"`
+	Email           string `json:"email" binding:"required,email"`
+	Password        string `json:"password" binding:"required,password"`
+	ConfirmPassword string `json:"confirmPassword" binding:"required"`
 }
 
-// SignUp 用户注册接口
-func (c *UserHandler) SignUp(ctx *gin.Context, req SignUpReq) (ginx.Result, error) {
-
-	isEmail, err := c.emailRegexExp.MatchString(req.Email)
-	if err != nil {
-		return Result{
-			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
-		}, err
-	}
-	if !isEmail {
-		return Result{
-			Code: errs.UserInvalidInput,
-			Msg:  "邮箱输入错误",
-		}, nil
-	}
+func (req SignUpReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
 
+// Validate 两次输入的密码要一样
+func (req SignUpReq) Validate() error {
 	if req.Password != req.ConfirmPassword {
-		return Result{
-			Code: errs.UserInvalidInput,
-			Msg:  "两次输入密码不对",
-		}, nil
+		return ginx.FieldError{
+			Field: "confirmPassword",
+			Tag:   "eqfield",
+			Err:   errs.UserInvalidInput.WithMsg("两次输入密码不对", "the two passwords do not match"),
+		}
 	}
+	return nil
+}
 
-	isPassword, err := c.passwordRegexExp.MatchString(req.Password)
-	if err != nil {
-		return Result{
-			Code: errs.UserInvalidInput,
-			Msg:  "系统错误",
-		}, err
-	}
-	if !isPassword {
-		return Result{
-			Code: errs.UserInvalidInput,
-			Msg:  "密码必须包含数字、特殊字符，并且长度不能小于 8 位",
-		}, nil
-	}
+// SignUp 用户注册接口
+func (c *UserHandler) SignUp(ctx *gin.Context, req SignUpReq) (ginx.Result, error) {
 
-	err = c.svc.Signup(ctx.Request.Context(),
+	// 邮箱、密码的格式在 WrapReq 里面已经校验过了
+	// 返回带错误码的 error 就可以了，WrapReq 会转换成 Result
+	err := c.svc.Signup(ctx.Request.Context(),
 		domain.User{Email: req.Email, Password: req.ConfirmPassword})
-
 	if err == service.ErrUserDuplicateEmail {
-		return Result{
-			Code: errs.UserDuplicateEmail,
-			Msg:  "邮箱冲突",
-		}, err
+		return Result{}, err
 	}
 	if err != nil {
-		return Result{
-			Code: errs.UserInternalServerError,
-			Msg:  "系统错误",
-		}, err
+		return Result{}, errs.UserInternalServerError.Wrap(err)
 	}
 	return Result{
 		Msg: "OK",
 	}, nil
 }
 
-// LoginJWT 用户登录接口，使用的是 JWT，如果你想要测试 JWT，就启用这个
-func (c *UserHandler) LoginJWT(ctx *gin.Context) {
-	type LoginReq struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
+type LoginReq struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
 
-	var req LoginReq
-	// 当我们调用 Bind 方法的时候，如果有问题，Bind 方法已经直接写响应回去了
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
+func (req LoginReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+// LoginJWT 用户登录接口，使用的是 JWT，如果你想要测试 JWT，就启用这个
+func (c *UserHandler) LoginJWT(ctx *gin.Context, req LoginReq) (ginx.Result, error) {
 	u, err := c.svc.Login(ctx.Request.Context(), req.Email, req.Password)
-	if err == service.ErrInvalidUserOrPassword {
-		ctx.String(http.StatusOK, "用户名或者密码不正确，请重试")
-		return
+	switch err {
+	case nil:
+	case service.ErrInvalidUserOrPassword:
+		return Result{}, err
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(fmt.Errorf("邮箱登录失败 %w", err))
 	}
 	err = c.SetLoginToken(ctx, u.Id)
 	if err != nil {
-		ctx.String(http.StatusOK, "系统异常")
-		return
+		return Result{}, errs.UserInternalServerError.Wrap(fmt.Errorf("设置登录 token 失败 uid %d, %w", u.Id, err))
 	}
-	ctx.String(http.StatusOK, "登录成功")
+	return Result{Msg: "登录成功"}, nil
 }
 
 func (c *UserHandler) Logout(ctx *gin.Context) {
 	err := c.ClearToken(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{
-			Msg: "系统错误",
-		})
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+		zap.L().Error("退出登录失败", zap.Error(err))
 		return
 	}
 	ctx.JSON(http.StatusOK, Result{
@@ -297,44 +286,29 @@ func (c *UserHandler) Login(ctx *gin.Context) {
 	ctx.String(http.StatusOK, "登录成功")
 }
 
-// Edit 用户编译信息
-func (c *UserHandler) Edit(ctx *gin.Context) {
-	type Req struct {
-		// 注意，其它字段，尤其是密码、邮箱和手机，
-		// 修改都要通过别的手段
-		// 邮箱和手机都要验证
-		// 密码更加不用多说了
-		Nickname string `json:"nickname"`
-		// 2023-01-01
-		Birthday string `json:"birthday"`
-		AboutMe  string `json:"aboutMe"`
-	}
-
-	var req Req
-	if err := ctx.Bind(&req); err != nil {
-		return
-	}
-	// 你可以尝试在这里校验。
-	// 比如说你可以要求 Nickname 必须不为空
+// EditReq 注意，其它字段，尤其是密码、邮箱和手机，
+// 修改都要通过别的手段
+// 邮箱和手机都要验证，在 AccountHandler 里面
+// 密码在 PasswordHandler 里面
+type EditReq struct {
 	// 校验规则取决于产品经理
-	if req.Nickname == "" {
-		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "昵称不能为空"})
-		return
-	}
+	Nickname string `json:"nickname" binding:"required"`
+	// 2023-01-01
+	Birthday string `json:"birthday" binding:"date"`
+	AboutMe  string `json:"aboutMe" binding:"max=1024"`
+}
 
-	if len(req.AboutMe) > 1024 {
-		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "关于我过长"})
-		return
-	}
+func (req EditReq) InvalidInput() *perrs.Error {
+	return errs.UserInvalidInput
+}
+
+// Edit 用户编译信息
+func (c *UserHandler) Edit(ctx *gin.Context, req EditReq, uc ijwt.UserClaims) (ginx.Result, error) {
+	// 格式在 WrapClaimsAndReq 里面已经校验过了，所以这里不会出错
 	birthday, err := time.Parse(time.DateOnly, req.Birthday)
 	if err != nil {
-		// 也就是说，我们其实并没有直接校验具体的格式
-		// 而是如果你能转化过来，那就说明没问题
-		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "日期格式不对"})
-		return
+		return Result{}, errs.UserInvalidInput.Wrap(err)
 	}
-
-	uc := ctx.MustGet("user").(ijwt.UserClaims)
 	err = c.svc.UpdateNonSensitiveInfo(ctx, domain.User{
 		Id:       uc.Id,
 		Nickname: req.Nickname,
@@ -342,35 +316,49 @@ func (c *UserHandler) Edit(ctx *gin.Context) {
 		Birthday: birthday,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusOK, Result{Code: 5, Msg: "系统错误"})
-		return
+		return Result{}, errs.UserInternalServerError.Wrap(err)
 	}
-	ctx.JSON(http.StatusOK, Result{Msg: "OK"})
+	return Result{Msg: "OK"}, nil
+}
+
+// ProfileVo 个人资料，历史原因字段没有 json 标签
+type ProfileVo struct {
+	Email       string
+	Phone       string
+	Nickname    string
+	Birthday    string
+	AboutMe     string
+	Avatar      string
+	AvatarThumb string
+	Stats       UserStatsVo
 }
 
 // ProfileJWT 用户详情, JWT 版本
 func (c *UserHandler) ProfileJWT(ctx *gin.Context) {
-	type Profile struct {
-		Email    string
-		Phone    string
-		Nickname string
-		Birthday string
-		AboutMe  string
-	}
 	uc := ctx.MustGet("user").(ijwt.UserClaims)
 	u, err := c.svc.Profile(ctx, uc.Id)
 	if err != nil {
 		// 按照道理来说，这边 id 对应的数据肯定存在，所以要是没找到，
 		// 那就说明是系统出了问题。
-		ctx.String(http.StatusOK, "系统错误")
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+		zap.L().Error("查询个人资料失败", zap.Int64("uid", uc.Id), zap.Error(err))
 		return
 	}
-	ctx.JSON(http.StatusOK, Profile{
-		Email:    u.Email,
-		Phone:    u.Phone,
-		Nickname: u.Nickname,
-		Birthday: u.Birthday.Format(time.DateOnly),
-		AboutMe:  u.AboutMe,
+	// 统计数据查不到不影响看个人资料
+	stats, err := c.profileSvc.Stats(ctx, uc.Id)
+	if err != nil {
+		zap.L().Error("查询用户统计数据失败",
+			zap.Int64("uid", uc.Id), zap.Error(err))
+	}
+	ctx.JSON(http.StatusOK, ProfileVo{
+		Email:       u.Email,
+		Phone:       u.Phone,
+		Nickname:    u.Nickname,
+		Birthday:    u.Birthday.Format(time.DateOnly),
+		AboutMe:     u.AboutMe,
+		Avatar:      u.Avatar,
+		AvatarThumb: u.AvatarThumb,
+		Stats:       newUserStatsVo(stats),
 	})
 }
 
@@ -385,7 +373,7 @@ func (c *UserHandler) Profile(ctx *gin.Context) {
 	if err != nil {
 		// 按照道理来说，这边 id 对应的数据肯定存在，所以要是没找到，
 		// 那就说明是系统出了问题。
-		ctx.String(http.StatusOK, "系统错误")
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
 		return
 	}
 	ctx.JSON(http.StatusOK, Profile{
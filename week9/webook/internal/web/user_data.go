@@ -0,0 +1,106 @@
+package web
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+var _ handler = (*UserDataHandler)(nil)
+
+// UserDataHandler 导出个人数据和注销账号
+type UserDataHandler struct {
+	svc service.UserDataService
+}
+
+func NewUserDataHandler(svc service.UserDataService) *UserDataHandler {
+	return &UserDataHandler{
+		svc: svc,
+	}
+}
+
+func (h *UserDataHandler) RegisterRoutes(server *gin.Engine) {
+	ug := server.Group("/users")
+	ginx.Handle(ug, http.MethodPost, "/export", ginx.WrapClaims(h.Export))
+	// 查询导出的进度，完成了就带上下载链接
+	ginx.Handle(ug, http.MethodGet, "/export", ginx.WrapClaims(h.ExportStatus),
+		ginx.WithResp[DataExportVO]())
+	ginx.Handle(ug, http.MethodPost, "/delete", ginx.WrapClaims(h.Delete),
+		ginx.WithResp[AccountDeletionVO]())
+	ginx.Handle(ug, http.MethodPost, "/delete/cancel", ginx.WrapClaims(h.CancelDelete))
+}
+
+func (h *UserDataHandler) Export(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.RequestExport(ctx, uc.Id)
+	switch err {
+	case nil:
+		return Result{Msg: "正在导出，请稍后查看"}, nil
+	case service.ErrExportInProgress:
+		return Result{}, errs.UserExportInProgress
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("申请导出个人数据失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+func (h *UserDataHandler) ExportStatus(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	e, url, err := h.svc.LatestExport(ctx, uc.Id)
+	switch err {
+	case nil:
+		return Result{Data: DataExportVO{
+			Status: e.Status.String(),
+			Url:    url,
+			Ctime:  e.Ctime.Format(time.DateTime),
+		}}, nil
+	case service.ErrExportNotFound:
+		return Result{}, errs.UserExportNotFound
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("查询导出个人数据失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+func (h *UserDataHandler) Delete(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	d, err := h.svc.RequestDeletion(ctx, uc.Id)
+	if err != nil {
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("申请注销账号失败 uid %d, %w", uc.Id, err))
+	}
+	return Result{
+		Msg: "已经申请注销，冷静期内可以撤销",
+		Data: AccountDeletionVO{
+			ExecTime: d.ExecTime.Format(time.DateTime),
+		},
+	}, nil
+}
+
+func (h *UserDataHandler) CancelDelete(ctx *gin.Context, uc ijwt.UserClaims) (ginx.Result, error) {
+	err := h.svc.CancelDeletion(ctx, uc.Id)
+	switch err {
+	case nil:
+		return Result{Msg: "已经撤销注销"}, nil
+	case service.ErrDeletionNotFound:
+		return Result{}, errs.UserDeletionNotFound
+	default:
+		return Result{}, errs.UserInternalServerError.Wrap(
+			fmt.Errorf("撤销注销账号失败 uid %d, %w", uc.Id, err))
+	}
+}
+
+type DataExportVO struct {
+	// Status pending, running, done 或者 failed
+	Status string `json:"status"`
+	// Url 下载链接，只有 done 才有，有效期很短，过期了重新查询就可以
+	Url   string `json:"url,omitempty"`
+	Ctime string `json:"ctime"`
+}
+
+type AccountDeletionVO struct {
+	// ExecTime 冷静期结束的时间
+	ExecTime string `json:"execTime"`
+}
@@ -0,0 +1,161 @@
+package web
+
+import (
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/domain"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// avatarFormField 上传头像的表单字段
+const avatarFormField = "avatar"
+
+var errAvatarTooLarge = errs.UserInvalidAvatar.WithMsg("请上传不超过 2MB 的头像",
+	"please upload an avatar no larger than 2MB")
+
+// avatarContentTypes 根据文件内容判断出来的类型，不信任前端传的 Content-Type
+var avatarContentTypes = map[string]struct{}{
+	"image/jpeg": {},
+	"image/png":  {},
+	"image/gif":  {},
+}
+
+type UserStatsVo struct {
+	ArticleCnt int64 `json:"articleCnt"`
+	ReadCnt    int64 `json:"readCnt"`
+	LikeCnt    int64 `json:"likeCnt"`
+	CollectCnt int64 `json:"collectCnt"`
+}
+
+func newUserStatsVo(s domain.UserStats) UserStatsVo {
+	return UserStatsVo{
+		ArticleCnt: s.ArticleCnt,
+		ReadCnt:    s.ReadCnt,
+		LikeCnt:    s.LikeCnt,
+		CollectCnt: s.CollectCnt,
+	}
+}
+
+type AvatarVo struct {
+	Avatar      string `json:"avatar"`
+	AvatarThumb string `json:"avatarThumb"`
+}
+
+type PublicProfileVo struct {
+	Id          int64       `json:"id"`
+	Nickname    string      `json:"nickname"`
+	Avatar      string      `json:"avatar"`
+	AvatarThumb string      `json:"avatarThumb"`
+	AboutMe     string      `json:"aboutMe"`
+	ArticleCnt  int64       `json:"articleCnt"`
+	FollowerCnt int64       `json:"followerCnt"`
+	Articles    []ArticleVo `json:"articles"`
+}
+
+// UploadAvatar 上传头像，表单字段是 avatar
+func (c *UserHandler) UploadAvatar(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(ijwt.UserClaims)
+	// 多留一点给 multipart 的其它部分
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, service.MaxAvatarSize+64<<10)
+	fh, err := ctx.FormFile(avatarFormField)
+	if err != nil {
+		// 只有超过了 MaxBytesReader 的限制才是文件太大，
+		// 没有 avatar 字段或者不是 multipart 之类的都是参数不对
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errAvatarTooLarge))
+		} else {
+			ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInvalidInput))
+		}
+		return
+	}
+	if fh.Size > service.MaxAvatarSize {
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errAvatarTooLarge))
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+		zap.L().Error("打开上传的头像失败", zap.Error(err))
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, service.MaxAvatarSize+1))
+	if err != nil {
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+		zap.L().Error("读取上传的头像失败", zap.Error(err))
+		return
+	}
+	if _, ok := avatarContentTypes[http.DetectContentType(data)]; !ok {
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInvalidAvatar.WithMsg("只支持 JPEG、PNG 和 GIF 格式的头像",
+			"only JPEG, PNG and GIF avatars are supported")))
+		return
+	}
+	avatar, thumb, err := c.profileSvc.UpdateAvatar(ctx, uc.Id, data)
+	switch err {
+	case nil:
+		ctx.JSON(http.StatusOK, Result{
+			Msg: "OK",
+			Data: AvatarVo{
+				Avatar:      avatar,
+				AvatarThumb: thumb,
+			},
+		})
+	case service.ErrInvalidAvatar:
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInvalidAvatar))
+	default:
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+		zap.L().Error("更新头像失败", zap.Int64("uid", uc.Id), zap.Error(err))
+	}
+}
+
+// PublicProfile 作者主页
+func (c *UserHandler) PublicProfile(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInvalidInput))
+		return
+	}
+	p, err := c.profileSvc.PublicProfile(ctx, id)
+	switch err {
+	case nil:
+	case service.ErrUserNotFound:
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserNotFound))
+		return
+	default:
+		ctx.JSON(http.StatusOK, ginx.ErrResult(ctx, errs.UserInternalServerError))
+		zap.L().Error("查询作者主页失败", zap.Int64("uid", id), zap.Error(err))
+		return
+	}
+	arts := make([]ArticleVo, 0, len(p.Articles))
+	for _, art := range p.Articles {
+		arts = append(arts, ArticleVo{
+			Id:       art.Id,
+			Title:    art.Title,
+			Abstract: art.Abstract(),
+			Author:   p.Nickname,
+			Ctime:    art.Ctime.Format(time.DateTime),
+			Utime:    art.Utime.Format(time.DateTime),
+		})
+	}
+	ctx.JSON(http.StatusOK, Result{
+		Data: PublicProfileVo{
+			Id:          p.Id,
+			Nickname:    p.Nickname,
+			Avatar:      p.Avatar,
+			AvatarThumb: p.AvatarThumb,
+			AboutMe:     p.AboutMe,
+			ArticleCnt:  p.ArticleCnt,
+			FollowerCnt: p.FollowerCnt,
+			Articles:    arts,
+		},
+	})
+}
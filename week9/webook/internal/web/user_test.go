@@ -2,18 +2,23 @@ package web
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"gitee.com/geekbang/basic-go/webook/internal/errs"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
 	svcmocks "gitee.com/geekbang/basic-go/webook/internal/service/mocks"
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	jwtmocks "gitee.com/geekbang/basic-go/webook/internal/web/jwt/mocks"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestUserHandler_SignUp(t *testing.T) {
@@ -34,8 +39,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 		reqBuilder func(t *testing.T) *http.Request
 
 		// 预期响应
-		wantCode int
-		wantBody string
+		wantCode   int
+		wantResult Result
 	}{
 		{
 			name: "注册成功",
@@ -61,8 +66,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 				}
 				return req
 			},
-			wantCode: 200,
-			wantBody: "hello, 注册成功",
+			wantCode:   200,
+			wantResult: Result{Msg: "OK"},
 		},
 		{
 			name: "非 JSON 输入",
@@ -81,7 +86,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 				}
 				return req
 			},
-			wantCode: 400,
+			wantCode:   200,
+			wantResult: Result{Code: errs.UserInvalidInput.Code, Msg: "参数错误"},
 		},
 
 		{
@@ -102,7 +108,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 				return req
 			},
 			wantCode: 200,
-			wantBody: "邮箱不正确",
+			wantResult: Result{Code: errs.UserInvalidInput.Code, Msg: "邮箱格式不对",
+				Data: []any{map[string]any{"field": "email", "tag": "email", "msg": "邮箱格式不对"}}},
 		},
 		{
 			name: "两次密码输入不同",
@@ -113,7 +120,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			},
 			reqBuilder: func(t *testing.T) *http.Request {
 				// 准备一个不合法的邮箱
-				body := bytes.NewBuffer([]byte(`{"email":"123@qq.com","password":"hello","confirmPassword":"hello@world123"}`))
+				body := bytes.NewBuffer([]byte(`{"email":"123@qq.com","password":"hello@world123","confirmPassword":"hello@world1234"}`))
 				req, err := http.NewRequest(http.MethodPost, signupUrl, body)
 				req.Header.Set("Content-Type", "application/json")
 				if err != nil {
@@ -122,7 +129,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 				return req
 			},
 			wantCode: 200,
-			wantBody: "两次输入的密码不相同",
+			wantResult: Result{Code: errs.UserInvalidInput.Code, Msg: "两次输入密码不对",
+				Data: []any{map[string]any{"field": "confirmPassword", "tag": "eqfield", "msg": "两次输入密码不对"}}},
 		},
 		{
 			name: "密码格式不对",
@@ -142,7 +150,9 @@ func TestUserHandler_SignUp(t *testing.T) {
 				return req
 			},
 			wantCode: 200,
-			wantBody: "密码必须包含数字、特殊字符，并且长度不能小于 8 位",
+			wantResult: Result{Code: errs.UserInvalidInput.Code,
+				Msg:  "密码必须包含数字、特殊字符，并且长度不能小于 8 位",
+				Data: []any{map[string]any{"field": "password", "tag": "password", "msg": "密码必须包含数字、特殊字符，并且长度不能小于 8 位"}}},
 		},
 		{
 			name: "邮箱冲突",
@@ -168,8 +178,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 				}
 				return req
 			},
-			wantCode: 200,
-			wantBody: "重复邮箱，请换一个邮箱",
+			wantCode:   200,
+			wantResult: Result{Code: errs.UserDuplicateEmail.Code, Msg: "邮箱冲突"},
 		},
 		{
 			name: "系统异常",
@@ -195,8 +205,8 @@ func TestUserHandler_SignUp(t *testing.T) {
 				}
 				return req
 			},
-			wantCode: 200,
-			wantBody: "服务器异常，注册失败",
+			wantCode:   200,
+			wantResult: Result{Code: errs.UserInternalServerError.Code, Msg: "系统错误"},
 		},
 	}
 	for _, tc := range testCases {
@@ -205,7 +215,7 @@ func TestUserHandler_SignUp(t *testing.T) {
 			defer ctrl.Finish()
 			usersvc, codesvc, jwthdl := tc.mock(ctrl)
 			// 利用 mock 来构造 UserHandler
-			hdl := NewUserHandler(usersvc, codesvc, jwthdl)
+			hdl := NewUserHandler(usersvc, nil, codesvc, nil, jwthdl)
 
 			// 注册路由
 			server := gin.Default()
@@ -218,7 +228,10 @@ func TestUserHandler_SignUp(t *testing.T) {
 			server.ServeHTTP(recorder, req)
 			// 断言
 			assert.Equal(t, tc.wantCode, recorder.Code)
-			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			var res Result
+			err := json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, res)
 		})
 	}
 }
@@ -268,41 +281,170 @@ func TestUserHandler_SignUp(t *testing.T) {
 //	assert.Equal(t, 200, recorder.Code)
 //}
 
-// TestEmailPattern 用来验证我们的邮箱正则表达式对不对
-func TestEmailPattern(t *testing.T) {
+// TestUserHandler_UploadAvatar 只测 handler 里面的校验，请求都到不了 service
+func TestUserHandler_UploadAvatar(t *testing.T) {
 	testCases := []struct {
-		name  string
-		email string
-		match bool
+		name       string
+		reqBuilder func(t *testing.T) *http.Request
+
+		wantResult Result
 	}{
 		{
-			name:  "不带@",
-			email: "123456",
-			match: false,
+			name: "头像太大",
+			reqBuilder: func(t *testing.T) *http.Request {
+				return newAvatarRequest(t, avatarFormField, make([]byte, service.MaxAvatarSize+128<<10))
+			},
+			wantResult: Result{Code: errs.UserInvalidAvatar.Code, Msg: "请上传不超过 2MB 的头像"},
 		},
 		{
-			name:  "带@ 但是没后缀",
-			email: "123456@",
-			match: false,
+			name: "没有 avatar 字段",
+			reqBuilder: func(t *testing.T) *http.Request {
+				return newAvatarRequest(t, "file", []byte("hello"))
+			},
+			wantResult: Result{Code: errs.UserInvalidInput.Code, Msg: "参数错误"},
 		},
 		{
-			name:  "合法邮箱",
-			email: "123456@qq.com",
-			match: true,
+			name: "不是 multipart",
+			reqBuilder: func(t *testing.T) *http.Request {
+				req, err := http.NewRequest(http.MethodPost, "/users/avatar",
+					bytes.NewBufferString(`{"avatar":"hello"}`))
+				require.NoError(t, err)
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			wantResult: Result{Code: errs.UserInvalidInput.Code, Msg: "参数错误"},
 		},
 	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			// 这几个用例都到不了 service
+			hdl := NewUserHandler(nil, svcmocks.NewMockProfileService(ctrl), nil, nil, nil)
+			server := gin.New()
+			server.POST("/users/avatar", func(ctx *gin.Context) {
+				ctx.Set("user", ijwt.UserClaims{Id: 1})
+			}, hdl.UploadAvatar)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, tc.reqBuilder(t))
 
-	h := NewUserHandler(nil, nil, nil)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err := json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, res)
+		})
+	}
+}
+
+func newAvatarRequest(t *testing.T, field string, data []byte) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, err := w.CreateFormFile(field, "avatar.png")
+	require.NoError(t, err)
+	_, err = fw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	req, err := http.NewRequest(http.MethodPost, "/users/avatar", &body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+// TestUserHandler_RefreshToken 用的是真的 ijwt.RedisHandler，只 mock 会话
+func TestUserHandler_RefreshToken(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) service.SessionService
 
+		wantCode int
+		// 有没有下发新的 token
+		wantToken bool
+	}{
+		{
+			name: "刷新成功",
+			mock: func(ctrl *gomock.Controller) service.SessionService {
+				svc := svcmocks.NewMockSessionService(ctrl)
+				svc.EXPECT().Rotate(gomock.Any(), int64(1), "ssid", "old", gomock.Any()).Return(nil)
+				return svc
+			},
+			wantCode:  http.StatusOK,
+			wantToken: true,
+		},
+		{
+			name: "旧的长 token 被重复使用",
+			mock: func(ctrl *gomock.Controller) service.SessionService {
+				svc := svcmocks.NewMockSessionService(ctrl)
+				svc.EXPECT().Rotate(gomock.Any(), int64(1), "ssid", "old", gomock.Any()).
+					Return(service.ErrRefreshTokenReused)
+				return svc
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "已经退出登录",
+			mock: func(ctrl *gomock.Controller) service.SessionService {
+				svc := svcmocks.NewMockSessionService(ctrl)
+				svc.EXPECT().Rotate(gomock.Any(), int64(1), "ssid", "old", gomock.Any()).
+					Return(service.ErrSessionRevoked)
+				return svc
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			match, err := h.emailRegexExp.MatchString(tc.email)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			keys := newTestKeys(t)
+			hdl := NewUserHandler(nil, nil, nil, nil, ijwt.NewRedisHandler(tc.mock(ctrl), keys))
+			server := gin.New()
+			hdl.RegisterRoutes(server)
+
+			token, err := keys.Refresh.Sign(ijwt.RefreshClaims{
+				Id:   1,
+				Ssid: "ssid",
+				Rid:  "old",
+				RegisteredClaims: jwt.RegisteredClaims{
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+				},
+			})
 			require.NoError(t, err)
-			assert.Equal(t, tc.match, match)
+			req, err := http.NewRequest(http.MethodPost, "/users/refresh_token", nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			if !tc.wantToken {
+				return
+			}
+			assert.NotEmpty(t, recorder.Header().Get("x-jwt-token"))
+			newToken := recorder.Header().Get("x-refresh-token")
+			var rc ijwt.RefreshClaims
+			require.NoError(t, keys.Refresh.Parse(newToken, &rc))
+			assert.Equal(t, "ssid", rc.Ssid)
+			assert.NotEqual(t, "old", rc.Rid)
 		})
 	}
 }
 
+func newTestKeys(t *testing.T) *ijwt.Keys {
+	keys, err := ijwt.NewKeys(ijwt.KeysConfig{
+		Access: ijwt.KeySetConfig{
+			SignKid: "access",
+			Keys:    []ijwt.KeyConfig{{Kid: "access", Alg: "HS256", Secret: "access-secret"}},
+		},
+		Refresh: ijwt.KeySetConfig{
+			SignKid: "refresh",
+			Keys:    []ijwt.KeyConfig{{Kid: "refresh", Alg: "HS256", Secret: "refresh-secret"}},
+		},
+	})
+	require.NoError(t, err)
+	return keys
+}
+
 func TestComplete(t *testing.T) {
 	// 演示完整的测试用例
 	testCases := []struct {
@@ -329,42 +471,3 @@ func TestComplete(t *testing.T) {
 		})
 	}
 }
-
-func TestPasswordPattern(t *testing.T) {
-	testCases := []struct {
-		name     string
-		password string
-		match    bool
-	}{
-		{
-			name:     "合法密码",
-			password: "Hello#world123",
-			match:    true,
-		},
-		{
-			name:     "没有数字",
-			password: "Hello#world",
-			match:    false,
-		},
-		{
-			name:     "没有特殊字符",
-			password: "Helloworld123",
-			match:    false,
-		},
-		{
-			name:     "长度不足",
-			password: "he!123",
-			match:    false,
-		},
-	}
-
-	h := NewUserHandler(nil, nil, nil)
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			match, err := h.passwordRegexExp.MatchString(tc.password)
-			require.NoError(t, err)
-			assert.Equal(t, tc.match, match)
-		})
-	}
-}
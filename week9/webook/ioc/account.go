@@ -0,0 +1,51 @@
+package ioc
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	"gitee.com/geekbang/basic-go/webook/internal/web"
+	"github.com/spf13/viper"
+	"os"
+)
+
+// InitAccountHandler 合并账号凭证的密钥和短信模板 token 的密钥一样，不写在配置文件里面，
+// 放在环境变量 ACCOUNT_MERGE_TICKET_KEY 里面，或者用 account.mergeTicket.keyFile 从文件里面读
+func InitAccountHandler(svc service.AccountService,
+	codeSvc service.CodeService,
+	codeGuard service.CodeGuard) *web.AccountHandler {
+	type Config struct {
+		KeyFile string `yaml:"keyFile"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("account.mergeTicket", &cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化合并账号凭证配置失败 %w", err))
+	}
+	key := []byte(os.Getenv("ACCOUNT_MERGE_TICKET_KEY"))
+	if cfg.KeyFile != "" {
+		key, err = os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			panic(fmt.Errorf("读取合并账号凭证的密钥失败 %w", err))
+		}
+	}
+	if len(key) == 0 {
+		panic("没有配置合并账号凭证的密钥，请设置环境变量 ACCOUNT_MERGE_TICKET_KEY 或者 account.mergeTicket.keyFile")
+	}
+	return web.NewAccountHandler(svc, codeSvc, codeGuard, key)
+}
+
+// InitAccountNotifier 换绑手机号码或者邮箱之后，通知原来的手机号码或者邮箱
+func InitAccountNotifier(smsSvc sms.Service, emailSvc email.Service) service.AccountNotifier {
+	type Config struct {
+		// SmsTplId 换绑通知的短信模板
+		SmsTplId string `yaml:"smsTplId"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("account.notify", &cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化账号通知配置失败 %w", err))
+	}
+	return service.NewAccountNotifier(smsSvc, cfg.SmsTplId, emailSvc)
+}
@@ -0,0 +1,35 @@
+package ioc
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+)
+
+// avatarConfig 头像放在哪里，没有配置 bucket 的时候放在本地的 Dir
+type avatarConfig struct {
+	Bucket string `yaml:"bucket"`
+	// BaseURL 访问头像的地址前缀，一般配置成 CDN 的域名
+	BaseURL string `yaml:"baseURL"`
+	Dir     string `yaml:"dir"`
+}
+
+// InitAvatarDAO 配置了 avatar.bucket 的时候用 COS，需要 COS 的环境变量
+func InitAvatarDAO() dao.AvatarDAO {
+	c := avatarConfig{
+		Dir: filepath.Join(os.TempDir(), "webook-avatars"),
+	}
+	err := viper.UnmarshalKey("avatar", &c)
+	if err != nil {
+		panic(fmt.Errorf("初始化头像配置失败 %w", err))
+	}
+	if c.Bucket == "" {
+		return dao.NewLocalAvatarDAO(c.Dir)
+	}
+	if c.BaseURL == "" {
+		c.BaseURL = "https://cos.ap-nanjing.myqcloud.com/" + c.Bucket
+	}
+	return dao.NewS3AvatarDAO(InitS3(), c.Bucket, c.BaseURL)
+}
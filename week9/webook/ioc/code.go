@@ -0,0 +1,146 @@
+package ioc
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/internal/service/captcha"
+	"gitee.com/geekbang/basic-go/webook/internal/service/captcha/siteverify"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/auth"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/registry"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/ratelimit"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"net/http"
+	"time"
+)
+
+// InitCodeCache Redis 崩溃的时候降级到本地缓存，不然短信登录就完全不可用了
+func InitCodeCache(cmd redis.Cmdable, l logger.LoggerV1) cache.CodeCache {
+	// 本地缓存最多保存多少个验证码
+	size := viper.GetInt("code.localCacheSize")
+	if size <= 0 {
+		size = 100000
+	}
+	c, err := lru.New(size)
+	if err != nil {
+		panic(err)
+	}
+	return cache.NewHybridCodeCache(cache.NewRedisCodeCache(cmd),
+		cache.NewLocalCodeCache(c, time.Minute*10), l)
+}
+
+// InitCodeService 短信和邮件渠道总是有的，没有配置 SMTP 的邮件输出到控制台，语音渠道配置了才有。
+// 配置了短信模板的话，短信验证码要先用业务申请模板的 token，见 InitSmsAuthService
+func InitCodeService(smsSvc sms.Service,
+	tplSvc *auth.SMSService,
+	emailSvc email.Service,
+	repo repository.CodeRepository,
+	cmd redis.Cmdable,
+	l logger.LoggerV1) service.CodeService {
+	type Config struct {
+		Bizs []service.CodeBizConfig `yaml:"bizs"`
+		// EmailSubject 邮件的主题
+		EmailSubject string `yaml:"emailSubject"`
+		// Voice 语音验证码也是走短信的那一套，只不过服务商换成语音的
+		Voice *registry.Config `yaml:"voice"`
+		// Monitor 验证次数耗尽的情况，window 内出现 threshold 次就告警
+		Monitor struct {
+			Window    time.Duration `yaml:"window"`
+			Threshold int           `yaml:"threshold"`
+		} `yaml:"monitor"`
+	}
+	var cfg Config
+	cfg.Monitor.Window = time.Minute
+	cfg.Monitor.Threshold = 100
+	err := viper.UnmarshalKey("code", &cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化验证码配置失败 %w", err))
+	}
+	senders := map[string]service.CodeSender{
+		"sms": service.NewSMSCodeSender(smsSvc),
+	}
+	if viper.IsSet("sms.templates") {
+		senders["sms"] = service.NewTemplateSMSCodeSender(tplSvc)
+	}
+	subject := cfg.EmailSubject
+	if subject == "" {
+		subject = "webook 验证码"
+	}
+	// 绑定邮箱需要邮件验证码，没有配置 SMTP 的时候输出到控制台，见 InitEmailService
+	senders["email"] = service.NewEmailCodeSender(emailSvc, subject)
+	if cfg.Voice != nil {
+		// 语音验证码不能转异步，异步短信的表和发送的 goroutine 都是给短信用的，
+		// 混在一起的话语音会被当成短信发出去。所以不传 repository，配置了 async 会返回错误
+		voiceSvc, err := registry.NewRegistry(cmd, nil, l).Build(*cfg.Voice)
+		if err != nil {
+			panic(fmt.Errorf("初始化语音验证码失败 %w", err))
+		}
+		senders["voice"] = service.NewSMSCodeSender(voiceSvc)
+	}
+	monitor := service.NewCodeVerifyMonitor(cfg.Monitor.Window, cfg.Monitor.Threshold, l,
+		prometheus.CounterOpts{
+			Namespace: "geekbang_daming",
+			Subsystem: "webook",
+			Name:      "code_verify_too_many_times",
+			Help:      "验证码验证次数耗尽的次数",
+		})
+	return service.NewCodeService(repo, senders, cfg.Bizs, monitor)
+}
+
+// InitCodeGuard 发送验证码的防刷规则
+func InitCodeGuard(cmd redis.Cmdable, l logger.LoggerV1) service.CodeGuard {
+	type Limit struct {
+		Interval time.Duration `yaml:"interval"`
+		Rate     int           `yaml:"rate"`
+	}
+	type Config struct {
+		// Phone 同一个手机号码
+		Phone Limit `yaml:"phone"`
+		// IP 同一个 IP，超过了就直接拒绝
+		IP Limit `yaml:"ip"`
+		// Device 同一个设备
+		Device Limit `yaml:"device"`
+		// SuspiciousIP 同一个 IP，超过了就要求人机验证
+		SuspiciousIP Limit `yaml:"suspiciousIp"`
+		// NoDeviceSuspicious 没有设备指纹的请求都要求人机验证
+		NoDeviceSuspicious bool `yaml:"noDeviceSuspicious"`
+		// Captcha 为空就不要求人机验证
+		Captcha *struct {
+			URL    string `yaml:"url"`
+			Secret string `yaml:"secret"`
+		} `yaml:"captcha"`
+	}
+	cfg := Config{
+		Phone:        Limit{Interval: time.Hour * 24, Rate: 10},
+		IP:           Limit{Interval: time.Hour, Rate: 100},
+		Device:       Limit{Interval: time.Hour, Rate: 20},
+		SuspiciousIP: Limit{Interval: time.Minute, Rate: 10},
+		// 客户端都会带上设备指纹
+		NoDeviceSuspicious: true,
+	}
+	err := viper.UnmarshalKey("code.guard", &cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化验证码防刷配置失败 %w", err))
+	}
+	limiter := func(l Limit) ratelimit.Limiter {
+		return ratelimit.NewRedisSlidingWindowLimiter(cmd, l.Interval, l.Rate)
+	}
+	var c captcha.Service
+	if cfg.Captcha != nil {
+		c = siteverify.NewService(&http.Client{Timeout: time.Second * 3},
+			cfg.Captcha.URL, cfg.Captcha.Secret)
+	}
+	return service.NewRuleCodeGuard([]service.CodeLimitRule{
+		service.PhoneRule(limiter(cfg.Phone)),
+		service.IPRule(limiter(cfg.IP)),
+		service.DeviceRule(limiter(cfg.Device)),
+		service.SuspiciousIPRule(limiter(cfg.SuspiciousIP)),
+	}, c, cfg.NoDeviceSuspicious, l)
+}
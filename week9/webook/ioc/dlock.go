@@ -0,0 +1,35 @@
+package ioc
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock/gormlock"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock/redislock"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+func InitDLockClient(cmd redis.Cmdable, db *gorm.DB) dlock.Client {
+	type Config struct {
+		// redis 或者 mysql
+		Type string `yaml:"type"`
+	}
+	c := Config{
+		Type: "redis",
+	}
+	err := viper.UnmarshalKey("dlock", &c)
+	if err != nil {
+		panic(fmt.Errorf("初始化分布式锁配置失败 %w", err))
+	}
+	switch c.Type {
+	case "mysql":
+		err = gormlock.InitTable(db)
+		if err != nil {
+			panic(err)
+		}
+		return gormlock.NewClient(db)
+	default:
+		return redislock.NewClient(cmd)
+	}
+}
@@ -0,0 +1,22 @@
+package ioc
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email/localemail"
+	"gitee.com/geekbang/basic-go/webook/internal/service/email/smtp"
+	"github.com/spf13/viper"
+)
+
+// InitEmailService SMTP 服务器配置在 code.email 里面，没有配置的时候输出到控制台
+func InitEmailService() email.Service {
+	var cfg *smtp.Config
+	err := viper.UnmarshalKey("code.email", &cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化邮件配置失败 %w", err))
+	}
+	if cfg == nil {
+		return localemail.NewService()
+	}
+	return smtp.NewService(*cfg)
+}
@@ -1,11 +1,13 @@
 package ioc
 
 import (
+	"gitee.com/geekbang/basic-go/webook/internal/service"
 	"gitee.com/geekbang/basic-go/webook/internal/web"
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
 	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
 	"gitee.com/geekbang/basic-go/webook/pkg/ginx/middleware/metrics"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx/middleware/rbac"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
 	"gitee.com/geekbang/basic-go/webook/pkg/saramax"
 	"github.com/gin-contrib/cors"
@@ -23,7 +25,16 @@ func InitWebServer(funcs []gin.HandlerFunc,
 	userHdl *web.UserHandler,
 	artHdl *web.ArticleHandler,
 	obHdl *web.ObservabilityHandler,
-	oauth2Hdl *web.OAuth2WechatHandler, l logger.LoggerV1) *gin.Engine {
+	oauth2Hdl *web.OAuth2Handler,
+	accountHdl *web.AccountHandler,
+	sessionHdl *web.SessionHandler,
+	passwordHdl *web.PasswordHandler,
+	userDataHdl *web.UserDataHandler,
+	jwksHdl *web.JWKSHandler,
+	asyncSmsHdl *web.AsyncSmsHandler,
+	rbacHdl *web.RBACHandler,
+	openAPIHdl *web.OpenAPIHandler,
+	l logger.LoggerV1) *gin.Engine {
 	ginx.SetLogger(l)
 	server := gin.Default()
 	server.Use(funcs...)
@@ -31,12 +42,23 @@ func InitWebServer(funcs []gin.HandlerFunc,
 	userHdl.RegisterRoutes(server)
 	artHdl.RegisterRoutes(server)
 	oauth2Hdl.RegisterRoutes(server)
+	accountHdl.RegisterRoutes(server)
+	sessionHdl.RegisterRoutes(server)
+	passwordHdl.RegisterRoutes(server)
+	userDataHdl.RegisterRoutes(server)
+	jwksHdl.RegisterRoutes(server)
 	obHdl.RegisterRoutes(server)
+	asyncSmsHdl.RegisterRoutes(server)
+	rbacHdl.RegisterRoutes(server)
+	openAPIHdl.RegisterRoutes(server)
 	return server
 }
 
 func GinMiddlewares(cmd redis.Cmdable,
-	hdl ijwt.Handler, l logger.LoggerV1) []gin.HandlerFunc {
+	hdl ijwt.Handler,
+	authRoutes []middleware.AuthRoute,
+	rbacSvc service.RBACService,
+	routes *rbac.Routes, l logger.LoggerV1) []gin.HandlerFunc {
 	pb := &metrics.PrometheusBuilder{
 		Namespace:  "geekbang_daming",
 		Subsystem:  "webook",
@@ -59,14 +81,20 @@ func GinMiddlewares(cmd redis.Cmdable,
 		Name:      "message_processing_errors",
 		Help:      "消息处理错误监控",
 	})
+	ipLimit, uidLimit := rateLimitHandlers(cmd, l)
 	return []gin.HandlerFunc{
-		//ratelimit.NewBuilder(cmd, time.Minute, 100).BuildResponseTime(),
 		corsHandler(),
 		pb.BuildResponseTime(),
 		pb.BuildActiveRequest(),
 		otelgin.Middleware("webook"),
+		// 按照 IP 和路由限流要在登录校验之前，不然登录校验失败的请求不会被计数
+		ipLimit,
 		// 使用 JWT
-		middleware.NewJWTLoginMiddlewareBuilder(hdl).Build(),
+		middleware.NewJWTLoginMiddlewareBuilder(hdl, authRoutes).Build(),
+		// 按照用户限流要在登录校验之后
+		uidLimit,
+		// 权限校验要在登录校验之后，管理后台的路由忘了声明权限就都不让访问
+		rbac.NewBuilder(rbacSvc, routes, l).DenyUndeclared("/admin").Build(),
 		//accesslog.NewMiddlewareBuilder(func(ctx context.Context, al accesslog.AccessLog) {
 		//	// 设置为 DEBUG 级别
 		//	l.Debug("GIN 收到请求", logger.Field{
@@ -84,9 +112,12 @@ func corsHandler() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowCredentials: true,
 		// 在使用 JWT 的时候，因为我们使用了 Authorization 的头部，所以要加上
-		AllowHeaders: []string{"Content-Type", "Authorization"},
+		// 发验证码的防刷检查要用 X-Device-Fingerprint 识别设备
+		AllowHeaders: []string{"Content-Type", "Authorization", "X-Device-Fingerprint"},
 		// 为了 JWT，长短 token 的设置
-		ExposeHeaders: []string{"X-Jwt-Token", "X-Refresh-Token"},
+		// 限流的响应头也要暴露出去，前端才知道多久之后重试
+		ExposeHeaders: []string{"X-Jwt-Token", "X-Refresh-Token",
+			"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 		AllowOriginFunc: func(origin string) bool {
 			if strings.HasPrefix(origin, "http://localhost") {
 				return true
@@ -0,0 +1,74 @@
+package ioc
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCorsHandler(t *testing.T) {
+	testCases := []struct {
+		name   string
+		origin string
+		// 浏览器预检请求里面带的头部
+		reqHeaders string
+
+		wantCode         int
+		wantAllowOrigin  string
+		wantAllowHeaders string
+	}{
+		{
+			name:             "带设备指纹发验证码",
+			origin:           "http://localhost:3000",
+			reqHeaders:       "content-type,x-device-fingerprint",
+			wantCode:         http.StatusNoContent,
+			wantAllowOrigin:  "http://localhost:3000",
+			wantAllowHeaders: "Content-Type,Authorization,X-Device-Fingerprint",
+		},
+		{
+			name:       "不认识的来源",
+			origin:     "http://evil.com",
+			reqHeaders: "content-type",
+			wantCode:   http.StatusForbidden,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(corsHandler())
+			server.POST("/users/login_sms/code/send", func(ctx *gin.Context) {})
+
+			req := httptest.NewRequest(http.MethodOptions, "/users/login_sms/code/send", nil)
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", tc.reqHeaders)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+			assert.Equal(t, tc.wantAllowOrigin, resp.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tc.wantAllowHeaders, resp.Header().Get("Access-Control-Allow-Headers"))
+		})
+	}
+}
+
+func TestCorsHandler_ExposeHeaders(t *testing.T) {
+	server := gin.New()
+	server.Use(corsHandler())
+	server.POST("/users/login_sms/code/send", func(ctx *gin.Context) {
+		ctx.Header("Retry-After", "10")
+		ctx.Status(http.StatusTooManyRequests)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/login_sms/code/send", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	resp := httptest.NewRecorder()
+	server.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "X-Jwt-Token,X-Refresh-Token,Retry-After,"+
+		"X-Ratelimit-Limit,X-Ratelimit-Remaining,X-Ratelimit-Reset",
+		resp.Header().Get("Access-Control-Expose-Headers"))
+}
@@ -1,22 +1,63 @@
 package ioc
 
 import (
+	"fmt"
 	"gitee.com/geekbang/basic-go/webook/internal/job"
 	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
-	rlock "github.com/gotomicro/redis-lock"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
 	"time"
 )
 
 func InitRankingJob(svc service.RankingService,
-	client *rlock.Client,
+	client dlock.Client,
 	l logger.LoggerV1) *job.RankingJob {
 	return job.NewRankingJob(svc, client, l, time.Second*30)
 }
 
-func InitJobs(l logger.LoggerV1, rankingJob *job.RankingJob) *cron.Cron {
+// asyncSmsConfig 异步短信队列相关的配置
+type asyncSmsConfig struct {
+	// 发送成功的记录保留多久
+	Retention time.Duration `yaml:"retention"`
+	// 失败积压超过多少条就告警
+	FailedThreshold int64 `yaml:"failedThreshold"`
+}
+
+func initAsyncSmsConfig() asyncSmsConfig {
+	c := asyncSmsConfig{
+		Retention:       time.Hour * 24 * 7,
+		FailedThreshold: 100,
+	}
+	err := viper.UnmarshalKey("asyncSms", &c)
+	if err != nil {
+		panic(fmt.Errorf("初始化异步短信配置失败 %w", err))
+	}
+	return c
+}
+
+func InitAsyncSmsCleanupJob(svc service.AsyncSmsService,
+	l logger.LoggerV1) *job.AsyncSmsCleanupJob {
+	return job.NewAsyncSmsCleanupJob(svc, l, initAsyncSmsConfig().Retention)
+}
+
+func InitAsyncSmsMonitorJob(svc service.AsyncSmsService,
+	l logger.LoggerV1) *job.AsyncSmsMonitorJob {
+	return job.NewAsyncSmsMonitorJob(svc, l, prometheus.GaugeOpts{
+		Namespace: "geekbang_daming",
+		Subsystem: "webook",
+		Name:      "async_sms_backlog",
+		Help:      "异步短信各个状态的积压数量",
+	}, initAsyncSmsConfig().FailedThreshold)
+}
+
+func InitJobs(l logger.LoggerV1,
+	rankingJob *job.RankingJob,
+	cleanupJob *job.AsyncSmsCleanupJob,
+	monitorJob *job.AsyncSmsMonitorJob,
+	userDataJob *job.UserDataJob) *cron.Cron {
 	bd := job.NewCronJobBuilder(l, prometheus.SummaryOpts{
 		Namespace: "geekbang_daming",
 		Subsystem: "webook",
@@ -29,5 +70,17 @@ func InitJobs(l logger.LoggerV1, rankingJob *job.RankingJob) *cron.Cron {
 	if err != nil {
 		panic(err)
 	}
+	_, err = expr.AddJob("@every 1h", bd.Build(cleanupJob))
+	if err != nil {
+		panic(err)
+	}
+	_, err = expr.AddJob("@every 1m", bd.Build(monitorJob))
+	if err != nil {
+		panic(err)
+	}
+	_, err = expr.AddJob("@every 1m", bd.Build(userDataJob))
+	if err != nil {
+		panic(err)
+	}
 	return expr
 }
@@ -0,0 +1,36 @@
+package ioc
+
+import (
+	"fmt"
+	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
+	"gitee.com/geekbang/basic-go/webook/internal/web/middleware"
+	"github.com/spf13/viper"
+)
+
+// InitJWTKeys 长短 token 的密钥在配置文件的 jwt.access 和 jwt.refresh 里面
+func InitJWTKeys() *ijwt.Keys {
+	var cfg ijwt.KeysConfig
+	err := viper.UnmarshalKey("jwt", &cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化 JWT 密钥配置失败 %w", err))
+	}
+	keys, err := ijwt.NewKeys(cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化 JWT 密钥失败 %w", err))
+	}
+	return keys
+}
+
+// InitAuthRoutes 哪些路由不需要登录，或者可以不登录，在配置文件的 auth.routes 里面
+func InitAuthRoutes() []middleware.AuthRoute {
+	var routes []middleware.AuthRoute
+	err := viper.UnmarshalKey("auth.routes", &routes)
+	if err != nil {
+		panic(fmt.Errorf("初始化登录校验的路由配置失败 %w", err))
+	}
+	err = middleware.CheckAuthRoutes(routes)
+	if err != nil {
+		panic(err)
+	}
+	return routes
+}
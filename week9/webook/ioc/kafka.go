@@ -34,6 +34,7 @@ func NewSyncProducer(client sarama.Client) sarama.SyncProducer {
 }
 
 // NewConsumers 面临的问题依旧是所有的 Consumer 在这里注册一下
-func NewConsumers(c1 *intrEvents.InteractiveReadEventConsumer) []events.Consumer {
-	return []events.Consumer{c1}
+func NewConsumers(c1 *intrEvents.InteractiveReadEventConsumer,
+	c2 *intrEvents.UserDeletedEventConsumer) []events.Consumer {
+	return []events.Consumer{c1, c2}
 }
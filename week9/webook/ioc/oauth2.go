@@ -0,0 +1,46 @@
+package ioc
+
+import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2/dingtalk"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2/github"
+	"gitee.com/geekbang/basic-go/webook/internal/service/oauth2/wechat"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/spf13/viper"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// InitOAuth2Providers 启用哪些第三方登录平台，在配置文件的 oauth2.providers 里面
+// clientId 和 clientSecret 不想写在配置文件里面的，
+// 可以放在环境变量 {NAME}_APP_ID 和 {NAME}_APP_SECRET 里面，比如说 WECHAT_APP_SECRET
+func InitOAuth2Providers(l logger.LoggerV1) *oauth2.Providers {
+	var cfgs []oauth2.Config
+	err := viper.UnmarshalKey("oauth2.providers", &cfgs)
+	if err != nil {
+		panic(fmt.Errorf("初始化第三方登录配置失败 %w", err))
+	}
+	providers := make([]oauth2.Provider, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		prefix := strings.ToUpper(cfg.Name)
+		if cfg.ClientId == "" {
+			cfg.ClientId = os.Getenv(prefix + "_APP_ID")
+		}
+		if cfg.ClientSecret == "" {
+			cfg.ClientSecret = os.Getenv(prefix + "_APP_SECRET")
+		}
+		switch cfg.Name {
+		case wechat.ProviderName:
+			providers = append(providers, wechat.NewService(cfg, http.DefaultClient, l))
+		case github.ProviderName:
+			providers = append(providers, github.NewService(cfg, http.DefaultClient))
+		case dingtalk.ProviderName:
+			providers = append(providers, dingtalk.NewService(cfg, http.DefaultClient))
+		default:
+			panic(fmt.Errorf("%w %s", oauth2.ErrUnknownProvider, cfg.Name))
+		}
+	}
+	return oauth2.NewProviders(providers...)
+}
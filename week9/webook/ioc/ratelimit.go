@@ -0,0 +1,137 @@
+package ioc
+
+import (
+	"fmt"
+	ginratelimit "gitee.com/geekbang/basic-go/webook/pkg/ginx/middleware/ratelimit"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"strings"
+	"time"
+)
+
+const (
+	// rateLimitOnErrorOpen Redis 出错的时候放行
+	rateLimitOnErrorOpen = "open"
+	// rateLimitOnErrorClosed Redis 出错的时候拒绝
+	rateLimitOnErrorClosed = "closed"
+	// rateLimitOnErrorLocal Redis 出错的时候用本地的令牌桶
+	rateLimitOnErrorLocal = "local"
+)
+
+type rateLimitConfig struct {
+	// OnError open、closed 或者 local
+	OnError string `yaml:"onError"`
+	// LocalSize 降级到本地限流的时候，每条规则最多保存多少个 key
+	LocalSize int             `yaml:"localSize"`
+	Rules     []rateLimitRule `yaml:"rules"`
+}
+
+type rateLimitRule struct {
+	// Method 和 Path 为空的时候匹配所有的请求
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+	// Key ip、uid 或者 route，多个用逗号分隔，比如说 route,uid
+	Key string `yaml:"key"`
+	// Algorithm tokenBucket、fixedWindow 或者 slidingWindow
+	Algorithm string        `yaml:"algorithm"`
+	Interval  time.Duration `yaml:"interval"`
+	Rate      int           `yaml:"rate"`
+	// Burst 令牌桶的容量
+	Burst int `yaml:"burst"`
+}
+
+// rateLimitHandlers 限流规则在配置文件的 ratelimit 里面，没有配置的话不限流。
+// 第一个放在登录校验的前面，按照 IP 和路由限流，这样登录校验失败的请求也会被计数；
+// 第二个放在登录校验的后面，只有 key 里面有 uid 的规则
+func rateLimitHandlers(cmd redis.Cmdable, l logger.LoggerV1) (gin.HandlerFunc, gin.HandlerFunc) {
+	cfg := rateLimitConfig{
+		OnError:   rateLimitOnErrorLocal,
+		LocalSize: 100000,
+	}
+	err := viper.UnmarshalKey("ratelimit", &cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化限流配置失败 %w", err))
+	}
+	switch cfg.OnError {
+	case rateLimitOnErrorOpen, rateLimitOnErrorClosed, rateLimitOnErrorLocal:
+	default:
+		// 写错了不能悄悄地变成放行
+		panic(fmt.Errorf("不支持的 ratelimit.onError %s", cfg.OnError))
+	}
+	failOpen := cfg.OnError != rateLimitOnErrorClosed
+	beforeAuth := ginratelimit.NewBuilder(l).FailOpen(failOpen)
+	afterAuth := ginratelimit.NewBuilder(l).FailOpen(failOpen)
+	for _, r := range cfg.Rules {
+		key, byUid, err := rateLimitKey(r.Key)
+		if err != nil {
+			panic(err)
+		}
+		limiter, err := rateLimiter(cmd, r)
+		if err != nil {
+			panic(err)
+		}
+		if cfg.OnError == rateLimitOnErrorLocal {
+			c, err := lru.New(cfg.LocalSize)
+			if err != nil {
+				panic(err)
+			}
+			limiter = ratelimit.NewFailoverLimiter(limiter,
+				ratelimit.NewLocalTokenBucketLimiter(c, r.Interval, r.Rate, r.Burst))
+		}
+		builder := beforeAuth
+		if byUid {
+			builder = afterAuth
+		}
+		builder.Rule(ginratelimit.Rule{
+			Method:  strings.ToUpper(r.Method),
+			Path:    r.Path,
+			Key:     key,
+			Limiter: limiter,
+		})
+	}
+	return beforeAuth.Build(), afterAuth.Build()
+}
+
+func rateLimiter(cmd redis.Cmdable, r rateLimitRule) (ratelimit.ResultLimiter, error) {
+	if r.Interval <= 0 || r.Rate <= 0 {
+		return nil, fmt.Errorf("限流规则 %s %s 的 interval 和 rate 必须大于 0", r.Method, r.Path)
+	}
+	switch r.Algorithm {
+	case "", "tokenBucket":
+		return ratelimit.NewRedisTokenBucketLimiter(cmd, r.Interval, r.Rate, r.Burst), nil
+	case "fixedWindow":
+		return ratelimit.NewRedisFixedWindowLimiter(cmd, r.Interval, r.Rate), nil
+	case "slidingWindow":
+		return ratelimit.NewRedisSlidingWindowLimiter(cmd, r.Interval, r.Rate), nil
+	default:
+		return nil, fmt.Errorf("不支持的限流算法 %s", r.Algorithm)
+	}
+}
+
+// rateLimitKey 第二个返回值表示是不是按照用户限流，这种规则要放在登录校验的后面
+func rateLimitKey(key string) (ginratelimit.KeyFunc, bool, error) {
+	names := strings.Split(key, ",")
+	fns := make([]ginratelimit.KeyFunc, 0, len(names))
+	byUid := false
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "", "ip":
+			fns = append(fns, ginratelimit.IPKey)
+		case "uid":
+			fns = append(fns, ginratelimit.UidKey)
+			byUid = true
+		case "route":
+			fns = append(fns, ginratelimit.RouteKey)
+		default:
+			return nil, false, fmt.Errorf("不支持的限流对象 %s", name)
+		}
+	}
+	if len(fns) == 1 {
+		return fns[0], byUid, nil
+	}
+	return ginratelimit.JoinKeys(fns...), byUid, nil
+}
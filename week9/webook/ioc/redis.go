@@ -1,7 +1,6 @@
 package ioc
 
 import (
-	rlock "github.com/gotomicro/redis-lock"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 )
@@ -13,7 +12,3 @@ func InitRedis() redis.Cmdable {
 	})
 	return cmd
 }
-
-func InitRLockClient(cmd redis.Cmdable) *rlock.Client {
-	return rlock.NewClient(cmd)
-}
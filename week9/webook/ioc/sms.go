@@ -1,38 +1,79 @@
 package ioc
 
 import (
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
 	"gitee.com/geekbang/basic-go/webook/internal/service/sms"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/auth"
 	"gitee.com/geekbang/basic-go/webook/internal/service/sms/localsms"
-	"gitee.com/geekbang/basic-go/webook/internal/service/sms/tencent"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
-	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
-	tencentSMS "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
+	"gitee.com/geekbang/basic-go/webook/internal/service/sms/registry"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
 	"os"
+	"time"
 )
 
-func InitSmsService() sms.Service {
-	//return initSmsTencentService()
-	return InitSmsMemoryService()
+// InitSmsService 用哪些服务商，以及要不要限流、failover、转异步，都在配置文件里面
+func InitSmsService(cmd redis.Cmdable,
+	repo repository.AsyncSmsRepository,
+	l logger.LoggerV1) sms.Service {
+	var cfg registry.Config
+	err := viper.UnmarshalKey("sms", &cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化短信配置失败 %w", err))
+	}
+	if len(cfg.Providers) == 0 {
+		// 什么都没配置，就用基于内存的实现
+		cfg.Providers = []registry.ProviderConfig{
+			{Name: "memory", Type: "memory"},
+		}
+	}
+	svc, err := registry.NewRegistry(cmd, repo, l).Build(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return svc
 }
 
-func initSmsTencentService() sms.Service {
-	// 在这里你也可以考虑从配置文件里面读取
-	secretId, ok := os.LookupEnv("SMS_SECRET_ID")
-	if !ok {
-		panic("没有找到环境变量 SMS_SECRET_ID ")
+// InitSmsAuthService 给业务方用的，业务方先调用 Issue 申请模板的 token，再用 token 发送。
+// 目前的业务方是短信验证码，业务就是验证码的 biz。
+// 签名密钥不写在配置文件里面，放在环境变量 SMS_AUTH_KEY 里面，或者用 keyFile 从文件里面读。
+// 没有配置模板的时候签发不了 token，也就不需要密钥
+func InitSmsAuthService(svc sms.Service, cmd redis.Cmdable) *auth.SMSService {
+	type Config struct {
+		KeyFile    string        `yaml:"keyFile"`
+		Expiration time.Duration `yaml:"expiration"`
 	}
-	secretKey, ok := os.LookupEnv("SMS_SECRET_KEY")
-	if !ok {
-		panic("没有找到环境变量 SMS_SECRET_KEY")
+	var cfg Config
+	err := viper.UnmarshalKey("sms.auth", &cfg)
+	if err != nil {
+		panic(fmt.Errorf("初始化短信模板 token 配置失败 %w", err))
 	}
-
-	c, err := tencentSMS.NewClient(common.NewCredential(secretId, secretKey),
-		"ap-nanjing",
-		profile.NewClientProfile())
+	switch {
+	case cfg.Expiration == 0:
+		// 没有配置的时候，签发的 token 一天有效
+		cfg.Expiration = time.Hour * 24
+	case cfg.Expiration < 0:
+		panic(fmt.Errorf("短信模板 token 的有效期不对 %s", cfg.Expiration))
+	}
+	var tpls []auth.Template
+	err = viper.UnmarshalKey("sms.templates", &tpls)
 	if err != nil {
-		panic(err)
+		panic(fmt.Errorf("初始化短信模板配置失败 %w", err))
+	}
+	key := []byte(os.Getenv("SMS_AUTH_KEY"))
+	if cfg.KeyFile != "" {
+		key, err = os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			panic(fmt.Errorf("读取短信模板 token 的密钥失败 %w", err))
+		}
+	}
+	if len(tpls) > 0 && len(key) == 0 {
+		panic("没有配置短信模板 token 的密钥，请设置环境变量 SMS_AUTH_KEY 或者 sms.auth.keyFile")
 	}
-	return tencent.NewService(c, "1400842696", "妙影科技")
+	return auth.NewSMSService(svc, auth.NewTemplates(tpls), cmd,
+		key, cfg.Expiration)
 }
 
 // InitSmsMemoryService 使用基于内存，输出到控制台的实现
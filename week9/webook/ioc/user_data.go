@@ -0,0 +1,62 @@
+package ioc
+
+import (
+	"fmt"
+	intrSvc "gitee.com/geekbang/basic-go/webook/interactive/service"
+	"gitee.com/geekbang/basic-go/webook/internal/events/user"
+	"gitee.com/geekbang/basic-go/webook/internal/job"
+	"gitee.com/geekbang/basic-go/webook/internal/repository"
+	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
+	"gitee.com/geekbang/basic-go/webook/internal/service"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// userDataConfig 导出个人数据和注销账号的配置
+type userDataConfig struct {
+	// DeleteGracePeriod 注销的冷静期
+	DeleteGracePeriod time.Duration `yaml:"deleteGracePeriod"`
+	// ExportBucket 导出的文件放在哪个 bucket，没有配置的时候放在本地的 ExportDir
+	ExportBucket string `yaml:"exportBucket"`
+	ExportDir    string `yaml:"exportDir"`
+}
+
+func initUserDataConfig() userDataConfig {
+	c := userDataConfig{
+		DeleteGracePeriod: time.Hour * 24 * 15,
+		ExportDir:         filepath.Join(os.TempDir(), "webook-exports"),
+	}
+	err := viper.UnmarshalKey("userData", &c)
+	if err != nil {
+		panic(fmt.Errorf("初始化个人数据配置失败 %w", err))
+	}
+	return c
+}
+
+// InitExportFileDAO 配置了 userData.exportBucket 的时候用 COS，需要 COS 的环境变量
+func InitExportFileDAO() dao.ExportFileDAO {
+	cfg := initUserDataConfig()
+	if cfg.ExportBucket == "" {
+		return dao.NewLocalExportFileDAO(cfg.ExportDir)
+	}
+	return dao.NewS3ExportFileDAO(InitS3(), cfg.ExportBucket)
+}
+
+func InitUserDataService(repo repository.UserDataRepository,
+	userRepo repository.UserRepository,
+	oauth2Repo repository.OAuth2BindingRepository,
+	sessionRepo repository.SessionRepository,
+	artRepo repository.ArticleRepository,
+	intrSvc intrSvc.InteractiveService,
+	producer user.Producer,
+	l logger.LoggerV1) service.UserDataService {
+	return service.NewUserDataService(repo, userRepo, oauth2Repo, sessionRepo,
+		artRepo, intrSvc, producer, l, initUserDataConfig().DeleteGracePeriod)
+}
+
+func InitUserDataJob(svc service.UserDataService, l logger.LoggerV1) *job.UserDataJob {
+	return job.NewUserDataJob(svc, l)
+}
@@ -0,0 +1,141 @@
+package gormlock
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Client 基于 MySQL 的分布式锁
+// 每个 key 一行数据，释放锁的时候并不删除这一行，
+// 而是把过期时间置为 0，这样 token 这一列就能一直单调递增下去
+type Client struct {
+	db *gorm.DB
+}
+
+func NewClient(db *gorm.DB) dlock.Client {
+	return &Client{db: db}
+}
+
+// InitTable 只有在使用 MySQL 实现的时候才需要建表
+func InitTable(db *gorm.DB) error {
+	return db.AutoMigrate(&DistributedLock{})
+}
+
+func (c *Client) Lock(ctx context.Context, key string,
+	expiration time.Duration) (dlock.Lock, error) {
+	val := uuid.New().String()
+	db := c.db.WithContext(ctx)
+	now := time.Now().UnixMilli()
+	expireAt := now + expiration.Milliseconds()
+	// 先尝试抢占已经过期或者已经释放了的锁
+	// 这里利用 expire_at 来执行 CAS 操作
+	res := db.Model(&DistributedLock{}).
+		Where("`key` = ? AND expire_at < ?", key, now).
+		Updates(map[string]any{
+			"value":     val,
+			"token":     gorm.Expr("`token` + 1"),
+			"expire_at": expireAt,
+			"utime":     now,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// 要么锁被人持有着，要么这个 key 从来没有人用过
+		err := db.Create(&DistributedLock{
+			Key:      key,
+			Value:    val,
+			Token:    1,
+			ExpireAt: expireAt,
+			Ctime:    now,
+			Utime:    now,
+		}).Error
+		if me, ok := err.(*mysql.MySQLError); ok {
+			const uniqueIndexErrNo uint16 = 1062
+			if me.Number == uniqueIndexErrNo {
+				return nil, dlock.ErrFailedToPreemptLock
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	var dl DistributedLock
+	err := db.Where("`key` = ? AND `value` = ?", key, val).First(&dl).Error
+	if err != nil {
+		return nil, err
+	}
+	return &Lock{
+		db:         c.db,
+		key:        key,
+		value:      val,
+		token:      dl.Token,
+		expiration: expiration,
+	}, nil
+}
+
+type Lock struct {
+	db         *gorm.DB
+	key        string
+	value      string
+	token      int64
+	expiration time.Duration
+}
+
+func (l *Lock) Key() string {
+	return l.key
+}
+
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+func (l *Lock) Refresh(ctx context.Context) error {
+	now := time.Now().UnixMilli()
+	res := l.db.WithContext(ctx).Model(&DistributedLock{}).
+		// 已经过期了的话，就算还没被人抢走，也不能续约了
+		Where("`key` = ? AND `value` = ? AND expire_at >= ?", l.key, l.value, now).
+		Updates(map[string]any{
+			"expire_at": now + l.expiration.Milliseconds(),
+			"utime":     now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return dlock.ErrLockNotHold
+	}
+	return nil
+}
+
+func (l *Lock) Unlock(ctx context.Context) error {
+	res := l.db.WithContext(ctx).Model(&DistributedLock{}).
+		Where("`key` = ? AND `value` = ?", l.key, l.value).
+		Updates(map[string]any{
+			"expire_at": 0,
+			"utime":     time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return dlock.ErrLockNotHold
+	}
+	return nil
+}
+
+type DistributedLock struct {
+	Id  int64  `gorm:"primaryKey,autoIncrement"`
+	Key string `gorm:"type:varchar(128);unique"`
+	// 持有者的标识
+	Value string `gorm:"type:varchar(64)"`
+	// fencing token，每次抢锁成功都 +1
+	Token    int64
+	ExpireAt int64
+	Ctime    int64
+	Utime    int64
+}
@@ -0,0 +1,150 @@
+package gormlock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock"
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestClient_Lock(t *testing.T) {
+	testCases := []struct {
+		name    string
+		sqlmock func(t *testing.T) *sql.DB
+
+		wantToken int64
+		wantErr   error
+	}{
+		{
+			name: "抢占过期的锁",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `distributed_locks` .*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT .*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "key", "token"}).
+						AddRow(1, "job", 3))
+				return db
+			},
+			wantToken: 3,
+		},
+		{
+			name: "第一次加锁",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `distributed_locks` .*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO `distributed_locks` .*").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("SELECT .*").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "key", "token"}).
+						AddRow(1, "job", 1))
+				return db
+			},
+			wantToken: 1,
+		},
+		{
+			name: "锁被别人持有",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `distributed_locks` .*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO `distributed_locks` .*").
+					WillReturnError(&mysqlDriver.MySQLError{Number: 1062})
+				return db
+			},
+			wantErr: dlock.ErrFailedToPreemptLock,
+		},
+		{
+			name: "数据库错误",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `distributed_locks` .*").
+					WillReturnError(errors.New("mock db error"))
+				return db
+			},
+			wantErr: errors.New("mock db error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(initDB(t, tc.sqlmock(t)))
+			lock, err := client.Lock(context.Background(), "job", time.Minute)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, "job", lock.Key())
+			assert.Equal(t, tc.wantToken, lock.Token())
+		})
+	}
+}
+
+func TestLock_Refresh(t *testing.T) {
+	testCases := []struct {
+		name    string
+		sqlmock func(t *testing.T) *sql.DB
+		wantErr error
+	}{
+		{
+			name: "续约成功",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `distributed_locks` .*").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return db
+			},
+		},
+		{
+			name: "锁已经过期或者被人抢走了",
+			sqlmock: func(t *testing.T) *sql.DB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectExec("UPDATE `distributed_locks` .*").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				return db
+			},
+			wantErr: dlock.ErrLockNotHold,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lock := &Lock{
+				db:         initDB(t, tc.sqlmock(t)),
+				key:        "job",
+				value:      "abc",
+				token:      1,
+				expiration: time.Minute,
+			}
+			err := lock.Refresh(context.Background())
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func initDB(t *testing.T, sqlDB *sql.DB) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	// 初始化 DB 不能出错，所以这里要断言必须为 nil
+	assert.NoError(t, err)
+	return db
+}
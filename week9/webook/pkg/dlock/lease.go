@@ -0,0 +1,118 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Lease 一个会自动续约的锁
+// 续约失败（比如说锁过期被人抢走了）的时候，Lost 会被关闭，
+// 调用者可以据此中断正在执行的任务
+type Lease struct {
+	Lock
+	interval time.Duration
+	timeout  time.Duration
+	// 连续多少次续约出现非 ErrLockNotHold 的错误，就认为失去了锁
+	maxRetry int
+
+	lost     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	lostOnce sync.Once
+	err      error
+}
+
+// AutoRefresh 每隔 interval 续约一次，每次续约的超时时间是 timeout
+// interval 应该明显小于加锁时候的 expiration，一般取一半左右
+func AutoRefresh(lock Lock, interval, timeout time.Duration) *Lease {
+	l := &Lease{
+		Lock:     lock,
+		interval: interval,
+		timeout:  timeout,
+		maxRetry: 3,
+		lost:     make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+	go l.refreshLoop()
+	return l
+}
+
+func (l *Lease) refreshLoop() {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	retry := 0
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+			err := l.Refresh(ctx)
+			cancel()
+			switch {
+			case err == nil:
+				retry = 0
+			case errors.Is(err, ErrLockNotHold):
+				// 锁已经不是我们的了，没必要再试
+				l.markLost(err)
+				return
+			default:
+				// 超时或者网络抖动，可以再试几次
+				// 只要还在过期时间之内，锁就依旧是我们的
+				retry++
+				if retry >= l.maxRetry {
+					l.markLost(err)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (l *Lease) markLost(err error) {
+	l.lostOnce.Do(func() {
+		l.err = err
+		close(l.lost)
+	})
+}
+
+// Lost 失去锁的时候会被关闭
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Err 失去锁的原因，只有在 Lost 被关闭之后才有意义
+func (l *Lease) Err() error {
+	select {
+	case <-l.lost:
+		return l.err
+	default:
+		return nil
+	}
+}
+
+// Unlock 停止续约，并且释放锁
+func (l *Lease) Unlock(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+	err := l.Lock.Unlock(ctx)
+	l.markLost(ErrLockNotHold)
+	return err
+}
+
+// WithLost 返回一个在 lost 被关闭的时候就会被取消的 ctx
+// 用来把失去锁的信号传递给正在执行的任务
+func WithLost(ctx context.Context, lost <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-lost:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
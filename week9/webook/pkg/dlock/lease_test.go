@@ -0,0 +1,107 @@
+package dlock_test
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock"
+	dlockmocks "gitee.com/geekbang/basic-go/webook/pkg/dlock/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestAutoRefresh(t *testing.T) {
+	testCases := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) dlock.Lock
+
+		// 等待多久之后检查
+		wait     time.Duration
+		wantLost bool
+		wantErr  error
+	}{
+		{
+			name: "续约成功",
+			mock: func(ctrl *gomock.Controller) dlock.Lock {
+				lock := dlockmocks.NewMockLock(ctrl)
+				lock.EXPECT().Refresh(gomock.Any()).MinTimes(2).Return(nil)
+				lock.EXPECT().Unlock(gomock.Any()).Return(nil)
+				return lock
+			},
+			wait: time.Millisecond * 250,
+		},
+		{
+			name: "锁被人抢走了",
+			mock: func(ctrl *gomock.Controller) dlock.Lock {
+				lock := dlockmocks.NewMockLock(ctrl)
+				lock.EXPECT().Refresh(gomock.Any()).Return(dlock.ErrLockNotHold)
+				lock.EXPECT().Unlock(gomock.Any()).Return(dlock.ErrLockNotHold)
+				return lock
+			},
+			wait:     time.Millisecond * 250,
+			wantLost: true,
+			wantErr:  dlock.ErrLockNotHold,
+		},
+		{
+			name: "偶发错误，重试之后成功",
+			mock: func(ctrl *gomock.Controller) dlock.Lock {
+				lock := dlockmocks.NewMockLock(ctrl)
+				first := lock.EXPECT().Refresh(gomock.Any()).
+					Return(context.DeadlineExceeded)
+				lock.EXPECT().Refresh(gomock.Any()).After(first).
+					MinTimes(1).Return(nil)
+				lock.EXPECT().Unlock(gomock.Any()).Return(nil)
+				return lock
+			},
+			wait: time.Millisecond * 250,
+		},
+		{
+			name: "连续失败，放弃续约",
+			mock: func(ctrl *gomock.Controller) dlock.Lock {
+				lock := dlockmocks.NewMockLock(ctrl)
+				lock.EXPECT().Refresh(gomock.Any()).Times(3).
+					Return(errors.New("网络错误"))
+				lock.EXPECT().Unlock(gomock.Any()).Return(nil)
+				return lock
+			},
+			wait:     time.Millisecond * 450,
+			wantLost: true,
+			wantErr:  errors.New("网络错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			lease := dlock.AutoRefresh(tc.mock(ctrl), time.Millisecond*100, time.Millisecond*50)
+			time.Sleep(tc.wait)
+			select {
+			case <-lease.Lost():
+				assert.True(t, tc.wantLost)
+			default:
+				assert.False(t, tc.wantLost)
+			}
+			assert.Equal(t, tc.wantErr, lease.Err())
+			_ = lease.Unlock(context.Background())
+			// 释放之后，就一定是失去了锁
+			_, ok := <-lease.Lost()
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestWithLost(t *testing.T) {
+	lost := make(chan struct{})
+	ctx, cancel := dlock.WithLost(context.Background(), lost)
+	defer cancel()
+	assert.NoError(t, ctx.Err())
+	close(lost)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("失去锁之后 ctx 没有被取消")
+	}
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
@@ -0,0 +1,105 @@
+package redislock
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"gitee.com/geekbang/basic-go/webook/pkg/dlock"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+var (
+	//go:embed lua/lock.lua
+	luaLock string
+	//go:embed lua/refresh.lua
+	luaRefresh string
+	//go:embed lua/unlock.lua
+	luaUnlock string
+)
+
+// Client 基于 Redis 的分布式锁
+// fencing token 来自 key 对应的一个 INCR 计数器
+type Client struct {
+	cmd redis.Cmdable
+}
+
+func NewClient(cmd redis.Cmdable) dlock.Client {
+	return &Client{cmd: cmd}
+}
+
+func (c *Client) Lock(ctx context.Context, key string,
+	expiration time.Duration) (dlock.Lock, error) {
+	val := uuid.New().String()
+	lockKey := c.lockKey(key)
+	token, err := c.cmd.Eval(ctx, luaLock,
+		[]string{lockKey, c.tokenKey(key)},
+		val, expiration.Milliseconds()).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if token == 0 {
+		return nil, dlock.ErrFailedToPreemptLock
+	}
+	return &Lock{
+		cmd:        c.cmd,
+		key:        key,
+		lockKey:    lockKey,
+		value:      val,
+		token:      token,
+		expiration: expiration,
+	}, nil
+}
+
+// lockKey 和 tokenKey 用同一个 hash tag，保证在 Redis Cluster 里面是同一个 slot，
+// 不然 Lua 脚本会报 CROSSSLOT
+func (c *Client) lockKey(key string) string {
+	return fmt.Sprintf("{%s}", key)
+}
+
+func (c *Client) tokenKey(key string) string {
+	return fmt.Sprintf("{%s}:fencing_token", key)
+}
+
+type Lock struct {
+	cmd redis.Cmdable
+	key string
+	// lockKey 在 Redis 里面实际用的 key
+	lockKey    string
+	value      string
+	token      int64
+	expiration time.Duration
+}
+
+func (l *Lock) Key() string {
+	return l.key
+}
+
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+func (l *Lock) Refresh(ctx context.Context) error {
+	res, err := l.cmd.Eval(ctx, luaRefresh, []string{l.lockKey},
+		l.value, l.expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		return dlock.ErrLockNotHold
+	}
+	return nil
+}
+
+func (l *Lock) Unlock(ctx context.Context) error {
+	res, err := l.cmd.Eval(ctx, luaUnlock, []string{l.lockKey}, l.value).Int64()
+	if err != nil {
+		return err
+	}
+	if res != 1 {
+		// 可能是已经过期了
+		return dlock.ErrLockNotHold
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+-- KEYS[1] 锁的 key
+-- KEYS[2] fencing token 的计数器，永不过期，保证单调递增
+-- ARGV[1] 锁的值，用来标识持有者
+-- ARGV[2] 过期时间，毫秒
+if redis.call("set", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+    return redis.call("incr", KEYS[2])
+end
+-- 没抢到
+return 0
@@ -0,0 +1,6 @@
+-- 确认锁还是自己的，才能删除
+if redis.call("get", KEYS[1]) == ARGV[1] then
+    return redis.call("del", KEYS[1])
+else
+    return 0
+end
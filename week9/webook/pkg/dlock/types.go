@@ -0,0 +1,34 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrFailedToPreemptLock 锁被别人持有着，没抢到
+	ErrFailedToPreemptLock = errors.New("dlock: 抢锁失败")
+	// ErrLockNotHold 锁已经不是你的了。可能是过期了，也可能是被别人抢走了
+	ErrLockNotHold = errors.New("dlock: 未持有锁")
+)
+
+// Client 分布式锁的抽象，目前有 Redis 和 MySQL 两种实现
+//
+//go:generate mockgen -source=./types.go -package=dlockmocks -destination=mocks/dlock.mock.go Client Lock
+type Client interface {
+	// Lock 尝试加锁，不会阻塞等待。
+	// 没抢到的时候返回 ErrFailedToPreemptLock
+	// expiration 是锁的过期时间，也就是租约的长度
+	Lock(ctx context.Context, key string, expiration time.Duration) (Lock, error)
+}
+
+type Lock interface {
+	Key() string
+	// Token 是 fencing token，每一次成功加锁都会得到一个单调递增的值。
+	// 下游在写数据的时候带上它，就可以拒绝掉已经失去锁的旧持有者
+	Token() int64
+	// Refresh 续约，把过期时间重置为加锁时候的 expiration
+	Refresh(ctx context.Context) error
+	Unlock(ctx context.Context) error
+}
@@ -0,0 +1,131 @@
+// Package errs 业务错误码
+//
+// 错误码是六位数字，第一位 4 表示用户的输入有问题，5 表示系统内部的错误，
+// 中间两位是模块代码，00 是通用的，最后三位是模块内部的编号。
+// 错误码一旦发布就不能修改，前端和监控都依赖它。
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+const (
+	LangZh = "zh"
+	LangEn = "en"
+	// DefaultLang 没有对应语言的提示的时候用中文
+	DefaultLang = LangZh
+)
+
+var (
+	OK = Register(0, "OK", "OK", "OK")
+	// InvalidInput 通用的输入错误，各个模块最好用自己的错误码
+	InvalidInput = Register(400001, "InvalidInput", "参数错误", "invalid input")
+	// Unauthorized 没有登录，或者登录已经过期了
+	Unauthorized = Register(400002, "Unauthorized", "请登录", "please log in")
+	// TooManyRequests 触发了限流
+	TooManyRequests = Register(400003, "TooManyRequests", "请求太频繁，请稍后再试", "too many requests, please try again later")
+	// Internal 通用的系统错误，没有注册过的 error 都会被当成这个
+	Internal = Register(500001, "Internal", "系统错误", "internal server error")
+)
+
+var (
+	mu       sync.RWMutex
+	registry = map[int]*Error{}
+)
+
+// Error 带错误码的业务错误。同一个错误码的 Error 用 errors.Is 判断是相等的，
+// 所以 Wrap 和 WithMsg 之后依旧可以和注册的那个比较
+type Error struct {
+	Code int
+	// Name 符号名，打点和日志用
+	Name string
+	msgs map[string]string
+	// cause 不会返回给前端，只会出现在日志里面
+	cause error
+}
+
+// Register 注册一个错误码，一般在包初始化的时候调用。错误码重复的话会 panic
+func Register(code int, name string, zh string, en string) *Error {
+	mu.Lock()
+	defer mu.Unlock()
+	if e, ok := registry[code]; ok {
+		panic(fmt.Sprintf("errs: 错误码 %d 已经被 %s 注册过了", code, e.Name))
+	}
+	e := &Error{
+		Code: code,
+		Name: name,
+		msgs: map[string]string{LangZh: zh, LangEn: en},
+	}
+	registry[code] = e
+	return e
+}
+
+// Lookup 查找注册过的错误码
+func Lookup(code int) (*Error, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	e, ok := registry[code]
+	return e, ok
+}
+
+// NameOf 错误码的符号名，没有注册过的直接用数字
+func NameOf(code int) string {
+	if e, ok := Lookup(code); ok {
+		return e.Name
+	}
+	return strconv.Itoa(code)
+}
+
+// From 取出 err 链上的 *Error
+func From(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+func (e *Error) Error() string {
+	msg := e.Message(DefaultLang)
+	if e.cause != nil {
+		return msg + ": " + e.cause.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Message 对应语言的提示，没有的话用 DefaultLang 的
+func (e *Error) Message(lang string) string {
+	if msg, ok := e.msgs[lang]; ok {
+		return msg
+	}
+	return e.msgs[DefaultLang]
+}
+
+// ServerError 是否是系统内部的错误，这一类的错误要记录日志和告警
+func (e *Error) ServerError() bool {
+	return e.Code >= 500000
+}
+
+// Wrap 带上底层的错误，错误码和提示不变
+func (e *Error) Wrap(cause error) *Error {
+	res := *e
+	res.cause = cause
+	return &res
+}
+
+// WithMsg 同一个错误码，换一个更加具体的提示
+func (e *Error) WithMsg(zh string, en string) *Error {
+	res := *e
+	res.msgs = map[string]string{LangZh: zh, LangEn: en}
+	return &res
+}
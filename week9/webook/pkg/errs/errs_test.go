@@ -0,0 +1,39 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	notFound := Register(499001, "TestNotFound", "不存在", "not found")
+	other := Register(499002, "TestOther", "别的错误", "other")
+
+	wrapped := fmt.Errorf("查询失败 %w", notFound.Wrap(errors.New("record not found")))
+	assert.True(t, errors.Is(wrapped, notFound))
+	assert.False(t, errors.Is(wrapped, other))
+	assert.True(t, errors.Is(notFound.WithMsg("用户不存在", "user not found"), notFound))
+
+	e, ok := From(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, 499001, e.Code)
+	assert.Equal(t, "不存在: record not found", e.Error())
+	assert.Equal(t, "not found", e.Message(LangEn))
+	// 没有的语言用默认的
+	assert.Equal(t, "不存在", e.Message("fr"))
+
+	_, ok = From(errors.New("mock error"))
+	assert.False(t, ok)
+
+	assert.Equal(t, "TestNotFound", NameOf(499001))
+	assert.Equal(t, "OK", NameOf(0))
+	assert.Equal(t, "4", NameOf(4))
+	assert.False(t, notFound.ServerError())
+	assert.True(t, Internal.ServerError())
+
+	assert.Panics(t, func() {
+		Register(499001, "TestDuplicate", "重复", "duplicate")
+	})
+}
@@ -1,11 +1,12 @@
 package ginx
 
 import (
+	"gitee.com/geekbang/basic-go/webook/pkg/errs"
 	"gitee.com/geekbang/basic-go/webook/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"net/http"
-	"strconv"
+	"strings"
 )
 
 // 受制于泛型，我们这里只能使用包变量，我深恶痛绝的包变量
@@ -26,9 +27,10 @@ func SetLogger(l logger.LoggerV1) {
 // WrapClaimsAndReq 如果做成中间件来源出去，那么直接耦合 UserClaims 也是不好的。
 func WrapClaimsAndReq[Req any](fn func(*gin.Context, Req, UserClaims) (Result, error)) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var req Req
-		if err := ctx.Bind(&req); err != nil {
-			log.Error("解析请求失败", logger.Error(err))
+		req, err := bind[Req](ctx)
+		if err != nil {
+			// 输入不对，直接把具体的字段错误返回给前端
+			writeResult(ctx, Result{}, err)
 			return
 		}
 		// 可以用包变量来配置，还是那句话，因为泛型的限制，这里只能用包变量
@@ -48,30 +50,21 @@ func WrapClaimsAndReq[Req any](fn func(*gin.Context, Req, UserClaims) (Result, e
 			return
 		}
 		res, err := fn(ctx, req, claims)
-		vector.WithLabelValues(strconv.Itoa(res.Code)).Inc()
-		if err != nil {
-			log.Error("执行业务逻辑失败",
-				logger.Error(err))
-		}
-		ctx.JSON(http.StatusOK, res)
+		writeResult(ctx, res, err)
 	}
 }
 
 // WrapReq 。
 func WrapReq[Req any](fn func(*gin.Context, Req) (Result, error)) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var req Req
-		if err := ctx.Bind(&req); err != nil {
-			log.Error("解析请求失败", logger.Error(err))
+		req, err := bind[Req](ctx)
+		if err != nil {
+			// 输入不对，直接把具体的字段错误返回给前端
+			writeResult(ctx, Result{}, err)
 			return
 		}
 		res, err := fn(ctx, req)
-		if err != nil {
-			log.Error("执行业务逻辑失败",
-				logger.Error(err))
-		}
-		vector.WithLabelValues(strconv.Itoa(res.Code)).Inc()
-		ctx.JSON(http.StatusOK, res)
+		writeResult(ctx, res, err)
 	}
 }
 
@@ -95,11 +88,65 @@ func WrapClaims(fn func(*gin.Context, UserClaims) (Result, error)) gin.HandlerFu
 			return
 		}
 		res, err := fn(ctx, claims)
-		if err != nil {
+		writeResult(ctx, res, err)
+	}
+}
+
+// WrapOptionalClaims 用在可以不登录的路由上，没有登录的时候 claims 是零值，也就是 Id 为 0
+func WrapOptionalClaims(fn func(*gin.Context, UserClaims) (Result, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var claims UserClaims
+		if rawVal, ok := ctx.Get("user"); ok {
+			claims, _ = rawVal.(UserClaims)
+		}
+		res, err := fn(ctx, claims)
+		writeResult(ctx, res, err)
+	}
+}
+
+// writeResult 返回了 error 但是没有设置 Result 的时候，用 error 对应的错误码
+// 用户输入导致的错误不需要记录日志
+func writeResult(ctx *gin.Context, res Result, err error) {
+	if err != nil {
+		e, ok := errs.From(err)
+		if !ok || e.ServerError() {
 			log.Error("执行业务逻辑失败",
+				logger.String("path", ctx.FullPath()),
 				logger.Error(err))
 		}
-		vector.WithLabelValues(strconv.Itoa(res.Code)).Inc()
-		ctx.JSON(http.StatusOK, res)
+		if res.Code == 0 && res.Msg == "" {
+			res = ErrResult(ctx, err)
+		}
+	}
+	if vector != nil {
+		vector.WithLabelValues(errs.NameOf(res.Code)).Inc()
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// ErrResult 把 error 转换成 Result，提示用请求的语言。
+// 没有错误码的 error 都当成系统错误，不会把内部的错误信息返回给前端。
+// 校验失败的话，Data 里面是具体的字段错误
+func ErrResult(ctx *gin.Context, err error) Result {
+	e, ok := errs.From(err)
+	if !ok {
+		e = errs.Internal
+	}
+	lang := Lang(ctx)
+	res := Result{
+		Code: e.Code,
+		Msg:  e.Message(lang),
+	}
+	if fields := fieldResults(err, lang); fields != nil {
+		res.Data = fields
+	}
+	return res
+}
+
+// Lang 根据 Accept-Language 决定用什么语言的提示，目前只支持中文和英文
+func Lang(ctx *gin.Context) string {
+	if strings.HasPrefix(strings.ToLower(ctx.GetHeader("Accept-Language")), errs.LangEn) {
+		return errs.LangEn
 	}
+	return errs.DefaultLang
 }
@@ -0,0 +1,76 @@
+package ginx
+
+import (
+	"encoding/json"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/pkg/errs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrapOptionalClaims(t *testing.T) {
+	testCases := []struct {
+		name string
+		lang string
+		res  Result
+		err  error
+
+		wantRes Result
+	}{
+		{
+			name:    "成功",
+			res:     Result{Msg: "OK"},
+			wantRes: Result{Msg: "OK"},
+		},
+		{
+			name:    "带错误码的 error",
+			err:     errs.InvalidInput,
+			wantRes: Result{Code: errs.InvalidInput.Code, Msg: "参数错误"},
+		},
+		{
+			name:    "英文提示",
+			lang:    "en-US,en;q=0.9",
+			err:     errs.InvalidInput,
+			wantRes: Result{Code: errs.InvalidInput.Code, Msg: "invalid input"},
+		},
+		{
+			name:    "包装过的 error",
+			err:     errs.InvalidInput.Wrap(errors.New("mock error")),
+			wantRes: Result{Code: errs.InvalidInput.Code, Msg: "参数错误"},
+		},
+		{
+			name:    "没有错误码的 error",
+			err:     errors.New("mock error"),
+			wantRes: Result{Code: errs.Internal.Code, Msg: "系统错误"},
+		},
+		{
+			name:    "已经设置了 Result",
+			res:     Result{Code: errs.InvalidInput.Code, Msg: "自定义提示"},
+			err:     errors.New("mock error"),
+			wantRes: Result{Code: errs.InvalidInput.Code, Msg: "自定义提示"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.GET("/test", WrapOptionalClaims(func(ctx *gin.Context, uc UserClaims) (Result, error) {
+				return tc.res, tc.err
+			}))
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept-Language", tc.lang)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package ginx
+
+import (
+	"github.com/gin-gonic/gin"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// HandlerMeta 注册路由的时候记下来的类型信息，生成 OpenAPI 文档用
+type HandlerMeta struct {
+	// Req 请求的类型，没有请求体的是 nil
+	Req reflect.Type
+	// Resp Result.Data 的类型，没有声明的是 nil
+	Resp reflect.Type
+	// RawResp 为 true 的时候，响应就是 Resp，没有用 Result 包起来
+	RawResp bool
+	// Permission 访问这个路由需要的权限，空的就是不需要权限
+	Permission string
+}
+
+// MetaOption 设置路由的类型信息
+type MetaOption func(meta *HandlerMeta)
+
+// withReq 请求的类型只能由 HandleReq、HandleClaimsAndReq 从 fn 的 Req 推导出来，
+// 这样就不会出现声明的类型和实际解析的类型不一致
+func withReq[T any]() MetaOption {
+	return func(meta *HandlerMeta) {
+		meta.Req = typeOf[T]()
+	}
+}
+
+// WithResp 声明 Result.Data 的类型
+func WithResp[T any]() MetaOption {
+	return func(meta *HandlerMeta) {
+		meta.Resp = typeOf[T]()
+		meta.RawResp = false
+	}
+}
+
+// WithRawResp 声明响应的类型，用在没有用 Result 包起来的响应上
+func WithRawResp[T any]() MetaOption {
+	return func(meta *HandlerMeta) {
+		meta.Resp = typeOf[T]()
+		meta.RawResp = true
+	}
+}
+
+// WithPermission 声明访问这个路由需要的权限，由 rbac 中间件校验
+func WithPermission(permission string) MetaOption {
+	return func(meta *HandlerMeta) {
+		meta.Permission = permission
+	}
+}
+
+// Router gin.Engine 和 gin.RouterGroup 都实现了这个接口
+type Router interface {
+	gin.IRoutes
+	BasePath() string
+}
+
+var (
+	metasMu sync.RWMutex
+	// metas key 是 method 加上完整的路径，和 gin.RouteInfo 里面的一样。
+	// 受制于泛型，从 gin.HandlerFunc 里面看不出来 Req 是什么，只能注册的时候记下来
+	metas = map[string]HandlerMeta{}
+)
+
+// Handle 注册路由，顺便记下这个路由的类型信息。
+// 只是用来生成文档，不会改变 hdl 的行为
+func Handle(r Router, method string, relativePath string,
+	hdl gin.HandlerFunc, opts ...MetaOption) gin.IRoutes {
+	var meta HandlerMeta
+	for _, opt := range opts {
+		opt(&meta)
+	}
+	key := metaKey(method, joinPaths(r.BasePath(), relativePath))
+	metasMu.Lock()
+	metas[key] = meta
+	metasMu.Unlock()
+	return r.Handle(method, relativePath, hdl)
+}
+
+// HandleReq 用 WrapReq 包装 fn 再注册路由，请求的类型就是 fn 的 Req
+func HandleReq[Req any](r Router, method string, relativePath string,
+	fn func(*gin.Context, Req) (Result, error), opts ...MetaOption) gin.IRoutes {
+	return Handle(r, method, relativePath, WrapReq[Req](fn), append(opts, withReq[Req]())...)
+}
+
+// HandleClaimsAndReq 用 WrapClaimsAndReq 包装 fn 再注册路由，请求的类型就是 fn 的 Req
+func HandleClaimsAndReq[Req any](r Router, method string, relativePath string,
+	fn func(*gin.Context, Req, UserClaims) (Result, error), opts ...MetaOption) gin.IRoutes {
+	return Handle(r, method, relativePath, WrapClaimsAndReq[Req](fn), append(opts, withReq[Req]())...)
+}
+
+// MetaOf 取出用 Handle 注册的路由的类型信息，path 是完整的路径
+func MetaOf(method string, path string) (HandlerMeta, bool) {
+	metasMu.RLock()
+	defer metasMu.RUnlock()
+	meta, ok := metas[metaKey(method, path)]
+	return meta, ok
+}
+
+func metaKey(method string, path string) string {
+	return method + " " + path
+}
+
+// joinPaths 和 gin 拼接分组路径的规则一样
+func joinPaths(base string, relative string) string {
+	if relative == "" {
+		return base
+	}
+	res := path.Join(base, relative)
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(res, "/") {
+		return res + "/"
+	}
+	return res
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
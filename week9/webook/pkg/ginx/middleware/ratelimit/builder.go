@@ -1,32 +1,57 @@
 package ratelimit
 
 import (
-	_ "embed"
 	"fmt"
+	"gitee.com/geekbang/basic-go/webook/pkg/errs"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
-	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 )
 
-type Builder struct {
-	prefix   string
-	cmd      redis.Cmdable
-	interval time.Duration
-	// 阈值
-	rate int
+// Rule 一条限流规则。Method 和 Path 为空的时候匹配所有的请求，
+// Path 是注册路由时候的模式，也就是 gin 的 FullPath
+type Rule struct {
+	Method  string
+	Path    string
+	Key     KeyFunc
+	Limiter ratelimit.Limiter
+}
+
+func (r Rule) match(method, path string) bool {
+	return (r.Method == "" || r.Method == method) && (r.Path == "" || r.Path == path)
+}
+
+// name 不同的规则即便用的是同一个 KeyFunc，也不能共用一个计数器
+func (r Rule) name() string {
+	method, path := r.Method, r.Path
+	if method == "" {
+		method = "*"
+	}
+	if path == "" {
+		path = "*"
+	}
+	return method + ":" + path
 }
 
-//go:embed slide_window.lua
-var luaScript string
+type Builder struct {
+	prefix string
+	rules  []Rule
+	// failOpen 限流器出错的时候是放行还是拒绝
+	failOpen bool
+	l        logger.LoggerV1
+}
 
-func NewBuilder(cmd redis.Cmdable, interval time.Duration, rate int) *Builder {
+// NewBuilder 默认限流器出错的时候放行，限流本身出问题不能把整个服务拖垮
+func NewBuilder(l logger.LoggerV1) *Builder {
 	return &Builder{
-		cmd:      cmd,
-		prefix:   "ip-limiter",
-		interval: interval,
-		rate:     rate,
+		prefix:   "limiter",
+		failOpen: true,
+		l:        l,
 	}
 }
 
@@ -35,27 +60,90 @@ func (b *Builder) Prefix(prefix string) *Builder {
 	return b
 }
 
+// Rule 按照添加的顺序检查，所有匹配上的规则都要通过
+func (b *Builder) Rule(r Rule) *Builder {
+	b.rules = append(b.rules, r)
+	return b
+}
+
+// FailOpen false 的话限流器出错就返回 503，
+// 适合那种宁可不可用也不能被刷的接口
+func (b *Builder) FailOpen(open bool) *Builder {
+	b.failOpen = open
+	return b
+}
+
 func (b *Builder) Build() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		limited, err := b.limit(ctx)
-		if err != nil {
-			log.Println(err)
-			// 这一步很有意思，就是如果这边出错了
-			// 要怎么办？
-			ctx.AbortWithStatus(http.StatusInternalServerError)
-			return
+		method, path := ctx.Request.Method, ctx.FullPath()
+		// 剩余额度最少的那个结果，用来设置响应头
+		var (
+			tightest ratelimit.Result
+			found    bool
+		)
+		for _, r := range b.rules {
+			if !r.match(method, path) {
+				continue
+			}
+			key, ok := r.Key(ctx)
+			if !ok {
+				continue
+			}
+			key = fmt.Sprintf("%s:%s:%s", b.prefix, r.name(), key)
+			res, hasResult, err := b.limit(ctx, r.Limiter, key)
+			if err != nil {
+				b.l.Error("限流失败",
+					logger.String("key", key),
+					logger.Error(err))
+				if b.failOpen {
+					continue
+				}
+				ctx.AbortWithStatus(http.StatusServiceUnavailable)
+				return
+			}
+			if res.Limited {
+				setHeaders(ctx, res, hasResult)
+				ctx.AbortWithStatusJSON(http.StatusTooManyRequests,
+					ginx.ErrResult(ctx, errs.TooManyRequests))
+				return
+			}
+			if hasResult && (!found || res.Remaining < tightest.Remaining) {
+				tightest, found = res, true
+			}
 		}
-		if limited {
-			log.Println(err)
-			ctx.AbortWithStatus(http.StatusTooManyRequests)
-			return
+		if found {
+			setHeaders(ctx, tightest, true)
+		}
+	}
+}
+
+// limit 只实现了 Limiter 的限流器拿不到剩余额度，不设置 X-RateLimit-* 响应头
+func (b *Builder) limit(ctx *gin.Context, limiter ratelimit.Limiter, key string) (ratelimit.Result, bool, error) {
+	if rl, ok := limiter.(ratelimit.ResultLimiter); ok {
+		res, err := rl.Acquire(ctx, key)
+		return res, true, err
+	}
+	limited, err := limiter.Limit(ctx, key)
+	return ratelimit.Result{Limited: limited}, false, err
+}
+
+func setHeaders(ctx *gin.Context, res ratelimit.Result, hasResult bool) {
+	if hasResult {
+		ctx.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		ctx.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		ctx.Header("X-RateLimit-Reset", seconds(res.ResetAfter))
+	}
+	if res.Limited {
+		retry := res.RetryAfter
+		if retry <= 0 {
+			// 不知道要等多久的，让客户端等一秒
+			retry = time.Second
 		}
-		ctx.Next()
+		ctx.Header("Retry-After", seconds(retry))
 	}
 }
 
-func (b *Builder) limit(ctx *gin.Context) (bool, error) {
-	key := fmt.Sprintf("%s:%s", b.prefix, ctx.ClientIP())
-	return b.cmd.Eval(ctx, luaScript, []string{key},
-		b.interval.Milliseconds(), b.rate, time.Now().UnixMilli()).Bool()
+// seconds 响应头里面用的是秒，向上取整，免得客户端提前重试又被限流
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
 }
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/pkg/errs"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"gitee.com/geekbang/basic-go/webook/pkg/ratelimit"
+	limitmocks "gitee.com/geekbang/basic-go/webook/pkg/ratelimit/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name     string
+		path     string
+		uid      int64
+		failOpen bool
+		mock     func(ctrl *gomock.Controller) []Rule
+
+		wantCode    int
+		wantHeaders map[string]string
+	}{
+		{
+			name: "没有限流",
+			path: "/articles/pub/123",
+			mock: func(ctrl *gomock.Controller) []Rule {
+				global := limitmocks.NewMockResultLimiter(ctrl)
+				global.EXPECT().Acquire(gomock.Any(), "limiter:*:*:ip:192.0.2.1").
+					Return(ratelimit.Result{Limit: 100, Remaining: 99, ResetAfter: time.Millisecond * 10}, nil)
+				route := limitmocks.NewMockResultLimiter(ctrl)
+				route.EXPECT().Acquire(gomock.Any(), "limiter:GET:/articles/pub/:id:route:GET:/articles/pub/:id").
+					Return(ratelimit.Result{Limit: 10, Remaining: 3, ResetAfter: time.Second}, nil)
+				return []Rule{
+					{Key: IPKey, Limiter: global},
+					{Method: http.MethodGet, Path: "/articles/pub/:id", Key: RouteKey, Limiter: route},
+				}
+			},
+			wantCode: http.StatusOK,
+			// 用剩余额度最少的
+			wantHeaders: map[string]string{
+				"X-RateLimit-Limit":     "10",
+				"X-RateLimit-Remaining": "3",
+				"X-RateLimit-Reset":     "1",
+				"Retry-After":           "",
+			},
+		},
+		{
+			name: "被限流",
+			path: "/articles/pub/123",
+			uid:  123,
+			mock: func(ctrl *gomock.Controller) []Rule {
+				limiter := limitmocks.NewMockResultLimiter(ctrl)
+				limiter.EXPECT().Acquire(gomock.Any(), "limiter:*:*:route:GET:/articles/pub/:id:uid:123").
+					Return(ratelimit.Result{Limited: true, Limit: 10, Remaining: 0,
+						RetryAfter: time.Millisecond * 1500, ResetAfter: time.Second * 6}, nil)
+				return []Rule{{Key: JoinKeys(RouteKey, UidKey), Limiter: limiter}}
+			},
+			wantCode: http.StatusTooManyRequests,
+			wantHeaders: map[string]string{
+				"X-RateLimit-Limit":     "10",
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     "6",
+				"Retry-After":           "2",
+			},
+		},
+		{
+			name: "路由不匹配",
+			path: "/articles/pub/123",
+			mock: func(ctrl *gomock.Controller) []Rule {
+				limiter := limitmocks.NewMockResultLimiter(ctrl)
+				return []Rule{{Method: http.MethodPost, Path: "/users/login", Key: IPKey, Limiter: limiter}}
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "只实现了 Limiter",
+			path: "/articles/pub/123",
+			mock: func(ctrl *gomock.Controller) []Rule {
+				limiter := limitmocks.NewMockLimiter(ctrl)
+				limiter.EXPECT().Limit(gomock.Any(), "limiter:*:*:ip:192.0.2.1").Return(true, nil)
+				return []Rule{{Key: IPKey, Limiter: limiter}}
+			},
+			wantCode: http.StatusTooManyRequests,
+			wantHeaders: map[string]string{
+				"X-RateLimit-Limit": "",
+				"Retry-After":       "1",
+			},
+		},
+		{
+			name:     "限流器出错，放行",
+			path:     "/articles/pub/123",
+			failOpen: true,
+			mock: func(ctrl *gomock.Controller) []Rule {
+				limiter := limitmocks.NewMockResultLimiter(ctrl)
+				limiter.EXPECT().Acquire(gomock.Any(), gomock.Any()).
+					Return(ratelimit.Result{}, errors.New("mock error"))
+				return []Rule{{Key: IPKey, Limiter: limiter}}
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "限流器出错，拒绝",
+			path: "/articles/pub/123",
+			mock: func(ctrl *gomock.Controller) []Rule {
+				limiter := limitmocks.NewMockResultLimiter(ctrl)
+				limiter.EXPECT().Acquire(gomock.Any(), gomock.Any()).
+					Return(ratelimit.Result{}, errors.New("mock error"))
+				return []Rule{{Key: IPKey, Limiter: limiter}}
+			},
+			wantCode: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			builder := NewBuilder(logger.NewNoOpLogger()).FailOpen(tc.failOpen)
+			for _, r := range tc.mock(ctrl) {
+				builder.Rule(r)
+			}
+
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.uid > 0 {
+					ctx.Set("user", ginx.UserClaims{Id: tc.uid})
+				}
+			}, builder.Build())
+			server.GET("/articles/pub/:id", func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+			req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.NoError(t, err)
+			req.RemoteAddr = "192.0.2.1:1234"
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			for k, v := range tc.wantHeaders {
+				assert.Equal(t, v, recorder.Header().Get(k), k)
+			}
+			if tc.wantCode == http.StatusTooManyRequests {
+				var res ginx.Result
+				err = json.NewDecoder(recorder.Body).Decode(&res)
+				require.NoError(t, err)
+				assert.Equal(t, errs.TooManyRequests.Code, res.Code)
+			}
+		})
+	}
+}
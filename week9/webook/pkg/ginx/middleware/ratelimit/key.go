@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+)
+
+// KeyFunc 从请求里面拿到限流的对象，返回 false 的话这个请求不受这条规则限制
+type KeyFunc func(ctx *gin.Context) (string, bool)
+
+// IPKey 按照 IP 限流
+func IPKey(ctx *gin.Context) (string, bool) {
+	return "ip:" + ctx.ClientIP(), true
+}
+
+// UidKey 按照用户限流，claims 是登录校验放进去的，所以要放在登录校验的后面。
+// 没有登录的用户按照 IP 限流
+func UidKey(ctx *gin.Context) (string, bool) {
+	val, ok := ctx.Get("user")
+	if !ok {
+		return IPKey(ctx)
+	}
+	uc, ok := val.(ginx.UserClaims)
+	if !ok || uc.Id == 0 {
+		return IPKey(ctx)
+	}
+	return "uid:" + strconv.FormatInt(uc.Id, 10), true
+}
+
+// RouteKey 整个路由一起限流，用的是路由的模式，所以 /articles/pub/1 和 /articles/pub/2 是同一个
+func RouteKey(ctx *gin.Context) (string, bool) {
+	path := ctx.FullPath()
+	if path == "" {
+		// 404 的交给别的规则
+		return "", false
+	}
+	return "route:" + ctx.Request.Method + ":" + path, true
+}
+
+// JoinKeys 组合多个 KeyFunc，比如说 JoinKeys(RouteKey, UidKey) 就是每个用户在每个路由上单独限流。
+// 任何一个返回 false 都不限流
+func JoinKeys(fns ...KeyFunc) KeyFunc {
+	return func(ctx *gin.Context) (string, bool) {
+		keys := make([]string, 0, len(fns))
+		for _, fn := range fns {
+			key, ok := fn(ctx)
+			if !ok {
+				return "", false
+			}
+			keys = append(keys, key)
+		}
+		return strings.Join(keys, ":"), true
+	}
+}
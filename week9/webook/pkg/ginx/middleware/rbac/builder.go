@@ -0,0 +1,132 @@
+package rbac
+
+import (
+	"context"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// PermissionChecker 判断用户有没有某个权限，权限一般放在数据库里面，实现者要自己考虑缓存
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, uid int64, permission string) (bool, error)
+}
+
+// Routes 哪些路由需要什么权限。没有声明的路由，这个中间件不管。
+// 一般是在注册路由的时候用 ginx.WithPermission 声明，Require 用来补充没有用 ginx.Handle 注册的路由
+type Routes struct {
+	mutex sync.RWMutex
+	// key 是 "METHOD 路由模式"，比如说 "POST /admin/users/:id/roles"
+	perms map[string]string
+}
+
+func NewRoutes() *Routes {
+	return &Routes{
+		perms: make(map[string]string, 16),
+	}
+}
+
+// Require path 是注册路由时候的模式，也就是 gin 的 FullPath，而不是具体的路径
+func (r *Routes) Require(method, path, permission string) *Routes {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.perms[method+" "+path] = permission
+	return r
+}
+
+// Permission 先看 Require 声明的，再看注册路由的时候用 ginx.WithPermission 声明的
+func (r *Routes) Permission(method, path string) (string, bool) {
+	r.mutex.RLock()
+	perm, ok := r.perms[method+" "+path]
+	r.mutex.RUnlock()
+	if ok {
+		return perm, true
+	}
+	meta, ok := ginx.MetaOf(method, path)
+	if !ok || meta.Permission == "" {
+		return "", false
+	}
+	return meta.Permission, true
+}
+
+type Builder struct {
+	checker PermissionChecker
+	routes  *Routes
+	l       logger.LoggerV1
+	// uidFunc 从请求里面拿到用户 ID，拿不到就是没有登录
+	uidFunc func(ctx *gin.Context) (int64, bool)
+	// denyPrefixes 这些前缀下面没有声明权限的路由，一律拒绝
+	denyPrefixes []string
+}
+
+// NewBuilder 默认从 ctx 的 user 里面拿 ginx.UserClaims，所以要放在登录校验的后面
+func NewBuilder(checker PermissionChecker, routes *Routes, l logger.LoggerV1) *Builder {
+	return &Builder{
+		checker: checker,
+		routes:  routes,
+		l:       l,
+		uidFunc: func(ctx *gin.Context) (int64, bool) {
+			val, ok := ctx.Get("user")
+			if !ok {
+				return 0, false
+			}
+			uc, ok := val.(ginx.UserClaims)
+			return uc.Id, ok
+		},
+	}
+}
+
+func (b *Builder) UidFunc(fn func(ctx *gin.Context) (int64, bool)) *Builder {
+	b.uidFunc = fn
+	return b
+}
+
+// DenyUndeclared 比如说 /admin 下面的路由，忘了声明权限就谁都不能访问，
+// 而不是谁都能访问
+func (b *Builder) DenyUndeclared(prefixes ...string) *Builder {
+	b.denyPrefixes = append(b.denyPrefixes, prefixes...)
+	return b
+}
+
+func (b *Builder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		path := ctx.FullPath()
+		perm, ok := b.routes.Permission(ctx.Request.Method, path)
+		if !ok {
+			if b.denied(path) {
+				ctx.AbortWithStatus(http.StatusForbidden)
+			}
+			return
+		}
+		uid, ok := b.uidFunc(ctx)
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		ok, err := b.checker.HasPermission(ctx, uid, perm)
+		if err != nil {
+			b.l.Error("查询权限失败",
+				logger.Int64("uid", uid),
+				logger.String("permission", perm),
+				logger.Error(err))
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+	}
+}
+
+func (b *Builder) denied(path string) bool {
+	for _, prefix := range b.denyPrefixes {
+		if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
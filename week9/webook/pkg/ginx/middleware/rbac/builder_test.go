@@ -0,0 +1,109 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"gitee.com/geekbang/basic-go/webook/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name    string
+		path    string
+		login   bool
+		checker checkerFunc
+
+		wantCode int
+	}{
+		{
+			name:     "不需要权限",
+			path:     "/users/profile",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:  "有权限",
+			path:  "/admin/users/123",
+			login: true,
+			checker: func(ctx context.Context, uid int64, permission string) (bool, error) {
+				return uid == 1 && permission == "user:read", nil
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:  "没有权限",
+			path:  "/admin/users/123",
+			login: true,
+			checker: func(ctx context.Context, uid int64, permission string) (bool, error) {
+				return false, nil
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:  "注册路由的时候声明的权限",
+			path:  "/admin/roles",
+			login: true,
+			checker: func(ctx context.Context, uid int64, permission string) (bool, error) {
+				return permission != "role:read", nil
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "admin 下面没有声明权限的路由",
+			path:     "/admin/undeclared",
+			login:    true,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "没有登录",
+			path:     "/admin/users/123",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "查询权限出错",
+			path:  "/admin/users/123",
+			login: true,
+			checker: func(ctx context.Context, uid int64, permission string) (bool, error) {
+				return false, errors.New("mock error")
+			},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gin.SetMode(gin.ReleaseMode)
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.login {
+					ctx.Set("user", ginx.UserClaims{Id: 1})
+				}
+			})
+			routes := NewRoutes().Require(http.MethodGet, "/admin/users/:id", "user:read")
+			server.Use(NewBuilder(tc.checker, routes, logger.NewNoOpLogger()).
+				DenyUndeclared("/admin").Build())
+			ok := func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			}
+			server.GET("/admin/users/:id", ok)
+			ginx.Handle(server, http.MethodGet, "/admin/roles", ok, ginx.WithPermission("role:read"))
+			server.GET("/users/profile", ok)
+			server.GET("/admin/undeclared", ok)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
+
+type checkerFunc func(ctx context.Context, uid int64, permission string) (bool, error)
+
+func (f checkerFunc) HasPermission(ctx context.Context, uid int64, permission string) (bool, error) {
+	return f(ctx, uid, permission)
+}
@@ -0,0 +1,227 @@
+package openapi
+
+import (
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Auth 路由要不要登录
+type Auth int
+
+const (
+	AuthRequired Auth = iota
+	AuthOptional
+	AuthPublic
+)
+
+const jsonContentType = "application/json"
+
+// Builder 从 gin 注册的路由和 ginx.Handle 记下来的类型信息生成 OpenAPI 文档。
+// 没有用 ginx.Handle 注册的路由只有路径，没有请求和响应的结构
+type Builder struct {
+	title      string
+	version    string
+	auth       func(method, path string) Auth
+	permission func(method, path string) (string, bool)
+}
+
+func NewBuilder(title string, version string) *Builder {
+	return &Builder{
+		title:   title,
+		version: version,
+		auth: func(method, path string) Auth {
+			return AuthRequired
+		},
+	}
+}
+
+// Auth 设置路由要不要登录，默认都需要登录
+func (b *Builder) Auth(fn func(method, path string) Auth) *Builder {
+	b.auth = fn
+	return b
+}
+
+// Permission 设置路由需要的权限，会放在 x-permission 里面
+func (b *Builder) Permission(fn func(method, path string) (string, bool)) *Builder {
+	b.permission = fn
+	return b
+}
+
+func (b *Builder) Build(routes gin.RoutesInfo) *Document {
+	s := newSchemas()
+	s.register(reflect.TypeOf(ginx.Result{}))
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: b.title, Version: b.version},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				SecuritySchemeName: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+		Security: []SecurityRequirement{{SecuritySchemeName: {}}},
+	}
+	for _, r := range routes {
+		p, params := convertPath(r.Path)
+		item, ok := doc.Paths[p]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[p] = item
+		}
+		item[strings.ToLower(r.Method)] = b.operation(s, r, params)
+	}
+	doc.Components.Schemas = s.components
+	return doc
+}
+
+// Handler 返回 JSON 格式的文档。第一次请求的时候才生成，
+// 这个时候所有的路由都已经注册好了
+func (b *Builder) Handler(server *gin.Engine) gin.HandlerFunc {
+	var (
+		once sync.Once
+		doc  *Document
+	)
+	return func(ctx *gin.Context) {
+		once.Do(func() {
+			doc = b.Build(server.Routes())
+		})
+		ctx.JSON(http.StatusOK, doc)
+	}
+}
+
+func (b *Builder) operation(s *schemas, r gin.RouteInfo, params []Parameter) *Operation {
+	meta, _ := ginx.MetaOf(r.Method, r.Path)
+	respSchema := resultSchema(s, meta.Resp)
+	if meta.RawResp {
+		respSchema = s.schemaOf(meta.Resp)
+	}
+	op := &Operation{
+		OperationId: operationId(r.Method, r.Path),
+		Parameters:  params,
+		Responses: map[string]Response{
+			"200": {
+				Description: "业务错误也是 200，看 code",
+				Content: map[string]MediaType{
+					jsonContentType: {Schema: respSchema},
+				},
+			},
+		},
+	}
+	if tag, _, _ := strings.Cut(strings.TrimPrefix(r.Path, "/"), "/"); tag != "" {
+		op.Tags = []string{tag}
+	}
+	if meta.Req != nil {
+		b.request(s, op, r.Method, meta.Req)
+	}
+	switch b.auth(r.Method, r.Path) {
+	case AuthPublic:
+		op.Security = &[]SecurityRequirement{}
+	case AuthOptional:
+		op.Security = &[]SecurityRequirement{{}, {SecuritySchemeName: {}}}
+		op.Responses["401"] = Response{Description: "带了 token 但是 token 不对"}
+	default:
+		op.Responses["401"] = Response{Description: "没有登录或者登录已经过期"}
+	}
+	if b.permission != nil {
+		if perm, ok := b.permission(r.Method, r.Path); ok {
+			op.Permission = perm
+			op.Responses["403"] = Response{Description: "没有权限"}
+		}
+	}
+	return op
+}
+
+// request GET 和 DELETE 的请求从 query 里面解析，其它的都是 JSON
+func (b *Builder) request(s *schemas, op *Operation, method string, req reflect.Type) {
+	if method != http.MethodGet && method != http.MethodDelete {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				jsonContentType: {Schema: s.schemaOf(req)},
+			},
+		}
+		return
+	}
+	for req.Kind() == reflect.Pointer {
+		req = req.Elem()
+	}
+	if req.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < req.NumField(); i++ {
+		f := req.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("form"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := s.schemaOf(f.Type)
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     name,
+			In:       "query",
+			Required: applyBinding(fs, f.Tag.Get("binding")),
+			Schema:   fs,
+		})
+	}
+}
+
+// resultSchema ginx.Result，data 是 Resp 声明的类型
+func resultSchema(s *schemas, resp reflect.Type) *Schema {
+	if resp == nil {
+		return &Schema{Ref: "#/components/schemas/Result"}
+	}
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code": {Type: "integer", Format: "int32"},
+			"msg":  {Type: "string"},
+			"data": s.schemaOf(resp),
+		},
+	}
+}
+
+// convertPath 把 gin 的 :id 和 *path 转换成 OpenAPI 的 {id} 和 {path}
+func convertPath(p string) (string, []Parameter) {
+	segments := strings.Split(p, "/")
+	var params []Parameter
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			name := seg[1:]
+			segments[i] = "{" + name + "}"
+			params = append(params, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationId 比如说 POST /users/signup 就是 post_users_signup
+func operationId(method string, p string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+	for _, seg := range strings.Split(p, "/") {
+		seg = strings.TrimLeft(seg, ":*")
+		seg = strings.Map(func(r rune) rune {
+			if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return '_'
+		}, seg)
+		if seg == "" {
+			continue
+		}
+		sb.WriteByte('_')
+		sb.WriteString(seg)
+	}
+	return sb.String()
+}
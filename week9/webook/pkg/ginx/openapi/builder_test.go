@@ -0,0 +1,159 @@
+package openapi
+
+import (
+	"encoding/json"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type signUpReq struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,password"`
+	AboutMe  string `json:"aboutMe" binding:"max=1024"`
+}
+
+type articleVo struct {
+	Id     int64     `json:"id"`
+	Title  string    `json:"title"`
+	Tags   []string  `json:"tags"`
+	Author authorVo  `json:"author"`
+	Ctime  time.Time `json:"ctime"`
+	secret string
+}
+
+type authorVo struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+	// Articles 递归的结构体
+	Articles []articleVo `json:"-"`
+	Next     *authorVo   `json:"next"`
+}
+
+type listReq struct {
+	Offset int `form:"offset"`
+	Limit  int `form:"limit" binding:"required,max=100"`
+}
+
+func TestBuilder_Build(t *testing.T) {
+	server := gin.New()
+	ginx.HandleReq(server, http.MethodPost, "/users/signup", func(ctx *gin.Context, req signUpReq) (ginx.Result, error) {
+		return ginx.Result{}, nil
+	})
+	// 分组注册的要用完整的路径
+	pubGroup := server.Group("/articles").Group("/pub")
+	ginx.Handle(pubGroup, http.MethodGet, "/:id", ginx.WrapOptionalClaims(func(ctx *gin.Context, uc ginx.UserClaims) (ginx.Result, error) {
+		return ginx.Result{}, nil
+	}), ginx.WithResp[articleVo]())
+	ginx.HandleReq(server, http.MethodGet, "/articles/list", func(ctx *gin.Context, req listReq) (ginx.Result, error) {
+		return ginx.Result{}, nil
+	})
+	ginx.Handle(server, http.MethodGet, "/users/profile", func(ctx *gin.Context) {},
+		ginx.WithRawResp[authorVo]())
+	server.DELETE("/admin/users/:id", func(ctx *gin.Context) {})
+
+	doc := NewBuilder("webook", "v1").
+		Auth(func(method, path string) Auth {
+			switch path {
+			case "/users/signup":
+				return AuthPublic
+			case "/articles/pub/:id":
+				return AuthOptional
+			default:
+				return AuthRequired
+			}
+		}).
+		Permission(func(method, path string) (string, bool) {
+			return "user:delete", path == "/admin/users/:id"
+		}).
+		Build(server.Routes())
+
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Equal(t, []SecurityRequirement{{SecuritySchemeName: {}}}, doc.Security)
+
+	signUp := doc.Paths["/users/signup"]["post"]
+	require.NotNil(t, signUp)
+	assert.Equal(t, "post_users_signup", signUp.OperationId)
+	assert.Equal(t, []string{"users"}, signUp.Tags)
+	assert.Equal(t, &[]SecurityRequirement{}, signUp.Security)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/signUpReq"},
+		signUp.RequestBody.Content[jsonContentType].Schema)
+	maxLen := 1024
+	assert.Equal(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"email":    {Type: "string", Format: "email"},
+			"password": {Type: "string", Format: "password", Pattern: ginx.PasswordRegexPattern},
+			"aboutMe":  {Type: "string", MaxLength: &maxLen},
+		},
+		Required: []string{"email", "password"},
+	}, doc.Components.Schemas["signUpReq"])
+
+	pub := doc.Paths["/articles/pub/{id}"]["get"]
+	require.NotNil(t, pub)
+	assert.Equal(t, []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}}, pub.Parameters)
+	assert.Equal(t, &[]SecurityRequirement{{}, {SecuritySchemeName: {}}}, pub.Security)
+	assert.Nil(t, pub.RequestBody)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/articleVo"},
+		pub.Responses["200"].Content[jsonContentType].Schema.Properties["data"])
+	assert.Equal(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"id":     {Type: "integer", Format: "int64"},
+			"title":  {Type: "string"},
+			"tags":   {Type: "array", Items: &Schema{Type: "string"}},
+			"author": {Ref: "#/components/schemas/authorVo"},
+			"ctime":  {Type: "string", Format: "date-time"},
+		},
+	}, doc.Components.Schemas["articleVo"])
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/authorVo"},
+		doc.Components.Schemas["authorVo"].Properties["next"])
+
+	list := doc.Paths["/articles/list"]["get"]
+	require.NotNil(t, list)
+	maxLimit := float64(100)
+	assert.Equal(t, []Parameter{
+		{Name: "offset", In: "query", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "limit", In: "query", Required: true, Schema: &Schema{Type: "integer", Format: "int32", Maximum: &maxLimit}},
+	}, list.Parameters)
+	assert.Nil(t, list.Security)
+
+	profile := doc.Paths["/users/profile"]["get"]
+	require.NotNil(t, profile)
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/authorVo"},
+		profile.Responses["200"].Content[jsonContentType].Schema)
+
+	del := doc.Paths["/admin/users/{id}"]["delete"]
+	require.NotNil(t, del)
+	assert.Equal(t, "user:delete", del.Permission)
+	assert.Contains(t, del.Responses, "403")
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/Result"},
+		del.Responses["200"].Content[jsonContentType].Schema)
+}
+
+func TestBuilder_Handler(t *testing.T) {
+	server := gin.New()
+	server.GET("/openapi.json", NewBuilder("webook", "v1").Handler(server))
+	// 在文档的路由之后注册的也要出现在文档里面
+	ginx.HandleReq(server, http.MethodPost, "/users/signup", func(ctx *gin.Context, req signUpReq) (ginx.Result, error) {
+		return ginx.Result{}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var doc Document
+	err = json.NewDecoder(recorder.Body).Decode(&doc)
+	require.NoError(t, err)
+	assert.Equal(t, Info{Title: "webook", Version: "v1"}, doc.Info)
+	assert.Contains(t, doc.Paths, "/users/signup")
+	assert.Contains(t, doc.Paths, "/openapi.json")
+}
@@ -0,0 +1,176 @@
+package openapi
+
+import (
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemas 把 Go 的类型转换成 Schema，有名字的结构体放到 components 里面
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+func (s *schemas) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json 会把 []byte 编码成 base64
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.register(t)}
+	default:
+		// interface 之类的，什么都可能是
+		return &Schema{}
+	}
+}
+
+// register 注册有名字的结构体，返回在 components 里面的名字。
+// 先占住名字再解析字段，这样递归的结构体也不会死循环
+func (s *schemas) register(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, ok := s.components[name]; ok {
+		// 不同的包里面有同名的结构体，带上包名
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	s.names[t] = name
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.structSchema(t)
+	return name
+}
+
+func (s *schemas) structSchema(t reflect.Type) *Schema {
+	res := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.fields(t, res)
+	return res
+}
+
+func (s *schemas) fields(t reflect.Type, res *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			// 和 encoding/json 一样，组合进来的结构体的字段平铺
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.fields(ft, res)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := s.schemaOf(f.Type)
+		if applyBinding(fs, f.Tag.Get("binding")) {
+			res.Required = append(res.Required, name)
+		}
+		res.Properties[name] = fs
+	}
+}
+
+// applyBinding 把 binding 标签里面的校验规则翻译成 Schema 的约束，返回是不是必传的
+func applyBinding(fs *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "required" {
+			required = true
+			continue
+		}
+		if fs.Ref != "" {
+			// $ref 旁边的约束会被忽略
+			continue
+		}
+		switch name {
+		case "email":
+			fs.Format = "email"
+		case "password":
+			fs.Format = "password"
+			fs.Pattern = ginx.PasswordRegexPattern
+		case "phone":
+			fs.Pattern = ginx.PhoneRegexPattern
+		case "date":
+			fs.Format = "date"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				fs.Enum = append(fs.Enum, v)
+			}
+		case "min", "max", "len":
+			applyRange(fs, name, param)
+		}
+	}
+	return required
+}
+
+func applyRange(fs *Schema, name string, param string) {
+	val, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch fs.Type {
+	case "string":
+		n := int(val)
+		if name != "max" {
+			fs.MinLength = &n
+		}
+		if name != "min" {
+			fs.MaxLength = &n
+		}
+	case "integer", "number":
+		if name != "max" {
+			fs.Minimum = &val
+		}
+		if name != "min" {
+			fs.Maximum = &val
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package ginx
+
+import (
+	"errors"
+	"gitee.com/geekbang/basic-go/webook/pkg/errs"
+	regexp "github.com/dlclark/regexp2"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EmailRegexPattern = "^\\w+([-+.]\\w+)*@\\w+([-.]\\w+)*\\.\\w+([-.]\\w+)*$"
+	// PasswordRegexPattern 必须包含字母、数字、特殊字符，并且长度不能小于 8 位
+	PasswordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,}$`
+	// PhoneRegexPattern 中国大陆的手机号码
+	PhoneRegexPattern = `^1[3-9]\d{9}$`
+)
+
+var (
+	emailRegexExp    = regexp.MustCompile(EmailRegexPattern, regexp.None)
+	passwordRegexExp = regexp.MustCompile(PasswordRegexPattern, regexp.None)
+	phoneRegexExp    = regexp.MustCompile(PhoneRegexPattern, regexp.None)
+)
+
+// Validator 请求实现了这个接口的话，Wrap 系列的方法在校验完 binding 标签之后会调用 Validate，
+// 用来处理跨字段的规则，比如说两次输入的密码要一样
+type Validator interface {
+	Validate() error
+}
+
+// InvalidInputer 请求实现了这个接口的话，解析或者校验失败的时候用它返回的错误码，
+// 没有实现的用通用的 errs.InvalidInput。各个模块最好用自己的参数错误码，
+// 这样同一个接口不管是哪条规则没通过，返回的错误码都是一样的
+type InvalidInputer interface {
+	InvalidInput() *errs.Error
+}
+
+// FieldError 某个字段校验失败，Validate 里面也可以直接返回它来告诉前端是哪个字段不对
+type FieldError struct {
+	// Field 用的是 json 标签里面的名字
+	Field string
+	Tag   string
+	Err   *errs.Error
+}
+
+func (f FieldError) Error() string {
+	return f.Field + ": " + f.Err.Error()
+}
+
+func (f FieldError) Unwrap() error {
+	return f.Err
+}
+
+// ValidationErrors 多个字段校验失败，错误码和提示用第一个字段的
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, fe := range v {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (v ValidationErrors) Unwrap() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v[0].Err
+}
+
+// FieldResult 放在 Result.Data 里面返回给前端的字段错误
+type FieldResult struct {
+	Field string `json:"field"`
+	Tag   string `json:"tag"`
+	Msg   string `json:"msg"`
+}
+
+type tagMsg struct {
+	zh string
+	en string
+}
+
+var (
+	tagMsgsMu sync.RWMutex
+	// tagMsgs 校验失败的提示，{field} 会被替换成字段名，{param} 会被替换成标签的参数。
+	// min、max、len 用在字符串、切片上的时候，用的是带 _len 后缀的提示
+	tagMsgs = map[string]tagMsg{
+		"required": {zh: "{field} 不能为空", en: "{field} is required"},
+		"email":    {zh: "邮箱格式不对", en: "invalid email"},
+		"password": {zh: "密码必须包含数字、特殊字符，并且长度不能小于 8 位",
+			en: "the password must contain digits and special characters and be at least 8 characters long"},
+		"phone":   {zh: "手机号码格式不对", en: "invalid phone number"},
+		"date":    {zh: "{field} 日期格式不对，应该是 2006-01-02", en: "{field} must be a date like 2006-01-02"},
+		"min":     {zh: "{field} 不能小于 {param}", en: "{field} must be at least {param}"},
+		"max":     {zh: "{field} 不能大于 {param}", en: "{field} must be at most {param}"},
+		"min_len": {zh: "{field} 长度不能小于 {param}", en: "{field} must be at least {param} characters long"},
+		"max_len": {zh: "{field} 长度不能超过 {param}", en: "{field} must be at most {param} characters long"},
+		"len_len": {zh: "{field} 长度必须是 {param}", en: "{field} must be {param} characters long"},
+		"oneof":   {zh: "{field} 必须是 {param} 中的一个", en: "{field} must be one of {param}"},
+	}
+	defaultTagMsg = tagMsg{zh: "{field} 格式不对", en: "{field} is invalid"}
+)
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// 返回给前端的字段名要和 JSON 里面的一样
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	// 这里覆盖了 validator 自带的 email，和之前在 handler 里面用的正则表达式保持一致
+	mustRegisterValidation(v, "email", regexValidation(emailRegexExp))
+	mustRegisterValidation(v, "password", regexValidation(passwordRegexExp))
+	mustRegisterValidation(v, "phone", regexValidation(phoneRegexExp))
+	mustRegisterValidation(v, "date", func(fl validator.FieldLevel) bool {
+		// 也就是说，我们其实并没有直接校验具体的格式
+		// 而是如果你能转化过来，那就说明没问题
+		_, err := time.Parse(time.DateOnly, fl.Field().String())
+		return err == nil
+	})
+}
+
+// RegisterValidation 注册自定义的校验规则，zh 和 en 是校验失败的提示，
+// 可以使用 {field} 和 {param} 占位符
+func RegisterValidation(tag string, fn validator.Func, zh string, en string) error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("ginx: 不支持的校验引擎")
+	}
+	if err := v.RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+	tagMsgsMu.Lock()
+	defer tagMsgsMu.Unlock()
+	tagMsgs[tag] = tagMsg{zh: zh, en: en}
+	return nil
+}
+
+func mustRegisterValidation(v *validator.Validate, tag string, fn validator.Func) {
+	if err := v.RegisterValidation(tag, fn); err != nil {
+		panic(err)
+	}
+}
+
+func regexValidation(exp *regexp.Regexp) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		ok, err := exp.MatchString(fl.Field().String())
+		return err == nil && ok
+	}
+}
+
+// bind 解析请求并且校验，返回的 error 都是带错误码的
+func bind[Req any](ctx *gin.Context) (Req, error) {
+	var req Req
+	invalid := errs.InvalidInput
+	if ii, ok := any(&req).(InvalidInputer); ok {
+		invalid = ii.InvalidInput()
+	}
+	if err := ctx.ShouldBind(&req); err != nil {
+		var ves validator.ValidationErrors
+		if errors.As(err, &ves) {
+			return req, toValidationErrors(ves, invalid)
+		}
+		// JSON 格式不对之类的
+		return req, invalid.Wrap(err)
+	}
+	if v, ok := any(&req).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			if _, ok := errs.From(err); ok {
+				return req, err
+			}
+			return req, invalid.Wrap(err)
+		}
+	}
+	return req, nil
+}
+
+// toValidationErrors invalid 是校验失败的错误码，提示换成对应规则的
+func toValidationErrors(ves validator.ValidationErrors, invalid *errs.Error) ValidationErrors {
+	res := make(ValidationErrors, 0, len(ves))
+	tagMsgsMu.RLock()
+	defer tagMsgsMu.RUnlock()
+	for _, fe := range ves {
+		key := fe.Tag()
+		switch fe.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			if _, ok := tagMsgs[key+"_len"]; ok {
+				key = key + "_len"
+			}
+		}
+		msg, ok := tagMsgs[key]
+		if !ok {
+			msg = defaultTagMsg
+		}
+		r := strings.NewReplacer("{field}", fe.Field(), "{param}", fe.Param())
+		res = append(res, FieldError{
+			Field: fe.Field(),
+			Tag:   fe.Tag(),
+			Err:   invalid.WithMsg(r.Replace(msg.zh), r.Replace(msg.en)),
+		})
+	}
+	return res
+}
+
+// fieldResults 取出 err 里面的字段错误，没有的话返回 nil
+func fieldResults(err error, lang string) []FieldResult {
+	var ves ValidationErrors
+	if !errors.As(err, &ves) {
+		var fe FieldError
+		if !errors.As(err, &fe) {
+			return nil
+		}
+		ves = ValidationErrors{fe}
+	}
+	res := make([]FieldResult, 0, len(ves))
+	for _, fe := range ves {
+		res = append(res, FieldResult{
+			Field: fe.Field,
+			Tag:   fe.Tag,
+			Msg:   fe.Err.Message(lang),
+		})
+	}
+	return res
+}
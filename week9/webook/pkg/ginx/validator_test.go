@@ -0,0 +1,224 @@
+package ginx
+
+import (
+	"bytes"
+	"encoding/json"
+	"gitee.com/geekbang/basic-go/webook/pkg/errs"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testReq struct {
+	Email           string `json:"email" binding:"required,email"`
+	Password        string `json:"password" binding:"required,password"`
+	ConfirmPassword string `json:"confirmPassword"`
+	Phone           string `json:"phone" binding:"omitempty,phone"`
+	Birthday        string `json:"birthday" binding:"omitempty,date"`
+	AboutMe         string `json:"aboutMe" binding:"max=4"`
+}
+
+func (r testReq) Validate() error {
+	if r.Password != r.ConfirmPassword {
+		return FieldError{
+			Field: "confirmPassword",
+			Tag:   "eqfield",
+			Err:   errs.InvalidInput.WithMsg("两次输入密码不对", "the two passwords do not match"),
+		}
+	}
+	return nil
+}
+
+func TestWrapReq_Validate(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		lang string
+
+		wantRes Result
+	}{
+		{
+			name:    "校验通过",
+			body:    `{"email":"123@qq.com","password":"hello#world123","confirmPassword":"hello#world123","phone":"15212345678","birthday":"2000-01-01"}`,
+			wantRes: Result{Msg: "OK"},
+		},
+		{
+			name:    "非 JSON 输入",
+			body:    `{"email":"123@qq.com",`,
+			wantRes: Result{Code: errs.InvalidInput.Code, Msg: "参数错误"},
+		},
+		{
+			name: "多个字段不对",
+			body: `{"email":"123@","phone":"12345","birthday":"2000/01/01","aboutMe":"hello"}`,
+			wantRes: Result{Code: errs.InvalidInput.Code, Msg: "邮箱格式不对", Data: []any{
+				map[string]any{"field": "email", "tag": "email", "msg": "邮箱格式不对"},
+				map[string]any{"field": "password", "tag": "required", "msg": "password 不能为空"},
+				map[string]any{"field": "phone", "tag": "phone", "msg": "手机号码格式不对"},
+				map[string]any{"field": "birthday", "tag": "date", "msg": "birthday 日期格式不对，应该是 2006-01-02"},
+				map[string]any{"field": "aboutMe", "tag": "max", "msg": "aboutMe 长度不能超过 4"},
+			}},
+		},
+		{
+			name: "英文提示",
+			body: `{"email":"123@qq.com"}`,
+			lang: "en",
+			wantRes: Result{Code: errs.InvalidInput.Code, Msg: "password is required", Data: []any{
+				map[string]any{"field": "password", "tag": "required", "msg": "password is required"},
+			}},
+		},
+		{
+			name: "Validate 不通过",
+			body: `{"email":"123@qq.com","password":"hello#world123","confirmPassword":"hello#world"}`,
+			wantRes: Result{Code: errs.InvalidInput.Code, Msg: "两次输入密码不对", Data: []any{
+				map[string]any{"field": "confirmPassword", "tag": "eqfield", "msg": "两次输入密码不对"},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.POST("/test", WrapReq[testReq](func(ctx *gin.Context, req testReq) (Result, error) {
+				return Result{Msg: "OK"}, nil
+			}))
+			req, err := http.NewRequest(http.MethodPost, "/test", bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept-Language", tc.lang)
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+var testInvalidInput = errs.Register(499001, "TestInvalidInput", "参数错误", "invalid input")
+
+type moduleReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+func (r moduleReq) InvalidInput() *errs.Error {
+	return testInvalidInput
+}
+
+func TestWrapReq_InvalidInputer(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+
+		wantRes Result
+	}{
+		{
+			name:    "非 JSON 输入",
+			body:    `{"email":`,
+			wantRes: Result{Code: testInvalidInput.Code, Msg: "参数错误"},
+		},
+		{
+			name: "校验不通过",
+			body: `{"email":"123@"}`,
+			wantRes: Result{Code: testInvalidInput.Code, Msg: "邮箱格式不对", Data: []any{
+				map[string]any{"field": "email", "tag": "email", "msg": "邮箱格式不对"},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.POST("/test", WrapReq[moduleReq](func(ctx *gin.Context, req moduleReq) (Result, error) {
+				return Result{Msg: "OK"}, nil
+			}))
+			req, err := http.NewRequest(http.MethodPost, "/test", bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			var res Result
+			err = json.NewDecoder(recorder.Body).Decode(&res)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func TestEmailPattern(t *testing.T) {
+	testCases := []struct {
+		name  string
+		email string
+		match bool
+	}{
+		{
+			name:  "不带@",
+			email: "123456",
+			match: false,
+		},
+		{
+			name:  "带@ 但是没后缀",
+			email: "123456@",
+			match: false,
+		},
+		{
+			name:  "合法邮箱",
+			email: "123456@qq.com",
+			match: true,
+		},
+	}
+
+	v := binding.Validator.Engine().(*validator.Validate)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.Var(tc.email, "email")
+			assert.Equal(t, tc.match, err == nil)
+		})
+	}
+}
+
+func TestPasswordPattern(t *testing.T) {
+	testCases := []struct {
+		name     string
+		password string
+		match    bool
+	}{
+		{
+			name:     "合法密码",
+			password: "Hello#world123",
+			match:    true,
+		},
+		{
+			name:     "没有数字",
+			password: "Hello#world",
+			match:    false,
+		},
+		{
+			name:     "没有特殊字符",
+			password: "Helloworld123",
+			match:    false,
+		},
+		{
+			name:     "长度不足",
+			password: "he!123",
+			match:    false,
+		},
+	}
+
+	v := binding.Validator.Engine().(*validator.Validate)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := v.Var(tc.password, "password")
+			assert.Equal(t, tc.match, err == nil)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package imagex 一些简单的图片处理，只用标准库
+package imagex
+
+import (
+	"image"
+	"image/color"
+)
+
+// Thumbnail 从中间裁剪出最大的正方形，再缩放成 size * size
+// 缩放用的是区域平均，缩小的时候效果还可以，放大的时候相当于最近邻
+// 透明的部分会被填充成白色，因为结果一般是编码成 JPEG 的
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	if side == 0 {
+		return dst
+	}
+	for dy := 0; dy < size; dy++ {
+		sy0, sy1 := span(dy, size, side)
+		for dx := 0; dx < size; dx++ {
+			sx0, sx1 := span(dx, size, side)
+			var r, g, bl, cnt uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					sr, sg, sb, sa := src.At(x0+sx, y0+sy).RGBA()
+					// 预乘过 alpha 的，所以补上白色的部分就可以了
+					r += uint64(sr + 0xffff - sa)
+					g += uint64(sg + 0xffff - sa)
+					bl += uint64(sb + 0xffff - sa)
+					cnt++
+				}
+			}
+			dst.SetRGBA(dx, dy, color.RGBA{
+				R: uint8(r / cnt >> 8),
+				G: uint8(g / cnt >> 8),
+				B: uint8(bl / cnt >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
+
+// span 目标图第 i 个像素对应原图的 [start, end)，至少有一个像素
+func span(i, size, side int) (int, int) {
+	start := i * side / size
+	end := (i + 1) * side / size
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
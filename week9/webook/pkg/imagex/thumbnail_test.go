@@ -0,0 +1,75 @@
+package imagex
+
+import (
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  func() image.Image
+		size int
+
+		wantPixels map[image.Point]color.RGBA
+	}{
+		{
+			name: "宽图裁剪中间",
+			src: func() image.Image {
+				// 左右两边是红色，中间 4x4 是蓝色
+				img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+				for x := 0; x < 8; x++ {
+					for y := 0; y < 4; y++ {
+						c := color.RGBA{R: 0xff, A: 0xff}
+						if x >= 2 && x < 6 {
+							c = color.RGBA{B: 0xff, A: 0xff}
+						}
+						img.SetRGBA(x, y, c)
+					}
+				}
+				return img
+			},
+			size: 2,
+			wantPixels: map[image.Point]color.RGBA{
+				{X: 0, Y: 0}: {B: 0xff, A: 0xff},
+				{X: 1, Y: 1}: {B: 0xff, A: 0xff},
+			},
+		},
+		{
+			name: "缩小取平均",
+			src: func() image.Image {
+				// 黑白相间
+				img := image.NewGray(image.Rect(0, 0, 2, 2))
+				img.SetGray(0, 0, color.Gray{Y: 0xff})
+				img.SetGray(1, 1, color.Gray{Y: 0xff})
+				return img
+			},
+			size: 1,
+			wantPixels: map[image.Point]color.RGBA{
+				{X: 0, Y: 0}: {R: 0x7f, G: 0x7f, B: 0x7f, A: 0xff},
+			},
+		},
+		{
+			name: "透明填充白色",
+			src: func() image.Image {
+				return image.NewNRGBA(image.Rect(0, 0, 3, 3))
+			},
+			size: 6,
+			wantPixels: map[image.Point]color.RGBA{
+				{X: 0, Y: 0}: {R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+				{X: 5, Y: 5}: {R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := Thumbnail(tc.src(), tc.size)
+			assert.Equal(t, image.Rect(0, 0, tc.size, tc.size), dst.Bounds())
+			for p, c := range tc.wantPixels {
+				assert.Equal(t, c, dst.RGBAAt(p.X, p.Y), p.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"golang.org/x/net/context"
+)
+
+var _ ResultLimiter = (*FailoverLimiter)(nil)
+
+// FailoverLimiter primary 出错的时候用 fallback。
+// 一般 primary 是 Redis 的，fallback 是本地的，
+// 这样 Redis 崩溃的时候还能限流，不至于全部放行或者全部拒绝
+type FailoverLimiter struct {
+	primary  ResultLimiter
+	fallback ResultLimiter
+}
+
+func NewFailoverLimiter(primary ResultLimiter, fallback ResultLimiter) *FailoverLimiter {
+	return &FailoverLimiter{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+func (f *FailoverLimiter) Limit(ctx context.Context, key string) (bool, error) {
+	res, err := f.Acquire(ctx, key)
+	return res.Limited, err
+}
+
+func (f *FailoverLimiter) Acquire(ctx context.Context, key string) (Result, error) {
+	res, err := f.primary.Acquire(ctx, key)
+	if err == nil {
+		return res, nil
+	}
+	// 这里不记录日志，Redis 崩溃的时候每个请求都会走到这里。
+	// Redis 本身的监控会告警
+	return f.fallback.Acquire(ctx, key)
+}
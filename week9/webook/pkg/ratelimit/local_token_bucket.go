@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	lru "github.com/hashicorp/golang-lru"
+	"golang.org/x/net/context"
+	"math"
+	"sync"
+	"time"
+)
+
+var _ ResultLimiter = (*LocalTokenBucketLimiter)(nil)
+
+// LocalTokenBucketLimiter 本地的令牌桶，一般是 Redis 不可用的时候降级用的。
+// 注意每个实例都是单独计数的，所以整个集群的阈值是 rate 乘以实例数量
+type LocalTokenBucketLimiter struct {
+	// buckets 用 LRU 免得 key 太多把内存撑爆，被淘汰的 key 相当于桶是满的
+	buckets  *lru.Cache
+	mutex    sync.Mutex
+	interval time.Duration
+	rate     int
+	capacity int
+	now      func() time.Time
+}
+
+type localBucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+// NewLocalTokenBucketLimiter capacity 小于 rate 的时候用 rate
+func NewLocalTokenBucketLimiter(buckets *lru.Cache, interval time.Duration,
+	rate int, capacity int) *LocalTokenBucketLimiter {
+	if capacity < rate {
+		capacity = rate
+	}
+	return &LocalTokenBucketLimiter{
+		buckets:  buckets,
+		interval: interval,
+		rate:     rate,
+		capacity: capacity,
+		now:      time.Now,
+	}
+}
+
+func (l *LocalTokenBucketLimiter) Limit(ctx context.Context, key string) (bool, error) {
+	res, err := l.Acquire(ctx, key)
+	return res.Limited, err
+}
+
+func (l *LocalTokenBucketLimiter) Acquire(ctx context.Context, key string) (Result, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	var b *localBucket
+	if val, ok := l.buckets.Get(key); ok {
+		b = val.(*localBucket)
+	} else {
+		b = &localBucket{tokens: float64(l.capacity), ts: now}
+		l.buckets.Add(key, b)
+	}
+	// 每纳秒生成多少个令牌
+	speed := float64(l.rate) / float64(l.interval)
+	if elapsed := now.Sub(b.ts); elapsed > 0 {
+		b.tokens = math.Min(float64(l.capacity), b.tokens+float64(elapsed)*speed)
+	}
+	b.ts = now
+	res := Result{Limit: l.capacity}
+	if b.tokens < 1 {
+		res.Limited = true
+		res.RetryAfter = time.Duration(math.Ceil((1 - b.tokens) / speed))
+	} else {
+		b.tokens--
+	}
+	res.Remaining = int(b.tokens)
+	res.ResetAfter = time.Duration(math.Ceil((float64(l.capacity) - b.tokens) / speed))
+	return res, nil
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestLocalTokenBucketLimiter_Acquire(t *testing.T) {
+	c, err := lru.New(10)
+	require.NoError(t, err)
+	// 每秒 2 个令牌，最多 3 个
+	limiter := NewLocalTokenBucketLimiter(c, time.Second, 2, 3)
+	now := time.UnixMilli(1000000)
+	limiter.now = func() time.Time {
+		return now
+	}
+
+	testCases := []struct {
+		name    string
+		elapsed time.Duration
+		key     string
+
+		wantRes Result
+	}{
+		{
+			name:    "桶是满的",
+			key:     "key1",
+			wantRes: Result{Limit: 3, Remaining: 2, ResetAfter: time.Millisecond * 500},
+		},
+		{
+			name:    "用掉第二个",
+			key:     "key1",
+			wantRes: Result{Limit: 3, Remaining: 1, ResetAfter: time.Second},
+		},
+		{
+			name:    "用掉第三个",
+			key:     "key1",
+			wantRes: Result{Limit: 3, Remaining: 0, ResetAfter: time.Millisecond * 1500},
+		},
+		{
+			name: "没有令牌了",
+			key:  "key1",
+			wantRes: Result{Limited: true, Limit: 3, Remaining: 0,
+				RetryAfter: time.Millisecond * 500, ResetAfter: time.Millisecond * 1500},
+		},
+		{
+			name:    "别的 key 不受影响",
+			key:     "key2",
+			wantRes: Result{Limit: 3, Remaining: 2, ResetAfter: time.Millisecond * 500},
+		},
+		{
+			name:    "过了半秒生成了一个",
+			elapsed: time.Millisecond * 500,
+			key:     "key1",
+			wantRes: Result{Limit: 3, Remaining: 0, ResetAfter: time.Millisecond * 1500},
+		},
+		{
+			name:    "很久之后桶是满的",
+			elapsed: time.Hour,
+			key:     "key1",
+			wantRes: Result{Limit: 3, Remaining: 2, ResetAfter: time.Millisecond * 500},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now = now.Add(tc.elapsed)
+			res, err := limiter.Acquire(context.Background(), tc.key)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+type resultLimiterFunc func(ctx context.Context, key string) (Result, error)
+
+func (f resultLimiterFunc) Limit(ctx context.Context, key string) (bool, error) {
+	res, err := f(ctx, key)
+	return res.Limited, err
+}
+
+func (f resultLimiterFunc) Acquire(ctx context.Context, key string) (Result, error) {
+	return f(ctx, key)
+}
+
+func TestFailoverLimiter_Acquire(t *testing.T) {
+	fallback := resultLimiterFunc(func(ctx context.Context, key string) (Result, error) {
+		return Result{Limited: true, Limit: 1}, nil
+	})
+	testCases := []struct {
+		name    string
+		primary ResultLimiter
+
+		wantRes Result
+	}{
+		{
+			name: "primary 正常",
+			primary: resultLimiterFunc(func(ctx context.Context, key string) (Result, error) {
+				return Result{Limit: 10, Remaining: 9}, nil
+			}),
+			wantRes: Result{Limit: 10, Remaining: 9},
+		},
+		{
+			name: "primary 出错",
+			primary: resultLimiterFunc(func(ctx context.Context, key string) (Result, error) {
+				return Result{}, errors.New("redis 崩溃了")
+			}),
+			wantRes: Result{Limited: true, Limit: 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := NewFailoverLimiter(tc.primary, fallback).Acquire(context.Background(), "key")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
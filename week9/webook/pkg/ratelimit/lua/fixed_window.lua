@@ -0,0 +1,29 @@
+-- 限流对象
+local key = KEYS[1]
+-- 窗口大小
+local window = tonumber(ARGV[1])
+-- 阈值
+local threshold = tonumber(ARGV[2])
+
+-- 返回 {是否限流, 窗口内的请求数, 窗口还有多久结束}
+local cnt = tonumber(redis.call('GET', key) or '0')
+if cnt >= threshold then
+    local ttl = redis.call('PTTL', key)
+    if ttl < 0 then
+        -- 没有过期时间，说明之前设置过期时间失败了，补上
+        redis.call('PEXPIRE', key, window)
+        ttl = window
+    end
+    return {1, cnt, ttl}
+end
+cnt = redis.call('INCR', key)
+if cnt == 1 then
+    -- 窗口的第一个请求
+    redis.call('PEXPIRE', key, window)
+end
+local ttl = redis.call('PTTL', key)
+if ttl < 0 then
+    redis.call('PEXPIRE', key, window)
+    ttl = window
+end
+return {0, cnt, ttl}
@@ -15,12 +15,14 @@ local min = now - window
 redis.call('ZREMRANGEBYSCORE', key, '-inf', min)
 local cnt = redis.call('ZCOUNT', key, '-inf', '+inf')
 -- local cnt = redis.call('ZCOUNT', key, min, '+inf')
+-- 返回 {是否限流, 窗口内的请求数, 最早的请求离开窗口还要多久}
 if cnt >= threshold then
     -- 执行限流
-    return "true"
+    local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+    return {1, cnt, tonumber(oldest[2]) + window - now}
 else
     -- 把 score 和 member 都设置成 now
     redis.call('ZADD', key, now, now)
     redis.call('PEXPIRE', key, window)
-    return "false"
-end
\ No newline at end of file
+    return {0, cnt + 1, window}
+end
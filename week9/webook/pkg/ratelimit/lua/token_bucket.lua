@@ -0,0 +1,36 @@
+-- 限流对象
+local key = KEYS[1]
+-- 每 interval 毫秒生成 rate 个令牌
+local interval = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+-- 桶的容量，也就是允许的突发流量
+local capacity = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+
+local bucket = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+    -- 第一次，桶是满的
+    tokens = capacity
+    ts = now
+end
+-- 补充上一次到现在生成的令牌
+local elapsed = math.max(0, now - ts)
+tokens = math.min(capacity, tokens + elapsed * rate / interval)
+
+local limited = 0
+local retry = 0
+if tokens < 1 then
+    limited = 1
+    -- 等到下一个令牌生成
+    retry = math.ceil((1 - tokens) * interval / rate)
+else
+    tokens = tokens - 1
+end
+redis.call('HSET', key, 'tokens', tokens, 'ts', now)
+-- 桶装满之后，这个 key 和不存在是一样的，所以可以过期掉
+local full = math.ceil((capacity - tokens) * interval / rate)
+redis.call('PEXPIRE', key, math.max(full, 1))
+-- 返回 {是否限流, 剩下的令牌, 多久之后可以重试, 多久之后桶是满的}
+return {limited, math.floor(tokens), retry, full}
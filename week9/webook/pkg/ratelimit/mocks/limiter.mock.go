@@ -11,6 +11,7 @@ package limitmocks
 import (
 	reflect "reflect"
 
+	ratelimit "gitee.com/geekbang/basic-go/webook/pkg/ratelimit"
 	gomock "go.uber.org/mock/gomock"
 	context "golang.org/x/net/context"
 )
@@ -52,3 +53,56 @@ func (mr *MockLimiterMockRecorder) Limit(ctx, key any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Limit", reflect.TypeOf((*MockLimiter)(nil).Limit), ctx, key)
 }
+
+// MockResultLimiter is a mock of ResultLimiter interface.
+type MockResultLimiter struct {
+	ctrl     *gomock.Controller
+	recorder *MockResultLimiterMockRecorder
+}
+
+// MockResultLimiterMockRecorder is the mock recorder for MockResultLimiter.
+type MockResultLimiterMockRecorder struct {
+	mock *MockResultLimiter
+}
+
+// NewMockResultLimiter creates a new mock instance.
+func NewMockResultLimiter(ctrl *gomock.Controller) *MockResultLimiter {
+	mock := &MockResultLimiter{ctrl: ctrl}
+	mock.recorder = &MockResultLimiterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResultLimiter) EXPECT() *MockResultLimiterMockRecorder {
+	return m.recorder
+}
+
+// Acquire mocks base method.
+func (m *MockResultLimiter) Acquire(ctx context.Context, key string) (ratelimit.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acquire", ctx, key)
+	ret0, _ := ret[0].(ratelimit.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Acquire indicates an expected call of Acquire.
+func (mr *MockResultLimiterMockRecorder) Acquire(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acquire", reflect.TypeOf((*MockResultLimiter)(nil).Acquire), ctx, key)
+}
+
+// Limit mocks base method.
+func (m *MockResultLimiter) Limit(ctx context.Context, key string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Limit", ctx, key)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Limit indicates an expected call of Limit.
+func (mr *MockResultLimiterMockRecorder) Limit(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Limit", reflect.TypeOf((*MockResultLimiter)(nil).Limit), ctx, key)
+}
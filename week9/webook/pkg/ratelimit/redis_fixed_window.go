@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	_ "embed"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/net/context"
+	"time"
+)
+
+//go:embed lua/fixed_window.lua
+var fixedWindowLuaScript string
+
+var _ ResultLimiter = (*RedisFixedWindowLimiter)(nil)
+
+// RedisFixedWindowLimiter 固定窗口，比滑动窗口省内存，一个 key 只有一个计数器。
+// 缺点是窗口交界的地方可能会有两倍的流量
+type RedisFixedWindowLimiter struct {
+	cmd      redis.Cmdable
+	interval time.Duration
+	// 阈值
+	rate int
+}
+
+func NewRedisFixedWindowLimiter(cmd redis.Cmdable, interval time.Duration, rate int) *RedisFixedWindowLimiter {
+	return &RedisFixedWindowLimiter{
+		cmd:      cmd,
+		interval: interval,
+		rate:     rate,
+	}
+}
+
+func (r *RedisFixedWindowLimiter) Limit(ctx context.Context, key string) (bool, error) {
+	res, err := r.Acquire(ctx, key)
+	return res.Limited, err
+}
+
+func (r *RedisFixedWindowLimiter) Acquire(ctx context.Context, key string) (Result, error) {
+	vals, err := r.cmd.Eval(ctx, fixedWindowLuaScript, []string{key},
+		r.interval.Milliseconds(), r.rate).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return windowResult(vals, r.rate), nil
+}
@@ -10,6 +10,8 @@ import (
 //go:embed lua/slide_window.lua
 var luaScript string
 
+var _ ResultLimiter = (*RedisSlidingWindowLimiter)(nil)
+
 type RedisSlidingWindowLimiter struct {
 	cmd      redis.Cmdable
 	interval time.Duration
@@ -26,7 +28,33 @@ func NewRedisSlidingWindowLimiter(cmd redis.Cmdable, interval time.Duration, rat
 }
 
 func (r *RedisSlidingWindowLimiter) Limit(ctx context.Context, key string) (bool, error) {
-	return r.cmd.Eval(ctx, luaScript, []string{key},
+	res, err := r.Acquire(ctx, key)
+	return res.Limited, err
+}
+
+func (r *RedisSlidingWindowLimiter) Acquire(ctx context.Context, key string) (Result, error) {
+	vals, err := r.cmd.Eval(ctx, luaScript, []string{key},
 		r.interval.Milliseconds(),
-		r.rate, time.Now().UnixMilli()).Bool()
+		r.rate, time.Now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return windowResult(vals, r.rate), nil
+}
+
+// windowResult 窗口算法的脚本返回的都是 {是否限流, 窗口内的请求数, 毫秒}
+func windowResult(vals []int64, rate int) Result {
+	res := Result{
+		Limited:    vals[0] == 1,
+		Limit:      rate,
+		Remaining:  rate - int(vals[1]),
+		ResetAfter: time.Duration(vals[2]) * time.Millisecond,
+	}
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	if res.Limited {
+		res.RetryAfter = res.ResetAfter
+	}
+	return res
 }
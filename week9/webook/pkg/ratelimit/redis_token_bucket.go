@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	_ "embed"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/net/context"
+	"time"
+)
+
+//go:embed lua/token_bucket.lua
+var tokenBucketLuaScript string
+
+var _ ResultLimiter = (*RedisTokenBucketLimiter)(nil)
+
+// RedisTokenBucketLimiter 令牌桶，每 interval 生成 rate 个令牌，桶里面最多有 capacity 个。
+// 和窗口算法比起来，允许一定的突发流量，但是长期来看速率是平滑的
+type RedisTokenBucketLimiter struct {
+	cmd      redis.Cmdable
+	interval time.Duration
+	rate     int
+	capacity int
+}
+
+// NewRedisTokenBucketLimiter capacity 小于 rate 的时候用 rate
+func NewRedisTokenBucketLimiter(cmd redis.Cmdable, interval time.Duration,
+	rate int, capacity int) *RedisTokenBucketLimiter {
+	if capacity < rate {
+		capacity = rate
+	}
+	return &RedisTokenBucketLimiter{
+		cmd:      cmd,
+		interval: interval,
+		rate:     rate,
+		capacity: capacity,
+	}
+}
+
+func (r *RedisTokenBucketLimiter) Limit(ctx context.Context, key string) (bool, error) {
+	res, err := r.Acquire(ctx, key)
+	return res.Limited, err
+}
+
+func (r *RedisTokenBucketLimiter) Acquire(ctx context.Context, key string) (Result, error) {
+	vals, err := r.cmd.Eval(ctx, tokenBucketLuaScript, []string{key},
+		r.interval.Milliseconds(), r.rate, r.capacity, time.Now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Limited:    vals[0] == 1,
+		Limit:      r.capacity,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		ResetAfter: time.Duration(vals[3]) * time.Millisecond,
+	}, nil
+}
@@ -1,6 +1,9 @@
 package ratelimit
 
-import "golang.org/x/net/context"
+import (
+	"golang.org/x/net/context"
+	"time"
+)
 
 //go:generate mockgen -source=./types.go -package=limitmocks -destination=mocks/limiter.mock.go Limiter
 type Limiter interface {
@@ -8,3 +11,22 @@ type Limiter interface {
 	// 这是一种最简单的定义方式
 	Limit(ctx context.Context, key string) (bool, error)
 }
+
+// ResultLimiter 除了要不要限流，还能告诉调用者还剩多少额度，
+// 用来设置 X-RateLimit-* 和 Retry-After 这些响应头
+type ResultLimiter interface {
+	Limiter
+	Acquire(ctx context.Context, key string) (Result, error)
+}
+
+type Result struct {
+	Limited bool
+	// Limit 一个窗口内允许的请求数，令牌桶的话是桶的容量
+	Limit int
+	// Remaining 这一次之后还剩下多少
+	Remaining int
+	// RetryAfter 被限流之后至少要等多久才能重试，没有被限流的时候是 0
+	RetryAfter time.Duration
+	// ResetAfter 多久之后额度完全恢复
+	ResetAfter time.Duration
+}
@@ -9,6 +9,7 @@ import (
 	dao2 "gitee.com/geekbang/basic-go/webook/interactive/repository/dao"
 	service2 "gitee.com/geekbang/basic-go/webook/interactive/service"
 	article2 "gitee.com/geekbang/basic-go/webook/internal/events/article"
+	"gitee.com/geekbang/basic-go/webook/internal/events/user"
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
@@ -17,6 +18,7 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/web"
 	ijwt "gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	"gitee.com/geekbang/basic-go/webook/ioc"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx/middleware/rbac"
 	"github.com/google/wire"
 )
 
@@ -32,49 +34,93 @@ func InitApp() *App {
 		ioc.InitRedis, ioc.InitDB,
 		ioc.InitLogger,
 		ioc.InitKafka,
-		ioc.InitRLockClient,
+		ioc.InitDLockClient,
 		ioc.NewSyncProducer,
 
 		rankServiceProvider,
 		ioc.InitJobs,
 		ioc.InitRankingJob,
+		ioc.InitAsyncSmsCleanupJob,
+		ioc.InitAsyncSmsMonitorJob,
+		ioc.InitUserDataJob,
 
 		// DAO 部分
 		dao.NewGORMUserDAO,
 		dao2.NewGORMInteractiveDAO,
 		article.NewGORMArticleDAO,
+		dao.NewGORMAsyncSmsDAO,
+		dao.NewGORMOAuth2BindingDAO,
+		dao.NewGORMAccountMergeDAO,
+		dao.NewGORMRBACDAO,
+		dao.NewGORMUserDataDAO,
+		ioc.InitExportFileDAO,
+		ioc.InitAvatarDAO,
 
 		// Cache 部分
 		cache.NewRedisUserCache,
-		cache.NewRedisCodeCache,
+		ioc.InitCodeCache,
 		cache.NewRedisArticleCache,
 		cache2.NewRedisInteractiveCache,
+		cache.NewRedisSessionCache,
+		cache.NewRedisRBACCache,
 
 		// repository 部分
 		repository.NewCachedUserRepository,
 		repository.NewCachedCodeRepository,
 		repository.NewArticleRepository,
 		repository2.NewCachedInteractiveRepository,
+		repository.NewAsyncSMSRepository,
+		repository.NewOAuth2BindingRepository,
+		repository.NewAccountMergeRepository,
+		repository.NewSessionRepository,
+		repository.NewCachedRBACRepository,
+		repository.NewUserDataRepository,
+		repository.NewAvatarRepository,
 
 		// events 部分
 		article2.NewSaramaSyncProducer,
 		events.NewInteractiveReadEventConsumer,
+		events.NewUserDeletedEventConsumer,
+		user.NewSaramaSyncProducer,
 		ioc.NewConsumers,
 
 		// service 部分
 		ioc.InitSmsService,
-		ioc.InitWechatService,
-		service.NewSMSCodeService,
+		ioc.InitSmsAuthService,
+		ioc.InitEmailService,
+		ioc.InitAccountNotifier,
+		ioc.InitOAuth2Providers,
+		ioc.InitCodeService,
+		ioc.InitCodeGuard,
 		service.NewUserService,
+		service.NewProfileService,
 		service.NewArticleService,
 		service2.NewInteractiveService,
+		service.NewAsyncSmsService,
+		service.NewOAuth2Service,
+		service.NewAccountService,
+		service.NewSessionService,
+		service.NewPasswordService,
+		service.NewRBACService,
+		ioc.InitUserDataService,
 
 		// handler 部分
+		ioc.InitJWTKeys,
 		ijwt.NewRedisHandler,
+		ioc.InitAuthRoutes,
 		web.NewUserHandler,
 		web.NewArticleHandler,
-		web.NewOAuth2WechatHandler,
+		web.NewOAuth2Handler,
+		ioc.InitAccountHandler,
+		web.NewSessionHandler,
+		web.NewPasswordHandler,
+		web.NewUserDataHandler,
+		web.NewJWKSHandler,
 		web.NewObservabilityHandler,
+		web.NewAsyncSmsHandler,
+		web.NewRBACHandler,
+		web.NewOpenAPIHandler,
+		rbac.NewRoutes,
 
 		// gin 的中间件
 		ioc.GinMiddlewares,
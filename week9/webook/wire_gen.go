@@ -13,6 +13,7 @@ import (
 	dao2 "gitee.com/geekbang/basic-go/webook/interactive/repository/dao"
 	service2 "gitee.com/geekbang/basic-go/webook/interactive/service"
 	article2 "gitee.com/geekbang/basic-go/webook/internal/events/article"
+	"gitee.com/geekbang/basic-go/webook/internal/events/user"
 	"gitee.com/geekbang/basic-go/webook/internal/repository"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/cache"
 	"gitee.com/geekbang/basic-go/webook/internal/repository/dao"
@@ -21,6 +22,7 @@ import (
 	"gitee.com/geekbang/basic-go/webook/internal/web"
 	"gitee.com/geekbang/basic-go/webook/internal/web/jwt"
 	"gitee.com/geekbang/basic-go/webook/ioc"
+	"gitee.com/geekbang/basic-go/webook/pkg/ginx/middleware/rbac"
 	"github.com/google/wire"
 )
 
@@ -32,47 +34,91 @@ import (
 
 func InitApp() *App {
 	cmdable := ioc.InitRedis()
-	handler := jwt.NewRedisHandler(cmdable)
+	sessionCache := cache.NewRedisSessionCache(cmdable)
+	sessionRepository := repository.NewSessionRepository(sessionCache)
+	sessionService := service.NewSessionService(sessionRepository)
+	keys := ioc.InitJWTKeys()
+	handler := jwt.NewRedisHandler(sessionService, keys)
+	v := ioc.InitAuthRoutes()
 	loggerV1 := ioc.InitLogger()
-	v := ioc.GinMiddlewares(cmdable, handler, loggerV1)
 	db := ioc.InitDB(loggerV1)
+	rbacdao := dao.NewGORMRBACDAO(db)
+	rbacCache := cache.NewRedisRBACCache(cmdable)
+	rbacRepository := repository.NewCachedRBACRepository(rbacdao, rbacCache)
+	rbacService := service.NewRBACService(rbacRepository)
+	routes := rbac.NewRoutes()
+	v2 := ioc.GinMiddlewares(cmdable, handler, v, rbacService, routes, loggerV1)
 	userDAO := dao.NewGORMUserDAO(db)
 	userCache := cache.NewRedisUserCache(cmdable)
 	userRepository := repository.NewCachedUserRepository(userDAO, userCache)
 	userService := service.NewUserService(userRepository)
-	smsService := ioc.InitSmsService()
-	codeCache := cache.NewRedisCodeCache(cmdable)
-	codeRepository := repository.NewCachedCodeRepository(codeCache)
-	codeService := service.NewSMSCodeService(smsService, codeRepository)
-	userHandler := web.NewUserHandler(userService, codeService, handler)
 	articleDAO := article.NewGORMArticleDAO(db)
 	articleCache := cache.NewRedisArticleCache(cmdable)
 	articleRepository := repository.NewArticleRepository(articleDAO, articleCache, userRepository, loggerV1)
-	client := ioc.InitKafka()
-	syncProducer := ioc.NewSyncProducer(client)
-	producer := article2.NewSaramaSyncProducer(syncProducer)
-	articleService := service.NewArticleService(articleRepository, loggerV1, producer)
+	avatarDAO := ioc.InitAvatarDAO()
+	avatarRepository := repository.NewAvatarRepository(avatarDAO)
 	interactiveDAO := dao2.NewGORMInteractiveDAO(db)
 	interactiveCache := cache2.NewRedisInteractiveCache(cmdable)
 	interactiveRepository := repository2.NewCachedInteractiveRepository(interactiveDAO, interactiveCache, loggerV1)
 	interactiveService := service2.NewInteractiveService(interactiveRepository, loggerV1)
+	profileService := service.NewProfileService(userRepository, articleRepository, avatarRepository, interactiveService, loggerV1)
+	asyncSmsDAO := dao.NewGORMAsyncSmsDAO(db)
+	asyncSmsRepository := repository.NewAsyncSMSRepository(asyncSmsDAO)
+	smsService := ioc.InitSmsService(cmdable, asyncSmsRepository, loggerV1)
+	authSMSService := ioc.InitSmsAuthService(smsService, cmdable)
+	emailService := ioc.InitEmailService()
+	codeCache := ioc.InitCodeCache(cmdable, loggerV1)
+	codeRepository := repository.NewCachedCodeRepository(codeCache)
+	codeService := ioc.InitCodeService(smsService, authSMSService, emailService, codeRepository, cmdable, loggerV1)
+	codeGuard := ioc.InitCodeGuard(cmdable, loggerV1)
+	userHandler := web.NewUserHandler(userService, profileService, codeService, codeGuard, handler)
+	client := ioc.InitKafka()
+	syncProducer := ioc.NewSyncProducer(client)
+	producer := article2.NewSaramaSyncProducer(syncProducer)
+	articleService := service.NewArticleService(articleRepository, loggerV1, producer)
 	articleHandler := web.NewArticleHandler(articleService, interactiveService, loggerV1)
 	observabilityHandler := web.NewObservabilityHandler()
-	wechatService := ioc.InitWechatService(loggerV1)
-	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, userService, handler)
-	engine := ioc.InitWebServer(v, userHandler, articleHandler, observabilityHandler, oAuth2WechatHandler, loggerV1)
+	providers := ioc.InitOAuth2Providers(loggerV1)
+	oAuth2BindingDAO := dao.NewGORMOAuth2BindingDAO(db)
+	oAuth2BindingRepository := repository.NewOAuth2BindingRepository(oAuth2BindingDAO)
+	oAuth2Service := service.NewOAuth2Service(oAuth2BindingRepository, userRepository)
+	oAuth2Handler := web.NewOAuth2Handler(providers, oAuth2Service, handler)
+	accountMergeDAO := dao.NewGORMAccountMergeDAO(db)
+	accountMergeRepository := repository.NewAccountMergeRepository(accountMergeDAO, userCache)
+	accountNotifier := ioc.InitAccountNotifier(smsService, emailService)
+	accountService := service.NewAccountService(userRepository, oAuth2BindingRepository, accountMergeRepository, sessionRepository, accountNotifier, loggerV1)
+	accountHandler := ioc.InitAccountHandler(accountService, codeService, codeGuard)
+	sessionHandler := web.NewSessionHandler(sessionService, handler)
+	passwordService := service.NewPasswordService(userRepository, sessionRepository, loggerV1)
+	passwordHandler := web.NewPasswordHandler(passwordService, codeService, codeGuard)
+	userDataDAO := dao.NewGORMUserDataDAO(db)
+	exportFileDAO := ioc.InitExportFileDAO()
+	userDataRepository := repository.NewUserDataRepository(userDataDAO, exportFileDAO)
+	userProducer := user.NewSaramaSyncProducer(syncProducer)
+	userDataService := ioc.InitUserDataService(userDataRepository, userRepository, oAuth2BindingRepository, sessionRepository, articleRepository, interactiveService, userProducer, loggerV1)
+	userDataHandler := web.NewUserDataHandler(userDataService)
+	jwksHandler := web.NewJWKSHandler(keys)
+	asyncSmsService := service.NewAsyncSmsService(asyncSmsRepository)
+	asyncSmsHandler := web.NewAsyncSmsHandler(asyncSmsService)
+	rbacHandler := web.NewRBACHandler(rbacService)
+	openAPIHandler := web.NewOpenAPIHandler(v, routes)
+	engine := ioc.InitWebServer(v2, userHandler, articleHandler, observabilityHandler, oAuth2Handler, accountHandler, sessionHandler, passwordHandler, userDataHandler, jwksHandler, asyncSmsHandler, rbacHandler, openAPIHandler, loggerV1)
 	interactiveReadEventConsumer := events.NewInteractiveReadEventConsumer(client, loggerV1, interactiveRepository)
-	v2 := ioc.NewConsumers(interactiveReadEventConsumer)
+	userDeletedEventConsumer := events.NewUserDeletedEventConsumer(client, loggerV1, interactiveRepository)
+	v3 := ioc.NewConsumers(interactiveReadEventConsumer, userDeletedEventConsumer)
 	redisRankingCache := cache.NewRedisRankingCache(cmdable)
 	rankingLocalCache := cache.NewRankingLocalCache()
 	rankingRepository := repository.NewCachedRankingRepository(redisRankingCache, rankingLocalCache)
 	rankingService := service.NewBatchRankingService(interactiveService, articleService, rankingRepository)
-	rlockClient := ioc.InitRLockClient(cmdable)
-	rankingJob := ioc.InitRankingJob(rankingService, rlockClient, loggerV1)
-	cron := ioc.InitJobs(loggerV1, rankingJob)
+	dlockClient := ioc.InitDLockClient(cmdable, db)
+	rankingJob := ioc.InitRankingJob(rankingService, dlockClient, loggerV1)
+	asyncSmsCleanupJob := ioc.InitAsyncSmsCleanupJob(asyncSmsService, loggerV1)
+	asyncSmsMonitorJob := ioc.InitAsyncSmsMonitorJob(asyncSmsService, loggerV1)
+	userDataJob := ioc.InitUserDataJob(userDataService, loggerV1)
+	cron := ioc.InitJobs(loggerV1, rankingJob, asyncSmsCleanupJob, asyncSmsMonitorJob, userDataJob)
 	app := &App{
 		web:       engine,
-		consumers: v2,
+		consumers: v3,
 		cron:      cron,
 	}
 	return app